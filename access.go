@@ -0,0 +1,175 @@
+package sunduk
+
+import (
+	"sort"
+	"sunduk/internal/format"
+	"sync"
+	"time"
+)
+
+// KeyStat holds statistics of an entry, see Stat
+type KeyStat struct {
+	Size       int64     // Size is the size of the chunk of the value in the store file, 0 for pending writes
+	RawSize    int64     // RawSize is the size of the value
+	Reads      uint64    // Reads is the count of reads of the key, 0 without WithAccessStats
+	LastAccess time.Time // LastAccess is the time of the last read, or write with WithSizeCap, zero if the key wasn't read or without WithAccessStats
+}
+
+// Stat returns statistics of the entry of key, as well as a bool that indicates whether an entry exists
+// for that key. Read counts of keys tell hot entries to pre-load and cold entries to evict, see WithAccessStats
+func (store *Sunduk) Stat(key string) (KeyStat, bool) {
+	store.mu.RLock()
+	e, ok := store.lookup(key)
+	store.mu.RUnlock()
+	if !ok {
+		return KeyStat{}, false
+	}
+	stat := KeyStat{Size: e.Size, RawSize: e.RawSize}
+	if a, ok := store.access.get(key); ok {
+		stat.Reads = a.reads
+		stat.LastAccess = time.Unix(0, a.last)
+	}
+	return stat, true
+}
+
+// accessStats counts reads of keys, see WithAccessStats
+type accessStats struct {
+	mu      sync.Mutex
+	keys    map[string]keyAccess
+	reads   uint64 // reads is the count of reads counted since the store was opened
+	flushed uint64 // flushed is the count of reads when the statistics were last committed
+}
+
+// keyAccess holds the access statistics of a key
+type keyAccess struct {
+	reads   uint64
+	last    int64 // last is the time of the last read, or write with WithSizeCap, in unix nanoseconds
+	written int64 // written is the time of the last write in unix nanoseconds, 0 if unknown or without WithSizeCap
+}
+
+// newAccessStats returns the access statistics of a store opened with o, nil if reads aren't counted
+func newAccessStats(o options) *accessStats {
+	if !o.accessStats && o.sizeCap <= 0 {
+		return nil
+	}
+	return &accessStats{keys: make(map[string]keyAccess)}
+}
+
+// record counts a read of key
+func (a *accessStats) record(key string) {
+	if a == nil {
+		return
+	}
+	now := time.Now().UnixNano()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	k := a.keys[key]
+	k.reads++
+	k.last = now
+	a.keys[key] = k
+	a.reads++
+}
+
+// wrote records a write of key, which doesn't count as a read
+func (a *accessStats) wrote(key string) {
+	if a == nil {
+		return
+	}
+	now := time.Now().UnixNano()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	k := a.keys[key]
+	k.last = now
+	k.written = now
+	a.keys[key] = k
+}
+
+// get returns the access statistics of key
+func (a *accessStats) get(key string) (keyAccess, bool) {
+	if a == nil {
+		return keyAccess{}, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	k, ok := a.keys[key]
+	return k, ok
+}
+
+// load sets the statistics read from the index of the store file
+func (a *accessStats) load(stats []format.Access) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys = make(map[string]keyAccess, len(stats))
+	for _, s := range stats {
+		a.keys[s.Key] = keyAccess{reads: s.Reads, last: s.Last, written: s.Written}
+	}
+	a.flushed = a.reads
+}
+
+// replace takes the statistics of next, the statistics of a store file replacing the store file
+func (a *accessStats) replace(next *accessStats) {
+	if a == nil || next == nil {
+		return
+	}
+	next.mu.Lock()
+	keys := next.keys
+	next.mu.Unlock()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys = keys
+	a.flushed = a.reads
+}
+
+// changed returns true if reads were counted since the statistics were last committed
+func (a *accessStats) changed() bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.reads != a.flushed
+}
+
+// list returns the statistics of the keys of index to be committed, in key order, and the count of reads
+// they include. Statistics of keys missing from index are dropped
+func (a *accessStats) list(index map[string]entry) ([]format.Access, uint64) {
+	if a == nil {
+		return nil, 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stats := make([]format.Access, 0, len(a.keys))
+	for k, s := range a.keys {
+		if _, ok := index[k]; !ok {
+			delete(a.keys, k)
+			continue
+		}
+		stats = append(stats, format.Access{Key: k, Reads: s.reads, Last: s.last, Written: s.written})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Key < stats[j].Key
+	})
+	return stats, a.reads
+}
+
+// committed records that the statistics including reads reads were committed
+func (a *accessStats) committed(reads uint64) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.flushed = reads
+}
+
+// flushAccess commits the access statistics if reads were counted since they were last committed,
+// unless the store can't be written to. It must be called with writeMu held
+func (store *Sunduk) flushAccess() error {
+	if !store.access.changed() || store.writable() != nil {
+		return nil
+	}
+	return store.commit(nil, nil, putOptions{})
+}
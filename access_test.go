@@ -0,0 +1,57 @@
+package sunduk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunduk_Stat(t *testing.T) {
+	store := New(TestStoreFile, WithAccessStats())
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"hot": []byte("value"), "cold": []byte("value"), "gone": []byte("value")})
+	start := time.Now()
+	store.Get("hot")
+	store.Get("hot")
+	_, _ = store.GetMany([]string{"hot", "gone", "missing"})
+	if b, ok := store.Borrow("hot"); ok {
+		b.Release()
+	}
+
+	stat, ok := store.Stat("hot")
+	if !ok || stat.Reads != 4 || stat.RawSize != 5 || stat.LastAccess.Before(start) {
+		t.Errorf("Expected 4 reads of hot key since %v, got %+v instead", start, stat)
+	}
+	if stat, ok := store.Stat("cold"); !ok || stat.Reads != 0 || !stat.LastAccess.IsZero() {
+		t.Errorf("Expected no reads of cold key, got %+v instead", stat)
+	}
+	if _, ok := store.Stat("missing"); ok {
+		t.Error("Expected no stat for a missing key")
+	}
+	_ = store.Delete("gone")
+	store.Close()
+
+	store = New(TestStoreFile, WithAccessStats())
+	if stat, _ := store.Stat("hot"); stat.Reads != 4 {
+		t.Errorf("Expected reads to be persisted, got %d reads instead", stat.Reads)
+	}
+	_ = store.Put("gone", []byte("again"))
+	if stat, _ := store.Stat("gone"); stat.Reads != 0 {
+		t.Errorf("Expected reads of deleted key to be dropped, got %d reads instead", stat.Reads)
+	}
+	store.Get("hot")
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	if stat, _ := store.Stat("hot"); stat.Reads != 0 {
+		t.Errorf("Expected no reads without WithAccessStats, got %d reads instead", stat.Reads)
+	}
+	store.Close()
+	store = New(TestStoreFile, WithAccessStats())
+	if stat, _ := store.Stat("hot"); stat.Reads != 5 {
+		t.Errorf("Expected reads to be kept by stores without WithAccessStats that don't write, got %d reads instead", stat.Reads)
+	}
+	store.Close()
+}
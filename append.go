@@ -0,0 +1,112 @@
+package sunduk
+
+import "sunduk/internal/format"
+
+// Append appends data to the value of key, creating the entry if key has none. Only data is compressed
+// and written to the store file, chained to the chunk of the value it is appended to, so log-like values
+// grow without the whole value being read and written again. Appending to a committed value commits
+// pending writes, and OnAppend hooks are called instead of OnBeforePut and OnAfterPut hooks. Values that
+// are pending, delta-encoded or in older formats, values which appended data outgrows the value it is
+// appended to, and values of stores recording digests, see WithHash, are read and put in full instead. Compaction rewrites appended values in full
+func (store *Sunduk) Append(key string, data []byte, opts ...PutOption) error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if err := store.checkOpen(); err != nil {
+		return err
+	}
+	po := newPutOptions(opts)
+	store.mu.RLock()
+	value, pending := store.data[key]
+	e, ok := store.lookup(key)
+	// Appending to an expired value creates the entry anew
+	ok = ok && !store.expired(key)
+	if po.expires == 0 && (pending || ok) {
+		po.expires = store.expiresAt(key)
+	}
+	file := store.file.acquire()
+	store.mu.RUnlock()
+	defer file.release()
+	switch {
+	case pending:
+		return store.write(map[string][]byte{key: concat(value, data)}, nil, po)
+	case !ok:
+		return store.write(map[string][]byte{key: data}, nil, po)
+	}
+
+	c, ok := store.appendChunk(file, e, data, po)
+	if !ok {
+		value, _, err := store.fetch(file, key, e)
+		if err != nil {
+			return err
+		}
+		return store.write(map[string][]byte{key: concat(value, data)}, nil, po)
+	}
+	info, err := store.flush(nil, nil, putOptions{chunks: map[string]chunk{key: c}})
+	if err != nil {
+		return err
+	}
+	for _, h := range store.opts.hooks {
+		if h.OnAppend != nil {
+			h.OnAppend(key, data)
+		}
+	}
+	store.notifyFlush(info)
+	return nil
+}
+
+// appendChunk returns the chunk holding the value of e with data appended, chained to the base of e.
+// The chunk holds the data appended to the base so far, so values are rebuilt from two chunks at most.
+// It returns false if the value must be written in full
+func (store *Sunduk) appendChunk(file *handle, e entry, data []byte, po putOptions) (chunk, bool) {
+	if !e.hasSum || e.Flags&format.FlagDelta != 0 || store.enc.hash != format.HashNone {
+		return chunk{}, false
+	}
+	base := format.Base{Offset: e.Offset, Size: e.Size, Flags: e.Flags, Sum: e.Sum}
+	var tail []byte
+	if e.Flags&format.FlagAppend != 0 {
+		base = e.Base
+		zdata, err := store.readChunk(file, e)
+		if err != nil {
+			return chunk{}, false
+		}
+		if tail, err = decodeChunk(zdata, e.Flags, file.dict); err != nil {
+			return chunk{}, false
+		}
+	}
+	// Appended data outgrowing its base is better compressed along with it
+	if baseSize := e.RawSize - int64(len(tail)); int64(len(tail)+len(data)) > baseSize {
+		return chunk{}, false
+	}
+
+	c := chunk{value: concat(tail, data), mode: po.compression, expires: po.expires}
+	if err := store.enc.withDict(store.dict).encode(&c); err != nil {
+		return chunk{}, false
+	}
+	c.flags |= format.FlagAppend
+	c.base = base
+	c.rawSize = e.RawSize + int64(len(data))
+	c.sum = format.UpdateChecksum(e.Sum, data)
+	return c, true
+}
+
+// decodeAppended returns the value held by an appended chunk with flags, baseData holds the chunk of its base
+func decodeAppended(data, baseData []byte, flags uint64, base format.Base, dict *dictionary) ([]byte, error) {
+	baseValue, err := decodeBase(baseData, base, dict)
+	if err != nil {
+		return nil, err
+	}
+	tail, err := decodeChunk(data, flags, dict)
+	if err != nil {
+		return nil, err
+	}
+	return concat(baseValue, tail), nil
+}
+
+// concat returns a new slice holding a followed by b
+func concat(a, b []byte) []byte {
+	value := make([]byte, 0, len(a)+len(b))
+	return append(append(value, a...), b...)
+}
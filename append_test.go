@@ -0,0 +1,80 @@
+package sunduk
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestSunduk_Append(t *testing.T) {
+	var appended []string
+	hooks := Hooks{OnAppend: func(key string, data []byte) {
+		appended = append(appended, key+":"+string(data))
+	}}
+	store := New(TestStoreFile, WithHooks(hooks))
+	defer deleteTestStoreFile()
+	if err := store.Append("log", []byte("first line\n")); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "log", []byte("first line\n"))
+	_ = store.Put("log", bytes.Repeat([]byte("old line\n"), 100))
+
+	want := bytes.Repeat([]byte("old line\n"), 100)
+	for _, line := range []string{"second line\n", "third line\n", "fourth line\n"} {
+		if err := store.Append("log", []byte(line)); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, line...)
+		checkValueForKey(t, store, "log", want)
+	}
+	if len(appended) != 3 {
+		t.Errorf("Expected OnAppend hook to be called 3 times, got %v instead", appended)
+	}
+	if n := store.DebugInfo().Index.AppendEntries; n != 1 {
+		t.Errorf("Expected 1 appended entry, got %d instead", n)
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "log", want)
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected store with appended values to verify, got %v instead", err)
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "log", want)
+	if n := store.DebugInfo().Index.AppendEntries; n != 0 {
+		t.Errorf("Expected compaction to rewrite appended values in full, got %d appended entries instead", n)
+	}
+	store.Close()
+}
+
+func TestSunduk_AppendWritesData(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	blob := make([]byte, 64<<10)
+	_, _ = rand.Read(blob)
+	_ = store.Put("blob", blob)
+	written := store.Metrics().BytesWritten
+
+	if err := store.Append("blob", []byte("tail")); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "blob", append(blob, "tail"...))
+	if n := store.Metrics().BytesWritten - written; n > 1<<10 {
+		t.Errorf("Expected only appended data to be written, got %d bytes written instead", n)
+	}
+
+	// Appended data outgrowing the value is written along with it
+	large := make([]byte, 128<<10)
+	_, _ = rand.Read(large)
+	if err := store.Append("blob", large); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "blob", append(append(blob, "tail"...), large...))
+	if n := store.DebugInfo().Index.AppendEntries; n != 0 {
+		t.Errorf("Expected the value to be written in full, got %d appended entries instead", n)
+	}
+}
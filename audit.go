@@ -0,0 +1,217 @@
+package sunduk
+
+import (
+	"fmt"
+	"sort"
+	"sunduk/internal/format"
+	"time"
+)
+
+// AuditOp is the kind of mutation recorded by an audit record
+type AuditOp int
+
+const (
+	// AuditCreate records a value put for a key which had none
+	AuditCreate AuditOp = iota + 1
+	// AuditUpdate records a value replacing the value of a key
+	AuditUpdate
+	// AuditDelete records the deletion of a key, by Delete, Rename or eviction
+	AuditDelete
+)
+
+// AuditRecord records a mutation of the value of a key, see WithAuditLog
+type AuditRecord struct {
+	Time       time.Time
+	Generation uint64 // Generation is the generation of the store once the mutation was applied
+	Actor      string // Actor is who made the mutation, see Actor
+	Key        string
+	Op         AuditOp
+	OldSum     uint32 // OldSum is the checksum of the value before the mutation, 0 for AuditCreate
+	NewSum     uint32 // NewSum is the checksum of the value after the mutation, 0 for AuditDelete
+}
+
+// WithAuditLog makes the store record every mutation of the values of keys: when it was made, by whom, and the
+// checksums of the value before and after it. Records are appended to the store file along with the values they
+// record and kept by compactions, see History. Mutations are recorded as made by actor, unless written with Actor
+func WithAuditLog(actor string) Option {
+	return func(o *options) {
+		o.audit = true
+		o.auditActor = actor
+	}
+}
+
+// Actor records the values put as put by name in the audit log, see WithAuditLog
+func Actor(name string) PutOption {
+	return func(o *putOptions) {
+		o.actor = name
+	}
+}
+
+// History returns the audit records of key in the order the mutations were made, see WithAuditLog.
+// Mutations are recorded once they are written to the store file, see WithWriteBuffer
+func (store *Sunduk) History(key string) ([]AuditRecord, error) {
+	store.mu.RLock()
+	file, chunks := store.file.acquire(), store.audit
+	store.mu.RUnlock()
+	defer file.release()
+	var history []AuditRecord
+	for _, c := range chunks {
+		records, err := store.readAudit(file, c)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			if r.Key == key {
+				history = append(history, newAuditRecord(r))
+			}
+		}
+	}
+	return history, nil
+}
+
+// newAuditRecord returns the audit record for a record of an audit chunk
+func newAuditRecord(r format.AuditRecord) AuditRecord {
+	a := AuditRecord{Time: time.Unix(0, r.Time), Generation: r.Generation, Actor: r.Actor, Key: r.Key, OldSum: r.OldSum, NewSum: r.NewSum}
+	switch {
+	case r.Flags&format.AuditNew == 0:
+		a.Op = AuditDelete
+	case r.Flags&format.AuditOld == 0:
+		a.Op = AuditCreate
+	default:
+		a.Op = AuditUpdate
+	}
+	return a
+}
+
+// auditRecords returns the audit records of the mutations of values, deleted keys, and the chunks and the renames
+// of po, applied at generation, nil without WithAuditLog. It must be called with mu held
+func (store *Sunduk) auditRecords(values map[string][]byte, deleted []string, po putOptions, generation uint64) []format.AuditRecord {
+	if !store.opts.audit {
+		return nil
+	}
+	now := store.timestamp()
+	actor := po.actor
+	if actor == "" {
+		actor = store.opts.auditActor
+	}
+	var records []format.AuditRecord
+	record := func(key string, newSum uint32, put bool) {
+		r := format.AuditRecord{Time: now, Generation: generation, Actor: actor, Key: key}
+		if sum, ok := store.valueSum(key); ok {
+			r.Flags, r.OldSum = format.AuditOld, sum
+		} else if !put {
+			return
+		}
+		if put {
+			r.Flags |= format.AuditNew
+			r.NewSum = newSum
+		}
+		records = append(records, r)
+	}
+	for _, k := range sortedCopy(deleted) {
+		record(k, 0, false)
+	}
+	from := make([]string, 0, len(po.renames))
+	for k := range po.renames {
+		from = append(from, k)
+	}
+	sort.Strings(from)
+	for _, k := range from {
+		sum, _ := store.valueSum(k)
+		record(po.renames[k], sum, true)
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		record(k, checksum(values[k]), true)
+	}
+	keys = keys[:0]
+	for k := range po.chunks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		c := po.chunks[k]
+		if c.data == nil {
+			c.sum = checksum(c.value)
+		}
+		record(k, c.sum, true)
+	}
+	return records
+}
+
+// valueSum returns the checksum of the value of key, pending or committed, and false if key has no value.
+// Values of legacy files have no checksum, it is 0. It must be called with mu held
+func (store *Sunduk) valueSum(key string) (uint32, bool) {
+	if value, ok := store.data[key]; ok {
+		return checksum(value), true
+	}
+	e, ok := store.lookup(key)
+	return e.Sum, ok
+}
+
+// sortedCopy returns a sorted copy of keys
+func sortedCopy(keys []string) []string {
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+	return keys
+}
+
+// writeAudit writes an audit chunk holding records and returns its location
+func writeAudit(w *offsetWriter, enc encoder, records []format.AuditRecord) (format.Base, error) {
+	raw := format.EncodeAudit(records)
+	data, err := enc.compress(raw)
+	if err != nil {
+		return format.Base{}, err
+	}
+	c := format.Base{Offset: w.offset, Size: int64(len(data)), Sum: checksum(raw)}
+	_, err = w.Write(data)
+	return c, err
+}
+
+// readAudit reads the audit records of the audit chunk c
+func (store *Sunduk) readAudit(file *handle, c format.Base) ([]format.AuditRecord, error) {
+	data, err := store.readChunk(file, entry{Offset: c.Offset, Size: c.Size})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read audit chunk: %v", err)
+	}
+	raw, err := format.DecodeChunk(data, c.Flags, nil)
+	if err == nil && checksum(raw) != c.Sum {
+		err = ErrChecksum
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read audit chunk: %w", err)
+	}
+	return format.DecodeAudit(raw)
+}
+
+// rewriteAudit copies the audit chunks of file to the new file of r, followed by an audit chunk of records
+// if there are any, and returns their locations in the new file
+func (store *Sunduk) rewriteAudit(r *rewrite, file *handle, chunks []format.Base, records []format.AuditRecord) ([]format.Base, error) {
+	if len(chunks)+len(records) == 0 {
+		return nil, nil
+	}
+	copied := make([]format.Base, 0, len(chunks)+1)
+	for _, c := range chunks {
+		data, err := store.readChunk(file, entry{Offset: c.Offset, Size: c.Size})
+		if err != nil {
+			return nil, fmt.Errorf("unable to read audit chunk: %v", err)
+		}
+		c.Offset = r.w.offset
+		if _, err := r.w.Write(data); err != nil {
+			return nil, err
+		}
+		copied = append(copied, c)
+	}
+	if len(records) > 0 {
+		c, err := writeAudit(r.w, r.enc, records)
+		if err != nil {
+			return nil, err
+		}
+		copied = append(copied, c)
+	}
+	return copied, nil
+}
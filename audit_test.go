@@ -0,0 +1,73 @@
+package sunduk
+
+import "testing"
+
+func TestSunduk_History(t *testing.T) {
+	store := New(TestStoreFile, WithAuditLog("app"))
+	defer deleteTestStoreFile()
+	_ = store.Put("a", []byte("1"))
+	_ = store.Put("a", []byte("2"), Actor("alice"))
+	_ = store.Rename("a", "b")
+	_ = store.Delete("b")
+	_ = store.Delete("missing")
+
+	want := []AuditRecord{
+		{Actor: "app", Key: "a", Op: AuditCreate, NewSum: checksum([]byte("1"))},
+		{Actor: "alice", Key: "a", Op: AuditUpdate, OldSum: checksum([]byte("1")), NewSum: checksum([]byte("2"))},
+		{Actor: "app", Key: "a", Op: AuditDelete, OldSum: checksum([]byte("2"))},
+	}
+	checkHistory := func(key string, want []AuditRecord) {
+		t.Helper()
+		history, err := store.History(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != len(want) {
+			t.Fatalf("Expected %d audit records of %s, got %v instead", len(want), key, history)
+		}
+		for i, r := range history {
+			if r.Time.IsZero() || r.Generation == 0 {
+				t.Errorf("Expected time and generation of audit record %v", r)
+			}
+			r.Time, r.Generation = want[i].Time, want[i].Generation
+			if r != want[i] {
+				t.Errorf("Expected audit record %v, got %v instead", want[i], r)
+			}
+		}
+	}
+	checkHistory("a", want)
+	checkHistory("b", []AuditRecord{
+		{Actor: "app", Key: "b", Op: AuditCreate, NewSum: checksum([]byte("2"))},
+		{Actor: "app", Key: "b", Op: AuditDelete, OldSum: checksum([]byte("2"))},
+	})
+	checkHistory("missing", nil)
+
+	// Audit records are kept by compactions and reopening
+	_ = store.Put("c", []byte("3"))
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkHistory("a", want)
+	checkHistory("c", []AuditRecord{{Actor: "app", Key: "c", Op: AuditCreate, NewSum: checksum([]byte("3"))}})
+}
+
+func TestSunduk_HistoryWriteBuffer(t *testing.T) {
+	store := New(TestStoreFile, WithAuditLog("app"), WithWriteBuffer(1<<20))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.Put("a", []byte("1"))
+	_ = store.Put("a", []byte("2"))
+	if history, _ := store.History("a"); len(history) != 0 {
+		t.Errorf("Expected no audit records before flush, got %v instead", history)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	history, err := store.History("a")
+	if err != nil || len(history) != 2 || history[0].Op != AuditCreate || history[1].Op != AuditUpdate || history[0].Generation >= history[1].Generation {
+		t.Errorf("Expected creation and update of a, got %v (%v) instead", history, err)
+	}
+}
@@ -0,0 +1,92 @@
+package sunduk
+
+import "fmt"
+
+// Operation is the kind of access to a key checked by an Authorizer
+type Operation int
+
+const (
+	// OperationRead reads the value of a key, with Get, GetMany, GetRange, GetLazy, GetInto, Borrow and the like
+	OperationRead Operation = iota + 1
+	// OperationWrite puts the value of a key, with Put, PutAll, Append, Copy, Rename and the like
+	OperationWrite
+	// OperationDelete deletes a key, with Delete, SoftDelete and Rename
+	OperationDelete
+)
+
+func (op Operation) String() string {
+	switch op {
+	case OperationRead:
+		return "read"
+	case OperationWrite:
+		return "write"
+	case OperationDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("Operation(%d)", int(op))
+	}
+}
+
+// Authorizer decides whether keys may be accessed, see WithAuthorizer
+type Authorizer interface {
+	// Authorize returns an error if the operation isn't allowed on key
+	Authorize(key string, op Operation) error
+}
+
+// AuthorizerFunc adapts a function to an Authorizer
+type AuthorizerFunc func(key string, op Operation) error
+
+// Authorize calls f
+func (f AuthorizerFunc) Authorize(key string, op Operation) error {
+	return f(key, op)
+}
+
+// WithAuthorizer makes the store consult a on every access to a key, such as to allow tenants access to their own
+// key prefix only. Denied reads act as if the key had no value, ForEach, Range and Cursor skip the keys denied,
+// and GetMany and GetRange return ErrPermission. Denied writes return ErrPermission and write nothing.
+// Listing keys, such as with Keys and Count, isn't checked
+func WithAuthorizer(a Authorizer) Option {
+	return func(o *options) {
+		o.authorizer = a
+	}
+}
+
+// authorize returns ErrPermission if the authorizer of the store denies op on key
+func (store *Sunduk) authorize(key string, op Operation) error {
+	if store.opts.authorizer == nil {
+		return nil
+	}
+	if err := store.opts.authorizer.Authorize(key, op); err != nil {
+		return fmt.Errorf("%w: %s of key %q: %v", ErrPermission, op, key, err)
+	}
+	return nil
+}
+
+// authorizeWrite returns ErrPermission if the authorizer of the store denies writing values, deleting deleted keys,
+// or writing the chunks and the renames of po
+func (store *Sunduk) authorizeWrite(values map[string][]byte, deleted []string, po putOptions) error {
+	if store.opts.authorizer == nil {
+		return nil
+	}
+	for k := range values {
+		if err := store.authorize(k, OperationWrite); err != nil {
+			return err
+		}
+	}
+	for _, k := range deleted {
+		if err := store.authorize(k, OperationDelete); err != nil {
+			return err
+		}
+	}
+	for k := range po.chunks {
+		if err := store.authorize(k, OperationWrite); err != nil {
+			return err
+		}
+	}
+	for _, to := range po.renames {
+		if err := store.authorize(to, OperationWrite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
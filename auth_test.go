@@ -0,0 +1,54 @@
+package sunduk
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSunduk_Authorizer(t *testing.T) {
+	// Tenant a may read everything but only write its own keys
+	a := AuthorizerFunc(func(key string, op Operation) error {
+		if op != OperationRead && !strings.HasPrefix(key, "a/") {
+			return errors.New("not a key of tenant a")
+		}
+		if op == OperationRead && strings.HasPrefix(key, "secret/") {
+			return errors.New("secret")
+		}
+		return nil
+	})
+	store := New(TestStoreFile)
+	_ = store.PutAll(map[string][]byte{"a/1": []byte("1"), "b/1": []byte("2"), "secret/1": []byte("3")})
+	store.Close()
+	store = New(TestStoreFile, WithAuthorizer(a))
+	defer deleteTestStoreFile()
+	defer store.Close()
+
+	if err := store.Put("a/2", []byte("4")); err != nil {
+		t.Errorf("Expected put to own key to succeed, got %v instead", err)
+	}
+	if err := store.Put("b/2", []byte("5")); !errors.Is(err, ErrPermission) {
+		t.Errorf("Expected ErrPermission for put to other tenant, got %v instead", err)
+	}
+	if err := store.Delete("b/1"); !errors.Is(err, ErrPermission) {
+		t.Errorf("Expected ErrPermission for delete of other tenant, got %v instead", err)
+	}
+	if err := store.Rename("a/1", "b/3"); !errors.Is(err, ErrPermission) {
+		t.Errorf("Expected ErrPermission for rename to other tenant, got %v instead", err)
+	}
+	checkValueForKey(t, store, "b/1", []byte("2"))
+	if _, ok := store.Get("secret/1"); ok {
+		t.Error("Expected denied read to find no value")
+	}
+	if _, err := store.GetMany([]string{"a/1", "secret/1"}); !errors.Is(err, ErrPermission) {
+		t.Errorf("Expected ErrPermission for GetMany, got %v instead", err)
+	}
+	var keys []string
+	_ = store.ForEach(func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if strings.Join(keys, ",") != "a/1,a/2,b/1" {
+		t.Errorf("Expected iteration to skip denied keys, got %v instead", keys)
+	}
+}
@@ -0,0 +1,240 @@
+package benchmarks
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sunduk"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// words make up generated values, so that they compress like text
+var words = strings.Fields(`register coil holding input device slave master request response timeout retry
+	address function code value status error voltage current temperature pressure flow level alarm event
+	sensor actuator controller gateway protocol frame checksum packet payload config version firmware`)
+
+// newValue returns a value of size bytes of words picked by rnd
+func newValue(rnd *rand.Rand, size int) []byte {
+	var b strings.Builder
+	b.Grow(size + 16)
+	for b.Len() < size {
+		b.WriteString(words[rnd.Intn(len(words))])
+		b.WriteByte(' ')
+		if rnd.Intn(8) == 0 {
+			fmt.Fprintf(&b, "%d ", rnd.Intn(100000))
+		}
+	}
+	return []byte(b.String()[:size])
+}
+
+// newValues returns count values of size bytes by key
+func newValues(count, size int) map[string][]byte {
+	rnd := rand.New(rand.NewSource(1))
+	values := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		values[fmt.Sprintf("key-%06d", i)] = newValue(rnd, size)
+	}
+	return values
+}
+
+// newStore returns a store in a temporary directory holding values, closed once the benchmark ends
+func newStore(b *testing.B, values map[string][]byte, opts ...sunduk.Option) *sunduk.Sunduk {
+	b.Helper()
+	store, err := sunduk.Open(filepath.Join(b.TempDir(), "bench.data"), opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		if err := store.Close(); err != nil {
+			b.Error(err)
+		}
+	})
+	if len(values) > 0 {
+		if err := store.PutAll(values); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return store
+}
+
+// reportRatio reports the compression ratio of the values of the store as the "ratio" metric
+func reportRatio(b *testing.B, store *sunduk.Sunduk) {
+	if compressed, raw := store.SizePrefix(""); compressed > 0 {
+		b.ReportMetric(float64(raw)/float64(compressed), "ratio")
+	}
+}
+
+// keysOf returns the keys of values in random order
+func keysOf(values map[string][]byte) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	rand.New(rand.NewSource(2)).Shuffle(len(keys), func(i, j int) {
+		keys[i], keys[j] = keys[j], keys[i]
+	})
+	return keys
+}
+
+func BenchmarkSmallKeys_Put(b *testing.B) {
+	values := newValues(1000, 200)
+	keys := keysOf(values)
+	store := newStore(b, nil)
+	b.SetBytes(200)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := keys[i%len(keys)]
+		if err := store.Put(k, values[k]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	reportRatio(b, store)
+}
+
+func BenchmarkSmallKeys_PutAll(b *testing.B) {
+	values := newValues(10000, 200)
+	b.SetBytes(int64(len(values) * 200))
+	b.ReportAllocs()
+	var store *sunduk.Sunduk
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		store = newStore(b, nil)
+		b.StartTimer()
+		if err := store.PutAll(values); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	reportRatio(b, store)
+}
+
+func BenchmarkSmallKeys_Get(b *testing.B) {
+	values := newValues(10000, 200)
+	keys := keysOf(values)
+	store := newStore(b, values, sunduk.WithCacheSize(0))
+	b.SetBytes(200)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := store.Get(keys[i%len(keys)]); !ok {
+			b.Fatal("missing value")
+		}
+	}
+}
+
+func BenchmarkSmallKeys_GetInto(b *testing.B) {
+	values := newValues(10000, 200)
+	keys := keysOf(values)
+	store := newStore(b, values, sunduk.WithCacheSize(0))
+	buf := make([]byte, 200)
+	b.SetBytes(200)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.GetInto(keys[i%len(keys)], buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSmallKeys_Flush flushes 100 buffered writes of small keys to a store of 10000 keys
+func BenchmarkSmallKeys_Flush(b *testing.B) {
+	values := newValues(10000, 200)
+	keys := keysOf(values)
+	store := newStore(b, values, sunduk.WithWriteBuffer(1<<30))
+	b.SetBytes(100 * 200)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for j := 0; j < 100; j++ {
+			k := keys[(i*100+j)%len(keys)]
+			if err := store.Put(k, values[k]); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StartTimer()
+		if err := store.Flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHugeBlobs_Put(b *testing.B) {
+	values := newValues(4, 16<<20)
+	keys := keysOf(values)
+	store := newStore(b, nil)
+	b.SetBytes(16 << 20)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := keys[i%len(keys)]
+		if err := store.Put(k, values[k]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	reportRatio(b, store)
+}
+
+func BenchmarkHugeBlobs_Get(b *testing.B) {
+	values := newValues(4, 16<<20)
+	keys := keysOf(values)
+	store := newStore(b, values, sunduk.WithCacheSize(0))
+	b.SetBytes(16 << 20)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := store.Get(keys[i%len(keys)]); !ok {
+			b.Fatal("missing value")
+		}
+	}
+}
+
+// BenchmarkMixed reads and writes small keys in parallel, one write for every 9 reads, with writes buffered and
+// flushed every 100 writes. The time flushes take is reported as the "ns/flush" metric
+func BenchmarkMixed(b *testing.B) {
+	values := newValues(10000, 200)
+	keys := keysOf(values)
+	store := newStore(b, values, sunduk.WithWriteBuffer(1<<30))
+	var seed, flushes, flushTime int64
+	b.SetBytes(200)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		rnd := rand.New(rand.NewSource(atomic.AddInt64(&seed, 1)))
+		for i := 0; pb.Next(); i++ {
+			k := keys[rnd.Intn(len(keys))]
+			if rnd.Intn(10) > 0 {
+				if _, ok := store.Get(k); !ok {
+					b.Error("missing value")
+					return
+				}
+				continue
+			}
+			if err := store.Put(k, values[k]); err != nil {
+				b.Error(err)
+				return
+			}
+			if i%100 == 0 {
+				start := time.Now()
+				if err := store.Flush(); err != nil {
+					b.Error(err)
+					return
+				}
+				atomic.AddInt64(&flushTime, int64(time.Since(start)))
+				atomic.AddInt64(&flushes, 1)
+			}
+		}
+	})
+	b.StopTimer()
+	if flushes > 0 {
+		b.ReportMetric(float64(flushTime)/float64(flushes), "ns/flush")
+	}
+	reportRatio(b, store)
+}
@@ -0,0 +1,8 @@
+// Package benchmarks measures sunduk stores on realistic workloads: many small keys, a few huge blobs, and
+// mixed reads and writes. Besides time and allocations, benchmarks report the compression ratio of the values
+// written and the time flushes take, so that performance changes are compared with benchstat:
+//
+//	go test -run - -bench . -count 10 ./benchmarks > old.txt
+//	go test -run - -bench . -count 10 ./benchmarks > new.txt
+//	benchstat old.txt new.txt
+package benchmarks
@@ -0,0 +1,76 @@
+package sunduk
+
+import "unsafe"
+
+// BinaryKeys is the store with []byte keys, such as hashes and serialized tuples, returned by Binary. A key is
+// the string of its bytes, so keys put with either the store or BinaryKeys are the same keys. Keys are looked up
+// without copying them, they are copied only when the store keeps them, such as for values found or written
+type BinaryKeys struct {
+	store *Sunduk
+}
+
+// Binary returns the store with []byte keys
+func (store *Sunduk) Binary() BinaryKeys {
+	return BinaryKeys{store: store}
+}
+
+// Has returns true if an entry exists for key, see Sunduk.Has. The key is never copied
+func (b BinaryKeys) Has(key []byte) bool {
+	return b.store.hasView(keyView(key))
+}
+
+// Get returns the value of key, see Sunduk.Get. Keys without value are looked up without copying them
+func (b BinaryKeys) Get(key []byte) ([]byte, bool) {
+	if !b.store.hasView(keyView(key)) {
+		return nil, false
+	}
+	return b.store.Get(string(key))
+}
+
+// GetInto reads the value of key into buf, see Sunduk.GetInto. Keys without value are looked up without copying them
+func (b BinaryKeys) GetInto(key, buf []byte) (int, error) {
+	if !b.store.hasView(keyView(key)) {
+		return 0, ErrNotFound
+	}
+	return b.store.GetInto(string(key), buf)
+}
+
+// Put creates an entry or updates the value of an existing key, see Sunduk.Put
+func (b BinaryKeys) Put(key, value []byte, opts ...PutOption) error {
+	return b.store.Put(string(key), value, opts...)
+}
+
+// Delete removes a key from the store, see Sunduk.Delete
+func (b BinaryKeys) Delete(key []byte) error {
+	return b.store.Delete(string(key))
+}
+
+// ForEach calls fn for every entry in key order, see Sunduk.ForEach
+func (b BinaryKeys) ForEach(fn func(key, value []byte) error) error {
+	return b.store.ForEach(func(key string, value []byte) error {
+		return fn([]byte(key), value)
+	})
+}
+
+// hasView returns true if an entry exists for key, which may share memory with a []byte and must not be kept.
+// Lookup tables log the keys they fail to look up, so keys are copied for them
+func (store *Sunduk) hasView(key string) bool {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if store.lazy != nil {
+		key = string([]byte(key))
+	}
+	if !store.mayContain(key) {
+		return false
+	}
+	_, ok := store.lookup(key)
+	return ok
+}
+
+// keyView returns key as a string sharing its memory, for lookups which don't keep the key
+func keyView(key []byte) string {
+	if len(key) == 0 {
+		return ""
+	}
+	return *(*string)(unsafe.Pointer(&key))
+}
@@ -0,0 +1,52 @@
+package sunduk
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSunduk_Binary(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	b := store.Binary()
+	key := []byte{0x00, 0xff, 'k', 0x80}
+	if err := b.Put(key, []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, string(key), []byte("value"))
+	if !b.Has(key) {
+		t.Error("Expected binary key to exist")
+	}
+	if value, ok := b.Get(key); !ok || string(value) != "value" {
+		t.Errorf("Expected 'value', got %q (%v) instead", value, ok)
+	}
+	buf := make([]byte, 10)
+	if n, err := b.GetInto(key, buf); err != nil || string(buf[:n]) != "value" {
+		t.Errorf("Expected 'value' read into buf, got %q (%v) instead", buf[:n], err)
+	}
+	var keys [][]byte
+	_ = b.ForEach(func(k, _ []byte) error {
+		keys = append(keys, k)
+		return nil
+	})
+	if len(keys) != 1 || !bytes.Equal(keys[0], key) {
+		t.Errorf("Expected ForEach over the binary key, got %q instead", keys)
+	}
+
+	missing := []byte("missing")
+	if b.Has(missing) {
+		t.Error("Expected missing key not to exist")
+	}
+	if _, err := b.GetInto(missing, buf); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing key, got %v instead", err)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { b.Get(missing) }); allocs != 0 {
+		t.Errorf("Expected missing key to be looked up without allocations, got %v instead", allocs)
+	}
+
+	if err := b.Delete(key); err != nil || b.Has(key) {
+		t.Errorf("Expected binary key to be deleted, got %v instead", err)
+	}
+}
@@ -0,0 +1,28 @@
+package sunduk
+
+import "sunduk/internal/format"
+
+// Has returns true if an entry exists for key, without reading its value
+func (store *Sunduk) Has(key string) bool {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if !store.mayContain(key) {
+		return false
+	}
+	_, ok := store.lookup(key)
+	return ok
+}
+
+// mayContain returns false if key certainly has no entry, as told by the bloom filter, or if its value has expired.
+// The filter doesn't hold the keys of pending writes, so it isn't used while there are any. It must be called with mu held
+func (store *Sunduk) mayContain(key string) bool {
+	if store.expired(key) {
+		return false
+	}
+	return len(store.data) > 0 || store.bloom.MayContain(key)
+}
+
+// newBloom returns the bloom filter of keys to write along with the index, empty unless WithBloomFilter
+func (store *Sunduk) newBloom(keys OrderedKeys) format.Bloom {
+	return format.NewBloom(keys.keys, store.opts.bloomBits)
+}
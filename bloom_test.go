@@ -0,0 +1,76 @@
+package sunduk
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSunduk_BloomFilter(t *testing.T) {
+	store, err := Open(TestStoreFile, WithBloomFilter(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	values := make(map[string][]byte)
+	for i := 0; i < 1000; i++ {
+		values[fmt.Sprintf("key%d", i)] = []byte(fmt.Sprintf("value %d", i))
+	}
+	_ = store.PutAll(values)
+	_ = store.Put("extra", []byte("extra value"))
+	if size := store.DebugInfo().Index.BloomSize; size == 0 {
+		t.Fatal("Expected bloom filter to be written")
+	}
+	for k := range values {
+		if !store.Has(k) {
+			t.Fatalf("Expected key %s to exist", k)
+		}
+	}
+	checkValueForKey(t, store, "extra", []byte("extra value"))
+	_ = store.Delete("key0")
+	if store.Has("key0") {
+		t.Error("Expected deleted key to not exist")
+	}
+	if store.Has("absent") {
+		t.Error("Expected absent key to not exist")
+	}
+	store.Close()
+
+	// Readers use the filter of the file without the option
+	store, err = Open(TestStoreFile, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if store.bloom.Empty() {
+		t.Fatal("Expected bloom filter to be read from the file")
+	}
+	positives := 0
+	for i := 0; i < 1000; i++ {
+		if store.mayContain(fmt.Sprintf("absent%d", i)) {
+			positives++
+		}
+	}
+	if positives > 50 {
+		t.Errorf("Expected bloom filter to tell most absent keys, got %d false positives of 1000 instead", positives)
+	}
+	checkValueForKey(t, store, "key1", []byte("value 1"))
+	checkKeyNotExists(t, store, "key0")
+}
+
+func TestSunduk_BloomFilterWithPendingWrites(t *testing.T) {
+	store, err := Open(TestStoreFile, WithBloomFilter(10), WithWriteBuffer(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.Put("a", []byte("apple"))
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Put("b", []byte("banana"))
+	if !store.Has("b") {
+		t.Error("Expected pending key to exist")
+	}
+	checkValueForKey(t, store, "b", []byte("banana"))
+}
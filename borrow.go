@@ -0,0 +1,103 @@
+package sunduk
+
+import (
+	"sunduk/internal/format"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultBufferPool is the default size of the largest chunks read into pooled buffers, see WithBufferPool
+const defaultBufferPool = 1 << 20
+
+var chunkPool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+// Borrowed is a value borrowed from the store with Borrow. It is read without copying, so its bytes
+// must not be modified, and must not be used after Release
+type Borrowed struct {
+	value []byte
+	buf   *[]byte // buf is the pooled buffer holding value, nil if value isn't pooled
+}
+
+// Bytes returns the borrowed value
+func (b *Borrowed) Bytes() []byte {
+	return b.value
+}
+
+// Release gives the value back to the store, which may reuse its memory for later reads
+func (b *Borrowed) Release() {
+	if b.buf != nil {
+		chunkPool.Put(b.buf)
+		b.buf = nil
+	}
+	b.value = nil
+}
+
+// Borrow returns the value of a key without copying it, even with WithValueCopies, as well as a bool that
+// indicates whether an entry exists for that key. Uncompressed values are read into buffers reused once the
+// value is released, so that hot reads don't allocate, and values read aren't cached. Every borrowed value must be released
+func (store *Sunduk) Borrow(key string) (*Borrowed, bool) {
+	if store.authorize(key, OperationRead) != nil {
+		return nil, false
+	}
+	atomic.AddUint64(&store.counters.gets, 1)
+	store.mu.RLock()
+	value, ok := store.data[key]
+	if !ok {
+		value, ok = store.cache.get(key)
+	}
+	if ok {
+		store.mu.RUnlock()
+		atomic.AddUint64(&store.counters.cacheHits, 1)
+		store.access.record(key)
+		return &Borrowed{value: value}, true
+	}
+	e, ok := store.lookup(key)
+	if !ok {
+		store.mu.RUnlock()
+		return nil, false
+	}
+	atomic.AddUint64(&store.counters.cacheMisses, 1)
+	file := store.file.acquire()
+	store.mu.RUnlock()
+	defer file.release()
+
+	if file != nil && e.hasSum && e.Flags&format.FlagRaw != 0 && e.Size <= store.opts.bufferPool {
+		buf := chunkPool.Get().(*[]byte)
+		if int64(cap(*buf)) < e.Size {
+			*buf = make([]byte, e.Size)
+		}
+		data := (*buf)[:e.Size]
+		n, err := store.readAt(file, data, e.Offset)
+		atomic.AddUint64(&store.counters.bytesRead, uint64(n))
+		if err == nil {
+			if value, err := store.decodeValue(file, data, e); err == nil {
+				store.access.record(key)
+				return &Borrowed{value: value, buf: buf}, true
+			}
+		}
+		chunkPool.Put(buf)
+	}
+
+	// Compressed values are decoded into new memory, and failures are repaired like with Get
+	value, repaired, err := store.fetch(file, key, e)
+	if err != nil {
+		return nil, false
+	}
+	if repaired {
+		store.rewriteRepaired(key, e, value)
+	}
+	store.access.record(key)
+	return &Borrowed{value: value}, true
+}
+
+// own returns value, or a copy of it if the store copies values
+func (store *Sunduk) own(value []byte) []byte {
+	if !store.opts.copies || value == nil {
+		return value
+	}
+	return append(make([]byte, 0, len(value)), value...)
+}
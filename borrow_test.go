@@ -0,0 +1,94 @@
+package sunduk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSunduk_WithValueCopies(t *testing.T) {
+	store := New(TestStoreFile, WithValueCopies())
+	defer deleteTestStoreFile()
+	defer store.Close()
+
+	value := []byte("value")
+	_ = store.Put("key", value)
+	value[0] = 'X'
+	checkValueForKey(t, store, "key", []byte("value"))
+
+	got, _ := store.Get("key")
+	got[0] = 'X'
+	checkValueForKey(t, store, "key", []byte("value"))
+
+	many, _ := store.GetMany([]string{"key"})
+	many["key"][0] = 'X'
+	checkValueForKey(t, store, "key", []byte("value"))
+}
+
+func TestSunduk_Borrow(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	large := bytes.Repeat([]byte("compressible "), 100)
+	_ = store.Put("raw", []byte("raw value"), Uncompressed())
+	_ = store.Put("compressed", large)
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	for i := 0; i < 2; i++ {
+		b, ok := store.Borrow("raw")
+		if !ok || string(b.Bytes()) != "raw value" {
+			t.Fatalf("Expected to borrow 'raw value', got %q (%v) instead", b.Bytes(), ok)
+		}
+		b.Release()
+		if b.Bytes() != nil {
+			t.Error("Expected released value to be nil")
+		}
+	}
+	b, ok := store.Borrow("compressed")
+	if !ok || !bytes.Equal(b.Bytes(), large) {
+		t.Errorf("Expected to borrow compressed value, got %d bytes (%v) instead", len(b.Bytes()), ok)
+	}
+	b.Release()
+	if _, ok := store.Borrow("missing"); ok {
+		t.Error("Expected missing key not to be borrowed")
+	}
+
+	_ = store.Put("cached", []byte("cached value"))
+	b, ok = store.Borrow("cached")
+	if !ok || string(b.Bytes()) != "cached value" {
+		t.Errorf("Expected to borrow 'cached value', got %q (%v) instead", b.Bytes(), ok)
+	}
+	b.Release()
+}
+
+func TestSunduk_BorrowCorrupted(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"), Uncompressed())
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	if _, ok := store.Borrow("key"); ok {
+		t.Error("Expected corrupted value not to be borrowed")
+	}
+}
+
+func TestSunduk_WithBufferPool(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	first := bytes.Repeat([]byte("first "), 100)
+	second := bytes.Repeat([]byte("second "), 100)
+	_ = store.PutAll(map[string][]byte{"first": first, "second": second})
+	store.Close()
+
+	for _, size := range []int64{0, defaultBufferPool} {
+		store = New(TestStoreFile, WithBufferPool(size), WithCacheSize(0))
+		for i := 0; i < 2; i++ {
+			checkValueForKey(t, store, "first", first)
+			checkValueForKey(t, store, "second", second)
+		}
+		store.Close()
+	}
+}
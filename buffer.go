@@ -0,0 +1,155 @@
+package sunduk
+
+import (
+	"sunduk/internal/format"
+	"sync/atomic"
+)
+
+// pending holds the writes applied in memory but not written to the store file yet, see WithWriteBuffer.
+// Their values are held in data, and overlay the index until they are merged into a new index by the next commit,
+// see lookup
+type pending struct {
+	modes   map[string]compressionMode // modes holds the compression mode of pending values
+	expires map[string]int64           // expires holds the expiry time of pending values which expire
+	deleted map[string]bool            // deleted holds the keys deleted since the last commit
+	audit   []format.AuditRecord       // audit holds the audit records of pending writes, see WithAuditLog
+	trash   []trashed                  // trash holds the committed values overwritten or deleted by pending writes, see WithTrash
+	size    int64                      // size is the total size of pending keys and values
+	count   int                        // count is the count of keys added by pending writes less the count of keys they deleted
+}
+
+func newPending() pending {
+	return pending{modes: make(map[string]compressionMode), expires: make(map[string]int64), deleted: make(map[string]bool)}
+}
+
+// Flush writes pending writes to the store file, see WithWriteBuffer, along with access statistics counted
+// since the last commit, see WithAccessStats. It does nothing if there are none
+func (store *Sunduk) Flush() error {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if atomic.LoadInt32(&store.closed) != 0 {
+		return ErrClosed
+	}
+	if err := store.flushPending(); err != nil {
+		return err
+	}
+	return store.flushAccess()
+}
+
+// flushPending commits pending writes if there are any and calls OnFlush hooks. It must be called with writeMu held
+func (store *Sunduk) flushPending() error {
+	if !store.staged() {
+		return nil
+	}
+	info, err := store.flush(nil, nil, putOptions{})
+	if err != nil {
+		return err
+	}
+	store.notifyFlush(info)
+	return nil
+}
+
+// stage applies values and deleted keys in memory, to be written by the next commit. It must be called with writeMu held
+func (store *Sunduk) stage(values map[string][]byte, deleted []string, po putOptions) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.pending.audit = append(store.pending.audit, store.auditRecords(values, deleted, po, store.generation+1)...)
+	store.pending.trash = append(store.pending.trash, store.trashed(values, deleted, po, store.generation+1)...)
+	// The index is shared with readers and compactions, so staged writes overlay it until the next commit
+	for _, k := range deleted {
+		if _, ok := store.lookup(k); ok {
+			store.pending.count--
+		}
+		store.unstage(k)
+		store.cache.remove(k)
+		store.pending.deleted[k] = true
+		store.pending.size += int64(len(k))
+	}
+	for k, v := range values {
+		if _, ok := store.lookup(k); !ok {
+			store.pending.count++
+		}
+		store.unstage(k)
+		store.data[k] = store.own(v)
+		store.cache.remove(k)
+		store.pending.modes[k] = po.compression
+		if po.expires != 0 {
+			store.pending.expires[k] = po.expires
+		}
+		store.pending.size += int64(len(k) + len(v))
+	}
+	if len(values)+len(deleted) > 0 {
+		store.generation++
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		store.changes.record(store.generation-1, Change{Generation: store.generation, Keys: keys, Deleted: deleted})
+	}
+}
+
+// staged returns true if there are pending writes. It must be called with mu held
+func (store *Sunduk) staged() bool {
+	return len(store.data)+len(store.pending.deleted) > 0
+}
+
+// pendingEntry returns the entry of a pending value
+func pendingEntry(value []byte) entry {
+	return entry{RawSize: int64(len(value)), pending: true}
+}
+
+// unstage drops the pending write of key. It must be called with writeMu and mu held
+func (store *Sunduk) unstage(key string) {
+	if value, ok := store.data[key]; ok {
+		store.pending.size -= int64(len(key) + len(value))
+		delete(store.data, key)
+		delete(store.pending.modes, key)
+		delete(store.pending.expires, key)
+	}
+	if store.pending.deleted[key] {
+		store.pending.size -= int64(len(key))
+		delete(store.pending.deleted, key)
+	}
+}
+
+// merge returns the chunks of pending values, of values and of chunks put as they are, and the keys deleted by pending writes and deleted.
+// It must be called with writeMu held
+func (store *Sunduk) merge(values map[string][]byte, deleted []string, po putOptions) (map[string]chunk, []string) {
+	chunks := make(map[string]chunk, len(store.data)+len(values))
+	for k, v := range store.data {
+		chunks[k] = chunk{value: v, mode: store.pending.modes[k], expires: store.pending.expires[k]}
+	}
+	merged := make([]string, 0, len(store.pending.deleted)+len(deleted))
+	for k := range store.pending.deleted {
+		merged = append(merged, k)
+	}
+	for _, k := range deleted {
+		delete(chunks, k)
+		merged = append(merged, k)
+	}
+	for k, v := range values {
+		chunks[k] = chunk{value: v, mode: po.compression, expires: po.expires}
+	}
+	for k, c := range po.chunks {
+		chunks[k] = c
+	}
+	return chunks, merged
+}
+
+// settle drops pending writes once they are committed along with values and deleted keys, and caches
+// the committed values. It must be called with writeMu and mu held
+func (store *Sunduk) settle(values map[string][]byte, deleted []string) {
+	for _, k := range deleted {
+		store.cache.remove(k)
+	}
+	for k, v := range store.data {
+		store.cache.add(k, v)
+	}
+	for k, v := range values {
+		store.cache.add(k, store.own(v))
+	}
+	if len(store.data) > 0 {
+		store.data = make(map[string][]byte)
+	}
+	store.pending = newPending()
+}
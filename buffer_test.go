@@ -0,0 +1,123 @@
+package sunduk
+
+import (
+	"testing"
+)
+
+func TestSunduk_WriteBuffer(t *testing.T) {
+	store := New(TestStoreFile, WithWriteBuffer(1<<20))
+	defer deleteTestStoreFile()
+	size := fileSize(t, TestStoreFile)
+
+	_ = store.Put("a", []byte("apple"))
+	_ = store.PutAll(map[string][]byte{"b": []byte("banana"), "c": []byte("cherry")})
+	_ = store.Delete("b")
+	if fileSize(t, TestStoreFile) != size {
+		t.Error("Expected buffered writes not to be written to the store file")
+	}
+	checkValueForKey(t, store, "a", []byte("apple"))
+	checkKeyNotExists(t, store, "b")
+	if store.Count() != 2 || store.Generation() != 3 {
+		t.Errorf("Expected 2 entries at generation 3, got %d entries at generation %d instead", store.Count(), store.Generation())
+	}
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected pending writes to be skipped by Verify, got %v instead", err)
+	}
+
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Expected Flush to succeed, got %v instead", err)
+	}
+	if fileSize(t, TestStoreFile) == size {
+		t.Error("Expected Flush to write pending writes to the store file")
+	}
+	_ = store.Put("d", []byte("date"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkValueForKey(t, store, "a", []byte("apple"))
+	checkKeyNotExists(t, store, "b")
+	checkValueForKey(t, store, "c", []byte("cherry"))
+	checkValueForKey(t, store, "d", []byte("date"))
+	if store.Generation() != 4 {
+		t.Errorf("Expected generation 4 to be persisted, got %d instead", store.Generation())
+	}
+}
+
+func TestSunduk_WriteBufferFull(t *testing.T) {
+	flushes := 0
+	store := New(TestStoreFile, WithWriteBuffer(12), WithHooks(Hooks{OnFlush: func(info FlushInfo) {
+		if info.Puts != 2 {
+			t.Errorf("Expected 2 values to be flushed, got %d instead", info.Puts)
+		}
+		flushes++
+	}}))
+	defer deleteTestStoreFile()
+	defer store.Close()
+
+	_ = store.Put("a", []byte("apple"))
+	if flushes != 0 {
+		t.Error("Expected a put below the buffer size not to be written")
+	}
+	_ = store.Put("b", []byte("banana"))
+	if flushes != 1 {
+		t.Errorf("Expected the put filling the buffer to write it, got %d flushes instead", flushes)
+	}
+	if info := store.DebugInfo(); info.Index.PendingEntries != 0 {
+		t.Errorf("Expected no pending entries after the buffer is written, got %d instead", info.Index.PendingEntries)
+	}
+}
+
+func TestSunduk_CompactWithPendingWrites(t *testing.T) {
+	store := New(TestStoreFile, WithWriteBuffer(1<<20))
+	defer deleteTestStoreFile()
+	_ = store.Put("a", []byte("apple"))
+	_ = store.Put("a", []byte("apricot"))
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "a", []byte("apricot"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkValueForKey(t, store, "a", []byte("apricot"))
+}
+
+func TestSunduk_WriteBufferOverlay(t *testing.T) {
+	store := New(TestStoreFile, WithWriteBuffer(1<<20))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.PutAll(map[string][]byte{"a": []byte("apple"), "b": []byte("banana"), "c": []byte("cherry")})
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	index := store.index
+
+	_ = store.Put("b", []byte("blueberry"))
+	_ = store.Delete("c")
+	_ = store.Put("d", []byte("date"))
+	_ = store.Delete("d")
+	_ = store.Put("e", []byte("elderberry"))
+	if len(store.index) != len(index) || store.index["b"] != index["b"] {
+		t.Error("Expected staged writes to leave the index as it was")
+	}
+	checkValueForKey(t, store, "b", []byte("blueberry"))
+	checkKeyNotExists(t, store, "c")
+	checkKeyNotExists(t, store, "d")
+	if keys := store.Keys(Prefix("")); len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "e" {
+		t.Errorf("Expected keys [a b e], got %v instead", keys)
+	}
+	if store.Count() != 3 {
+		t.Errorf("Expected 3 entries, got %d instead", store.Count())
+	}
+
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if store.Count() != 3 || len(store.index) != 3 {
+		t.Errorf("Expected 3 committed entries, got %d instead", len(store.index))
+	}
+	checkValueForKey(t, store, "b", []byte("blueberry"))
+	checkValueForKey(t, store, "e", []byte("elderberry"))
+}
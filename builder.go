@@ -0,0 +1,166 @@
+package sunduk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sunduk/internal/format"
+	"sunduk/internal/vfs"
+)
+
+// Builder creates a new store file in a single forward pass from values added in bytewise ascending key order,
+// such as when packaging large bundles in build pipelines. Values are compressed as they are read and never held
+// in memory, only the entries of the index are, and the file replaces any file at the path once the builder is
+// closed. Builders honor the options of created files: WithTempDir, WithFileMode, WithFileOwner, WithDurability,
+// WithHash, WithBloomFilter, WithLazyIndex, WithDeterministic and WithCollation, which orders listings while values
+// are still added in bytewise order
+type Builder struct {
+	store   *Sunduk // store holds the options and the path of the store file
+	path    string  // path is the path of the file written, which replaces the store file on Close
+	file    vfs.File
+	w       *bufio.Writer
+	offset  int64
+	entries []format.Entry
+	err     error // err is the first error of the builder, which fails every later call
+}
+
+// NewBuilder creates a builder of a store file at filePath, configured with opts
+func NewBuilder(filePath string, opts ...Option) (*Builder, error) {
+	store := &Sunduk{FilePath: filePath, opts: defaultOptions()}
+	for _, opt := range opts {
+		opt(&store.opts)
+	}
+	store.enc, _ = store.opts.compression()
+	b := &Builder{store: store, path: store.tempPath()}
+	file, err := store.createFile(b.path, "")
+	if err != nil {
+		return nil, err
+	}
+	b.file, b.w = file, bufio.NewWriter(file)
+	if err := b.write(func(w io.Writer) error { return writePreamble(w) }); err != nil {
+		b.Abort()
+		return nil, err
+	}
+	return b, nil
+}
+
+// write calls fn with the writer of the file, counting the bytes written
+func (b *Builder) write(fn func(w io.Writer) error) error {
+	w := &countingWriter{w: b.w}
+	err := fn(w)
+	b.offset += w.n
+	return err
+}
+
+// Add adds the value read from r until the end of r under key. Keys must be added in bytewise ascending order,
+// once each. The builder fails once Add fails, and must be aborted
+func (b *Builder) Add(key string, r io.Reader) error {
+	if b.err != nil {
+		return b.err
+	}
+	if n := len(b.entries); n > 0 && key <= b.entries[n-1].Key {
+		b.err = fmt.Errorf("key %q added after %q, keys must be added in bytewise ascending order", key, b.entries[n-1].Key)
+		return b.err
+	}
+	sum, digest := format.NewChecksum(), format.NewDigest(b.store.enc.hash)
+	var tee io.Writer = sum
+	if digest != nil {
+		tee = io.MultiWriter(sum, digest)
+	}
+	e := format.Entry{Key: key, Offset: b.offset}
+	b.err = b.write(func(w io.Writer) (err error) {
+		e.RawSize, err = format.CompressStream(w, io.TeeReader(r, tee), b.store.enc.windowBits)
+		return err
+	})
+	if b.err != nil {
+		b.err = fmt.Errorf("unable to add value for key %q: %w", key, b.err)
+		return b.err
+	}
+	e.Size, e.Sum = b.offset-e.Offset, sum.Sum32()
+	if digest != nil {
+		e.Digest = string(digest.Sum(nil))
+	}
+	b.entries = append(b.entries, e)
+	return nil
+}
+
+// Close writes the index of the added values and replaces the store file with the new file. A failed builder
+// is aborted instead, and Close returns its error
+func (b *Builder) Close() error {
+	if b.err != nil {
+		b.Abort()
+		return b.err
+	}
+	store := b.store
+	keys := OrderedKeys{keys: make([]string, len(b.entries))}
+	for i, e := range b.entries {
+		keys.keys[i] = e.Key
+	}
+	idx := format.Index{Entries: b.entries, Offset: b.offset, Bloom: store.newBloom(keys), Collation: store.opts.collation.name(), Hash: store.enc.hash}
+	idx.Meta.Created = store.timestamp()
+	if len(b.entries) > 0 {
+		idx.Generation = 1
+	}
+	err := b.write(func(w io.Writer) error {
+		if store.opts.lazyIndex {
+			n, err := format.WriteLookup(w, idx)
+			if err != nil {
+				return err
+			}
+			idx.Offset += n
+		}
+		return format.WriteIndex(w, idx, store.enc.windowBits)
+	})
+	if err == nil {
+		err = b.w.Flush()
+	}
+	if err == nil {
+		err = store.syncData(b.file)
+	}
+	if err == nil {
+		err = b.file.Close()
+		b.file = nil
+	}
+	if err == nil {
+		err = fsys.Rename(b.path, store.FilePath)
+	}
+	if err != nil {
+		b.err = fmt.Errorf("unable to build store file %s: %w", store.FilePath, err)
+		b.Abort()
+		return b.err
+	}
+	b.err = ErrClosed
+	if err := store.syncEntry(); err != nil {
+		store.opts.logger.Warn("unable to sync directory of built store file", "file", store.FilePath, "err", err)
+	}
+	store.opts.logger.Info("built store", "file", store.FilePath, "entries", len(b.entries), "size", b.offset)
+	return nil
+}
+
+// Abort discards the file written by the builder, leaving any store file at the path as it was
+func (b *Builder) Abort() {
+	if b.err == ErrClosed {
+		return
+	}
+	if b.file != nil {
+		_ = b.file.Close()
+		b.file = nil
+	}
+	_ = os.Remove(b.path)
+	if b.err == nil {
+		b.err = ErrClosed
+	}
+}
+
+// countingWriter counts the bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,78 @@
+package sunduk
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	b, err := NewBuilder(TestStoreFile, WithHash(HashSHA256), WithBloomFilter(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := bytes.Repeat([]byte("asset "), 100000)
+	for i := 0; i < 100; i++ {
+		if err := b.Add(fmt.Sprintf("asset-%03d", i), bytes.NewReader(large[:i*1000])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Add("empty", strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(TestStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if count := len(store.Keys()); count != 101 {
+		t.Errorf("Expected 101 keys, got %d instead", count)
+	}
+	checkValueForKey(t, store, "asset-099", large[:99000])
+	checkValueForKey(t, store, "empty", []byte{})
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected built store to verify, got %v instead", err)
+	}
+	if len(store.index["asset-001"].Digest) != 32 {
+		t.Error("Expected built store to record digests")
+	}
+}
+
+func TestBuilder_Order(t *testing.T) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	store := New(TestStoreFile)
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+
+	b, err := NewBuilder(TestStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = b.Add("b", strings.NewReader("b"))
+	if err := b.Add("a", strings.NewReader("a")); err == nil {
+		t.Error("Expected key out of order to be rejected")
+	}
+	if err := b.Close(); err == nil {
+		t.Error("Expected failed builder to fail on Close")
+	}
+	if _, err := os.Stat(TestStoreFile + ".new"); !os.IsNotExist(err) {
+		t.Errorf("Expected file of failed builder to be removed, got %v instead", err)
+	}
+
+	// The store file is left as it was
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkValueForKey(t, store, "key", []byte("value"))
+	if err := b.Add("c", strings.NewReader("c")); err == nil {
+		t.Error("Expected failed builder to keep failing")
+	}
+}
@@ -0,0 +1,176 @@
+package sunduk
+
+import (
+	"container/list"
+	"sync"
+)
+
+// defaultCacheSize is the default limit of the total size of cached values
+const defaultCacheSize = 64 << 20
+
+// cache holds values written to or read from the store file, evicting the least recently used values
+// once their total size exceeds limit. Its methods do nothing if limit isn't positive, except for pinned keys
+// which values are held apart from the other values and never evicted, see Pin
+type cache struct {
+	mu    sync.Mutex
+	limit int64
+	size  int64
+	order *list.List // order holds the cached items, the most recently used first
+	items map[string]*list.Element
+
+	pins       map[string]pin // pins holds the pinned keys
+	pinnedSize int64
+}
+
+type cacheItem struct {
+	key   string
+	value []byte
+}
+
+// pin is a pinned key, which value may not be held yet
+type pin struct {
+	value []byte
+	held  bool
+}
+
+func newCache(limit int64) *cache {
+	return &cache{limit: limit, order: list.New(), items: make(map[string]*list.Element), pins: make(map[string]pin)}
+}
+
+// get returns the value of key if it is cached, making it the most recently used
+func (c *cache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p := c.pins[key]; p.held {
+		return p.value, true
+	}
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheItem).value, true
+}
+
+// add caches the value of key, evicting the least recently used values to make room for it.
+// Values larger than the limit aren't cached, unless key is pinned
+func (c *cache) add(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pins[key]; ok {
+		c.pinnedSize += int64(len(value)) - int64(len(p.value))
+		c.pins[key] = pin{value: value, held: true}
+		return
+	}
+	if c.limit <= 0 || int64(len(value)) > c.limit {
+		if el, ok := c.items[key]; ok {
+			c.evict(el)
+		}
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		item := el.Value.(*cacheItem)
+		c.size += int64(len(value)) - int64(len(item.value))
+		item.value = value
+		c.order.MoveToFront(el)
+	} else {
+		c.items[key] = c.order.PushFront(&cacheItem{key: key, value: value})
+		c.size += int64(len(value))
+	}
+	for c.size > c.limit {
+		c.evict(c.order.Back())
+	}
+}
+
+// remove drops the value of key from the cache. Pinned keys stay pinned
+func (c *cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pins[key]; ok {
+		c.pinnedSize -= int64(len(p.value))
+		c.pins[key] = pin{}
+	}
+	if el, ok := c.items[key]; ok {
+		c.evict(el)
+	}
+}
+
+// clear drops every value from the cache. Pinned keys stay pinned
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+	c.size = 0
+	for k := range c.pins {
+		c.pins[k] = pin{}
+	}
+	c.pinnedSize = 0
+}
+
+// pin pins key, moving its value out of the least recently used values if it is cached.
+// It returns true if the value of key is held
+func (c *cache) pin(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pins[key]; ok {
+		return p.held
+	}
+	el, ok := c.items[key]
+	if !ok {
+		c.pins[key] = pin{}
+		return false
+	}
+	value := el.Value.(*cacheItem).value
+	c.evict(el)
+	c.pins[key] = pin{value: value, held: true}
+	c.pinnedSize += int64(len(value))
+	return true
+}
+
+// unpin unpins key, making its value the most recently used value of the cache
+func (c *cache) unpin(key string) {
+	c.mu.Lock()
+	p, ok := c.pins[key]
+	if ok {
+		delete(c.pins, key)
+		c.pinnedSize -= int64(len(p.value))
+	}
+	c.mu.Unlock()
+	if p.held {
+		c.add(key, p.value)
+	}
+}
+
+// unheld returns the pinned keys which values aren't held
+func (c *cache) unheld() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keys []string
+	for k, p := range c.pins {
+		if !p.held {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// bytes returns the total size of cached values, pinned values included
+func (c *cache) bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size + c.pinnedSize
+}
+
+// pinnedBytes returns the total size of the values of pinned keys
+func (c *cache) pinnedBytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pinnedSize
+}
+
+func (c *cache) evict(el *list.Element) {
+	item := c.order.Remove(el).(*cacheItem)
+	delete(c.items, item.key)
+	c.size -= int64(len(item.value))
+}
@@ -0,0 +1,89 @@
+package sunduk
+
+import "testing"
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newCache(10)
+	c.add("a", []byte("1234"))
+	c.add("b", []byte("1234"))
+	c.get("a")
+	c.add("c", []byte("1234"))
+	if _, ok := c.get("b"); ok {
+		t.Error("Expected least recently used value to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.get(k); !ok {
+			t.Errorf("Expected key %s to be cached", k)
+		}
+	}
+	if c.bytes() != 8 {
+		t.Errorf("Expected 8 cached bytes, got %d instead", c.bytes())
+	}
+
+	c.add("large", []byte("12345678901"))
+	if _, ok := c.get("large"); ok {
+		t.Error("Expected value larger than the limit not to be cached")
+	}
+	c.add("a", []byte("12"))
+	if c.bytes() != 6 {
+		t.Errorf("Expected 6 cached bytes after replacing a value, got %d instead", c.bytes())
+	}
+	c.remove("a")
+	c.clear()
+	if _, ok := c.get("c"); ok || c.bytes() != 0 {
+		t.Errorf("Expected cleared cache to be empty, got %d bytes instead", c.bytes())
+	}
+}
+
+func TestSunduk_CacheOfReads(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "key", []byte("value"))
+	checkValueForKey(t, store, "key", []byte("value"))
+	if m := store.Metrics(); m.CacheHits != 1 || m.CacheMisses != 1 {
+		t.Errorf("Expected the second get to hit the cache, got %d hits and %d misses instead", m.CacheHits, m.CacheMisses)
+	}
+	store.Close()
+
+	store = New(TestStoreFile, WithCacheSize(0))
+	defer store.Close()
+	_ = store.Put("other", []byte("other value"))
+	checkValueForKey(t, store, "key", []byte("value"))
+	checkValueForKey(t, store, "key", []byte("value"))
+	checkValueForKey(t, store, "other", []byte("other value"))
+	if m := store.Metrics(); m.CacheHits != 0 || m.CacheMisses != 3 {
+		t.Errorf("Expected every get to miss the disabled cache, got %d hits and %d misses instead", m.CacheHits, m.CacheMisses)
+	}
+}
+
+func TestCache_Pin(t *testing.T) {
+	c := newCache(10)
+	c.add("a", []byte("1234"))
+	if !c.pin("a") || c.pin("b") {
+		t.Error("Expected only the cached value to be held once pinned")
+	}
+	c.add("b", []byte("123456"))
+	c.add("c", []byte("123456789"))
+	for _, k := range []string{"a", "b"} {
+		if _, ok := c.get(k); !ok {
+			t.Errorf("Expected pinned key %s to be held regardless of eviction", k)
+		}
+	}
+	if c.bytes() != 19 || c.pinnedBytes() != 10 {
+		t.Errorf("Expected 19 cached bytes with 10 pinned bytes, got %d and %d instead", c.bytes(), c.pinnedBytes())
+	}
+	c.clear()
+	if _, ok := c.get("a"); ok || len(c.unheld()) != 2 {
+		t.Errorf("Expected cleared pinned keys to stay pinned without values, got %v unheld instead", c.unheld())
+	}
+	c.add("a", []byte("1234"))
+	c.unpin("a")
+	c.add("d", []byte("123456789"))
+	if _, ok := c.get("a"); ok {
+		t.Error("Expected unpinned value to be evicted")
+	}
+}
@@ -0,0 +1,66 @@
+package sunduk
+
+// Checksum returns the checksum of value as it is recorded in the index, to be passed to CompareAndSwap
+func Checksum(value []byte) uint32 {
+	return checksum(value)
+}
+
+// PutIfAbsent puts value under key unless key has an entry, and returns true if the value was put.
+// Writers creating the same key concurrently don't overwrite each other, only the first one puts its value
+func (store *Sunduk) PutIfAbsent(key string, value []byte, opts ...PutOption) (bool, error) {
+	if err := store.writable(); err != nil {
+		return false, err
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if store.Has(key) {
+		return false, nil
+	}
+	if err := store.write(map[string][]byte{key: value}, nil, newPutOptions(opts)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CompareAndSwap puts value under key if the current value of key has the checksum expected, see Checksum,
+// and returns true if the value was put. Writers updating a value they read don't overwrite changes made
+// meanwhile by other writers, they read the value again and retry instead. It returns false if key has no entry
+func (store *Sunduk) CompareAndSwap(key string, expected uint32, value []byte, opts ...PutOption) (bool, error) {
+	if err := store.writable(); err != nil {
+		return false, err
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	sum, ok, err := store.currentSum(key)
+	if err != nil || !ok || sum != expected {
+		return false, err
+	}
+	if err := store.write(map[string][]byte{key: value}, nil, newPutOptions(opts)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// currentSum returns the checksum of the value of key and whether key has an entry. Values of pending writes
+// and of legacy entries, which have no checksum, are checksummed. It must be called with writeMu held
+func (store *Sunduk) currentSum(key string) (uint32, bool, error) {
+	store.mu.RLock()
+	value, pending := store.data[key]
+	e, ok := store.lookup(key)
+	file := store.file.acquire()
+	store.mu.RUnlock()
+	defer file.release()
+	switch {
+	case pending:
+		return checksum(value), true, nil
+	case !ok:
+		return 0, false, nil
+	case e.hasSum:
+		return e.Sum, true, nil
+	}
+	value, _, err := store.fetch(file, key, e)
+	if err != nil {
+		return 0, false, err
+	}
+	return checksum(value), true, nil
+}
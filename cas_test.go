@@ -0,0 +1,43 @@
+package sunduk
+
+import "testing"
+
+func TestSunduk_PutIfAbsent(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	if ok, err := store.PutIfAbsent("lock", []byte("writer 1")); !ok || err != nil {
+		t.Errorf("Expected value to be put for an absent key, got %v (%v) instead", ok, err)
+	}
+	if ok, err := store.PutIfAbsent("lock", []byte("writer 2")); ok || err != nil {
+		t.Errorf("Expected value not to be put for an existing key, got %v (%v) instead", ok, err)
+	}
+	checkValueForKey(t, store, "lock", []byte("writer 1"))
+}
+
+func TestSunduk_CompareAndSwap(t *testing.T) {
+	for _, buffered := range []bool{false, true} {
+		var opts []Option
+		if buffered {
+			opts = append(opts, WithWriteBuffer(1<<20))
+		}
+		store, err := Open(TestStoreFile, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = store.Put("counter", []byte("1"))
+		read := Checksum([]byte("1"))
+		if ok, err := store.CompareAndSwap("counter", read, []byte("2")); !ok || err != nil {
+			t.Errorf("Expected value to be swapped, got %v (%v) instead", ok, err)
+		}
+		if ok, err := store.CompareAndSwap("counter", read, []byte("3")); ok || err != nil {
+			t.Errorf("Expected changed value not to be swapped, got %v (%v) instead", ok, err)
+		}
+		if ok, err := store.CompareAndSwap("missing", read, []byte("3")); ok || err != nil {
+			t.Errorf("Expected missing key not to be swapped, got %v (%v) instead", ok, err)
+		}
+		checkValueForKey(t, store, "counter", []byte("2"))
+		store.Close()
+		deleteTestStoreFile()
+	}
+}
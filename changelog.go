@@ -0,0 +1,109 @@
+package sunduk
+
+import (
+	"sort"
+	"sync"
+)
+
+// Change is a mutation of the store, see Changes
+type Change struct {
+	Generation uint64   // Generation is the generation of the store once the change was applied
+	Keys       []string // Keys are the keys which values were put, copied, appended to or renamed to, in ascending order
+	Deleted    []string // Deleted are the keys deleted, renamed from or evicted, in ascending order
+	Meta       bool     // Meta is true if the metadata changed
+}
+
+// WithChangeLog makes the store keep its last size changes in memory, see Changes
+func WithChangeLog(size int) Option {
+	return func(o *options) {
+		o.changeLog = size
+	}
+}
+
+// Changes returns the changes applied to the store since generation since, in generation order, so that
+// copies of the store can catch up with it by reading the current values of the keys changed. It returns
+// ErrChangesTruncated if the change log doesn't hold every change since then: the log only holds the changes
+// applied by this store since it was opened, up to the size of WithChangeLog, and is reset by reloads and swaps
+func (store *Sunduk) Changes(since uint64) ([]Change, error) {
+	generation := store.Generation()
+	if since == generation {
+		return nil, nil
+	}
+	return store.changes.since(since, generation)
+}
+
+// changeLog holds the last changes applied to a store, see WithChangeLog
+type changeLog struct {
+	mu      sync.Mutex
+	size    int
+	base    uint64   // base is the generation the first change was applied to
+	changes []Change // changes holds the changes since base, the oldest first
+}
+
+// newChangeLog returns the change log of a store opened with o at generation, nil if changes aren't kept
+func newChangeLog(o options, generation uint64) *changeLog {
+	if o.changeLog <= 0 {
+		return nil
+	}
+	return &changeLog{size: o.changeLog, base: generation}
+}
+
+// record adds a change applied to the store at generation prev. Changes that don't follow the last change
+// recorded, such as the first change after a reload, reset the log
+func (l *changeLog) record(prev uint64, c Change) {
+	if l == nil || c.Generation == prev {
+		return
+	}
+	c.Keys = append([]string(nil), c.Keys...)
+	c.Deleted = append([]string(nil), c.Deleted...)
+	sort.Strings(c.Keys)
+	sort.Strings(c.Deleted)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.head() != prev {
+		l.base, l.changes = prev, nil
+	}
+	l.changes = append(l.changes, c)
+	if len(l.changes) > l.size {
+		l.base = l.changes[0].Generation
+		l.changes = append([]Change(nil), l.changes[1:]...)
+	}
+}
+
+// head returns the generation of the store once the last recorded change was applied. It must be called with mu held
+func (l *changeLog) head() uint64 {
+	if len(l.changes) == 0 {
+		return l.base
+	}
+	return l.changes[len(l.changes)-1].Generation
+}
+
+// since returns the changes since generation since for a store at generation generation
+func (l *changeLog) since(since, generation uint64) ([]Change, error) {
+	if l == nil {
+		return nil, ErrChangesTruncated
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.head() != generation || since < l.base || since > generation {
+		return nil, ErrChangesTruncated
+	}
+	i := sort.Search(len(l.changes), func(i int) bool {
+		return l.changes[i].Generation > since
+	})
+	return append([]Change(nil), l.changes[i:]...), nil
+}
+
+// changedKeys returns the keys of values and of chunks
+func changedKeys(values map[string][]byte, chunks map[string]chunk) []string {
+	keys := make([]string, 0, len(values)+len(chunks))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	for k := range chunks {
+		if _, ok := values[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
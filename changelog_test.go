@@ -0,0 +1,41 @@
+package sunduk
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSunduk_Changes(t *testing.T) {
+	store := New(TestStoreFile, WithChangeLog(3))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	start := store.Generation()
+	_ = store.PutAll(map[string][]byte{"b": []byte("2"), "a": []byte("1")})
+	_ = store.Rename("a", "c")
+	_ = store.SetMeta(Meta{Application: "test"})
+
+	changes, err := store.Changes(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Generation: start + 1, Keys: []string{"a", "b"}},
+		{Generation: start + 2, Keys: []string{"c"}, Deleted: []string{"a"}},
+		{Generation: start + 3, Meta: true},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Expected changes %v, got %v instead", want, changes)
+	}
+	if changes, _ := store.Changes(store.Generation()); changes != nil {
+		t.Errorf("Expected no changes since the current generation, got %v instead", changes)
+	}
+
+	_ = store.Delete("b")
+	if _, err := store.Changes(start); !errors.Is(err, ErrChangesTruncated) {
+		t.Errorf("Expected ErrChangesTruncated for changes dropped from the log, got %v instead", err)
+	}
+	if changes, err := store.Changes(store.Generation() - 1); err != nil || len(changes) != 1 || changes[0].Deleted[0] != "b" {
+		t.Errorf("Expected deletion of b, got %v (%v) instead", changes, err)
+	}
+}
@@ -0,0 +1,47 @@
+package sunduk
+
+import "sync/atomic"
+
+// Clear removes every entry of the store, pending writes included, and compacts the store file so that it holds
+// no values. Entries are removed in a single commit, so a failed Clear leaves the store with all or none of them.
+// Writers are blocked until the store file is rewritten, so no value written meanwhile survives the compaction.
+// As with Delete, OnDelete hooks are called for every key, and soft-deleted and trashed values are kept, see
+// SoftDelete and WithTrash. If compaction is paused, entries are removed but Clear returns ErrCompactionPaused
+func (store *Sunduk) Clear() error {
+	store.compaction.mu.Lock()
+	defer store.compaction.mu.Unlock()
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if keys := store.Keys(); len(keys) > 0 {
+		if err := store.write(nil, keys, putOptions{}); err != nil {
+			return err
+		}
+	}
+	if err := store.flushPending(); err != nil {
+		return err
+	}
+	if atomic.LoadInt32(&store.compaction.paused) != 0 {
+		return ErrCompactionPaused
+	}
+	return store.rewriteEmpty()
+}
+
+// rewriteEmpty rewrites the store file without values, keeping its sections. It must be called with writeMu held
+func (store *Sunduk) rewriteEmpty() error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	r, err := store.newRewrite(store.dict.bytes())
+	if err != nil {
+		return err
+	}
+	defer r.discard()
+	noRecompress := func(entry) bool { return false }
+	if _, err := store.rewriteHeader(r, noRecompress); err != nil {
+		return err
+	}
+	if r.header.Audit, err = store.rewriteAudit(r, store.file, r.header.Audit, nil); err != nil {
+		return err
+	}
+	return store.replace(r)
+}
@@ -0,0 +1,57 @@
+package sunduk
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestSunduk_Clear(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithWriteBuffer(1 << 20)}} {
+		store := New(TestStoreFile, opts...)
+		_ = store.PutAll(map[string][]byte{"a": bytes.Repeat([]byte("a"), 1000), "b": []byte("b")})
+		_ = store.Flush()
+		_ = store.Put("pending", []byte("pending"))
+		var deleted []string
+		store.opts.hooks = append(store.opts.hooks, Hooks{OnDelete: func(key string) {
+			deleted = append(deleted, key)
+		}})
+		if err := store.Clear(); err != nil {
+			t.Fatalf("Expected store to be cleared, got %v instead", err)
+		}
+		if store.Count() != 0 || len(deleted) != 3 {
+			t.Errorf("Expected no entries and 3 keys deleted, got %d entries and %v instead", store.Count(), deleted)
+		}
+		store.Close()
+
+		info, _ := os.Stat(TestStoreFile)
+		deleteTestStoreFile()
+		empty := New(TestStoreFile, opts...)
+		empty.Close()
+		emptyInfo, _ := os.Stat(TestStoreFile)
+		if info.Size() > emptyInfo.Size()+16 {
+			t.Errorf("Expected cleared file of about %d bytes, got %d instead", emptyInfo.Size(), info.Size())
+		}
+		deleteTestStoreFile()
+	}
+}
+
+func TestSunduk_ClearPaused(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.Put("a", []byte("apple"))
+	store.PauseCompaction()
+	if err := store.Clear(); err != ErrCompactionPaused {
+		t.Errorf("Expected ErrCompactionPaused, got %v instead", err)
+	}
+	checkKeyNotExists(t, store, "a")
+	store.ResumeCompaction()
+	if err := store.Clear(); err != nil {
+		t.Errorf("Expected an empty store to be cleared, got %v instead", err)
+	}
+	if err := store.Put("b", []byte("banana")); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "b", []byte("banana"))
+}
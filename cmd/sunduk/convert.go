@@ -0,0 +1,115 @@
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sunduk"
+	"sunduk/sundukconvert"
+)
+
+const convertUsage = "convert [-sep separator] <src> <dst>"
+
+// The prefixes of the foreign side of a conversion
+const (
+	boltPrefix   = "bolt:"
+	badgerPrefix = "badger:"
+	zipPrefix    = "zip:"
+)
+
+// foreign splits a conversion argument into its prefix and path, the prefix is empty for stores
+func foreign(arg string) (prefix, path string) {
+	for _, p := range []string{boltPrefix, badgerPrefix, zipPrefix} {
+		if strings.HasPrefix(arg, p) {
+			return p, strings.TrimPrefix(arg, p)
+		}
+	}
+	return "", arg
+}
+
+// runConvert imports a BoltDB file, a BadgerDB directory or a zip archive into a store, or exports a store to one.
+// Exactly one of src and dst is foreign, named with the bolt:, badger: or zip: prefix. BoltDB keys are the bucket
+// paths joined with the keys by separator, BadgerDB keys are kept as they are, zip keys are the paths of the files
+func runConvert(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	sep := flags.String("sep", "/", "join BoltDB buckets and keys with `separator`")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		fmt.Fprintln(stderr, "usage: sunduk "+convertUsage)
+		return 2
+	}
+	srcPrefix, src := foreign(flags.Arg(0))
+	dstPrefix, dst := foreign(flags.Arg(1))
+	if (srcPrefix == "") == (dstPrefix == "") {
+		fmt.Fprintln(stderr, "sunduk: exactly one of src and dst must be a bolt:, badger: or zip: path")
+		return 2
+	}
+
+	var n int
+	var err error
+	if srcPrefix != "" {
+		n, err = importFrom(srcPrefix, src, dst, *sep)
+	} else {
+		n, err = exportTo(dstPrefix, src, dst, *sep)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "sunduk: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%d entries converted\n", n)
+	return 0
+}
+
+// importFrom imports the foreign file or directory at src into the store at dst
+func importFrom(prefix, src, dst, sep string) (int, error) {
+	var source sundukconvert.Source
+	switch prefix {
+	case boltPrefix:
+		source = sundukconvert.BoltSource(src, sep)
+	case badgerPrefix:
+		source = sundukconvert.BadgerSource(src)
+	default:
+		zr, err := zip.OpenReader(src)
+		if err != nil {
+			return 0, err
+		}
+		defer zr.Close()
+		source = sundukconvert.ZipSource(&zr.Reader)
+	}
+	store, err := sunduk.Open(dst)
+	if err != nil {
+		return 0, err
+	}
+	n, err := sundukconvert.Import(store, source)
+	if cerr := store.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// exportTo exports the store at src to the foreign file or directory at dst
+func exportTo(prefix, src, dst, sep string) (int, error) {
+	store, err := openExisting(src)
+	if err != nil {
+		return 0, err
+	}
+	defer store.Close()
+	switch prefix {
+	case boltPrefix:
+		return sundukconvert.ExportBolt(store, dst, sep)
+	case badgerPrefix:
+		return sundukconvert.ExportBadger(store, dst)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return 0, err
+	}
+	n, err := sundukconvert.ToZip(store, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
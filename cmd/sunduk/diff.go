@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sunduk"
+)
+
+const diffUsage = "diff <old> <new>"
+
+// runDiff prints keys added (+), removed (-) and changed (~) between two stores.
+// Like diff(1), it exits with 0 if stores hold the same entries, 1 if they differ and 2 on trouble
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 {
+		fmt.Fprintln(stderr, "usage: sunduk "+diffUsage)
+		return 2
+	}
+	a, err := openExisting(args[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "sunduk: %v\n", err)
+		return 2
+	}
+	defer a.Close()
+	b, err := openExisting(args[1])
+	if err != nil {
+		fmt.Fprintf(stderr, "sunduk: %v\n", err)
+		return 2
+	}
+	defer b.Close()
+
+	added, removed, changed := sunduk.Diff(a, b)
+	for _, k := range added {
+		fmt.Fprintf(stdout, "+ %s\n", k)
+	}
+	for _, k := range removed {
+		fmt.Fprintf(stdout, "- %s\n", k)
+	}
+	for _, k := range changed {
+		fmt.Fprintf(stdout, "~ %s\n", k)
+	}
+	if len(added)+len(removed)+len(changed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// openExisting opens a store read-only, which fails for a missing file rather than creating it
+func openExisting(path string) (*sunduk.Sunduk, error) {
+	return sunduk.Open(path, sunduk.WithSharedReadLock())
+}
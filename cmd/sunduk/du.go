@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const duUsage = "du [-n count] <store>"
+
+// runDu prints the sizes of the entries of a store, largest chunks first, so that users tell which entries
+// dominate the store file
+func runDu(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("du", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	count := flags.Int("n", 0, "print the `count` largest entries only")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: sunduk "+duUsage)
+		return 2
+	}
+	store, err := openExisting(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "sunduk: %v\n", err)
+		return 2
+	}
+	defer store.Close()
+
+	sizes := store.EntrySizes()
+	keys := make([]string, 0, len(sizes))
+	for k := range sizes {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := sizes[keys[i]], sizes[keys[j]]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return keys[i] < keys[j]
+	})
+	if *count > 0 && *count < len(keys) {
+		keys = keys[:*count]
+	}
+	fmt.Fprintf(stdout, "%12s %12s %7s  %s\n", "size", "raw size", "ratio", "key")
+	for _, k := range keys {
+		s := sizes[k]
+		fmt.Fprintf(stdout, "%12d %12d %7.2f  %s\n", s.Size, s.RawSize, s.Ratio, k)
+	}
+	return 0
+}
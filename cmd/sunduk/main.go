@@ -0,0 +1,56 @@
+// Command sunduk inspects and manipulates sunduk store files
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command is a sunduk subcommand. run returns the process exit code
+type command struct {
+	usage string
+	help  string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"convert": {convertUsage, "import a BoltDB file, a BadgerDB directory or a zip archive into a store, or export a store to one", runConvert},
+	"diff":    {diffUsage, "show keys added, removed and changed between two stores", runDiff},
+	"du":      {duUsage, "show the sizes of entries, largest first", runDu},
+	"meta":    {metaUsage, "show the metadata of a store", runMeta},
+	"seal":    {sealUsage, "seal a store, signing it with the Ed25519 key in keyfile", runSeal},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "sunduk: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "usage: sunduk <command> [arguments]")
+	fmt.Fprintln(w, "commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-24s %s\n", commands[name].usage, commands[name].help)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const metaUsage = "meta <store>"
+
+// runMeta prints the metadata of a store, which tells what a store holds without reading its values
+func runMeta(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: sunduk "+metaUsage)
+		return 2
+	}
+	store, err := openExisting(args[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "sunduk: %v\n", err)
+		return 2
+	}
+	defer store.Close()
+
+	meta := store.GetMeta()
+	fmt.Fprintf(stdout, "version: %d\n", meta.Version)
+	fmt.Fprintf(stdout, "application: %s\n", meta.Application)
+	if !meta.Created.IsZero() {
+		fmt.Fprintf(stdout, "created: %s\n", meta.Created.Format(time.RFC3339))
+	}
+	if !meta.Sealed.IsZero() {
+		fmt.Fprintf(stdout, "sealed: %s\n", meta.Sealed.Format(time.RFC3339))
+	}
+	fmt.Fprintf(stdout, "generation: %d\n", store.Generation())
+	keys := make([]string, 0, len(meta.Values))
+	for k := range meta.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(stdout, "%s: %s\n", k, meta.Values[k])
+	}
+	return 0
+}
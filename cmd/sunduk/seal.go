@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sunduk"
+)
+
+const sealUsage = "seal [-key keyfile] <store>"
+
+// runSeal seals a store, so that later opens refuse to write to it. The key file holds
+// an Ed25519 private key or its 32-byte seed, raw
+func runSeal(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("seal", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	keyFile := flags.String("key", "", "sign the store with the Ed25519 private key in `keyfile`")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: sunduk "+sealUsage)
+		return 2
+	}
+	var key ed25519.PrivateKey
+	if *keyFile != "" {
+		data, err := os.ReadFile(*keyFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "sunduk: %v\n", err)
+			return 2
+		}
+		switch len(data) {
+		case ed25519.SeedSize:
+			key = ed25519.NewKeyFromSeed(data)
+		case ed25519.PrivateKeySize:
+			key = data
+		default:
+			fmt.Fprintf(stderr, "sunduk: invalid key of %d bytes in %s\n", len(data), *keyFile)
+			return 2
+		}
+	}
+
+	path := flags.Arg(0)
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(stderr, "sunduk: %v\n", err)
+		return 2
+	}
+	store, err := sunduk.Open(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "sunduk: %v\n", err)
+		return 2
+	}
+	defer store.Close()
+	if err := store.Seal(key); err != nil {
+		fmt.Fprintf(stderr, "sunduk: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package sunduk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Collation orders the keys of a store, see WithCollation
+type Collation struct {
+	// Name identifies the collation in the store file, so that the store file is opened with the same collation
+	Name string
+	// Compare returns a negative number if a sorts before b, a positive number if a sorts after b, and 0 if they
+	// sort equally. Keys sorting equally are ordered bytewise
+	Compare func(a, b string) int
+}
+
+var (
+	// NaturalOrder orders keys bytewise, but runs of digits by their numeric value, so "file2" sorts before "file10"
+	NaturalOrder = Collation{Name: "natural", Compare: compareNatural}
+	// CaseInsensitive orders keys bytewise, ignoring the case of letters
+	CaseInsensitive = Collation{Name: "nocase", Compare: compareNoCase}
+)
+
+// collations are the collations store files recording them are opened with without WithCollation
+var collations = map[string]*Collation{NaturalOrder.Name: &NaturalOrder, CaseInsensitive.Name: &CaseInsensitive}
+
+// WithCollation orders keys by c instead of bytewise: OrderedKeys, Keys with options, ForEach, Range and Cursor
+// follow the collation, while Prefix still selects keys starting with the prefix. The index of the store file
+// stays in bytewise order, so that lookups and prefix scans aren't slowed down and older versions of the package
+// read the file, but the name of the collation is recorded in it. Opening a store file recording another
+// collation fails with ErrCollation, and store files recording NaturalOrder or CaseInsensitive are opened with
+// them without WithCollation
+func WithCollation(c Collation) Option {
+	return func(o *options) {
+		o.collation = &c
+	}
+}
+
+// name returns the name of the collation recorded in the store file, empty for bytewise order
+func (c *Collation) name() string {
+	if c == nil {
+		return ""
+	}
+	return c.Name
+}
+
+// compare compares keys by the collation, and bytewise if they sort equally
+func (c *Collation) compare(a, b string) int {
+	if c != nil {
+		if r := c.Compare(a, b); r != 0 {
+			return r
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// sort returns keys in bytewise order ordered by the collation
+func (c *Collation) sort(keys OrderedKeys) OrderedKeys {
+	if c == nil {
+		return keys
+	}
+	sorted := append([]string(nil), keys.keys...)
+	c.sortStrings(sorted)
+	return OrderedKeys{keys: sorted, collation: c}
+}
+
+// sortStrings sorts keys by the collation in place
+func (c *Collation) sortStrings(keys []string) {
+	if c == nil {
+		sort.Strings(keys)
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.compare(keys[i], keys[j]) < 0
+	})
+}
+
+// useCollation sets the collation of the store from the name recorded by the store file. It fails with ErrCollation
+// if it isn't the name of the collation of the store, or the file records an unknown collation
+func (store *Sunduk) useCollation(name string) error {
+	c := store.opts.collation
+	switch {
+	case name == "":
+		// Files recording no collation record the collation of the store on the next write
+	case c != nil && c.Name != name:
+		return fmt.Errorf("%w: %q instead of %q", ErrCollation, name, c.Name)
+	case c == nil:
+		if c = collations[name]; c == nil {
+			return fmt.Errorf("%w: %q is unknown", ErrCollation, name)
+		}
+	}
+	store.collation = c
+	return nil
+}
+
+// compareNatural compares a and b bytewise, but runs of digits by their numeric value
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			da, db := digits(a), digits(b)
+			// Leading zeros don't change the value, numbers with more digits are larger
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) - len(nb)
+			}
+			if r := strings.Compare(na, nb); r != 0 {
+				return r
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+// digits returns the run of digits s starts with
+func digits(s string) string {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+// compareNoCase compares a and b bytewise, ignoring the case of letters
+func compareNoCase(a, b string) int {
+	for a != "" && b != "" {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if la, lb := unicode.ToLower(ra), unicode.ToLower(rb); la != lb {
+			return int(la) - int(lb)
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return len(a) - len(b)
+}
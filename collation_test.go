@@ -0,0 +1,83 @@
+package sunduk
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSunduk_WithCollation(t *testing.T) {
+	store := New(TestStoreFile, WithCollation(NaturalOrder))
+	defer deleteTestStoreFile()
+	for _, k := range []string{"file10", "file2", "file1", "other"} {
+		_ = store.Put(k, []byte(k))
+	}
+	expected := []string{"file1", "file2", "file10", "other"}
+	if keys := store.OrderedKeys().Strings(); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected keys %v, got %v instead", expected, keys)
+	}
+	if keys := store.Keys(Sorted()); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected sorted keys %v, got %v instead", expected, keys)
+	}
+	if keys := store.Keys(Prefix("file"), Reversed()); !reflect.DeepEqual(keys, []string{"file10", "file2", "file1"}) {
+		t.Errorf("Expected reversed keys with prefix, got %v instead", keys)
+	}
+	var ranged []string
+	_ = store.Range("file2", "other", func(key string, _ []byte) error {
+		ranged = append(ranged, key)
+		return nil
+	})
+	if !reflect.DeepEqual(ranged, []string{"file2", "file10"}) {
+		t.Errorf("Expected range [file2 file10], got %v instead", ranged)
+	}
+	c := store.Cursor()
+	if !c.Seek("file3") || c.Key() != "file10" {
+		t.Errorf("Expected cursor to seek to file10, got %q instead", c.Key())
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	if keys := store.OrderedKeys().Strings(); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected keys in the recorded collation %v, got %v instead", expected, keys)
+	}
+	store.Close()
+
+	if _, err := Open(TestStoreFile, WithCollation(CaseInsensitive)); !errors.Is(err, ErrCollation) {
+		t.Errorf("Expected ErrCollation for another collation, got %v instead", err)
+	}
+}
+
+func TestSunduk_WithCollationCustom(t *testing.T) {
+	reverse := Collation{Name: "reverse", Compare: func(a, b string) int { return strings.Compare(b, a) }}
+	store := New(TestStoreFile, WithCollation(reverse))
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": nil, "b": nil, "c": nil})
+	if keys := store.OrderedKeys().Strings(); !reflect.DeepEqual(keys, []string{"c", "b", "a"}) {
+		t.Errorf("Expected keys in reverse order, got %v instead", keys)
+	}
+	store.Close()
+
+	if _, err := Open(TestStoreFile); !errors.Is(err, ErrCollation) {
+		t.Errorf("Expected ErrCollation for an unknown collation, got %v instead", err)
+	}
+}
+
+func TestCollation_Compare(t *testing.T) {
+	for _, tc := range []struct {
+		c    Collation
+		a, b string
+	}{
+		{NaturalOrder, "file2", "file10"},
+		{NaturalOrder, "file002", "file10"},
+		{NaturalOrder, "file2", "file2a"},
+		{NaturalOrder, "a9b", "a10a"},
+		{CaseInsensitive, "apple", "Banana"},
+		{CaseInsensitive, "Apple", "apple"},
+		{CaseInsensitive, "ä", "Ö"},
+	} {
+		if tc.c.compare(tc.a, tc.b) >= 0 || tc.c.compare(tc.b, tc.a) <= 0 {
+			t.Errorf("Expected %q before %q in collation %s", tc.a, tc.b, tc.c.Name)
+		}
+	}
+}
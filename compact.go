@@ -0,0 +1,628 @@
+package sunduk
+
+import (
+	"fmt"
+	"os"
+	"sunduk/internal/format"
+	"sunduk/internal/vfs"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// compaction holds the state of manual and background compactions
+type compaction struct {
+	mu       sync.Mutex // mu serializes compactions
+	paused   int32
+	stopOnce sync.Once
+	stop     chan struct{} // stop is closed to stop background compaction
+	done     chan struct{} // done is closed once background compaction is stopped
+	throttle *throttle     // throttle limits the IO of compactions, nil for no limit
+}
+
+// Compact rewrites the store file keeping only live entries, reclaiming the space of overwritten and deleted
+// values. Writers are blocked only while the entries written during compaction are copied to the new file.
+// The original file is backed up until it is replaced
+func (store *Sunduk) Compact() error {
+	store.compaction.mu.Lock()
+	defer store.compaction.mu.Unlock()
+	return store.compact(nil, 0)
+}
+
+// PauseCompaction stops background compaction and aborts a running compaction, leaving the store file as it was.
+// Compact returns ErrCompactionPaused until ResumeCompaction is called
+func (store *Sunduk) PauseCompaction() {
+	atomic.StoreInt32(&store.compaction.paused, 1)
+}
+
+// ResumeCompaction allows compactions paused by PauseCompaction
+func (store *Sunduk) ResumeCompaction() {
+	atomic.StoreInt32(&store.compaction.paused, 0)
+}
+
+// compact rewrites the store file, it is aborted when stop is closed. If dictSize is positive, a new dictionary
+// of at most dictSize bytes is trained from the values and small values are compressed again with it.
+// It must be called with compaction.mu held
+func (store *Sunduk) compact(stop <-chan struct{}, dictSize int) error {
+	err := store.rewriteFile(stop, dictSize)
+	switch err {
+	case nil:
+	case ErrCompactionPaused:
+		store.opts.logger.Info("compaction aborted", "file", store.FilePath)
+	default:
+		store.opts.logger.Error("compaction failed", "file", store.FilePath, "err", err)
+	}
+	return err
+}
+
+// rewriteFile rewrites the store file for compact
+func (store *Sunduk) rewriteFile(stop <-chan struct{}, dictSize int) error {
+	aborted := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return atomic.LoadInt32(&store.compaction.paused) != 0
+		}
+	}
+	if err := store.writable(); err != nil {
+		return err
+	}
+	if aborted() {
+		return ErrCompactionPaused
+	}
+	start := time.Now()
+	store.opts.logger.Info("compaction started", "file", store.FilePath, "dictionary", dictSize > 0)
+
+	// Take a snapshot of entries, the index is never modified in place so it can be shared
+	store.writeMu.Lock()
+	if err := store.checkOpen(); err != nil {
+		store.writeMu.Unlock()
+		return err
+	}
+	if err := store.flushPending(); err != nil {
+		store.writeMu.Unlock()
+		return err
+	}
+	store.mu.RLock()
+	file, snapshot := store.file.acquire(), store.index
+	data := make(map[string][]byte, len(store.data))
+	for k, v := range store.data {
+		data[k] = v
+	}
+	evicted := store.evictions(snapshot)
+	store.mu.RUnlock()
+	store.writeMu.Unlock()
+	defer file.release()
+
+	dict, recompress := file.dict.bytes(), func(e entry) bool { return false }
+	if dictSize > 0 {
+		var err error
+		if dict, err = store.trainDictionary(file, snapshot, data, dictSize, aborted); err != nil {
+			return err
+		}
+		// Chunks compressed with the old dictionary can't be copied, and small values gain from the new one
+		recompress = func(e entry) bool {
+			return e.Flags&format.FlagDict != 0 || e.Flags == 0 && e.RawSize <= maxDictValueSize
+		}
+	}
+	r, err := store.newRewrite(dict)
+	if err != nil {
+		return err
+	}
+	defer r.discard()
+
+	// Evicted entries aren't copied, the snapshot still tells the entries written meanwhile
+	live := snapshot
+	if len(evicted) > 0 {
+		live = make(map[string]entry, len(snapshot)-len(evicted))
+		for k, e := range snapshot {
+			if !evicted[k] {
+				live[k] = e
+			}
+		}
+	}
+	keys := newOrderedKeys(live)
+	var read int64
+	load := func(i int) (chunk, error) {
+		if aborted() {
+			return chunk{}, ErrCompactionPaused
+		}
+		k := keys.At(i)
+		return store.copyChunk(file, k, snapshot, data, recompress(snapshot[k]))
+	}
+	err = r.enc.compressOrdered(store.workers, keys.Len(), load, func(i int, c chunk) error {
+		if err := r.put(keys.At(i), c); err != nil {
+			return err
+		}
+		read += snapshot[keys.At(i)].Size
+		store.compaction.throttle.wait(snapshot[keys.At(i)].Size+int64(len(c.data)), stop)
+		store.progress(Progress{Operation: OperationCompact, Done: i + 1, Total: keys.Len(), Bytes: read})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Copy entries written meanwhile, writers are blocked until the new file replaces the old one
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if err := store.flushPending(); err != nil {
+		return err
+	}
+	var changed []string
+	for k, e := range store.index {
+		if se, ok := snapshot[k]; !ok || se != e {
+			changed = append(changed, k)
+		}
+	}
+	load = func(i int) (chunk, error) {
+		k := changed[i]
+		return store.copyChunk(store.file, k, store.index, store.data, recompress(store.index[k]))
+	}
+	err = r.enc.compressOrdered(store.workers, len(changed), load, func(i int, c chunk) error {
+		return r.put(changed[i], c)
+	})
+	if err != nil {
+		return err
+	}
+	for k := range r.index {
+		if _, ok := store.index[k]; !ok {
+			delete(r.index, k)
+		}
+	}
+	// Keys written meanwhile aren't evicted
+	var dropped []string
+	for k := range evicted {
+		if _, ok := r.index[k]; !ok {
+			if _, ok := store.index[k]; ok {
+				dropped = append(dropped, k)
+			}
+		}
+	}
+	purged, err := store.rewriteHeader(r, recompress)
+	if err != nil {
+		return err
+	}
+	var records []format.AuditRecord
+	if len(dropped) > 0 {
+		store.mu.RLock()
+		records = store.auditRecords(nil, dropped, putOptions{}, r.header.Generation+1)
+		store.mu.RUnlock()
+		r.header = evictHeader(r.header, dropped)
+		r.header.Generation++
+	}
+	if r.header.Audit, err = store.rewriteAudit(r, store.file, r.header.Audit, records); err != nil {
+		return err
+	}
+	if err := store.replace(r); err != nil {
+		return err
+	}
+	if len(dropped) > 0 {
+		store.mu.Lock()
+		for _, k := range dropped {
+			store.cache.remove(k)
+		}
+		store.changes.record(store.generation-1, Change{Generation: store.generation, Deleted: dropped})
+		store.mu.Unlock()
+		for _, h := range store.opts.hooks {
+			if h.OnEvict != nil {
+				for _, k := range dropped {
+					h.OnEvict(k)
+				}
+			}
+		}
+	}
+	atomic.StoreInt64(&store.counters.lastCompaction, int64(time.Since(start)))
+	store.opts.logger.Info("compaction finished", "file", store.FilePath, "size", store.size, "evicted", len(dropped), "purged", purged, "duration", time.Since(start))
+	return nil
+}
+
+// rewriteHeader copies the soft-deleted and trashed values to the new file of r and sets its header, and returns
+// the count of tombstones purged. It must be called with writeMu held
+func (store *Sunduk) rewriteHeader(r *rewrite, recompress func(entry) bool) (int, error) {
+	tombstones, purged, err := store.rewriteTombstones(r, recompress)
+	if err != nil {
+		return 0, err
+	}
+	r.header = store.header()
+	r.header.Tombstones = encodeTombstones(tombstones)
+	trash, err := store.rewriteTrash(r, recompress)
+	if err != nil {
+		return 0, err
+	}
+	r.header.Trash = encodeTrash(trash)
+	return purged, nil
+}
+
+// convert applies the chunks of values and deleted keys merged by commit, and their audit records, to a store read
+// from a legacy file by rewriting it in the current format. It must be called with writeMu held
+func (store *Sunduk) convert(chunks map[string]chunk, deleted []string, values map[string][]byte, header format.Index, records []format.AuditRecord) error {
+	store.opts.logger.Info("converting store file to the current format", "file", store.FilePath)
+	store.mu.RLock()
+	index := make(map[string]entry, len(store.index)+len(chunks))
+	for k, e := range store.index {
+		index[k] = e
+	}
+	store.mu.RUnlock()
+	for _, k := range deleted {
+		delete(index, k)
+	}
+	for k := range chunks {
+		index[k] = entry{}
+	}
+
+	r, err := store.newRewrite(store.dict.bytes())
+	if err != nil {
+		return err
+	}
+	defer r.discard()
+	keys := newOrderedKeys(index)
+	load := func(i int) (chunk, error) {
+		if c, ok := chunks[keys.At(i)]; ok {
+			return c, nil
+		}
+		return store.copyChunk(store.file, keys.At(i), index, nil, false)
+	}
+	err = r.enc.compressOrdered(store.workers, keys.Len(), load, func(i int, c chunk) error {
+		return r.put(keys.At(i), c)
+	})
+	if err != nil {
+		return err
+	}
+	r.header = header
+	if r.header.Audit, err = store.rewriteAudit(r, store.file, header.Audit, records); err != nil {
+		return err
+	}
+	if err := store.replace(r); err != nil {
+		return err
+	}
+	store.mu.Lock()
+	store.settle(values, deleted)
+	store.mu.Unlock()
+	return nil
+}
+
+// load returns the value of key from data, or reads it from file
+func (store *Sunduk) load(file *handle, key string, index map[string]entry, data map[string][]byte) ([]byte, error) {
+	if value, ok := data[key]; ok {
+		return value, nil
+	}
+	value, _, err := store.fetch(file, key, index[key])
+	if err != nil {
+		return nil, fmt.Errorf("storage consistancy is broken: value for key %q is not readable: %w", key, err)
+	}
+	return value, nil
+}
+
+// copyChunk returns the chunk of key for copying to a new file. Chunks are copied verbatim once their
+// checksums are verified, unless recompress is true, so only values of legacy entries, of repaired
+// chunks, of delta and appended chunks, which bases aren't copied, and of entries missing their digest
+// are compressed again
+func (store *Sunduk) copyChunk(file *handle, key string, index map[string]entry, data map[string][]byte, recompress bool) (chunk, error) {
+	e := index[key]
+	if e.hasSum && !recompress && e.Flags&(format.FlagDelta|format.FlagAppend) == 0 && (store.enc.hash == format.HashNone || e.Digest != "") {
+		zdata, err := store.readChunk(file, e)
+		if err != nil {
+			return chunk{}, fmt.Errorf("storage consistancy is broken: value for key %q is not readable: %v", key, err)
+		}
+		if rawSize, sum, err := chunkSum(zdata, e.Flags, file.dict); err == nil && rawSize == e.RawSize && sum == e.Sum {
+			return chunk{data: zdata, rawSize: rawSize, sum: sum, digest: []byte(e.Digest), flags: e.Flags}, nil
+		}
+	}
+	value, err := store.load(file, key, index, data)
+	return chunk{value: value}, err
+}
+
+// rewriteChunks copies the chunks of entries of keys, which aren't entries of the index, such as the entries of
+// soft-deleted keys, to the new file of r and returns their entries in the new file. It must be called with writeMu held
+func (store *Sunduk) rewriteChunks(r *rewrite, keys []string, entries []entry, recompress func(e entry) bool) ([]entry, error) {
+	load := func(i int) (chunk, error) {
+		return store.copyChunk(store.file, keys[i], map[string]entry{keys[i]: entries[i]}, nil, recompress(entries[i]))
+	}
+	copied := make([]entry, len(entries))
+	err := r.enc.compressOrdered(store.workers, len(keys), load, func(i int, c chunk) (err error) {
+		copied[i], err = writeShared(r.w, r.file, r.chunks, c)
+		return
+	})
+	return copied, err
+}
+
+// rewrite is a new store file, written next to the store file, that replaces it when complete
+type rewrite struct {
+	path  string
+	file  vfs.File
+	w     *offsetWriter
+	enc   encoder
+	index map[string]entry
+	dict  *dictionary
+	id    format.FileID // id is the ID of the new file if its index goes to an index file, see WithIndexFile
+	log   Logger
+
+	chunks map[chunkKey]entry // chunks locates the chunks of the new file by content, nil without deduplication
+
+	header format.Index // header holds the generation, the metadata, the signature and the seal of the store once the new file replaces the store file
+}
+
+// newRewrite creates the file for rewriting the store, with dict as dictionary if it isn't nil
+func (store *Sunduk) newRewrite(dict []byte) (*rewrite, error) {
+	path := store.tempPath()
+	if err := store.checkSpace(path, store.EstimateFileSize()); err != nil {
+		return nil, err
+	}
+	file, err := store.createFile(path, store.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	r := &rewrite{path: path, file: file, w: &offsetWriter{file: file}, index: make(map[string]entry), log: store.opts.logger}
+	r.id, err = store.writeFilePreamble(r.w)
+	if err == nil && dict != nil {
+		r.dict = &dictionary{data: dict, offset: r.w.offset}
+		_, err = r.w.Write(dict)
+	}
+	if err != nil {
+		r.discard()
+		return nil, fmt.Errorf("unable to create %s file for flushing: %s", path, err.Error())
+	}
+	r.enc = store.enc.withDict(r.dict)
+	if store.opts.dedup {
+		r.chunks = make(map[chunkKey]entry)
+	}
+	return r, nil
+}
+
+// put writes the chunk of an entry into the new file
+func (r *rewrite) put(key string, c chunk) error {
+	e, err := writeShared(r.w, r.file, r.chunks, c)
+	if err != nil {
+		return fmt.Errorf("unable to create %s file for flushing: %s", r.path, err.Error())
+	}
+	r.index[key] = e
+	return nil
+}
+
+// discard removes the new file unless it has replaced the store file
+func (r *rewrite) discard() {
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			r.log.Warn("unable to close discarded file", "file", r.path, "err", err)
+		}
+	}
+	if err := fsys.Remove(r.path); err != nil && !os.IsNotExist(err) {
+		r.log.Warn("unable to remove discarded file", "file", r.path, "err", err)
+	}
+}
+
+// replace completes the new file and puts it in place of the store file. The original file is backed up
+// until the new one is in place, and restored if replacing fails. The index file of a new file with one is
+// put in place right after the new file, and recovered on open if replacing is interrupted in between.
+// It must be called with writeMu held
+func (store *Sunduk) replace(r *rewrite) error {
+	start := r.w.offset
+	keys := newOrderedKeys(r.index)
+	header := r.header
+	header.Dictionary = r.dict.location()
+	header.Bloom = store.newBloom(keys)
+	var reads uint64
+	header.Access, reads = store.access.list(r.index)
+	header.Offset = start
+	header.Data = format.DataFile{ID: r.id}
+	var indexSize int64
+	var err error
+	if r.id != (format.FileID{}) {
+		header.Data.Size = start
+		indexSize, err = store.writeIndexFile(r.enc, keys, r.index, header)
+	} else {
+		err = store.writeIndex(r.w, r.enc, keys, r.index, header)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to create %s file for flushing: %s", r.path, err.Error())
+	}
+	if err := store.syncData(r.file); err != nil {
+		return fmt.Errorf("unable to sync %s file after flushing: %s", r.path, err.Error())
+	}
+	err = r.file.Close()
+	r.file = nil
+	if err != nil {
+		return fmt.Errorf("unable to close %s file after flushing: %s", r.path, err.Error())
+	}
+
+	// Back up the old file before doing the flushing. Readers holding the old file keep reading it
+	bakname := store.FilePath + ".bak"
+	if err := fsys.Rename(store.FilePath, bakname); err != nil {
+		return fmt.Errorf("unable to rename %s to %s during flushing: %s", store.FilePath, bakname, err.Error())
+	}
+	if err := fsys.Rename(r.path, store.FilePath); err != nil {
+		return store.restore(bakname, fmt.Errorf("unable to save new file at %s during flushing: %s", store.FilePath, err.Error()))
+	}
+
+	// Re-open the store on the new file, or put the new file aside and restore the old one
+	file, err := fsys.OpenFile(store.FilePath, os.O_RDWR, 0)
+	if err != nil {
+		err = fmt.Errorf("unable to re-open %s after flushing: %s", store.FilePath, err.Error())
+		if rerr := fsys.Rename(store.FilePath, r.path); rerr != nil {
+			return fmt.Errorf("%v, and unable to move it back to %s: %v", err, r.path, rerr)
+		}
+		return store.restore(bakname, err)
+	}
+	var indexInfo os.FileInfo
+	if r.id != (format.FileID{}) {
+		if indexInfo, err = store.replaceIndexFile(); err != nil {
+			_ = file.Close()
+			err = fmt.Errorf("unable to save new index file at %s during flushing: %s", store.indexPath(), err.Error())
+			if rerr := fsys.Rename(store.FilePath, r.path); rerr != nil {
+				return fmt.Errorf("%v, and unable to move it back to %s: %v", err, r.path, rerr)
+			}
+			return store.restore(bakname, err)
+		}
+	} else if store.hasIndexFile() {
+		store.removeIndexFile()
+	}
+	if err := store.syncEntry(); err != nil {
+		// The store file is replaced already, it may only be lost with the directory entry on a power loss
+		store.opts.logger.Warn("unable to sync directory of replaced store file", "file", store.FilePath, "err", err)
+	}
+	store.mu.Lock()
+	old := store.file
+	store.file = newHandle(file, r.dict)
+	store.file.hash = r.enc.hash
+	store.leak.track(store.file, store.lock)
+	store.dict = r.dict
+	store.index = r.index
+	store.setHeader(header)
+	store.access.committed(reads)
+	store.chunks = nil
+	store.size = r.w.offset
+	store.tail = r.w.offset - start
+	store.indexInfo = indexInfo
+	store.legacy = false
+	store.mu.Unlock()
+	old.release()
+	atomic.AddUint64(&store.counters.bytesWritten, uint64(r.w.offset+indexSize))
+
+	// The backup is removed once the store lets go of it, Windows keeps the name of a removed file while it is open
+	if err := fsys.Remove(bakname); err != nil {
+		// The store file is replaced already, a leftover backup only takes space
+		store.opts.logger.Warn("unable to remove backup file", "file", bakname, "err", err)
+	}
+	return nil
+}
+
+// restore puts the backup of the store file back in place after replacing it failed with err
+func (store *Sunduk) restore(bakname string, err error) error {
+	if rerr := fsys.Rename(bakname, store.FilePath); rerr != nil {
+		store.opts.logger.Error("unable to restore store file from backup", "file", store.FilePath, "backup", bakname, "err", rerr)
+		return fmt.Errorf("%v, and unable to restore %s from %s: %v", err, store.FilePath, bakname, rerr)
+	}
+	return err
+}
+
+// openBackup opens the backup of the store file left by a compaction which crashed between moving the store file
+// aside and moving the new file in place, which open of the store file failed with err. Such a crash leaves the new
+// file next to the backup, the backup is restored then, or opened in place if the store is read-only, and the new
+// file is removed. A backup without a new file is a leftover of a compaction which completed, and is removed rather
+// than restored over a store file removed since. It returns err if there is no backup of a crashed compaction
+func (store *Sunduk) openBackup(err error) (vfs.File, error) {
+	bakname := store.FilePath + ".bak"
+	if _, serr := os.Stat(bakname); serr != nil {
+		return nil, err
+	}
+	if _, serr := os.Stat(store.tempPath()); serr != nil {
+		if !store.opts.readOnly {
+			if rerr := fsys.Remove(bakname); rerr != nil {
+				store.opts.logger.Warn("unable to remove stale backup file", "file", bakname, "err", rerr)
+			}
+		}
+		return nil, err
+	}
+	name := bakname
+	if !store.opts.readOnly {
+		if rerr := fsys.Rename(bakname, store.FilePath); rerr != nil {
+			return nil, fmt.Errorf("%v, and unable to restore %s from %s: %v", err, store.FilePath, bakname, rerr)
+		}
+		name = store.FilePath
+		if rerr := fsys.Remove(store.tempPath()); rerr != nil {
+			store.opts.logger.Warn("unable to remove new file of crashed compaction", "file", store.tempPath(), "err", rerr)
+		}
+	}
+	store.opts.logger.Warn("opening backup of store file left by a crashed compaction", "file", store.FilePath, "backup", bakname)
+	return fsys.OpenFile(name, store.openFlag(), 0)
+}
+
+// garbage returns the size of dead bytes in the store file, left by overwritten and deleted entries
+// and by superseded indexes, and the size of live bytes
+func (store *Sunduk) garbage() (dead, live int64) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if store.legacy || store.size == 0 {
+		return 0, store.size
+	}
+	live = format.PreambleSize + store.tail + store.dict.location().Size
+	if store.hasIndexFile() {
+		live += format.FileIDSize
+	}
+	var shared map[int64]bool
+	if store.opts.dedup {
+		shared = make(map[int64]bool, len(store.index))
+	}
+	// Shared chunks and bases are counted once
+	count := func(offset, size int64) {
+		if shared == nil || !shared[offset] {
+			live += size
+		}
+		if shared != nil {
+			shared[offset] = true
+		}
+	}
+	for _, c := range store.audit {
+		live += c.Size
+	}
+	countEntry := func(_ string, e entry) {
+		count(e.Offset, e.Size)
+		if e.Flags&(format.FlagDelta|format.FlagAppend) != 0 {
+			count(e.Base.Offset, e.Base.Size)
+		}
+	}
+	store.scan("", countEntry)
+	for k, t := range store.tombstones {
+		countEntry(k, t.entry)
+	}
+	for _, t := range store.trash {
+		countEntry(t.key, t.entry)
+	}
+	return store.size - live, live
+}
+
+// startCompactor starts background compaction if it is enabled, or if the store has a size cap
+func (store *Sunduk) startCompactor() {
+	if store.opts.compactionThreshold <= 0 && store.opts.sizeCap <= 0 || store.opts.readOnly {
+		return
+	}
+	interval := store.opts.compactionInterval
+	if interval <= 0 {
+		interval = defaultEvictionInterval
+	}
+	store.compaction.stop = make(chan struct{})
+	store.compaction.done = make(chan struct{})
+	go store.runCompactor(store.opts.compactionThreshold, interval)
+}
+
+// stopCompactor stops background compaction, aborting a running compaction, and waits for it to finish
+func (store *Sunduk) stopCompactor() {
+	if store.compaction.stop == nil {
+		return
+	}
+	store.compaction.stopOnce.Do(func() {
+		close(store.compaction.stop)
+	})
+	<-store.compaction.done
+}
+
+// runCompactor checks the ratio of dead to live bytes every interval and compacts the store
+// when the ratio reaches a positive threshold, or when the store file has grown past its size cap
+func (store *Sunduk) runCompactor(threshold float64, interval time.Duration) {
+	defer close(store.compaction.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-store.compaction.stop:
+			return
+		case <-ticker.C:
+		}
+		// Compactions of frozen stores would wait for Thaw holding the compaction lock
+		if atomic.LoadInt32(&store.compaction.paused) != 0 || store.isFrozen() || store.writable() != nil {
+			continue
+		}
+		if dead, live := store.garbage(); !store.overCap() && (threshold <= 0 || dead == 0 || float64(dead) < threshold*float64(live)) {
+			continue
+		}
+		if !store.compaction.mu.TryLock() {
+			continue
+		}
+		// Failures are logged by compact and retried on the next tick
+		_ = store.compact(store.compaction.stop, 0)
+		store.compaction.mu.Unlock()
+	}
+}
@@ -0,0 +1,319 @@
+package sunduk
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"sunduk/internal/vfs"
+	"testing"
+	"time"
+)
+
+func TestSunduk_PutAppendsWithoutRewriting(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", bytes.Repeat([]byte("value"), 100))
+	before, _ := os.ReadFile(TestStoreFile)
+	_ = store.Put("other", []byte("other value"))
+	after, _ := os.ReadFile(TestStoreFile)
+	if !bytes.HasPrefix(after, before) {
+		t.Error("Expected Put to append to the store file")
+	}
+	store.Close()
+}
+
+func TestSunduk_Compact(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	for i := 0; i < 10; i++ {
+		_ = store.Put("key", bytes.Repeat([]byte{byte(i)}, 1000))
+	}
+	_ = store.Put("other", []byte("other value"))
+	_ = store.Delete("other")
+	if dead, _ := store.garbage(); dead == 0 {
+		t.Error("Expected overwritten entries to leave dead bytes")
+	}
+	before := fileSize(t, TestStoreFile)
+
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to succeed, got %v instead", err)
+	}
+	if dead, _ := store.garbage(); dead != 0 {
+		t.Errorf("Expected no dead bytes after compaction, got %d instead", dead)
+	}
+	if after := fileSize(t, TestStoreFile); after >= before {
+		t.Errorf("Expected compaction to shrink the file from %d bytes, got %d instead", before, after)
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "key", bytes.Repeat([]byte{9}, 1000))
+	checkKeyNotExists(t, store, "other")
+	store.Close()
+}
+
+func TestSunduk_PutDuringCompaction(t *testing.T) {
+	var store *Sunduk
+	progress := func(done, total int) {
+		if done == 1 {
+			// Writers must not be blocked while the new file is written
+			_ = store.Put("key1", []byte("updated"))
+			_ = store.Put("key4", []byte("added"))
+			_ = store.Delete("key2")
+		}
+	}
+	store = New(TestStoreFile, WithCompactionProgress(progress))
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"key1": []byte("1"), "key2": []byte("2"), "key3": []byte("3")})
+
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to succeed, got %v instead", err)
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "key1", []byte("updated"))
+	checkKeyNotExists(t, store, "key2")
+	checkValueForKey(t, store, "key3", []byte("3"))
+	checkValueForKey(t, store, "key4", []byte("added"))
+	store.Close()
+}
+
+func TestSunduk_AutoCompaction(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	for i := 0; i < 10; i++ {
+		_ = store.Put("key", bytes.Repeat([]byte{byte(i)}, 1000))
+	}
+	store.Close()
+
+	// A compaction running between puts could leave garbage below the threshold, so enable it once garbage is written
+	store = New(TestStoreFile, WithAutoCompaction(1, time.Millisecond))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if dead, _ := store.garbage(); dead == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected background compaction to reclaim dead bytes")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	checkValueForKey(t, store, "key", bytes.Repeat([]byte{9}, 1000))
+	store.Close()
+}
+
+func TestSunduk_PauseCompaction(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	_ = store.Put("key", []byte("new value"))
+
+	store.PauseCompaction()
+	if err := store.Compact(); err != ErrCompactionPaused {
+		t.Errorf("Expected Compact to return ErrCompactionPaused, got %v instead", err)
+	}
+	store.ResumeCompaction()
+	if err := store.Compact(); err != nil {
+		t.Errorf("Expected Compact to succeed after ResumeCompaction, got %v instead", err)
+	}
+	checkValueForKey(t, store, "key", []byte("new value"))
+	store.Close()
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestSunduk_GetDuringCompaction(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	entries := make(map[string][]byte)
+	for i := 0; i < 50; i++ {
+		entries[string(rune('a'+i%26))+string(rune('0'+i/26))] = bytes.Repeat([]byte{byte(i)}, 500)
+	}
+	_ = store.PutAll(entries)
+	store.mu.Lock()
+	store.data = make(map[string][]byte) // force reads from the file
+	store.mu.Unlock()
+
+	stop := make(chan struct{})
+	errs := make(chan string, 4)
+	for g := 0; g < 4; g++ {
+		go func() {
+			for {
+				select {
+				case <-stop:
+					errs <- ""
+					return
+				default:
+				}
+				for k, v := range entries {
+					if value, ok := store.Get(k); !ok || !bytes.Equal(value, v) {
+						errs <- k
+						return
+					}
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if err := store.Compact(); err != nil {
+			t.Errorf("Expected Compact to succeed, got %v instead", err)
+		}
+	}
+	close(stop)
+	for g := 0; g < 4; g++ {
+		if k := <-errs; k != "" {
+			t.Errorf("Expected key '%s' to be readable during compaction", k)
+		}
+	}
+	store.Close()
+}
+
+func TestSunduk_CompactCopiesChunksVerbatim(t *testing.T) {
+	value := bytes.Repeat([]byte("verbatim value "), 1000)
+	store := New(TestStoreFile, WithCompressionMemoryBudget(1)) // smallest window
+	defer deleteTestStoreFile()
+	_ = store.Put("key", value)
+	e := store.index["key"]
+	chunk, _ := store.readChunk(store.file, e)
+	_ = store.Put("other", []byte("other value"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to succeed, got %v instead", err)
+	}
+	copied, err := store.readChunk(store.file, store.index["key"])
+	if err != nil || !bytes.Equal(copied, chunk) {
+		t.Errorf("Expected compaction to copy the chunk of an unchanged entry verbatim")
+	}
+	checkValueForKey(t, store, "key", value)
+	store.Close()
+}
+
+func TestSunduk_CompactVerifiesCopiedChunks(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	_ = store.Put("other", []byte("other value"))
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	store = New(TestStoreFile)
+	if err := store.Compact(); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected Compact to fail on a corrupted chunk, got %v instead", err)
+	}
+	store.Close()
+
+	mirror := New(TestMirrorFile)
+	defer deleteStoreFile(TestMirrorFile)
+	_ = mirror.Put("key", []byte("value"))
+	store = New(TestStoreFile, WithRepairSource(mirror))
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to repair the corrupted chunk, got %v instead", err)
+	}
+	store.Close()
+	mirror.Close()
+
+	store = New(TestStoreFile)
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected the compacted file to verify, got %v instead", err)
+	}
+	checkValueForKey(t, store, "key", []byte("value"))
+	store.Close()
+}
+
+func TestSunduk_CompactRestoresBackupOnFailure(t *testing.T) {
+	faults := []struct {
+		name string
+		op   string
+		path string
+	}{
+		{"backup", "rename", TestStoreFile + " -> " + TestStoreFile + ".bak"},
+		{"rename", "rename", TestStoreFile + ".new -> " + TestStoreFile},
+		{"reopen", "open", TestStoreFile},
+	}
+	for _, fault := range faults {
+		t.Run(fault.name, func(t *testing.T) {
+			store := New(TestStoreFile)
+			defer deleteTestStoreFile()
+			_ = store.Put("key", []byte("value"))
+			_ = store.Put("key", []byte("new value"))
+			_ = store.Put("other", []byte("other value"))
+			before, _ := os.ReadFile(TestStoreFile)
+
+			faults := useFaultFS(t)
+			faults.Inject(vfs.Faults{Fail: func(op, path string) bool { return op == fault.op && path == fault.path }})
+			if err := store.Compact(); err == nil {
+				t.Fatal("Expected Compact to fail on injected fault")
+			}
+			faults.Inject(vfs.Faults{})
+			after, _ := os.ReadFile(TestStoreFile)
+			if !bytes.Equal(after, before) {
+				t.Error("Expected failed Compact to leave the store file unchanged")
+			}
+			for _, name := range []string{TestStoreFile + ".new", TestStoreFile + ".bak"} {
+				if _, err := os.Stat(name); !os.IsNotExist(err) {
+					t.Errorf("Expected failed Compact to leave no %s file", name)
+				}
+			}
+			checkValueForKey(t, store, "key", []byte("new value"))
+			if err := store.Put("third", []byte("third value")); err != nil {
+				t.Errorf("Expected Put to succeed after failed Compact, got %v instead", err)
+			}
+			store.Close()
+
+			store = New(TestStoreFile)
+			checkValueForKey(t, store, "key", []byte("new value"))
+			checkValueForKey(t, store, "other", []byte("other value"))
+			checkValueForKey(t, store, "third", []byte("third value"))
+			store.Close()
+		})
+	}
+}
+
+func TestSunduk_CompactReportsFailedRestore(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer os.Remove(TestStoreFile + ".bak")
+	_ = store.Put("key", []byte("value"))
+	_ = store.Put("key", []byte("new value"))
+
+	useFaultFS(t).Inject(vfs.Faults{Fail: func(op, path string) bool {
+		return op == "rename" && (path == TestStoreFile+".new -> "+TestStoreFile || path == TestStoreFile+".bak -> "+TestStoreFile)
+	}})
+	err := store.Compact()
+	if err == nil || !strings.Contains(err.Error(), "unable to restore") {
+		t.Errorf("Expected Compact to report failed restore of the backup, got %v instead", err)
+	}
+	if _, err := os.Stat(TestStoreFile + ".bak"); err != nil {
+		t.Error("Expected backup to be kept when it can't be restored")
+	}
+	store.Close()
+}
+
+func TestSunduk_CompactKeepsBackupOnFailedRemoval(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer os.Remove(TestStoreFile + ".bak")
+	_ = store.Put("key", []byte("value"))
+	_ = store.Put("key", []byte("new value"))
+
+	useFaultFS(t).Inject(vfs.Faults{Fail: func(op, path string) bool { return op == "remove" && path == TestStoreFile+".bak" }})
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to succeed despite failed removal of the backup, got %v instead", err)
+	}
+	if dead, _ := store.garbage(); dead != 0 {
+		t.Error("Expected Compact to replace the store file")
+	}
+	checkValueForKey(t, store, "key", []byte("new value"))
+	store.Close()
+}
@@ -0,0 +1,56 @@
+package sunduk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeySeparator separates the parts of composite keys built by Key. Separators and escape characters in parts
+// are percent-encoded, as "%2F" and "%25"
+const KeySeparator = "/"
+
+// keyEscaper and keyUnescaper escape and unescape the separator and the escape character in parts of composite keys
+var (
+	keyEscaper   = strings.NewReplacer("%", "%25", "/", "%2F")
+	keyUnescaper = strings.NewReplacer("%25", "%", "%2F", "/")
+)
+
+// Key returns the composite key of parts, such as Key("modem", "ALE2G", "v2") for "modem/ALE2G/v2", escaping
+// separators in parts so that SplitKey returns parts as they are
+func Key(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, p := range parts {
+		escaped[i] = keyEscaper.Replace(p)
+	}
+	return strings.Join(escaped, KeySeparator)
+}
+
+// KeyPrefix returns the prefix of the composite keys which first parts are parts, such as to list them with
+// Keys and Prefix, or to range over them with RangePrefix. Keys equal to Key(parts...) don't have the prefix
+func KeyPrefix(parts ...string) string {
+	if len(parts) == 0 {
+		return ""
+	}
+	return Key(parts...) + KeySeparator
+}
+
+// SplitKey returns the parts of a composite key built by Key. It fails for keys holding escape characters
+// other than those of Key
+func SplitKey(key string) ([]string, error) {
+	parts := strings.Split(key, KeySeparator)
+	for i, p := range parts {
+		for j := 0; j < len(p); j++ {
+			if p[j] == '%' && !strings.HasPrefix(p[j:], "%25") && !strings.HasPrefix(p[j:], "%2F") {
+				return nil, fmt.Errorf("invalid escape in part %d of composite key %q", i, key)
+			}
+		}
+		parts[i] = keyUnescaper.Replace(p)
+	}
+	return parts, nil
+}
+
+// RangePrefix calls fn in key order for every entry which key starts with prefix, such as the composite keys
+// with the prefix of KeyPrefix. Iteration stops at the first error returned by fn
+func (store *Sunduk) RangePrefix(prefix string, fn func(key string, value []byte) error) error {
+	return iterate(store.Get, OrderedKeys{keys: store.Keys(Prefix(prefix), Sorted())}, "", "", fn)
+}
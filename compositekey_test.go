@@ -0,0 +1,44 @@
+package sunduk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	for _, parts := range [][]string{
+		{"modem", "ALE2G", "v2"},
+		{"a/b", "c%2Fd", "%"},
+		{"", "x", ""},
+	} {
+		key := Key(parts...)
+		split, err := SplitKey(key)
+		if err != nil || !reflect.DeepEqual(split, parts) {
+			t.Errorf("Expected parts %q of key %q, got %q (%v) instead", parts, key, split, err)
+		}
+	}
+	if key := Key("modem", "ALE2G", "v2"); key != "modem/ALE2G/v2" {
+		t.Errorf("Expected key modem/ALE2G/v2, got %q instead", key)
+	}
+	if _, err := SplitKey("a/b%zz"); err == nil {
+		t.Error("Expected invalid escape to fail")
+	}
+}
+
+func TestSunduk_RangePrefix(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	for _, k := range []string{Key("modem", "ALE2G", "v2"), Key("modem", "ALE2G", "v1"), Key("modem", "ALE2G/x", "v1"), Key("modem", "ALE2"), Key("modem", "ALE2G")} {
+		_ = store.Put(k, []byte(k))
+	}
+	var keys []string
+	err := store.RangePrefix(KeyPrefix("modem", "ALE2G"), func(key string, value []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	expected := []string{Key("modem", "ALE2G", "v1"), Key("modem", "ALE2G", "v2")}
+	if err != nil || !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected keys %q, got %q (%v) instead", expected, keys, err)
+	}
+}
@@ -0,0 +1,189 @@
+package sunduk
+
+import (
+	"bytes"
+	"runtime"
+	"sunduk/internal/format"
+)
+
+const (
+	minWindowBits     = 10 // minWindowBits is the smallest brotli window, 1 KiB
+	defaultWindowBits = 22 // defaultWindowBits is the brotli default window, 4 MiB
+
+	// defaultCompressionMinSize is the size below which values are stored raw, brotli
+	// hardly shrinks such values and decompressing them costs more than reading them raw
+	defaultCompressionMinSize = 64
+)
+
+// compressedMagics start the data of common compressed formats, values starting with them are stored raw
+var compressedMagics = [][]byte{
+	{0x1f, 0x8b, 0x08},                 // gzip
+	{'P', 'K', 0x03, 0x04},             // zip
+	{0x28, 0xb5, 0x2f, 0xfd},           // zstd
+	{0xfd, '7', 'z', 'X', 'Z', 0x00},   // xz
+	{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, // 7z
+	{0x04, 0x22, 0x4d, 0x18},           // lz4
+	{0x89, 'P', 'N', 'G', '\r', '\n'},  // png
+	{0xff, 0xd8, 0xff},                 // jpeg
+	{'G', 'I', 'F', '8'},               // gif
+}
+
+// encoderMemory is the approximate memory used by a brotli encoder at default quality, measured for
+// window bits from minWindowBits to defaultWindowBits. Buffers holding values and compressed chunks aren't included
+var encoderMemory = [...]int64{
+	2 << 20, 2 << 20, 2 << 20, 2 << 20, 2 << 20, 2 << 20, 2 << 20, // 10-16
+	7 << 19, 5 << 20, 10 << 20, 19 << 20, 30 << 20, 47 << 20, // 17-22
+}
+
+// encoder compresses chunks with the brotli parameters of the store
+type encoder struct {
+	windowBits int
+	minSize    int    // minSize is the size below which values are stored raw
+	frameSize  int    // frameSize is the size of frames values larger than it are compressed in, 0 if they are compressed whole
+	dict       []byte // dict is the dictionary small values are compressed with, if any
+	hash       uint64 // hash is the hash of the digests of values, see WithHash
+}
+
+// withDict returns the encoder compressing small values with dict
+func (enc encoder) withDict(dict *dictionary) encoder {
+	enc.dict = dict.bytes()
+	return enc
+}
+
+// compress returns data compressed with brotli
+func (enc encoder) compress(data []byte) ([]byte, error) {
+	return format.Compress(data, enc.windowBits)
+}
+
+// encode sets the data of a chunk to its value, compressed unless compression is disabled by the mode of the chunk
+// or, in auto mode, the value is small, looks already compressed or doesn't shrink when compressed.
+// Values up to maxDictValueSize are compressed with the dictionary if the encoder has one, and values larger
+// than the frame size of the encoder are compressed in frames. Nil values are written as empty chunks flagged as nil
+func (enc encoder) encode(c *chunk) (err error) {
+	c.rawSize, c.sum = int64(len(c.value)), checksum(c.value)
+	c.digest = format.Digest(enc.hash, c.value)
+	if c.value == nil {
+		c.data, c.flags = []byte{}, format.FlagRaw|format.FlagNil
+		return nil
+	}
+	if c.mode == compressNever || c.mode == compressAuto && (len(c.value) < enc.minSize || looksCompressed(c.value)) {
+		c.data, c.flags = c.value, format.FlagRaw
+		return nil
+	}
+	if enc.dict != nil && len(c.value) <= maxDictValueSize {
+		c.flags = format.FlagDict
+		c.data, err = format.CompressDict(c.value, enc.dict)
+	} else if enc.frameSize > 0 && len(c.value) > enc.frameSize {
+		c.flags = format.FlagFramed
+		c.data, err = format.CompressFrames(c.value, enc.frameSize, enc.windowBits)
+	} else {
+		c.flags = 0
+		c.data, err = enc.compress(c.value)
+	}
+	if err != nil {
+		return err
+	}
+	if c.mode == compressAuto && len(c.data) >= len(c.value) {
+		c.data, c.flags = c.value, format.FlagRaw
+	}
+	return nil
+}
+
+// looksCompressed tells whether value starts with the magic of a compressed format
+func looksCompressed(value []byte) bool {
+	for _, magic := range compressedMagics {
+		if bytes.HasPrefix(value, magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// compression returns the encoder and the count of parallel compression workers fitting the memory budget.
+// Workers are throttled first, the window is shrunk only when even a single encoder with the default
+// window exceeds the budget
+func (o *options) compression() (enc encoder, workers int) {
+	enc.windowBits = defaultWindowBits
+	enc.minSize = o.compressionMinSize
+	enc.frameSize = o.frameSize
+	enc.hash = uint64(o.hash)
+	workers = o.compressionWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if o.compressionBudget <= 0 {
+		return
+	}
+
+	if n := o.compressionBudget / encoderMemory[defaultWindowBits-minWindowBits]; n < int64(workers) {
+		workers = int(n)
+	}
+	if workers > 0 {
+		return
+	}
+	workers = 1
+	for enc.windowBits > minWindowBits && encoderMemory[enc.windowBits-minWindowBits] > o.compressionBudget {
+		enc.windowBits--
+	}
+	return
+}
+
+// chunk is a value to be written to the store file. Its data is nil until the value is encoded
+type chunk struct {
+	value   []byte
+	mode    compressionMode
+	data    []byte
+	rawSize int64
+	sum     uint32
+	digest  []byte // digest is the digest of the value with the hash of the store, nil without hash
+	flags   uint64
+	base    format.Base // base is the base of a delta chunk, see WithDeltaEncoding
+	expires int64       // expires is the expiry time of the value in unix nanoseconds, 0 if it doesn't expire, see TTL
+	err     error
+}
+
+// compressOrdered loads and compresses n values with up to workers goroutines, and calls write
+// for every chunk in order. Chunks loaded with data are written as they are. At most workers
+// values are held in memory at once
+func (enc encoder) compressOrdered(workers, n int, load func(i int) (chunk, error), write func(i int, c chunk) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	// A value is in flight while its result waits in the queue or is awaited by the writer
+	queue := make(chan chan chunk, workers-1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(queue)
+		for i := 0; i < n; i++ {
+			result := make(chan chunk, 1)
+			select {
+			case queue <- result:
+			case <-done:
+				return
+			}
+			go func(i int) {
+				c, err := load(i)
+				if err == nil && c.data == nil {
+					err = enc.encode(&c)
+				}
+				c.err = err
+				result <- c
+			}(i)
+		}
+	}()
+
+	i := 0
+	for result := range queue {
+		c := <-result
+		if c.err != nil {
+			return c.err
+		}
+		if err := write(i, c); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package sunduk
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"sunduk/internal/format"
+	"testing"
+	"time"
+)
+
+func TestOptions_CompressionMemoryBudget(t *testing.T) {
+	twoWorkers := 2
+	if runtime.NumCPU() < twoWorkers {
+		twoWorkers = runtime.NumCPU()
+	}
+	scenarios := []struct {
+		budget     int64
+		windowBits int
+		workers    int
+	}{
+		{0, defaultWindowBits, runtime.NumCPU()},
+		{2 * 47 << 20, defaultWindowBits, twoWorkers},
+		{47 << 20, defaultWindowBits, 1},
+		{10 << 20, 19, 1},
+		{1 << 20, minWindowBits, 1},
+	}
+	for _, scenario := range scenarios {
+		o := options{compressionBudget: scenario.budget}
+		enc, workers := o.compression()
+		if enc.windowBits != scenario.windowBits || workers != scenario.workers {
+			t.Errorf("Expected budget of %d bytes to give window bits %d and %d workers, got %d and %d instead",
+				scenario.budget, scenario.windowBits, scenario.workers, enc.windowBits, workers)
+		}
+	}
+}
+
+func TestSunduk_PutWithSmallCompressionBudget(t *testing.T) {
+	store := New(TestStoreFile, WithCompressionMemoryBudget(1<<20))
+	defer deleteTestStoreFile()
+	value := bytes.Repeat([]byte("0123456789abcdef"), 1<<14)
+	_ = store.Put("key", value)
+	store.Close()
+
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "key", value)
+	store.Close()
+}
+
+func TestSunduk_ParallelCompactionMatchesSequential(t *testing.T) {
+	entries := make(map[string][]byte)
+	for i := 0; i < 100; i++ {
+		entries[string(rune('a'+i%26))+string(rune('a'+i/26))] = bytes.Repeat([]byte{byte(i), byte(i * 7)}, 100+i*10)
+	}
+
+	compacted := func(workers int) []byte {
+		store := New(TestStoreFile, WithCompressionWorkers(workers))
+		defer deleteTestStoreFile()
+		// Stores differ by creation time otherwise
+		_ = store.SetMeta(Meta{Created: time.Unix(1, 0)})
+		_ = store.PutAll(entries)
+		if err := store.Compact(); err != nil {
+			t.Fatalf("Expected Compact to succeed, got %v instead", err)
+		}
+		store.Close()
+		data, _ := os.ReadFile(TestStoreFile)
+		return data
+	}
+	if !bytes.Equal(compacted(1), compacted(8)) {
+		t.Error("Expected parallel compaction to write the same file as sequential compaction")
+	}
+}
+
+func TestSunduk_PutStoresSomeValuesRaw(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	large := bytes.Repeat([]byte("compressible "), 100)
+	gzipped := append([]byte{0x1f, 0x8b, 0x08}, large...)
+	scenarios := []struct {
+		key   string
+		value []byte
+		opts  []PutOption
+		raw   bool
+	}{
+		{"small", []byte(`{"id":42}`), nil, true},
+		{"large", large, nil, false},
+		{"gzipped", gzipped, nil, true},
+		{"incompressible", []byte("0123456789abcdefghijklmnopqrstuvwxyz!@#$%^&*()_+ABCDEFGHIJKLMNOPQRSTUVWXYZ"), nil, true},
+		{"forced raw", large, []PutOption{Uncompressed()}, true},
+		{"forced compression", []byte(`{"id":42}`), []PutOption{Compressed()}, false},
+	}
+	for _, scenario := range scenarios {
+		_ = store.Put(scenario.key, scenario.value, scenario.opts...)
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	for _, scenario := range scenarios {
+		if raw := store.index[scenario.key].Flags&format.FlagRaw != 0; raw != scenario.raw {
+			t.Errorf("Expected value of key '%s' to be stored raw: %v, got %v instead", scenario.key, scenario.raw, raw)
+		}
+		checkValueForKey(t, store, scenario.key, scenario.value)
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to succeed, got %v instead", err)
+	}
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected raw chunks to be verified after compaction, got %v instead", err)
+	}
+	store.Close()
+}
+
+func TestSunduk_CompressionMinSize(t *testing.T) {
+	store := New(TestStoreFile, WithCompressionMinSize(0))
+	defer deleteTestStoreFile()
+	_ = store.Put("key", bytes.Repeat([]byte("a"), 40))
+	if store.index["key"].Flags&format.FlagRaw != 0 {
+		t.Error("Expected small compressible value to be compressed without a minimum size")
+	}
+	store.Close()
+}
@@ -0,0 +1,46 @@
+package sunduk
+
+// Copy puts the value of srcKey under dstKey, replacing the value of dstKey if it has one. The compressed chunk
+// of srcKey is copied to the store file as it is, or referenced with WithDeduplication, so the value isn't
+// compressed again. It returns ErrNotFound if srcKey has no entry. OnBeforePut and OnAfterPut hooks aren't
+// called, as the value isn't decompressed, OnCopy hooks are called instead
+func (store *Sunduk) Copy(srcKey, dstKey string) error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if err := store.checkOpen(); err != nil {
+		return err
+	}
+	store.mu.RLock()
+	file, data := store.file.acquire(), store.data
+	e, ok := store.lookup(srcKey)
+	expired, expires := store.expired(srcKey), store.expiresAt(srcKey)
+	store.mu.RUnlock()
+	defer file.release()
+	if !ok || expired {
+		return ErrNotFound
+	}
+	if srcKey == dstKey {
+		return nil
+	}
+
+	// Pending values, values of legacy files and deltas are copied as values, compressed once committed
+	c, err := store.copyChunk(file, srcKey, map[string]entry{srcKey: e}, data, false)
+	if err != nil {
+		return err
+	}
+	c.expires = expires
+	info, err := store.flush(nil, nil, putOptions{chunks: map[string]chunk{dstKey: c}})
+	if err != nil {
+		return err
+	}
+	for _, h := range store.opts.hooks {
+		if h.OnCopy != nil {
+			h.OnCopy(srcKey, dstKey)
+		}
+	}
+	store.notifyFlush(info)
+	return nil
+}
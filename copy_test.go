@@ -0,0 +1,56 @@
+package sunduk
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestSunduk_Copy(t *testing.T) {
+	var copied []string
+	hooks := Hooks{OnCopy: func(srcKey, dstKey string) {
+		copied = append(copied, srcKey+">"+dstKey)
+	}}
+	store := New(TestStoreFile, WithHooks(hooks))
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"v1/app.js": []byte("app code"), "v2/app.js": []byte("old")})
+	checkValueForKey(t, store, "v2/app.js", []byte("old"))
+
+	if err := store.Copy("v1/app.js", "v2/app.js"); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "v1/app.js", []byte("app code"))
+	checkValueForKey(t, store, "v2/app.js", []byte("app code"))
+	if len(copied) != 1 || copied[0] != "v1/app.js>v2/app.js" {
+		t.Errorf("Expected OnCopy hook to be called once, got %v instead", copied)
+	}
+	if err := store.Copy("missing", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing key, got %v instead", err)
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkValueForKey(t, store, "v2/app.js", []byte("app code"))
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected store with copies to verify, got %v instead", err)
+	}
+}
+
+func TestSunduk_CopyDeduplicated(t *testing.T) {
+	store := New(TestStoreFile, WithDeduplication())
+	defer deleteTestStoreFile()
+	defer store.Close()
+	blob := make([]byte, 64<<10)
+	_, _ = rand.Read(blob)
+	_ = store.Put("blob", blob)
+	written := store.Metrics().BytesWritten
+
+	if err := store.Copy("blob", "copy"); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "copy", blob)
+	if n := store.Metrics().BytesWritten - written; n > 1<<10 {
+		t.Errorf("Expected the chunk to be referenced, got %d bytes written instead", n)
+	}
+}
@@ -0,0 +1,165 @@
+package sunduk
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"os"
+	"sunduk/internal/format"
+	"sync/atomic"
+	"time"
+)
+
+// DebugInfo is a snapshot of the state of a store, served by DebugHandler
+type DebugInfo struct {
+	FilePath string
+	Metrics  Metrics
+	Index    IndexSummary
+	Config   Config
+}
+
+// IndexSummary summarizes the index of a store
+type IndexSummary struct {
+	Entries        int
+	RawEntries     int // RawEntries is the count of values stored uncompressed
+	DictEntries    int // DictEntries is the count of values compressed with the dictionary
+	DeltaEntries   int // DeltaEntries is the count of values stored as deltas against previous values
+	AppendEntries  int // AppendEntries is the count of values stored as data appended to previous values, see Append
+	FramedEntries  int // FramedEntries is the count of values compressed in frames, see WithCompressionFrames
+	FirstKey       string
+	LastKey        string
+	Legacy         bool  // Legacy is true for files in older formats, rewritten on the first write
+	Signed         bool  // Signed is true for store files holding a signature, see Sign
+	Sealed         bool  // Sealed is true for sealed store files, see Seal
+	IndexFile      bool  // IndexFile is true for store files which index is in an index file, see WithIndexFile
+	FileSize       int64 // FileSize is the size of the last committed file
+	LiveBytes      int64
+	DeadBytes      int64 // DeadBytes is the size of overwritten and deleted values and superseded indexes
+	DictionarySize int
+	BloomSize      int // BloomSize is the size of the bloom filter of keys in bytes, 0 without bloom filter
+	Generation     uint64
+	PendingEntries int   // PendingEntries is the count of entries of pending writes, not written to the store file yet
+	CachedBytes    int64 // CachedBytes is the total size of cached values, pinned values included
+	PinnedBytes    int64 // PinnedBytes is the total size of the values of pinned keys, see Pin
+}
+
+// Config is the configuration of a store
+type Config struct {
+	CompactionThreshold float64
+	CompactionInterval  time.Duration
+	CompactionPaused    bool
+	BackgroundIOLimit   int64 // BackgroundIOLimit is the limit of IO of compactions in bytes per second, 0 for no limit
+	CompressionWorkers  int
+	CompressionWindow   int // CompressionWindow is the brotli window bits
+	CompressionMinSize  int
+	CompressionFrames   int  // CompressionFrames is the size of compression frames, 0 if values are compressed whole
+	RepairSource        bool // RepairSource is true if a repair source is configured
+	Frozen              bool
+	ReadOnly            bool
+	Durability          string
+	LazyIndex           bool // LazyIndex is true if entries are looked up in the lookup table of the store file
+	IndexFile           bool // IndexFile is true if new store files keep their index in an index file
+	ReloadInterval      time.Duration
+	CacheSize           int64
+	WriteBuffer         int64
+	Deduplication       bool
+	DeltaEncoding       bool
+	BloomBitsPerKey     int
+	AccessStats         bool
+	SizeCap             int64 // SizeCap is the size past which compaction evicts entries, 0 for no limit
+	TempDir             string
+	FileMode            os.FileMode // FileMode is the mode of created files, 0 if not configured
+}
+
+// DebugInfo returns a snapshot of the metrics, the index summary and the configuration of the store
+func (store *Sunduk) DebugInfo() DebugInfo {
+	info := DebugInfo{
+		FilePath: store.FilePath,
+		Metrics:  store.Metrics(),
+		Config: Config{
+			CompactionThreshold: store.opts.compactionThreshold,
+			CompactionInterval:  store.opts.compactionInterval,
+			CompactionPaused:    atomic.LoadInt32(&store.compaction.paused) != 0,
+			BackgroundIOLimit:   store.opts.backgroundIO,
+			CompressionWorkers:  store.workers,
+			CompressionWindow:   store.enc.windowBits,
+			CompressionMinSize:  store.enc.minSize,
+			CompressionFrames:   store.enc.frameSize,
+			RepairSource:        store.opts.repair != nil,
+			Frozen:              atomic.LoadInt32(&store.frozen) == frozen,
+			ReadOnly:            store.opts.readOnly,
+			Durability:          store.opts.durability.String(),
+			ReloadInterval:      store.opts.reloadInterval,
+			CacheSize:           store.opts.cacheSize,
+			WriteBuffer:         store.opts.writeBuffer,
+			Deduplication:       store.opts.dedup,
+			DeltaEncoding:       store.opts.delta,
+			BloomBitsPerKey:     store.opts.bloomBits,
+			AccessStats:         store.opts.accessStats,
+			TempDir:             store.opts.tempDir,
+			FileMode:            store.opts.fileMode,
+			IndexFile:           store.opts.indexFile,
+			SizeCap:             store.opts.sizeCap,
+		},
+	}
+	info.Index.DeadBytes, info.Index.LiveBytes = store.garbage()
+
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	info.Index.Legacy = store.legacy
+	info.Index.Signed = store.signature != nil
+	info.Index.Sealed = store.sealed != 0
+	info.Index.IndexFile = store.hasIndexFile()
+	info.Index.FileSize = store.size
+	info.Index.DictionarySize = len(store.dict.bytes())
+	info.Index.BloomSize = len(store.bloom.Bits)
+	info.Index.Generation = store.generation
+	info.Index.PendingEntries = len(store.data)
+	info.Index.CachedBytes = store.cache.bytes()
+	info.Index.PinnedBytes = store.cache.pinnedBytes()
+	info.Config.LazyIndex = store.lazy != nil
+	info.Index.Entries = store.count()
+	first := true
+	store.scan("", func(k string, e entry) {
+		if first || k < info.Index.FirstKey {
+			info.Index.FirstKey = k
+		}
+		if first || k > info.Index.LastKey {
+			info.Index.LastKey = k
+		}
+		first = false
+		if e.Flags&format.FlagRaw != 0 {
+			info.Index.RawEntries++
+		}
+		if e.Flags&format.FlagDict != 0 {
+			info.Index.DictEntries++
+		}
+		if e.Flags&format.FlagDelta != 0 {
+			info.Index.DeltaEntries++
+		}
+		if e.Flags&format.FlagAppend != 0 {
+			info.Index.AppendEntries++
+		}
+		if e.Flags&format.FlagFramed != 0 {
+			info.Index.FramedEntries++
+		}
+	})
+	return info
+}
+
+// DebugHandler returns a handler serving DebugInfo as JSON, to be mounted e.g. under /debug/sunduk
+func (store *Sunduk) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(store.DebugInfo())
+	})
+}
+
+// DebugVar returns DebugInfo as an expvar variable, to be published with expvar.Publish
+func (store *Sunduk) DebugVar() expvar.Var {
+	return expvar.Func(func() interface{} {
+		return store.DebugInfo()
+	})
+}
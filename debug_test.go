@@ -0,0 +1,35 @@
+package sunduk
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSunduk_DebugHandler(t *testing.T) {
+	store := New(TestStoreFile, WithCompressionWorkers(2))
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"b": []byte("2"), "a": []byte("1"), "c": []byte("3")})
+	_ = store.Delete("c")
+	store.Get("a")
+
+	rec := httptest.NewRecorder()
+	store.DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/sunduk", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content, got '%s' instead", ct)
+	}
+	var info DebugInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Index.Entries != 2 || info.Index.FirstKey != "a" || info.Index.LastKey != "b" || info.Index.RawEntries != 2 {
+		t.Errorf("Expected 2 raw entries from 'a' to 'b', got %+v instead", info.Index)
+	}
+	if info.Index.DeadBytes == 0 || info.Index.FileSize != fileSize(t, TestStoreFile) {
+		t.Errorf("Expected dead bytes in a file of %d bytes, got %+v instead", fileSize(t, TestStoreFile), info.Index)
+	}
+	if info.Metrics.Gets != 1 || info.Config.CompressionWorkers != 2 {
+		t.Errorf("Expected 1 get and 2 compression workers, got %+v and %+v instead", info.Metrics, info.Config)
+	}
+	store.Close()
+}
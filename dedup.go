@@ -0,0 +1,96 @@
+package sunduk
+
+import (
+	"bytes"
+	"io"
+	"sunduk/internal/format"
+)
+
+// chunkKey is the content of a chunk as recorded in the index. Equal values written with the same compression
+// have equal chunks, so chunks with equal keys are likely equal, and are compared byte for byte to be sure
+type chunkKey struct {
+	sum     uint32
+	rawSize int64
+	size    int64
+	flags   uint64
+	base    format.Base
+}
+
+// DedupStats describes the chunks shared by entries of equal values, see WithDeduplication
+type DedupStats struct {
+	SharedChunks  int   // SharedChunks is the count of chunks shared by several entries
+	SharedEntries int   // SharedEntries is the count of entries pointing at shared chunks
+	SavedBytes    int64 // SavedBytes is the size of the copies of shared chunks the file would hold without deduplication
+}
+
+// DedupStats returns the count of chunks shared by entries of equal values and the bytes saved by sharing them
+func (store *Sunduk) DedupStats() DedupStats {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	var stats DedupStats
+	if store.legacy {
+		return stats
+	}
+	type shared struct {
+		refs int
+		size int64
+	}
+	chunks := make(map[int64]shared, len(store.index))
+	store.scan("", func(_ string, e entry) {
+		if !e.pending && e.Size > 0 {
+			c := chunks[e.Offset]
+			chunks[e.Offset] = shared{refs: c.refs + 1, size: e.Size}
+		}
+	})
+	for _, c := range chunks {
+		if c.refs > 1 {
+			stats.SharedChunks++
+			stats.SharedEntries += c.refs
+			// Every entry but one would hold a copy of the chunk
+			stats.SavedBytes += int64(c.refs-1) * c.size
+		}
+	}
+	return stats
+}
+
+// dedupIndex returns the entries of the store file by chunk content, or nil if deduplication is disabled.
+// It is built on first use and dropped when the store file is replaced. It must be called with writeMu held
+func (store *Sunduk) dedupIndex() map[chunkKey]entry {
+	if !store.opts.dedup {
+		return nil
+	}
+	if store.chunks == nil {
+		store.mu.RLock()
+		store.chunks = make(map[chunkKey]entry, len(store.index))
+		for _, e := range store.index {
+			if e.hasSum && !e.pending && e.Size > 0 {
+				store.chunks[e.chunkKey()] = e
+			}
+		}
+		store.mu.RUnlock()
+	}
+	return store.chunks
+}
+
+// chunkKey returns the content of the chunk of the entry
+func (e entry) chunkKey() chunkKey {
+	return chunkKey{sum: e.Sum, rawSize: e.RawSize, size: e.Size, flags: e.Flags, base: e.Base}
+}
+
+// writeShared writes the chunk with w, unless chunks locates an equal chunk in the file read by r,
+// and returns the entry of the chunk. Written chunks are added to chunks, unless it is nil
+func writeShared(w *offsetWriter, r io.ReaderAt, chunks map[chunkKey]entry, c chunk) (entry, error) {
+	if len(c.data) > 0 {
+		if e, ok := chunks[chunkKey{sum: c.sum, rawSize: c.rawSize, size: int64(len(c.data)), flags: c.flags, base: c.base}]; ok {
+			data := make([]byte, e.Size)
+			if _, err := r.ReadAt(data, e.Offset); err == nil && bytes.Equal(data, c.data) {
+				return e, nil
+			}
+		}
+	}
+	e, err := writeChunk(w, c)
+	if err == nil && chunks != nil && e.Size > 0 {
+		chunks[e.chunkKey()] = e
+	}
+	return e, err
+}
@@ -0,0 +1,84 @@
+package sunduk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSunduk_Deduplication(t *testing.T) {
+	store, err := Open(TestStoreFile, WithDeduplication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	asset := bytes.Repeat([]byte("plugin asset "), 1000)
+	_ = store.Put("a/logo.png", asset)
+	before := fileSize(t, TestStoreFile)
+	_ = store.PutAll(map[string][]byte{"b/logo.png": asset, "c/logo.png": asset, "other": []byte("other value")})
+	if grown := fileSize(t, TestStoreFile) - before; grown >= int64(len(asset))/10 {
+		t.Errorf("Expected duplicated values to take no room in the store file, file grew by %d bytes", grown)
+	}
+
+	stats := store.DedupStats()
+	if stats.SharedChunks != 1 || stats.SharedEntries != 3 || stats.SavedBytes != 2*store.index["a/logo.png"].Size {
+		t.Errorf("Expected 1 chunk shared by 3 entries, got %+v instead", stats)
+	}
+	// Counting the shared chunk for every entry would make live bytes exceed the file
+	if dead, _ := store.garbage(); dead < 0 {
+		t.Errorf("Expected shared chunks to be counted once, got %d dead bytes instead", dead)
+	}
+
+	_ = store.Delete("a/logo.png")
+	_ = store.Put("other", []byte("new value"))
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := store.DedupStats(); stats.SharedChunks != 1 || stats.SharedEntries != 2 {
+		t.Errorf("Expected compaction to keep the chunk shared by 2 entries, got %+v instead", stats)
+	}
+	if dead, _ := store.garbage(); dead != 0 {
+		t.Errorf("Expected no dead bytes after compaction, got %d instead", dead)
+	}
+	store.Close()
+
+	store, _ = Open(TestStoreFile, WithDeduplication())
+	checkValueForKey(t, store, "b/logo.png", asset)
+	checkValueForKey(t, store, "c/logo.png", asset)
+	checkKeyNotExists(t, store, "a/logo.png")
+	values, err := store.GetMany([]string{"b/logo.png", "c/logo.png"})
+	if err != nil || !bytes.Equal(values["b/logo.png"], asset) || !bytes.Equal(values["c/logo.png"], asset) {
+		t.Errorf("Expected GetMany to read shared chunks, got %v instead", err)
+	}
+	store.Close()
+}
+
+func TestSunduk_DeduplicationComparesContent(t *testing.T) {
+	store, err := Open(TestStoreFile, WithDeduplication())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	_ = store.Put("a", []byte("first value"))
+	// Forge an entry recording the content of another value, so that only comparing chunks tells them apart
+	store.chunks = nil
+	e := store.index["a"]
+	e.Sum = checksum([]byte("other value"))
+	store.index = map[string]entry{"a": e}
+	store.dedupIndex()
+	_ = store.Put("b", []byte("other value"))
+	if store.index["b"].Offset == e.Offset {
+		t.Error("Expected chunks of different values not to be shared")
+	}
+	checkValueForKey(t, store, "b", []byte("other value"))
+	store.Close()
+}
+
+func TestSunduk_NoDeduplicationByDefault(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": []byte("same value"), "b": []byte("same value")})
+	if stats := store.DedupStats(); stats != (DedupStats{}) {
+		t.Errorf("Expected no shared chunks without deduplication, got %+v instead", stats)
+	}
+	store.Close()
+}
@@ -0,0 +1,66 @@
+package sunduk
+
+import "sunduk/internal/format"
+
+// deltaMinSize is the size of values from which they are delta-encoded, see WithDeltaEncoding
+const deltaMinSize = 4 << 10
+
+// deltaChunk returns the chunk of a value put for key, delta-encoded against the current value of key if deltas
+// are enabled and the delta is smaller than the value compressed. It is called by compression workers of commit,
+// with writeMu held
+func (store *Sunduk) deltaChunk(c chunk, key string) (chunk, error) {
+	if !store.opts.delta || c.data != nil || c.mode == compressNever || len(c.value) < deltaMinSize {
+		return c, nil
+	}
+	e, ok := store.index[key]
+	if !ok || !e.hasSum || e.pending || e.Flags&format.FlagAppend != 0 {
+		return c, nil
+	}
+	// Deltas apply to values written in full, so that values are rebuilt from a single base
+	base := e.Base
+	if e.Flags&format.FlagDelta == 0 {
+		base = format.Base{Offset: e.Offset, Size: e.Size, Flags: e.Flags, Sum: e.Sum}
+	}
+	data, err := store.readChunk(store.file, entry{Offset: base.Offset, Size: base.Size})
+	if err != nil {
+		return c, nil
+	}
+	baseValue, err := decodeBase(data, base, store.dict)
+	if err != nil {
+		// A broken base only loses the delta, the value is written in full
+		store.opts.logger.Warn("unable to read base of delta", "file", store.FilePath, "key", key, "err", err)
+		return c, nil
+	}
+
+	enc := store.enc.withDict(store.dict)
+	if err := enc.encode(&c); err != nil {
+		return c, err
+	}
+	delta, err := format.CompressDelta(baseValue, c.value, enc.windowBits)
+	if err != nil || len(delta) >= len(c.data) {
+		return c, nil
+	}
+	c.data, c.flags, c.base = delta, format.FlagDelta, base
+	return c, nil
+}
+
+// decodeBase returns the value of the base of a delta chunk held by data, verifying its checksum
+func decodeBase(data []byte, base format.Base, dict *dictionary) ([]byte, error) {
+	value, err := decodeChunk(data, base.Flags, dict)
+	if err != nil {
+		return nil, err
+	}
+	if checksum(value) != base.Sum {
+		return nil, ErrChecksum
+	}
+	return value, nil
+}
+
+// decodeDelta returns the value held by a delta chunk, baseData holds the chunk of its base
+func decodeDelta(data, baseData []byte, base format.Base, dict *dictionary) ([]byte, error) {
+	baseValue, err := decodeBase(baseData, base, dict)
+	if err != nil {
+		return nil, err
+	}
+	return format.DecodeDelta(data, baseValue)
+}
@@ -0,0 +1,106 @@
+package sunduk
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// nextBuild returns a copy of build with a few changed bytes and an inserted section, like a new build of a binary
+func nextBuild(build []byte, n int) []byte {
+	next := append([]byte(nil), build[:len(build)/2]...)
+	next = append(next, bytes.Repeat([]byte{byte(n)}, 100)...)
+	next = append(next, build[len(build)/2:]...)
+	next[n*100] ^= 0xff
+	return next
+}
+
+func TestSunduk_DeltaEncoding(t *testing.T) {
+	store, err := Open(TestStoreFile, WithDeltaEncoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	v1 := make([]byte, 256<<10)
+	rand.New(rand.NewSource(1)).Read(v1)
+	v2, v3 := nextBuild(v1, 1), nextBuild(nextBuild(v1, 1), 2)
+
+	_ = store.Put("app.dll", v1)
+	before := fileSize(t, TestStoreFile)
+	_ = store.Put("app.dll", v2)
+	if grown := fileSize(t, TestStoreFile) - before; grown > int64(len(v2))/100 {
+		t.Errorf("Expected a new version to be stored as a small delta, file grew by %d bytes", grown)
+	}
+	if n := store.DebugInfo().Index.DeltaEntries; n != 1 {
+		t.Errorf("Expected 1 delta entry, got %d instead", n)
+	}
+	checkValueForKey(t, store, "app.dll", v2)
+
+	_ = store.Put("app.dll", v3)
+	if dead, _ := store.garbage(); dead > int64(len(v1))/100 {
+		t.Errorf("Expected the base of the delta to stay live, got %d dead bytes instead", dead)
+	}
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected Verify to succeed on delta entries, got %v instead", err)
+	}
+	store.Close()
+
+	store, _ = Open(TestStoreFile, WithDeltaEncoding())
+	values, err := store.GetMany([]string{"app.dll"})
+	if err != nil || !bytes.Equal(values["app.dll"], v3) {
+		t.Errorf("Expected GetMany to rebuild the delta entry, got %v instead", err)
+	}
+	store.cache.clear()
+	checkValueForKey(t, store, "app.dll", v3)
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if n := store.DebugInfo().Index.DeltaEntries; n != 0 {
+		t.Errorf("Expected compaction to write values in full, got %d delta entries instead", n)
+	}
+	if size := fileSize(t, TestStoreFile); size > int64(len(v3))*11/10 {
+		t.Errorf("Expected compaction to drop the base, file is %d bytes", size)
+	}
+	checkValueForKey(t, store, "app.dll", v3)
+	_ = store.Put("app.dll", v2)
+	if n := store.DebugInfo().Index.DeltaEntries; n != 1 {
+		t.Errorf("Expected a delta against the compacted value, got %d delta entries instead", n)
+	}
+	checkValueForKey(t, store, "app.dll", v2)
+	store.Close()
+}
+
+func TestSunduk_DeltaEncodingSkipsUnrelatedValues(t *testing.T) {
+	store, err := Open(TestStoreFile, WithDeltaEncoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	rnd := rand.New(rand.NewSource(1))
+	v1, v2 := make([]byte, 64<<10), make([]byte, 64<<10)
+	rnd.Read(v1)
+	rnd.Read(v2)
+	_ = store.Put("key", v1)
+	_ = store.Put("key", v2)
+	_ = store.Put("small", []byte("value"))
+	_ = store.Put("small", []byte("values"))
+	if n := store.DebugInfo().Index.DeltaEntries; n != 0 {
+		t.Errorf("Expected unrelated and small values to be stored in full, got %d delta entries instead", n)
+	}
+	checkValueForKey(t, store, "key", v2)
+	store.Close()
+}
+
+func TestSunduk_NoDeltaEncodingByDefault(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	v1 := make([]byte, 64<<10)
+	rand.New(rand.NewSource(1)).Read(v1)
+	_ = store.Put("key", v1)
+	_ = store.Put("key", nextBuild(v1, 1))
+	if n := store.DebugInfo().Index.DeltaEntries; n != 0 {
+		t.Errorf("Expected no delta entries without delta encoding, got %d instead", n)
+	}
+	store.Close()
+}
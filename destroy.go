@@ -0,0 +1,136 @@
+package sunduk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// openStores holds the stores open in the process by the absolute paths of their files, so that a store file isn't
+// opened twice and Destroy closes them
+var openStores = struct {
+	sync.Mutex
+	paths map[string][]registered
+}{paths: make(map[string][]registered)}
+
+// registered is a store in openStores. Stores detecting leaks are held by their tracker only, so that the registry
+// doesn't keep them from being garbage collected, see WithLeakDetection
+type registered struct {
+	store    *Sunduk      // store is nil for stores detecting leaks
+	tracker  *leakTracker // tracker is the leak tracker of stores detecting leaks
+	path     string       // path is the path of the store file
+	temp     string       // temp is the path of the temporary file written by compaction
+	readOnly bool
+	unlocked bool
+}
+
+// newRegistered returns the entry of store in openStores
+func newRegistered(store *Sunduk) registered {
+	r := registered{tracker: store.leak, path: store.FilePath, temp: store.tempPath(), readOnly: store.opts.readOnly, unlocked: store.opts.unlocked}
+	if store.leak == nil {
+		r.store = store
+	}
+	return r
+}
+
+// storeKey returns the key of the store file at path in openStores
+func storeKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// register adds the store to the stores open in the process. It fails with ErrLocked if another store of the
+// process holds a conflicting lock on the store file, as the lock file doesn't lock it on every platform
+func (store *Sunduk) register() error {
+	openStores.Lock()
+	defer openStores.Unlock()
+	key := storeKey(store.FilePath)
+	for _, r := range openStores.paths[key] {
+		if !store.opts.unlocked && !r.unlocked && !(store.opts.readOnly && r.readOnly) {
+			return fmt.Errorf("%w: %s is already open in this process", ErrLocked, store.FilePath)
+		}
+	}
+	openStores.paths[key] = append(openStores.paths[key], newRegistered(store))
+	return nil
+}
+
+// unregister removes the store from the stores open in the process
+func (store *Sunduk) unregister() {
+	unregister(newRegistered(store))
+}
+
+// unregister removes the entry of a store from openStores
+func unregister(entry registered) {
+	openStores.Lock()
+	defer openStores.Unlock()
+	key := storeKey(entry.path)
+	open := openStores.paths[key]
+	for i, r := range open {
+		if r == entry {
+			open = append(open[:i:i], open[i+1:]...)
+			break
+		}
+	}
+	if len(open) == 0 {
+		delete(openStores.paths, key)
+	} else {
+		openStores.paths[key] = open
+	}
+}
+
+// Destroy removes the store file at path along with the files the package keeps next to it: the lock file,
+// the index file, backups and temporary files left by compaction and migration. Stores of this process open on
+// the file are closed first, so that later calls to them fail with ErrClosed. The pending writes of stores detecting
+// leaks are dropped, as the registry doesn't hold them, see WithLeakDetection. It fails with
+// ErrLocked if another process has the store open. Shards and segments are destroyed by the path of each file
+func Destroy(path string) error {
+	openStores.Lock()
+	open := append([]registered(nil), openStores.paths[storeKey(path)]...)
+	openStores.Unlock()
+	var names []string
+	for _, r := range open {
+		if r.store != nil {
+			r.store.Close()
+		} else {
+			r.tracker.release()
+			unregister(r)
+		}
+		names = append(names, r.temp)
+	}
+
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR, 0)
+	switch {
+	case err == nil:
+		if err := lockFile(lock, true); err != nil {
+			_ = lock.Close()
+			return err
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+	backups, _ := filepath.Glob(path + ".v*.bak")
+	names = append(names, path, path+".bak", path+".new", path+".probe", path+".idx", path+".idx.new")
+	names = append(names, backups...)
+	var first error
+	for _, name := range names {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) && first == nil {
+			first = err
+		}
+	}
+	// The lock file is removed while locked where open files can be removed, so that other processes fail to
+	// open the store meanwhile
+	if lock != nil {
+		err := os.Remove(path + ".lock")
+		_ = lock.Close()
+		if err != nil {
+			err = os.Remove(path + ".lock")
+		}
+		if err != nil && !os.IsNotExist(err) && first == nil {
+			first = err
+		}
+	}
+	return first
+}
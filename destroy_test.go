@@ -0,0 +1,42 @@
+package sunduk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDestroy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.data")
+	store := New(path, WithIndexFile())
+	_ = store.Put("key", []byte("value"))
+	if err := os.WriteFile(path+".v1.bak", nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Destroy(path); err != nil {
+		t.Fatalf("Expected store to be destroyed, got %v instead", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 0 {
+		t.Errorf("Expected no files left, got %d instead", len(entries))
+	}
+	if err := Destroy(path); err != nil {
+		t.Errorf("Expected missing store to be destroyed, got %v instead", err)
+	}
+}
+
+func TestDestroy_Locked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.data")
+	store := New(path)
+	store.unregister() // as if the store were open in another process
+	defer store.Close()
+
+	if err := Destroy(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked destroying a store open elsewhere, got %v instead", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected store file to be kept, got %v instead", err)
+	}
+}
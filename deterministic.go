@@ -0,0 +1,37 @@
+package sunduk
+
+import (
+	"sunduk/internal/format"
+	"time"
+)
+
+// deterministicID is the file ID of store files written with WithDeterministic and WithIndexFile
+var deterministicID = format.FileID{'s', 'u', 'n', 'd', 'u', 'k', '-', 'd', 'e', 't', 'e', 'r', 'm', 'i', 'n', 'e'}
+
+// WithDeterministic writes byte-identical store files given identical writes, so that store files built in CI
+// are reproducible and their hashes attested. Chunks are written in key order and compressed with the fixed
+// parameters of the options, and the times recorded in the file are zeroed: the creation time, the times of
+// audit records and the seal time, which is the epoch. Store files with an index file get a fixed file ID
+// instead of a random one. Expiry times set with TTL, soft-deleted values, the trash and access statistics
+// record the time they are written at, and aren't deterministic
+func WithDeterministic() Option {
+	return func(o *options) {
+		o.deterministic = true
+	}
+}
+
+// timestamp returns the current time to record in the store file in unix nanoseconds, 0 with WithDeterministic
+func (store *Sunduk) timestamp() int64 {
+	if store.opts.deterministic {
+		return 0
+	}
+	return time.Now().UnixNano()
+}
+
+// newFileID returns the ID of a new store file with an index file
+func (store *Sunduk) newFileID() (format.FileID, error) {
+	if store.opts.deterministic {
+		return deterministicID, nil
+	}
+	return format.NewFileID()
+}
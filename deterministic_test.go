@@ -0,0 +1,66 @@
+package sunduk
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeDeterministic writes the same store file with opts, and returns its content and the content of its index file
+func writeDeterministic(t *testing.T, opts ...Option) ([]byte, []byte) {
+	defer deleteTestStoreFile()
+	index := WithSecondaryIndex("words", func(_ string, value []byte) []string { return strings.Fields(string(value)) })
+	store := New(TestStoreFile, append(opts, index, WithAuditLog("ci"), WithBloomFilter(10))...)
+	values := make(map[string][]byte)
+	for i := 0; i < 100; i++ {
+		values[fmt.Sprintf("key-%d", i)] = []byte(fmt.Sprintf("value %d of %d", i, i%7))
+	}
+	_ = store.PutAll(values, Compressed())
+	_ = store.Put("key-1", []byte("updated value"))
+	_ = store.Delete("key-2")
+	_ = store.SetMeta(Meta{Application: "ci", Values: map[string]string{"a": "1", "b": "2", "c": "3"}})
+	_ = store.Compact()
+	if err := store.Seal(nil); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	data, _ := os.ReadFile(TestStoreFile)
+	idx, _ := os.ReadFile(TestStoreFile + ".idx")
+	return data, idx
+}
+
+func TestSunduk_WithDeterministic(t *testing.T) {
+	for _, scenario := range []struct {
+		name string
+		opts []Option
+	}{
+		{"index", nil},
+		{"index-file", []Option{WithIndexFile()}},
+	} {
+		t.Run(scenario.name, func(t *testing.T) {
+			opts := append(scenario.opts, WithDeterministic())
+			data, idx := writeDeterministic(t, opts...)
+			time.Sleep(time.Millisecond)
+			again, againIdx := writeDeterministic(t, opts...)
+			if !bytes.Equal(data, again) || !bytes.Equal(idx, againIdx) {
+				t.Error("Expected identical writes to write byte-identical files")
+			}
+			if different, _ := writeDeterministic(t, scenario.opts...); bytes.Equal(data, different) {
+				t.Error("Expected files written without WithDeterministic to differ")
+			}
+		})
+	}
+}
+
+func TestSunduk_WithDeterministicTimes(t *testing.T) {
+	defer deleteTestStoreFile()
+	store := New(TestStoreFile, WithDeterministic())
+	defer store.Close()
+	_ = store.Seal(nil)
+	if meta := store.GetMeta(); !meta.Created.IsZero() || meta.Sealed.UnixNano() != 1 {
+		t.Errorf("Expected zero creation time and the epoch as seal time, got %v and %v instead", meta.Created, meta.Sealed)
+	}
+}
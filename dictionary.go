@@ -0,0 +1,183 @@
+package sunduk
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"sunduk/internal/format"
+)
+
+const (
+	// maxDictValueSize is the size of values up to which values are compressed with the dictionary,
+	// larger values are compressed with brotli as they gain little from a dictionary
+	maxDictValueSize = 16 << 10
+
+	dictSamplesRatio = 16 // dictSamplesRatio is the size of samples read for training, relative to the dictionary size
+	dictKmerSize     = 8  // dictKmerSize is the length of substrings counted in samples
+	dictSegmentSize  = 64 // dictSegmentSize is the length of segments of samples the dictionary is made of
+)
+
+// dictionary is a compression dictionary stored raw in the store file at offset
+type dictionary struct {
+	data   []byte
+	offset int64
+}
+
+// bytes returns the content of the dictionary, nil for no dictionary
+func (d *dictionary) bytes() []byte {
+	if d == nil {
+		return nil
+	}
+	return d.data
+}
+
+// location returns the location of the dictionary as it is recorded in the index
+func (d *dictionary) location() format.Dictionary {
+	if d == nil {
+		return format.Dictionary{}
+	}
+	return format.Dictionary{Offset: d.offset, Size: int64(len(d.data)), Sum: checksum(d.data)}
+}
+
+// TrainDictionary builds a compression dictionary of at most size bytes, up to 32 KiB, from the values of the store,
+// then rewrites the store file with small values compressed with the dictionary. Values put later are compressed
+// with it too. It greatly improves compression of many small similar values, such as records sharing a schema.
+// Like Compact, it returns ErrCompactionPaused while compaction is paused
+func (store *Sunduk) TrainDictionary(size int) error {
+	if size <= 0 || size > format.MaxDictionarySize {
+		size = format.MaxDictionarySize
+	}
+	store.compaction.mu.Lock()
+	defer store.compaction.mu.Unlock()
+	return store.compact(nil, size)
+}
+
+// trainDictionary reads samples of the small values of index and trains a dictionary of at most size bytes from them
+func (store *Sunduk) trainDictionary(file *handle, index map[string]entry, data map[string][]byte, size int, aborted func() bool) ([]byte, error) {
+	keys := newOrderedKeys(index)
+	var total int64
+	for _, k := range keys.keys {
+		if e := index[k]; e.RawSize <= maxDictValueSize {
+			total += e.RawSize
+		}
+	}
+	// Sample values evenly across keys, so that no range of keys dominates the dictionary
+	budget := int64(size) * dictSamplesRatio
+	stride := total/budget + 1
+	var samples [][]byte
+	var n int64
+	for _, k := range keys.keys {
+		if e := index[k]; e.RawSize > maxDictValueSize {
+			continue
+		}
+		if n++; n%stride != 0 {
+			continue
+		}
+		if aborted() {
+			return nil, ErrCompactionPaused
+		}
+		value, err := store.load(file, k, index, data)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, value)
+	}
+
+	dict := buildDictionary(samples, size)
+	if len(dict) == 0 {
+		return nil, errors.New("unable to train dictionary: no values with repeated content")
+	}
+	return dict, nil
+}
+
+// buildDictionary picks the segments of samples covering the most substrings shared by samples, until the
+// dictionary is size bytes. Substrings are counted once per sample and only once in the dictionary, so
+// content common to many samples comes before content repeated within a few
+func buildDictionary(samples [][]byte, size int) []byte {
+	freq := make(map[uint64]int)
+	for _, sample := range samples {
+		seen := make(map[uint64]bool)
+		for i := 0; i+dictKmerSize <= len(sample); i++ {
+			kmer := binary.LittleEndian.Uint64(sample[i:])
+			if !seen[kmer] {
+				seen[kmer] = true
+				freq[kmer]++
+			}
+		}
+	}
+	score := func(segment []byte) int {
+		s := 0
+		seen := make(map[uint64]bool)
+		for i := 0; i+dictKmerSize <= len(segment); i++ {
+			kmer := binary.LittleEndian.Uint64(segment[i:])
+			if f := freq[kmer]; f > 1 && !seen[kmer] {
+				seen[kmer] = true
+				s += f
+			}
+		}
+		return s
+	}
+
+	segments := &segmentHeap{}
+	for _, sample := range samples {
+		for i := 0; i < len(sample); i += dictSegmentSize {
+			end := i + dictSegmentSize
+			if end > len(sample) {
+				end = len(sample)
+			}
+			if s := score(sample[i:end]); s > 0 {
+				segments.items = append(segments.items, segment{data: sample[i:end], score: s})
+			}
+		}
+	}
+	heap.Init(segments)
+
+	// Scores only decrease as segments are picked, so a segment which score is unchanged is the best one
+	var picked [][]byte
+	for n := 0; n < size && segments.Len() > 0; {
+		top := heap.Pop(segments).(segment)
+		if s := score(top.data); s != top.score {
+			if s > 0 {
+				heap.Push(segments, segment{data: top.data, score: s})
+			}
+			continue
+		}
+		if len(top.data) > size-n {
+			top.data = top.data[:size-n]
+		}
+		picked = append(picked, top.data)
+		n += len(top.data)
+		for i := 0; i+dictKmerSize <= len(top.data); i++ {
+			delete(freq, binary.LittleEndian.Uint64(top.data[i:]))
+		}
+	}
+
+	// Deflate encodes matches closer to the data in fewer bits, so the best segments go last
+	var dict []byte
+	for i := len(picked) - 1; i >= 0; i-- {
+		dict = append(dict, picked[i]...)
+	}
+	return dict
+}
+
+// segment is a segment of a sample scored by buildDictionary
+type segment struct {
+	data  []byte
+	score int
+}
+
+// segmentHeap is a max-heap of segments by score
+type segmentHeap struct {
+	items []segment
+}
+
+func (h *segmentHeap) Len() int           { return len(h.items) }
+func (h *segmentHeap) Less(i, j int) bool { return h.items[i].score > h.items[j].score }
+func (h *segmentHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *segmentHeap) Push(x interface{}) { h.items = append(h.items, x.(segment)) }
+
+func (h *segmentHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
@@ -0,0 +1,85 @@
+package sunduk
+
+import (
+	"fmt"
+	"sunduk/internal/format"
+	"testing"
+)
+
+// channelConfigs returns n small values sharing a schema
+func channelConfigs(n int) map[string][]byte {
+	entries := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		entries[fmt.Sprintf("channel-%04d", i)] = []byte(fmt.Sprintf(
+			`{"channel":%d,"name":"channel-%d","enabled":%t,"codec":"opus","bitrate":%d,"region":"eu-west-%d"}`,
+			i, i, i%3 != 0, 64000+i%4*32000, i%3))
+	}
+	return entries
+}
+
+func TestSunduk_TrainDictionary(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	entries := channelConfigs(1000)
+	_ = store.PutAll(entries)
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to succeed, got %v instead", err)
+	}
+	before := fileSize(t, TestStoreFile)
+
+	if err := store.TrainDictionary(4 << 10); err != nil {
+		t.Fatalf("Expected TrainDictionary to succeed, got %v instead", err)
+	}
+	if after := fileSize(t, TestStoreFile); after > before*2/3 {
+		t.Errorf("Expected the dictionary to shrink the store file of %d bytes by a third, got %d bytes instead", before, after)
+	}
+	if size := len(store.dict.bytes()); size == 0 || size > 4<<10 {
+		t.Errorf("Expected a dictionary of up to %d bytes, got %d bytes instead", 4<<10, size)
+	}
+	_ = store.Put("channel-new", []byte(`{"channel":5000,"name":"channel-5000","enabled":true,"codec":"opus"}`))
+	if store.index["channel-new"].Flags&format.FlagDict == 0 {
+		t.Error("Expected values put after training to be compressed with the dictionary")
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	for k, v := range entries {
+		checkValueForKey(t, store, k, v)
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to succeed, got %v instead", err)
+	}
+	if err := store.TrainDictionary(0); err != nil {
+		t.Fatalf("Expected training the dictionary again to succeed, got %v instead", err)
+	}
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected the store to verify, got %v instead", err)
+	}
+	checkValueForKey(t, store, "channel-new", []byte(`{"channel":5000,"name":"channel-5000","enabled":true,"codec":"opus"}`))
+	store.Close()
+}
+
+func TestSunduk_TrainDictionaryWithoutSharedContent(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("a single value shares nothing with other values"))
+	if err := store.TrainDictionary(0); err == nil {
+		t.Error("Expected TrainDictionary to fail without shared content")
+	}
+	if store.dict != nil {
+		t.Error("Expected the store to have no dictionary")
+	}
+	store.Close()
+}
+
+func TestBuildDictionary_Size(t *testing.T) {
+	var samples [][]byte
+	for _, v := range channelConfigs(100) {
+		samples = append(samples, v)
+	}
+	for _, size := range []int{1, 100, 1000} {
+		if dict := buildDictionary(samples, size); len(dict) == 0 || len(dict) > size {
+			t.Errorf("Expected a dictionary of up to %d bytes, got %d bytes instead", size, len(dict))
+		}
+	}
+}
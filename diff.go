@@ -0,0 +1,60 @@
+package sunduk
+
+import "sort"
+
+// Diff compares two stores by the checksums of their values. It returns the sorted keys present only in b (added),
+// present only in a (removed) and present in both with different values (changed).
+// Entries which checksum can't be determined are reported as changed
+func Diff(a, b *Sunduk) (added, removed, changed []string) {
+	a.mu.RLock()
+	aindex := a.loadIndex()
+	a.mu.RUnlock()
+	b.mu.RLock()
+	bindex := b.loadIndex()
+	b.mu.RUnlock()
+	for k := range aindex {
+		if _, ok := bindex[k]; !ok {
+			removed = append(removed, k)
+			continue
+		}
+		as, aok := a.sum(k, aindex)
+		bs, bok := b.sum(k, bindex)
+		if !aok || !bok || as != bs {
+			changed = append(changed, k)
+		}
+	}
+	for k := range bindex {
+		if _, ok := aindex[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return
+}
+
+// valueSum identifies a value by its checksum and size
+type valueSum struct {
+	Sum  uint32
+	Size int64
+}
+
+// sum returns the checksum of the value of key, reading the value if index has no checksum for it
+func (store *Sunduk) sum(key string, index map[string]entry) (valueSum, bool) {
+	if value, ok := store.data[key]; ok {
+		return valueSum{checksum(value), int64(len(value))}, true
+	}
+	e, ok := index[key]
+	if !ok {
+		return valueSum{}, false
+	}
+	if e.hasSum {
+		return valueSum{e.Sum, e.RawSize}, true
+	}
+	value, ok := store.Get(key)
+	if !ok {
+		return valueSum{}, false
+	}
+	return valueSum{checksum(value), int64(len(value))}, true
+}
@@ -0,0 +1,51 @@
+package sunduk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = a.PutAll(map[string][]byte{
+		"same":    []byte("value"),
+		"changed": []byte("old value"),
+		"removed": []byte("gone"),
+	})
+	b := New(TestMirrorFile)
+	defer deleteStoreFile(TestMirrorFile)
+	_ = b.PutAll(map[string][]byte{
+		"same":    []byte("value"),
+		"changed": []byte("new value"),
+		"added1":  []byte("new"),
+		"added2":  nil,
+	})
+	a.Close()
+	b.Close()
+
+	a, b = New(TestStoreFile), New(TestMirrorFile)
+	added, removed, changed := Diff(a, b)
+	if !reflect.DeepEqual(added, []string{"added1", "added2"}) {
+		t.Errorf("Expected added keys to be [added1 added2], got %v instead", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"removed"}) {
+		t.Errorf("Expected removed keys to be [removed], got %v instead", removed)
+	}
+	if !reflect.DeepEqual(changed, []string{"changed"}) {
+		t.Errorf("Expected changed keys to be [changed], got %v instead", changed)
+	}
+	a.Close()
+	b.Close()
+}
+
+func TestDiff_SameStore(t *testing.T) {
+	a := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = a.Put("key", []byte("value"))
+	added, removed, changed := Diff(a, a)
+	if len(added)+len(removed)+len(changed) != 0 {
+		t.Errorf("Expected no differences, got %v, %v, %v instead", added, removed, changed)
+	}
+	a.Close()
+}
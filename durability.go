@@ -0,0 +1,47 @@
+package sunduk
+
+import "sunduk/internal/vfs"
+
+// Durability is the level of durability of writes to the store file, see WithDurability
+type Durability int
+
+const (
+	// DurabilityNone leaves writes in the buffers of the OS, which writes them to disk at its pace.
+	// Committed writes survive a crash of the process, but not a crash of the OS or a power loss
+	DurabilityNone Durability = iota
+	// DurabilityFlush syncs the store file to disk on every commit, and the new file before compaction replaces the store file
+	DurabilityFlush
+	// DurabilityFull syncs the store file like DurabilityFlush, and the directory of the store file once the file
+	// is created or replaced, so that the file itself survives a power loss
+	DurabilityFull
+)
+
+// String returns the name of the durability level
+func (d Durability) String() string {
+	switch d {
+	case DurabilityNone:
+		return "none"
+	case DurabilityFlush:
+		return "flush"
+	case DurabilityFull:
+		return "full"
+	default:
+		return "unknown"
+	}
+}
+
+// syncData commits the content of file to stable storage if the durability level requires it
+func (store *Sunduk) syncData(file vfs.File) error {
+	if store.opts.durability < DurabilityFlush {
+		return nil
+	}
+	return file.Sync()
+}
+
+// syncEntry commits the directory entry of the store file to stable storage if the durability level requires it
+func (store *Sunduk) syncEntry() error {
+	if store.opts.durability < DurabilityFull {
+		return nil
+	}
+	return syncDir(store.FilePath)
+}
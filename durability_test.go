@@ -0,0 +1,30 @@
+package sunduk
+
+import "testing"
+
+func TestSunduk_WithDurability(t *testing.T) {
+	for _, d := range []Durability{DurabilityNone, DurabilityFlush, DurabilityFull} {
+		t.Run(d.String(), func(t *testing.T) {
+			store, err := Open(TestStoreFile, WithDurability(d))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer deleteTestStoreFile()
+			if name := store.DebugInfo().Config.Durability; name != d.String() {
+				t.Errorf("Expected durability %s, got %s instead", d, name)
+			}
+			_ = store.Put("a", []byte("apple"))
+			_ = store.Put("a", []byte("apricot"))
+			if err := store.Compact(); err != nil {
+				t.Fatal(err)
+			}
+			_ = store.Put("b", []byte("banana"))
+			store.Close()
+
+			store = New(TestStoreFile)
+			defer store.Close()
+			checkValueForKey(t, store, "a", []byte("apricot"))
+			checkValueForKey(t, store, "b", []byte("banana"))
+		})
+	}
+}
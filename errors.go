@@ -0,0 +1,102 @@
+package sunduk
+
+import (
+	"errors"
+	"fmt"
+	"sunduk/internal/format"
+)
+
+var (
+	// ErrChecksum is returned when a stored value doesn't match the checksum recorded in the index
+	ErrChecksum = errors.New("checksum mismatch")
+
+	// ErrFrozen is returned by Freeze when the store is already frozen, or being frozen by another call
+	ErrFrozen = errors.New("store is frozen")
+
+	// ErrCompactionPaused is returned by compaction while compaction is paused
+	ErrCompactionPaused = errors.New("compaction is paused")
+
+	// ErrLocked is returned by Open when the store file is locked by another store, in this or another process
+	ErrLocked = errors.New("store file is locked")
+
+	// ErrVersion is returned by Open and Migrate for store files in a format version this package can't read,
+	// or which require features of the format it doesn't know, such as files written by newer versions of the package
+	ErrVersion = format.ErrVersion
+
+	// ErrSignature is returned by VerifySignature when the store isn't signed, or not by the owner of the key
+	ErrSignature = errors.New("store signature is missing or invalid")
+
+	// ErrShards is returned by OpenSharded when the store was created with another count of shards
+	ErrShards = errors.New("store has another count of shards")
+
+	// ErrTempDir is returned by Open and CheckTempDir when files can't be renamed from the temp directory over the store file
+	ErrTempDir = errors.New("temp directory is unusable for the store file")
+
+	// ErrNotFound is returned by Rename, Copy, GetRange, GetInto and ExtractTo when the key has no entry
+	ErrNotFound = errors.New("key not found")
+
+	// ErrReadOnly is returned by writes to a store opened read-only
+	ErrReadOnly = errors.New("store is read-only")
+
+	// ErrSealed is returned by writes to a sealed store opened without WithForceWrites
+	ErrSealed = errors.New("store is sealed")
+
+	// ErrCorruptHeader is returned by Open for store files which index can't be read, such as truncated or damaged
+	// files and files that aren't store files. Counts and sizes read from files are bounded by the size of the file
+	ErrCorruptHeader = format.ErrCorruptHeader
+
+	// ErrRange is returned by GetRange for ranges with a negative offset or length
+	ErrRange = errors.New("invalid range")
+
+	// ErrChangesTruncated is returned by Changes when the change log doesn't hold every change asked for
+	ErrChangesTruncated = errors.New("changes are missing from the change log")
+
+	// ErrQuotaExceeded is returned by writes growing the values under a prefix past its quota, see WithQuota
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrPermission is returned by accesses to keys denied by the authorizer of the store, see WithAuthorizer
+	ErrPermission = errors.New("permission denied")
+
+	// ErrCollation is returned by Open for store files which keys are ordered by another collation than the one of
+	// the store, or by an unknown one, see WithCollation
+	ErrCollation = errors.New("store file is ordered by another collation")
+
+	// ErrClosed is returned by writes to a closed store, reads of values it doesn't hold in memory and Close
+	ErrClosed = errors.New("store is closed")
+
+	// ErrIndexLimit is returned by Open and Reload for store files which index exceeds the limits of the store,
+	// see WithIndexLimits
+	ErrIndexLimit = format.ErrIndexLimit
+
+	// ErrTimeout is returned by Open and reads of values taking longer than their timeout, see WithOpenTimeout
+	// and WithReadTimeout
+	ErrTimeout = errors.New("store operation timed out")
+
+	// ErrHash is returned by Open for store files which values are digested with another hash than the one of
+	// the store, or with an unknown one, see WithHash
+	ErrHash = errors.New("store file records another hash")
+
+	// ErrNoSpace is returned by writes and compactions when the file system has no room for the data written and
+	// the headroom, see WithSpaceHeadroom
+	ErrNoSpace = errors.New("not enough free space")
+)
+
+// PutAllError is returned by PutAll when it fails, telling which keys it applied. Entries are validated before any
+// is applied and written all at once, so Applied is empty unless the entries were staged in the write buffer and
+// writing the buffer failed, see WithWriteBuffer. Staged entries stay pending and readable, and are written by the
+// next flush
+type PutAllError struct {
+	Applied []string // Applied holds the keys applied in bytewise order
+	Err     error
+}
+
+func (e *PutAllError) Error() string {
+	if len(e.Applied) == 0 {
+		return fmt.Sprintf("no entries applied: %v", e.Err)
+	}
+	return fmt.Sprintf("%d entries applied but not written: %v", len(e.Applied), e.Err)
+}
+
+func (e *PutAllError) Unwrap() error {
+	return e.Err
+}
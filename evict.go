@@ -0,0 +1,113 @@
+package sunduk
+
+import (
+	"sort"
+	"sunduk/internal/format"
+	"time"
+)
+
+// defaultEvictionInterval is the interval of the background compactions evicting entries when WithSizeCap
+// is used without WithAutoCompaction
+const defaultEvictionInterval = time.Minute
+
+// WithSizeCap turns the store into a bounded cache of at most about size bytes on disk. Compaction evicts the
+// entries of expired values, see TTL, then the entries chosen by the eviction policy until the live bytes of
+// the store file fit size, see WithEvictionPolicy. Reads and writes count as uses of entries, and their times
+// are committed to the store file like with WithAccessStats. The store file grows past size between compactions,
+// so background compaction runs whenever it does, every minute unless WithAutoCompaction sets the interval.
+// OnEvict hooks are called for evicted keys
+func WithSizeCap(size int64) Option {
+	return func(o *options) {
+		o.sizeCap = size
+	}
+}
+
+// overCap returns true if the store file has grown past its size cap, see WithSizeCap
+func (store *Sunduk) overCap() bool {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.opts.sizeCap > 0 && store.size > store.opts.sizeCap
+}
+
+// evictions returns the keys of index which entries are evicted by compaction: the keys of expired values,
+// then the keys chosen by the eviction policy until the estimated size of the new file fits the size cap.
+// It must be called with mu held
+func (store *Sunduk) evictions(index map[string]entry) map[string]bool {
+	evicted := make(map[string]bool)
+	now := time.Now().UnixNano()
+	for k, t := range store.expiry {
+		if _, ok := index[k]; ok && t <= now {
+			evicted[k] = true
+		}
+	}
+	if store.opts.sizeCap <= 0 {
+		return evicted
+	}
+
+	candidates := make([]EvictionCandidate, 0, len(index))
+	var live int64
+	for k, e := range index {
+		if evicted[k] {
+			continue
+		}
+		// Bases are counted along with each chunk chained to them, as compaction writes such values in full
+		c := EvictionCandidate{Key: k, Size: e.Size}
+		if e.Flags&(format.FlagDelta|format.FlagAppend) != 0 {
+			c.Size += e.Base.Size
+		}
+		if a, ok := store.access.get(k); ok {
+			c.Reads, c.LastAccess, c.Written = a.reads, unixTime(a.last), unixTime(a.written)
+		}
+		c.Expires = unixTime(store.expiry[k])
+		candidates = append(candidates, c)
+		live += c.Size
+	}
+	limit := store.opts.sizeCap - format.PreambleSize - store.tail - store.dict.location().Size
+	if live <= limit {
+		return evicted
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Key < candidates[j].Key
+	})
+	for _, k := range store.opts.evictionPolicy.Evict(candidates, live-limit) {
+		if _, ok := index[k]; ok {
+			evicted[k] = true
+		}
+	}
+	return evicted
+}
+
+// unixTime returns the time of t in unix nanoseconds, the zero time for 0
+func unixTime(t int64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t)
+}
+
+// evictHeader returns header without the expiry times and the secondary index entries of evicted keys
+func evictHeader(header format.Index, evicted []string) format.Index {
+	drop := make(map[string]bool, len(evicted))
+	for _, k := range evicted {
+		drop[k] = true
+	}
+	var expiry []format.Expiry
+	for _, e := range header.Expiry {
+		if !drop[e.Key] {
+			expiry = append(expiry, e)
+		}
+	}
+	header.Expiry = expiry
+	secondary := make([]format.Secondary, 0, len(header.Secondary))
+	for _, s := range header.Secondary {
+		keys := make([]format.SecondaryKey, 0, len(s.Keys))
+		for _, k := range s.Keys {
+			if !drop[k.Key] {
+				keys = append(keys, k)
+			}
+		}
+		secondary = append(secondary, format.Secondary{Name: s.Name, Keys: keys})
+	}
+	header.Secondary = secondary
+	return header
+}
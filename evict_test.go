@@ -0,0 +1,140 @@
+package sunduk
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSunduk_TTL(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("short", []byte("short"), TTL(50*time.Millisecond))
+	_ = store.Put("long", []byte("long"), TTL(time.Hour))
+	_ = store.Put("forever", []byte("forever"))
+	_ = store.Append("short", []byte(" lived"))
+	_ = store.Copy("short", "copy")
+	checkValueForKey(t, store, "short", []byte("short lived"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	time.Sleep(100 * time.Millisecond)
+	for _, k := range []string{"short", "copy"} {
+		if _, ok := store.Get(k); ok {
+			t.Errorf("Expected value of %q to expire", k)
+		}
+		if store.Has(k) {
+			t.Errorf("Expected Has to leave out expired key %q", k)
+		}
+		if _, err := store.GetRange(k, 0, 1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for expired key %q, got %v instead", k, err)
+		}
+	}
+	if values, _ := store.GetMany([]string{"short", "long", "forever"}); len(values) != 2 {
+		t.Errorf("Expected GetMany to leave out expired values, got %d values instead", len(values))
+	}
+	checkValueForKey(t, store, "long", []byte("long"))
+	checkValueForKey(t, store, "forever", []byte("forever"))
+
+	// Expired values are replaced like missing ones
+	_ = store.Append("short", []byte("new"))
+	checkValueForKey(t, store, "short", []byte("new"))
+}
+
+func TestSunduk_CompactEvictsExpired(t *testing.T) {
+	var evicted []string
+	store := New(TestStoreFile, WithHooks(Hooks{OnEvict: func(key string) { evicted = append(evicted, key) }}))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.PutAll(map[string][]byte{"a": []byte("a"), "b": []byte("b")}, TTL(time.Millisecond))
+	_ = store.Put("c", []byte("c"))
+	generation := store.Generation()
+	time.Sleep(10 * time.Millisecond)
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if keys := store.Keys(); len(keys) != 1 || keys[0] != "c" {
+		t.Errorf("Expected expired keys to be evicted, got %v instead", keys)
+	}
+	if len(evicted) != 2 {
+		t.Errorf("Expected OnEvict to be called for 2 keys, got %v instead", evicted)
+	}
+	if store.Generation() == generation {
+		t.Error("Expected eviction to bump the generation")
+	}
+}
+
+func TestSunduk_SizeCap(t *testing.T) {
+	store := New(TestStoreFile, WithSizeCap(64<<10))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	value := make([]byte, 10<<10)
+	for i := 0; i < 10; i++ {
+		_, _ = rand.Read(value)
+		_ = store.Put(fmt.Sprintf("key%d", i), append([]byte(nil), value...))
+	}
+	// key0 is used again, so key1 is the least recently used
+	_, _ = store.Get("key0")
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if size := store.DebugInfo().Index.LiveBytes; size > 64<<10 {
+		t.Errorf("Expected live bytes to fit the size cap, got %d bytes instead", size)
+	}
+	if !store.Has("key0") || !store.Has("key9") {
+		t.Error("Expected recently used keys to be kept")
+	}
+	if store.Has("key1") {
+		t.Error("Expected least recently used key to be evicted")
+	}
+}
+
+func TestSunduk_EvictionPolicy(t *testing.T) {
+	value := make([]byte, 10<<10)
+	for _, c := range []struct {
+		name   string
+		policy EvictionPolicy
+		kept   string
+		gone   string
+	}{
+		{"LRU", LRU(), "key0", "key1"},
+		{"LFU", LFU(), "key0", "key1"},
+		{"FIFO", FIFO(), "key9", "key0"},
+		{"TTLFirst", TTLFirst(), "key0", "key5"},
+		{"custom", EvictionFunc(func(candidates []EvictionCandidate, excess int64) []string {
+			var keys []string
+			for i := len(candidates) - 1; i >= 0 && excess > 0; i-- {
+				keys = append(keys, candidates[i].Key)
+				excess -= candidates[i].Size
+			}
+			return keys
+		}), "key0", "key9"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			store := New(TestStoreFile, WithSizeCap(64<<10), WithEvictionPolicy(c.policy))
+			defer deleteTestStoreFile()
+			defer store.Close()
+			for i := 0; i < 10; i++ {
+				_, _ = rand.Read(value)
+				var opts []PutOption
+				if i == 5 {
+					opts = append(opts, TTL(time.Hour))
+				}
+				_ = store.Put(fmt.Sprintf("key%d", i), append([]byte(nil), value...), opts...)
+			}
+			_, _ = store.Get("key0")
+			if err := store.Compact(); err != nil {
+				t.Fatal(err)
+			}
+			if !store.Has(c.kept) {
+				t.Errorf("Expected %s to be kept", c.kept)
+			}
+			if store.Has(c.gone) {
+				t.Errorf("Expected %s to be evicted", c.gone)
+			}
+		})
+	}
+}
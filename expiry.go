@@ -0,0 +1,95 @@
+package sunduk
+
+import (
+	"sort"
+	"sunduk/internal/format"
+	"time"
+)
+
+// TTL makes the values put expire after ttl. Expired values are left out by Get, GetRange, GetMany and Has, and
+// their entries are evicted by the next compaction, see WithSizeCap, Keys lists them until then. Values put
+// without TTL don't expire, and putting a value again replaces its expiry time. Values appended to by Append
+// and renamed keep their expiry time, copies get the expiry time of their source
+func TTL(ttl time.Duration) PutOption {
+	expires := time.Now().Add(ttl).UnixNano()
+	return func(o *putOptions) {
+		o.expires = expires
+	}
+}
+
+// expired returns true if the committed value of key has expired. Pending values don't expire until they are
+// committed. It must be called with mu held
+func (store *Sunduk) expired(key string) bool {
+	t, ok := store.expiry[key]
+	if !ok || t > time.Now().UnixNano() {
+		return false
+	}
+	_, pending := store.data[key]
+	return !pending
+}
+
+// expiresAt returns the expiry time of the value of key, pending or committed, 0 if it doesn't expire.
+// It must be called with mu held
+func (store *Sunduk) expiresAt(key string) int64 {
+	if _, pending := store.data[key]; pending {
+		return store.pending.expires[key]
+	}
+	return store.expiry[key]
+}
+
+// nextExpiry returns the expiry times of the store once chunks are committed along with deleted and renamed keys.
+// It must be called with writeMu held
+func (store *Sunduk) nextExpiry(chunks map[string]chunk, deleted []string, renames map[string]string) map[string]int64 {
+	next := make(map[string]int64, len(store.expiry))
+	for k, t := range store.expiry {
+		next[k] = t
+	}
+	for _, k := range deleted {
+		delete(next, k)
+	}
+	for from, to := range renames {
+		if t, ok := store.expiry[from]; ok {
+			next[to] = t
+		} else {
+			delete(next, to)
+		}
+	}
+	for k, c := range chunks {
+		if c.expires != 0 {
+			next[k] = c.expires
+		} else {
+			delete(next, k)
+		}
+	}
+	if len(next) == 0 {
+		return nil
+	}
+	return next
+}
+
+// encodeExpiry returns the expiry section of the index of the store file for the expiry times of keys
+func encodeExpiry(expiry map[string]int64) []format.Expiry {
+	if len(expiry) == 0 {
+		return nil
+	}
+	list := make([]format.Expiry, 0, len(expiry))
+	for k, t := range expiry {
+		list = append(list, format.Expiry{Key: k, Time: t})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// decodeExpiry returns the expiry times of keys for the expiry section of the index of the store file
+func decodeExpiry(list []format.Expiry) map[string]int64 {
+	if len(list) == 0 {
+		return nil
+	}
+	expiry := make(map[string]int64, len(list))
+	for _, e := range list {
+		expiry[e.Key] = e.Time
+	}
+	return expiry
+}
@@ -0,0 +1,43 @@
+package sunduk
+
+import "os"
+
+// ExtractTo writes the value of key to the file at destPath, streaming it instead of reading it whole, such as to
+// dump a library on disk before loading it. The value is written to a file next to destPath, synced to stable
+// storage and renamed over destPath, so that destPath holds either its previous content or the whole value.
+// The file gets the mode and the owner of the files created by the store, see WithFileMode, or else the mode
+// of the file it replaces. It returns ErrNotFound if key has no entry
+func (store *Sunduk) ExtractTo(key, destPath string) error {
+	if err := store.authorize(key, OperationRead); err != nil {
+		return err
+	}
+	value, ok := store.getLazy(key)
+	if !ok {
+		return ErrNotFound
+	}
+	defer value.Close()
+	temp := destPath + ".new"
+	file, err := store.createFile(temp, destPath)
+	if err != nil {
+		return err
+	}
+	if _, err := value.WriteTo(file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(temp)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(temp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+	if err := replaceFile(temp, destPath); err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+	return syncDir(destPath)
+}
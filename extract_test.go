@@ -0,0 +1,44 @@
+package sunduk
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSunduk_ExtractTo(t *testing.T) {
+	store := New(TestStoreFile, WithFileMode(0600))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	large := bytes.Repeat([]byte("library "), 1000)
+	_ = store.Put("lib.so", large)
+
+	dest := filepath.Join(t.TempDir(), "lib.so")
+	if err := os.WriteFile(dest, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.ExtractTo("lib.so", dest); err != nil {
+		t.Fatalf("Expected value to be extracted, got %v instead", err)
+	}
+	if data, err := os.ReadFile(dest); err != nil || !bytes.Equal(data, large) {
+		t.Errorf("Expected extracted file to hold the value, got %d bytes (%v) instead", len(data), err)
+	}
+	if info, err := os.Stat(dest); err != nil {
+		t.Error(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected extracted file mode 0600, got %v instead", info.Mode().Perm())
+	}
+	if _, err := os.Stat(dest + ".new"); !os.IsNotExist(err) {
+		t.Errorf("Expected no temporary file left, got %v instead", err)
+	}
+
+	if err := store.ExtractTo("missing", dest); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing key, got %v instead", err)
+	}
+	if data, _ := os.ReadFile(dest); !bytes.Equal(data, large) {
+		t.Error("Expected failed extraction to keep the destination file")
+	}
+}
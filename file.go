@@ -0,0 +1,100 @@
+package sunduk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sunduk/internal/vfs"
+)
+
+// fsys holds the files of stores, replaced by tests to inject faults
+var fsys vfs.FS = osFS{}
+
+// osFS is the file system of the OS, which opens store files with openStoreFile
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (vfs.File, error) {
+	file, err := openStoreFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (osFS) Rename(from, to string) error {
+	return replaceFile(from, to)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// CheckTempDir checks that files written in dir can be renamed over the store file at path, which WithTempDir
+// requires. It creates a probe file in dir and renames it next to the store file, which fails if dir is missing,
+// isn't writable or is on another file system. It returns an error wrapping ErrTempDir on failure
+func CheckTempDir(path, dir string) error {
+	probe, err := os.CreateTemp(dir, filepath.Base(path)+".*.probe")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTempDir, err)
+	}
+	name := probe.Name()
+	if err := probe.Close(); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("%w: %v", ErrTempDir, err)
+	}
+	target := path + ".probe"
+	if err := replaceFile(name, target); err != nil {
+		_ = os.Remove(name)
+		return fmt.Errorf("%w: %v", ErrTempDir, err)
+	}
+	return os.Remove(target)
+}
+
+// tempPath returns the path of the temporary file written by compaction
+func (store *Sunduk) tempPath() string {
+	if store.opts.tempDir == "" {
+		return store.FilePath + ".new"
+	}
+	return filepath.Join(store.opts.tempDir, filepath.Base(store.FilePath)+".new")
+}
+
+// indexPath returns the path of the index file of the store, see WithIndexFile
+func (store *Sunduk) indexPath() string {
+	return store.FilePath + ".idx"
+}
+
+// createFile creates or truncates the file at name with the mode and the owner of created files. If mode
+// isn't configured, the file gets the mode of the file at like, or 0666 masked by the umask without one
+func (store *Sunduk) createFile(name, like string) (vfs.File, error) {
+	file, err := fsys.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if err := store.setPerm(file, like); err != nil {
+		_ = file.Close()
+		_ = os.Remove(name)
+		return nil, err
+	}
+	return file, nil
+}
+
+// setPerm sets the configured mode and owner of a file created by the store, or the mode of the file at like
+func (store *Sunduk) setPerm(file vfs.File, like string) error {
+	mode := store.opts.fileMode
+	if mode == 0 && like != "" {
+		if info, err := os.Stat(like); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+	if mode != 0 {
+		if err := file.Chmod(mode); err != nil {
+			return err
+		}
+	}
+	if store.opts.uid != -1 || store.opts.gid != -1 {
+		if err := file.Chown(store.opts.uid, store.opts.gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
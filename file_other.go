@@ -0,0 +1,31 @@
+//go:build !windows
+
+package sunduk
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// openStoreFile opens a store file. Open files are replaced and removed freely on this platform
+func openStoreFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+// replaceFile renames from to to, atomically replacing to if it exists
+func replaceFile(from, to string) error {
+	return os.Rename(from, to)
+}
+
+// syncDir commits the entries of the directory holding the file at path to stable storage
+func syncDir(path string) error {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	if err := dir.Sync(); err != nil {
+		_ = dir.Close()
+		return err
+	}
+	return dir.Close()
+}
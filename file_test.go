@@ -0,0 +1,274 @@
+package sunduk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"sunduk/internal/vfs"
+	"testing"
+)
+
+func TestReplaceFile_ReplacesOpenFile(t *testing.T) {
+	from, to := TestStoreFile+".new", TestStoreFile
+	defer os.Remove(from)
+	defer deleteTestStoreFile()
+	if err := os.WriteFile(to, []byte("old"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(from, []byte("new"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	file, err := openStoreFile(to, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("Expected openStoreFile to succeed, got %v instead", err)
+	}
+	defer file.Close()
+
+	if err := replaceFile(from, to); err != nil {
+		t.Fatalf("Expected replaceFile to replace an open file, got %v instead", err)
+	}
+	if _, err := os.Stat(from); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be moved by replaceFile", from)
+	}
+	if data, _ := os.ReadFile(to); string(data) != "new" {
+		t.Errorf("Expected %s to have replaced content, got %q instead", to, data)
+	}
+	if data, _ := io.ReadAll(file); string(data) != "old" {
+		t.Errorf("Expected open file to keep old content, got %q instead", data)
+	}
+}
+
+func TestSunduk_CompactRepeatedlyWithReaders(t *testing.T) {
+	writer := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer writer.Close()
+	reader, err := Open(TestStoreFile, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	for i := 0; i < 3; i++ {
+		value := []byte{'v', byte('0' + i)}
+		_ = writer.Put("key", value)
+		_ = writer.Put("key", value)
+		if err := writer.Compact(); err != nil {
+			t.Fatalf("Expected Compact %d to succeed with a reader open, got %v instead", i+1, err)
+		}
+		if err := reader.Reload(); err != nil {
+			t.Fatalf("Expected Reload to succeed after Compact %d, got %v instead", i+1, err)
+		}
+		checkValueForKey(t, reader, "key", value)
+	}
+	if _, err := os.Stat(TestStoreFile + ".bak"); !os.IsNotExist(err) {
+		t.Error("Expected Compact to remove the backup file")
+	}
+}
+
+func TestSunduk_WithTempDir(t *testing.T) {
+	dir, err := os.MkdirTemp(".", "tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := Open(TestStoreFile, WithTempDir(dir))
+	if err != nil {
+		t.Fatalf("Expected Open to succeed with a temp dir next to the store file, got %v instead", err)
+	}
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	_ = store.Put("key", []byte("new value"))
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to succeed with a temp dir, got %v instead", err)
+	}
+	checkValueForKey(t, store, "key", []byte("new value"))
+	store.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no files left in the temp dir, got %d instead", len(entries))
+	}
+}
+
+func TestCheckTempDir(t *testing.T) {
+	if err := CheckTempDir(TestStoreFile, "."); err != nil {
+		t.Errorf("Expected the directory of the store file to be a valid temp dir, got %v instead", err)
+	}
+	if err := CheckTempDir(TestStoreFile, "missing"); !errors.Is(err, ErrTempDir) {
+		t.Errorf("Expected ErrTempDir for a missing temp dir, got %v instead", err)
+	}
+	if _, err := Open(TestStoreFile, WithTempDir("missing")); !errors.Is(err, ErrTempDir) {
+		t.Errorf("Expected Open to fail with ErrTempDir for a missing temp dir, got %v instead", err)
+	}
+	if _, err := os.Stat(TestStoreFile); !os.IsNotExist(err) {
+		t.Error("Expected failed Open to create no store file")
+	}
+}
+
+func TestSunduk_WithFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no permission bits")
+	}
+	store, err := Open(TestStoreFile, WithFileMode(0600))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	checkMode := func(name string, mode os.FileMode) {
+		t.Helper()
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("Expected %s to have mode %v, got %v instead", name, mode, info.Mode().Perm())
+		}
+	}
+	checkMode(TestStoreFile, 0600)
+	checkMode(TestStoreFile+".lock", 0600)
+	_ = store.Put("key", []byte("value"))
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	checkMode(TestStoreFile, 0600)
+	store.Close()
+
+	// Without a configured mode compaction keeps the mode of the store file
+	if err := os.Chmod(TestStoreFile, 0640); err != nil {
+		t.Fatal(err)
+	}
+	store = New(TestStoreFile)
+	_ = store.Put("key", []byte("new value"))
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	checkMode(TestStoreFile, 0640)
+	store.Close()
+}
+
+func TestSunduk_WithFileOwner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows doesn't support file owners")
+	}
+	store, err := Open(TestStoreFile, WithFileOwner(os.Getuid(), os.Getgid()))
+	if err != nil {
+		t.Fatalf("Expected Open to succeed with the current user as owner, got %v instead", err)
+	}
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	if err := store.Compact(); err != nil {
+		t.Errorf("Expected Compact to succeed with the current user as owner, got %v instead", err)
+	}
+	store.Close()
+}
+
+// useFaultFS replaces the file system of stores with a FaultFS until the test ends
+func useFaultFS(t *testing.T) *vfs.FaultFS {
+	saved := fsys
+	fs := vfs.NewFaultFS(saved)
+	fsys = fs
+	t.Cleanup(func() {
+		fsys = saved
+	})
+	return fs
+}
+
+func TestSunduk_FlushFaults(t *testing.T) {
+	faults := []struct {
+		name   string
+		faults vfs.Faults
+	}{
+		{"fail-first-write", vfs.Faults{FailWrite: 1}},
+		{"fail-index-write", vfs.Faults{FailWrite: 2}},
+		{"short-write", vfs.Faults{ShortWrite: 1}},
+		{"crash-mid-write", vfs.Faults{CrashWrite: 1}},
+		{"crash-mid-index", vfs.Faults{CrashWrite: 2}},
+		{"fail-sync", vfs.Faults{Fail: func(op, _ string) bool { return op == "sync" }}},
+	}
+	for _, fault := range faults {
+		t.Run(fault.name, func(t *testing.T) {
+			defer deleteTestStoreFile()
+			fs := useFaultFS(t)
+			store := New(TestStoreFile, WithDurability(DurabilityFlush))
+			_ = store.Put("a", []byte("apple"))
+			fs.Inject(fault.faults)
+			if err := store.Put("b", bytes.Repeat([]byte("banana"), 100)); err == nil {
+				t.Fatal("Expected Put to fail on injected fault")
+			}
+			_ = store.Close()
+
+			// The store is reopened as by a restarted process
+			fsys = osFS{}
+			store, err := Open(TestStoreFile)
+			if err != nil {
+				t.Fatalf("Expected the store to open after the fault, got %v instead", err)
+			}
+			defer store.Close()
+			checkValueForKey(t, store, "a", []byte("apple"))
+			if _, ok := store.Get("b"); ok {
+				t.Error("Expected the failed value to be missing")
+			}
+			if err := store.Verify(); err != nil {
+				t.Errorf("Expected the store to verify, got %v instead", err)
+			}
+		})
+	}
+}
+
+func TestSunduk_CompactCrashes(t *testing.T) {
+	crashes := []struct {
+		name   string
+		faults vfs.Faults
+	}{
+		{"before-backup", vfs.Faults{CrashRename: 1}},
+		{"after-backup", vfs.Faults{CrashRename: 1, RenameBeforeCrash: true}},
+		{"before-replace", vfs.Faults{CrashRename: 2}},
+		{"after-replace", vfs.Faults{CrashRename: 2, RenameBeforeCrash: true}},
+		{"mid-write", vfs.Faults{CrashWrite: 2}},
+	}
+	for _, crash := range crashes {
+		t.Run(crash.name, func(t *testing.T) {
+			defer deleteTestStoreFile()
+			defer os.Remove(TestStoreFile + ".new")
+			fs := useFaultFS(t)
+			store := New(TestStoreFile)
+			_ = store.Put("a", []byte("apple"))
+			_ = store.Put("a", []byte("apricot"))
+			_ = store.Put("b", []byte("banana"))
+			fs.Inject(crash.faults)
+			if err := store.Compact(); err == nil || !fs.Crashed() {
+				t.Fatalf("Expected Compact to crash, got %v instead", err)
+			}
+			_ = store.Close()
+
+			// The store is reopened as by a restarted process
+			fsys = osFS{}
+			store, err := Open(TestStoreFile)
+			if err != nil {
+				t.Fatalf("Expected the store to open after the crash, got %v instead", err)
+			}
+			defer store.Close()
+			checkValueForKey(t, store, "a", []byte("apricot"))
+			checkValueForKey(t, store, "b", []byte("banana"))
+		})
+	}
+}
+
+func TestSunduk_StaleBackup(t *testing.T) {
+	defer deleteTestStoreFile()
+	store := New(TestStoreFile)
+	_ = store.Put("a", []byte("apple"))
+	store.Close()
+	// A backup left by a completed compaction which failed to remove it, then the store file is removed
+	if err := os.Rename(TestStoreFile, TestStoreFile+".bak"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(TestStoreFile + ".bak")
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkKeyNotExists(t, store, "a")
+	if _, err := os.Stat(TestStoreFile + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale backup to be removed, got %v instead", err)
+	}
+}
@@ -0,0 +1,80 @@
+//go:build windows
+
+package sunduk
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	movefileReplaceExisting = 0x1
+	movefileWriteThrough    = 0x8
+)
+
+var procMoveFileExW = syscall.NewLazyDLL("kernel32.dll").NewProc("MoveFileExW")
+
+// openStoreFile opens a store file sharing it for deletion, so that compaction can rename and remove the file
+// while it is open by this store and by readers in other processes. os.OpenFile doesn't share files for deletion
+func openStoreFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	path, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	var access uint32
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_RDONLY:
+		access = syscall.GENERIC_READ
+	case os.O_WRONLY:
+		access = syscall.GENERIC_WRITE
+	default:
+		access = syscall.GENERIC_READ | syscall.GENERIC_WRITE
+	}
+	var mode uint32
+	switch {
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		mode = syscall.CREATE_NEW
+	case flag&(os.O_CREATE|os.O_TRUNC) == os.O_CREATE|os.O_TRUNC:
+		mode = syscall.CREATE_ALWAYS
+	case flag&os.O_CREATE != 0:
+		mode = syscall.OPEN_ALWAYS
+	case flag&os.O_TRUNC != 0:
+		mode = syscall.TRUNCATE_EXISTING
+	default:
+		mode = syscall.OPEN_EXISTING
+	}
+	var attrs uint32 = syscall.FILE_ATTRIBUTE_NORMAL
+	if perm&0200 == 0 {
+		attrs = syscall.FILE_ATTRIBUTE_READONLY
+	}
+	share := uint32(syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE | syscall.FILE_SHARE_DELETE)
+	h, err := syscall.CreateFile(path, access, share, nil, mode, attrs, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return os.NewFile(uintptr(h), name), nil
+}
+
+// replaceFile renames from to to with MoveFileEx, replacing to if it exists. The move is written through
+// to the disk before it returns
+func replaceFile(from, to string) error {
+	fromp, err := syscall.UTF16PtrFromString(from)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+	top, err := syscall.UTF16PtrFromString(to)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+	r, _, err := procMoveFileExW.Call(uintptr(unsafe.Pointer(fromp)), uintptr(unsafe.Pointer(top)), movefileReplaceExisting|movefileWriteThrough)
+	if r == 0 {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+	return nil
+}
+
+// syncDir does nothing, directories can't be synced on this platform. Renames are written through by replaceFile
+func syncDir(path string) error {
+	return nil
+}
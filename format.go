@@ -0,0 +1,213 @@
+package sunduk
+
+import (
+	"fmt"
+	"io"
+	"sunduk/internal/format"
+)
+
+// checksum returns the checksum of data as it is recorded in the index
+func checksum(data []byte) uint32 {
+	return format.Checksum(data)
+}
+
+// decodeChunk returns the value held by a chunk with flags, dict is the dictionary of the file
+func decodeChunk(data []byte, flags uint64, dict *dictionary) ([]byte, error) {
+	return format.DecodeChunk(data, flags, dict.bytes())
+}
+
+// chunkSum returns the size and the checksum of the value held by a chunk with flags
+func chunkSum(data []byte, flags uint64, dict *dictionary) (int64, uint32, error) {
+	return format.ChunkSum(data, flags, dict.bytes())
+}
+
+// writePreamble writes the magic and the format version at the beginning of the file
+func writePreamble(w io.Writer) error {
+	return format.WritePreamble(w)
+}
+
+// writeIndex compresses the entries of index with the sections of idx, such as the location of the dictionary and
+// the generation, and writes them at idx.Offset followed by the trailer. With WithLazyIndex they are preceded by a lookup table
+func (store *Sunduk) writeIndex(w io.Writer, enc encoder, keys OrderedKeys, index map[string]entry, idx format.Index) error {
+	idx.Entries = indexEntries(keys, index)
+	if store.opts.lazyIndex {
+		n, err := format.WriteLookup(w, idx)
+		if err != nil {
+			return err
+		}
+		idx.Offset += n
+	}
+	return format.WriteIndex(w, idx, enc.windowBits)
+}
+
+// indexEntries returns the entries of the index of the store file for the entries of index, in the order of keys
+func indexEntries(keys OrderedKeys, index map[string]entry) []format.Entry {
+	entries := make([]format.Entry, keys.Len())
+	for i, k := range keys.keys {
+		e := index[k]
+		entries[i] = format.Entry{Key: k, Offset: e.Offset, Size: e.Size, RawSize: e.RawSize, Sum: e.Sum, Flags: e.Flags, Base: e.Base, Digest: e.Digest}
+	}
+	return entries
+}
+
+// newEntry returns the entry of the store for an entry of the index of the store file
+func newEntry(e format.Entry) entry {
+	return entry{Offset: e.Offset, Size: e.Size, RawSize: e.RawSize, Sum: e.Sum, Flags: e.Flags, Base: e.Base, Digest: e.Digest, hasSum: true}
+}
+
+// readFormat checks the format version of the file and reads the index with the matching reader
+func (store *Sunduk) readFormat() error {
+	// Empty files were created for empty stores before format versioning
+	info, err := store.file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	store.size = info.Size()
+	version, err := format.ReadVersion(store.file)
+	if err != nil {
+		return err
+	}
+	switch version {
+	case 0:
+		return store.readLegacyHeader()
+	case 1, 2, format.Version:
+		// Files in older versions are rewritten in the current version on the first write
+		store.legacy = version != format.Version
+		// Store files which index is in an index file have no index of their own
+		if id, ok, err := format.ReadFileID(store.file); err != nil || ok {
+			if err != nil {
+				return err
+			}
+			return store.readIndexFile(id, info.Size())
+		}
+		if version == format.Version && store.opts.lazyIndex && store.opts.readOnly {
+			if ok, err := store.readLookup(); ok || err != nil {
+				return err
+			}
+		}
+		return store.readHeader()
+	default:
+		return fmt.Errorf("%w %d, the latest supported version is %d", ErrVersion, version, format.Version)
+	}
+}
+
+// readHeader reads the storage index from the end of the file
+func (store *Sunduk) readHeader() error {
+	info, err := store.file.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat storage header: %v", err)
+	}
+	size := info.Size()
+	index, err := store.opts.indexLimits.ReadIndex(store.file, size)
+	if err != nil {
+		if size, err = store.dropTornTail(size, err); err != nil {
+			return err
+		}
+		if index, err = store.opts.indexLimits.ReadIndex(store.file, size); err != nil {
+			return err
+		}
+	}
+	if err := store.useIndex(index); err != nil {
+		return err
+	}
+	store.size = size
+	store.tail = size - index.Offset
+	return nil
+}
+
+// dropTornTail finds the end of the last commit of a file of size bytes which index failed to read with err,
+// when a crash in the middle of a commit left data after the last trailer. The torn tail is truncated like the
+// tail of a failed commit. Only writers holding the lock of the store file drop torn tails, as the tail seen by
+// others may be a commit in progress. It returns err if the file ends with a trailer, which index is damaged
+// rather than torn
+func (store *Sunduk) dropTornTail(size int64, err error) (int64, error) {
+	if store.opts.readOnly || store.opts.unlocked {
+		return 0, err
+	}
+	if _, terr := format.LocateIndex(store.file, size); terr == nil {
+		return 0, err
+	}
+	for _, end := range format.FindTrailers(store.file, size) {
+		if end >= size {
+			continue
+		}
+		if _, ierr := store.opts.indexLimits.ReadIndex(store.file, end); ierr != nil {
+			continue
+		}
+		store.opts.logger.Warn("dropping torn tail of store file", "file", store.FilePath, "size", size, "end", end)
+		if terr := store.file.Truncate(end); terr != nil {
+			return 0, fmt.Errorf("%v, and unable to drop the torn tail: %v", err, terr)
+		}
+		return end, nil
+	}
+	return 0, err
+}
+
+// useIndex sets the entries, the dictionary and the sections of the store from the index of the store file
+func (store *Sunduk) useIndex(index format.Index) error {
+	if err := store.readDictionary(index.Dictionary); err != nil {
+		return err
+	}
+	if err := store.useCollation(index.Collation); err != nil {
+		return err
+	}
+	if err := store.useHash(index.Hash); err != nil {
+		return err
+	}
+	store.index = make(map[string]entry, len(index.Entries))
+	for _, e := range index.Entries {
+		store.index[e.Key] = newEntry(e)
+	}
+	store.setHeader(index)
+	store.access.load(index.Access)
+	return nil
+}
+
+// header returns the sections of the index of the store other than the dictionary, with the ID of the store file
+// but not the size recorded by an index file
+func (store *Sunduk) header() format.Index {
+	return format.Index{Generation: store.generation, Meta: store.meta, Bloom: store.bloom, Signature: store.signature, Sealed: store.sealed, Secondary: encodeSecondary(store.secondary), Expiry: encodeExpiry(store.expiry), Audit: store.audit, Tombstones: encodeTombstones(store.tombstones), Trash: encodeTrash(store.trash), Collation: store.collation.name(), Hash: store.enc.hash, Data: format.DataFile{ID: store.fileID}}
+}
+
+// setHeader sets the store from the sections of index other than the dictionary
+func (store *Sunduk) setHeader(index format.Index) {
+	store.generation = index.Generation
+	store.meta = index.Meta
+	store.bloom = index.Bloom
+	store.signature = index.Signature
+	store.sealed = index.Sealed
+	store.fileID = index.Data.ID
+	store.secondary = decodeSecondary(index.Secondary)
+	store.expiry = decodeExpiry(index.Expiry)
+	store.wakeNotifier()
+	store.audit = index.Audit
+	store.tombstones = decodeTombstones(index.Tombstones)
+	store.trash = decodeTrash(index.Trash)
+}
+
+// readDictionary reads the dictionary located by d, if the file has one
+func (store *Sunduk) readDictionary(d format.Dictionary) error {
+	if d.Size == 0 {
+		return nil
+	}
+	data, err := format.ReadDictionary(store.file, d)
+	if err != nil {
+		return err
+	}
+	store.dict = &dictionary{data: data, offset: d.Offset}
+	store.file.dict = store.dict
+	return nil
+}
+
+// readLegacyHeader reads the storage header of a file written before format versioning
+func (store *Sunduk) readLegacyHeader() error {
+	entries, err := store.opts.indexLimits.ReadLegacyIndex(store.file)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		store.index[e.Key] = entry{Offset: e.Offset, Size: e.Size}
+	}
+	store.legacy = true
+	return nil
+}
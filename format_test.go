@@ -0,0 +1,177 @@
+package sunduk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sunduk/internal/format"
+	"testing"
+)
+
+// writeVersion1File writes a store file in format version 1, which has no flags of entries
+func writeVersion1File(t *testing.T, path string, values map[string][]byte) {
+	var buf bytes.Buffer
+	if err := format.WritePreamble(&buf); err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint16(buf.Bytes()[4:], 1)
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var index bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) {
+		index.Write(vb[:binary.PutUvarint(vb[:], v)])
+	}
+	putUvarint(uint64(len(keys)))
+	for _, k := range keys {
+		data, err := format.Compress(values[k], 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		putUvarint(uint64(len(k)))
+		index.WriteString(k)
+		putUvarint(uint64(buf.Len()))
+		putUvarint(uint64(len(data)))
+		putUvarint(uint64(len(values[k])))
+		binary.LittleEndian.PutUint32(vb[:], format.Checksum(values[k]))
+		index.Write(vb[:4])
+		buf.Write(data)
+	}
+
+	offset := buf.Len()
+	data, err := format.Compress(index.Bytes(), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(data)
+	var tb [format.TrailerSize]byte
+	binary.LittleEndian.PutUint64(tb[0:], uint64(offset))
+	binary.LittleEndian.PutUint32(tb[8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(tb[12:], format.Checksum(data))
+	copy(tb[16:], format.Magic[:])
+	buf.Write(tb[:])
+
+	if err := os.WriteFile(path, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSunduk_UpgradeVersion1File(t *testing.T) {
+	writeVersion1File(t, TestStoreFile, map[string][]byte{"a": []byte("apple"), "b": []byte("banana")})
+	defer deleteTestStoreFile()
+
+	store := New(TestStoreFile)
+	checkValueForKey(t, store, "a", []byte("apple"))
+	checkValueForKey(t, store, "b", []byte("banana"))
+	if err := store.Put("c", []byte("cherry")); err != nil {
+		t.Fatalf("Expected Put to succeed on version 1 file, got %v instead", err)
+	}
+	store.Close()
+
+	file, err := os.Open(TestStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, _ := format.ReadVersion(file)
+	_ = file.Close()
+	if version != format.Version {
+		t.Errorf("Expected version 1 file to be rewritten in version %d, got version %d instead", format.Version, version)
+	}
+
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "a", []byte("apple"))
+	checkValueForKey(t, store, "b", []byte("banana"))
+	checkValueForKey(t, store, "c", []byte("cherry"))
+	store.Close()
+}
+
+func TestOpen_CorruptHeader(t *testing.T) {
+	defer deleteTestStoreFile()
+	for _, data := range []string{"\xff\xff\xff\xff\xff\xff\xff\xffgarbage", "SNDK\x02\x00\x00\x00truncated"} {
+		if err := os.WriteFile(TestStoreFile, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(TestStoreFile); !errors.Is(err, ErrCorruptHeader) {
+			t.Errorf("Expected ErrCorruptHeader for %q, got %v instead", data, err)
+		}
+	}
+}
+
+func TestSunduk_ReopenRoundtrip(t *testing.T) {
+	for _, count := range []int{0, 1, 10000} {
+		t.Run(fmt.Sprint(count), func(t *testing.T) {
+			deleteTestStoreFile()
+			defer deleteTestStoreFile()
+			random := rand.New(rand.NewSource(int64(count)))
+			values := make(map[string][]byte, count)
+			for i := 0; i < count; i++ {
+				// Values are empty, small, compressible and incompressible, so that chunks of every kind are written
+				value := make([]byte, random.Intn(4)*random.Intn(3000))
+				if i%2 == 0 {
+					random.Read(value)
+				}
+				values[fmt.Sprintf("key-%d", i)] = value
+			}
+			store := New(TestStoreFile)
+			if err := store.PutAll(values); err != nil {
+				t.Fatal(err)
+			}
+			store.Close()
+
+			// The file is read as written, then as rewritten by compaction
+			for _, compact := range []bool{true, false} {
+				store = New(TestStoreFile)
+				if store.Count() != count {
+					t.Errorf("Expected %d entries after reopen, got %d instead", count, store.Count())
+				}
+				for k, v := range values {
+					if value, ok := store.Get(k); !ok || !bytes.Equal(value, v) {
+						t.Fatalf("Expected value of %d bytes for key '%s' after reopen, got %d bytes (%t) instead", len(v), k, len(value), ok)
+					}
+				}
+				if compact {
+					if err := store.Compact(); err != nil {
+						t.Fatal(err)
+					}
+				}
+				store.Close()
+			}
+		})
+	}
+}
+
+func TestSunduk_WithIndexLimits(t *testing.T) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	store, err := Open(TestStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		_ = store.Put(fmt.Sprintf("key-%d", i), []byte("value"))
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opt := range []Option{WithIndexLimits(9, 0), WithIndexLimits(0, 64)} {
+		if _, err := Open(TestStoreFile, opt); !errors.Is(err, ErrIndexLimit) {
+			t.Errorf("Expected ErrIndexLimit, got %v instead", err)
+		}
+	}
+	store, err = Open(TestStoreFile, WithIndexLimits(10, 1<<10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	checkValueForKey(t, store, "key-9", []byte("value"))
+}
@@ -0,0 +1,127 @@
+package sunduk
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSunduk_FreezeBlocksWriters(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+
+	if err := store.Freeze(); err != nil {
+		t.Fatalf("Expected Freeze to succeed, got %v instead", err)
+	}
+	if err := store.Freeze(); err != ErrFrozen {
+		t.Errorf("Expected second Freeze to return ErrFrozen, got %v instead", err)
+	}
+	before, _ := os.ReadFile(TestStoreFile)
+
+	done := make(chan error)
+	go func() {
+		done <- store.Put("other", []byte("other value"))
+	}()
+	select {
+	case <-done:
+		t.Fatal("Expected Put to be blocked while the store is frozen")
+	case <-time.After(50 * time.Millisecond):
+	}
+	checkValueForKey(t, store, "key", []byte("value"))
+	if after, _ := os.ReadFile(TestStoreFile); !bytes.Equal(before, after) {
+		t.Error("Expected store file to be unchanged while the store is frozen")
+	}
+
+	store.Thaw()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Put to succeed after Thaw, got %v instead", err)
+	}
+	checkValueForKey(t, store, "other", []byte("other value"))
+	store.Thaw()
+	store.Close()
+}
+
+func TestSunduk_FreezeClose(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	if err := store.Freeze(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- store.Close()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Close to close the frozen store, got %v instead", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Close not to wait for Thaw")
+	}
+	store.Thaw()
+	if err := store.Freeze(); err != ErrClosed {
+		t.Errorf("Expected ErrClosed freezing a closed store, got %v instead", err)
+	}
+	if err := store.Freeze(); err != ErrClosed {
+		t.Errorf("Expected a failed Freeze to leave the store thawed, got %v instead", err)
+	}
+}
+
+func TestSunduk_FreezeConcurrent(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			errs <- store.Freeze()
+		}()
+	}
+	frozen := 0
+	for i := 0; i < cap(errs); i++ {
+		switch err := <-errs; err {
+		case nil:
+			frozen++
+		case ErrFrozen:
+		default:
+			t.Errorf("Expected ErrFrozen for concurrent freezes, got %v instead", err)
+		}
+	}
+	if frozen != 1 {
+		t.Errorf("Expected a single Freeze to succeed, got %d instead", frozen)
+	}
+	store.Thaw()
+	if err := store.Put("key", []byte("value")); err != nil {
+		t.Errorf("Expected Put to succeed after Thaw, got %v instead", err)
+	}
+}
+
+func TestSunduk_FreezeCloseAutoCompaction(t *testing.T) {
+	store := New(TestStoreFile, WithAutoCompaction(0.01, 10*time.Millisecond))
+	defer deleteTestStoreFile()
+	for i := 0; i < 10; i++ {
+		_ = store.Put("key", bytes.Repeat([]byte{byte(i)}, 1000))
+	}
+	if err := store.Freeze(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		done <- store.Close()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Close to close the frozen store, got %v instead", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Close of a frozen store with auto-compaction not to wait for Thaw")
+	}
+}
@@ -0,0 +1,27 @@
+package sunduk
+
+import "strconv"
+
+// Generation returns the count of mutations committed to the store, which is persisted in the store file.
+// It is incremented by every successful Put, PutAll, Delete and SetMeta, and kept by compactions, so layers above
+// can cheaply tell whether anything changed. Read-only stores see the generation of the last reload
+func (store *Sunduk) Generation() uint64 {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.generation
+}
+
+// ETag returns an entity tag of the current state of the store, for HTTP caching of responses
+// built from its content. The tag changes with the generation
+func (store *Sunduk) ETag() string {
+	return strconv.Quote(strconv.FormatUint(store.Generation(), 16))
+}
+
+// nextGeneration returns the generation of the store once values and deleted are committed.
+// It must be called with writeMu held
+func (store *Sunduk) nextGeneration(values map[string][]byte, deleted []string, po putOptions) uint64 {
+	if len(values)+len(deleted)+len(po.chunks) == 0 && po.meta == nil || po.repair {
+		return store.generation
+	}
+	return store.generation + 1
+}
@@ -0,0 +1,62 @@
+package sunduk
+
+import "testing"
+
+func TestSunduk_Generation(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	if store.Generation() != 0 {
+		t.Errorf("Expected generation 0 for a new store, got %d instead", store.Generation())
+	}
+	etag := store.ETag()
+
+	_ = store.Put("a", []byte("apple"))
+	_ = store.PutAll(map[string][]byte{"b": []byte("banana"), "c": []byte("cherry")})
+	_ = store.Delete("c")
+	_ = store.Delete("missing")
+	if store.Generation() != 3 {
+		t.Errorf("Expected generation 3 after 3 mutations, got %d instead", store.Generation())
+	}
+	if store.ETag() == etag {
+		t.Errorf("Expected ETag to change with the generation, got %s instead", etag)
+	}
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if store.Generation() != 3 {
+		t.Errorf("Expected compaction to keep generation 3, got %d instead", store.Generation())
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	if store.Generation() != 3 {
+		t.Errorf("Expected generation 3 to be persisted, got %d instead", store.Generation())
+	}
+	if info := store.DebugInfo(); info.Index.Generation != 3 {
+		t.Errorf("Expected debug info to report generation 3, got %d instead", info.Index.Generation)
+	}
+}
+
+func TestSunduk_GenerationOfReader(t *testing.T) {
+	writer := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer writer.Close()
+	reader, err := Open(TestStoreFile, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	_ = writer.Put("a", []byte("apple"))
+	if reader.Generation() != 0 {
+		t.Errorf("Expected reader to keep generation 0 until reloaded, got %d instead", reader.Generation())
+	}
+	if err := reader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if reader.Generation() != writer.Generation() {
+		t.Errorf("Expected reader to reload generation %d, got %d instead", writer.Generation(), reader.Generation())
+	}
+}
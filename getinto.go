@@ -0,0 +1,90 @@
+package sunduk
+
+import (
+	"io"
+	"sunduk/internal/format"
+)
+
+// GetInto reads the value of key into buf and returns its size, so that readers of large values reuse buffers
+// instead of allocating one per value. It returns io.ErrShortBuffer along with the size of the value if it
+// doesn't fit buf, and ErrNotFound if key has no entry. Values failing checksum verification are repaired like
+// with Get
+func (store *Sunduk) GetInto(key string, buf []byte) (n int, err error) {
+	if err := store.authorize(key, OperationRead); err != nil {
+		return 0, err
+	}
+	value, ok := store.getLazy(key)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	defer value.Close()
+	size := value.Size()
+	if size > int64(len(buf)) {
+		return int(size), io.ErrShortBuffer
+	}
+	if err := value.readInto(buf[:size]); err != nil {
+		return 0, err
+	}
+	return int(size), nil
+}
+
+// GetAppend appends the value of key to buf and returns the extended buffer, allocating a larger one only if the
+// value doesn't fit the capacity of buf, so that buffers are reused, such as with a sync.Pool:
+//
+//	buf, err := store.GetAppend(key, pool.Get().([]byte)[:0])
+//	...
+//	pool.Put(buf)
+//
+// It returns ErrNotFound if key has no entry, and buf as it was, possibly grown, on failure
+func (store *Sunduk) GetAppend(key string, buf []byte) ([]byte, error) {
+	if err := store.authorize(key, OperationRead); err != nil {
+		return buf, err
+	}
+	value, ok := store.getLazy(key)
+	if !ok {
+		return buf, ErrNotFound
+	}
+	defer value.Close()
+	n, size := len(buf), value.Size()
+	if int64(cap(buf)-n) < size {
+		grown := make([]byte, n, int64(n)+size)
+		copy(grown, buf)
+		buf = grown
+	}
+	if err := value.readInto(buf[n : int64(n)+size]); err != nil {
+		return buf, err
+	}
+	return buf[:int64(n)+size], nil
+}
+
+// readInto reads the value into buf, which has the size of the value
+func (v *Value) readInto(buf []byte) error {
+	if v.file == nil {
+		copy(buf, v.value)
+		return nil
+	}
+	e := v.e
+	read := func(r io.Reader) error {
+		_, err := io.ReadFull(r, buf)
+		return err
+	}
+	var err error
+	if e.Flags&format.FlagAppend != 0 {
+		err = read(v.Reader())
+	} else {
+		err = format.ReadChunk(v.store.chunkSection(v.file, e.Offset, e.Size), e.Flags, v.file.dict.bytes(), read)
+	}
+	if err == nil && checksum(buf) == e.Sum && v.file.checkDigest(buf, e.Digest) {
+		return nil
+	}
+	// Values failing verification are read again, repairing them
+	value, err := v.Bytes()
+	if err != nil {
+		return err
+	}
+	if len(value) != len(buf) {
+		return ErrChecksum
+	}
+	copy(buf, value)
+	return nil
+}
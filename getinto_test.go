@@ -0,0 +1,72 @@
+package sunduk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSunduk_GetInto(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	large := bytes.Repeat([]byte("compressible "), 100)
+	_ = store.Put("raw", []byte("raw value"), Uncompressed())
+	_ = store.Put("compressed", large)
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	buf := make([]byte, 2000)
+	for key, expected := range map[string][]byte{"raw": []byte("raw value"), "compressed": large} {
+		n, err := store.GetInto(key, buf)
+		if err != nil || !bytes.Equal(buf[:n], expected) {
+			t.Errorf("Expected %q for key %s, got %q (%v) instead", expected, key, buf[:n], err)
+		}
+	}
+	if n, err := store.GetInto("compressed", buf[:10]); !errors.Is(err, io.ErrShortBuffer) || n != len(large) {
+		t.Errorf("Expected io.ErrShortBuffer and size %d, got %d (%v) instead", len(large), n, err)
+	}
+	if _, err := store.GetInto("missing", buf); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing key, got %v instead", err)
+	}
+}
+
+func TestSunduk_GetAppend(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	large := bytes.Repeat([]byte("compressible "), 100)
+	_ = store.Put("key", large)
+	_ = store.Flush()
+
+	buf := make([]byte, 0, 2000)
+	got, err := store.GetAppend("key", buf)
+	if err != nil || !bytes.Equal(got, large) {
+		t.Fatalf("Expected value of %d bytes, got %d (%v) instead", len(large), len(got), err)
+	}
+	if &got[0] != &buf[:1][0] {
+		t.Error("Expected value to be read into the capacity of buf")
+	}
+	got, err = store.GetAppend("key", []byte("prefix "))
+	if err != nil || !bytes.Equal(got, append([]byte("prefix "), large...)) {
+		t.Errorf("Expected value appended to prefix, got %d bytes (%v) instead", len(got), err)
+	}
+	if got, err := store.GetAppend("missing", buf); !errors.Is(err, ErrNotFound) || len(got) != 0 {
+		t.Errorf("Expected ErrNotFound and buf for missing key, got %d bytes (%v) instead", len(got), err)
+	}
+}
+
+func TestSunduk_GetIntoCorrupted(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"), Uncompressed())
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	if _, err := store.GetInto("key", make([]byte, 10)); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum for a corrupted value, got %v instead", err)
+	}
+}
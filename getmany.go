@@ -0,0 +1,129 @@
+package sunduk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+const (
+	// getManyMaxGap is the largest gap between chunks read at once by GetMany, reading a smaller gap
+	// is cheaper than seeking over it
+	getManyMaxGap = 64 << 10
+	// getManyMaxRead is the largest span of chunks read at once by GetMany
+	getManyMaxRead = 4 << 20
+)
+
+// GetMany returns the values of keys, keys without entry are left out of the map. Chunks are read in
+// a single sequential pass in file order, with nearby chunks read at once, which is much faster than
+// calling Get for each key on spinning disks. Values failing checksum verification are repaired like with Get
+func (store *Sunduk) GetMany(keys []string) (map[string][]byte, error) {
+	for _, k := range keys {
+		if err := store.authorize(k, OperationRead); err != nil {
+			return nil, err
+		}
+	}
+	atomic.AddUint64(&store.counters.gets, uint64(len(keys)))
+	values, err := store.getMany(keys, false)
+	if err != nil {
+		return nil, err
+	}
+	for k := range values {
+		store.access.record(k)
+	}
+	return values, nil
+}
+
+// Preload reads the values of keys and caches them, so that the first reads of critical values, such as assets
+// served right after startup, don't wait for the store file and decompression. Chunks are read in file order
+// like with GetMany, keys without entry are skipped. Preloaded values stay cached as long as they fit in the
+// cache, see WithCacheSize, and preloading doesn't count as reads in Metrics and Stat
+func (store *Sunduk) Preload(keys ...string) error {
+	if store.opts.cacheSize <= 0 {
+		return nil
+	}
+	_, err := store.getMany(keys, true)
+	return err
+}
+
+// PreloadPrefix preloads the values of the keys starting with prefix, see Preload
+func (store *Sunduk) PreloadPrefix(prefix string) error {
+	return store.Preload(store.Keys(Prefix(prefix))...)
+}
+
+// getMany reads the values of keys for GetMany, or caches the values of keys not cached yet for Preload
+func (store *Sunduk) getMany(keys []string, preload bool) (map[string][]byte, error) {
+	type request struct {
+		key string
+		e   entry
+	}
+	values := make(map[string][]byte)
+	requested := make(map[string]bool, len(keys))
+	var requests []request
+	store.mu.RLock()
+	for _, k := range keys {
+		value, ok := store.data[k]
+		if !ok {
+			value, ok = store.cache.get(k)
+		}
+		if store.expired(k) {
+			continue
+		}
+		if ok {
+			if !preload {
+				values[k] = store.own(value)
+				atomic.AddUint64(&store.counters.cacheHits, 1)
+			}
+		} else if e, ok := store.lookup(k); ok && !requested[k] {
+			requested[k] = true
+			requests = append(requests, request{key: k, e: e})
+		}
+	}
+	file := store.file.acquire()
+	store.mu.RUnlock()
+	defer file.release()
+	if !preload {
+		atomic.AddUint64(&store.counters.cacheMisses, uint64(len(requests)))
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].e.Offset < requests[j].e.Offset
+	})
+	for i := 0; i < len(requests); {
+		start, end := requests[i].e.Offset, requests[i].e.Offset+requests[i].e.Size
+		j := i + 1
+		for ; j < len(requests); j++ {
+			e := requests[j].e
+			if e.Offset-end > getManyMaxGap || e.Offset+e.Size-start > getManyMaxRead {
+				break
+			}
+			if e.Offset+e.Size > end {
+				end = e.Offset + e.Size
+			}
+		}
+		data, err := store.readChunk(file, entry{Offset: start, Size: end - start})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range requests[i:j] {
+			value, err := store.decodeValue(file, data[r.e.Offset-start:r.e.Offset-start+r.e.Size], r.e)
+			if errors.Is(err, ErrChecksum) {
+				var repaired bool
+				if value, repaired, err = store.fetch(file, r.key, r.e); repaired {
+					store.rewriteRepaired(r.key, r.e, value)
+				}
+			} else if err == nil {
+				store.cacheRead(r.key, r.e, value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("unable to read value for key %q: %w", r.key, err)
+			}
+			if !preload {
+				values[r.key] = value
+			}
+		}
+		i = j
+	}
+	return values, nil
+}
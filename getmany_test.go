@@ -0,0 +1,93 @@
+package sunduk
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSunduk_GetMany(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	values := make(map[string][]byte)
+	for i := 0; i < 100; i++ {
+		values[fmt.Sprintf("key-%03d", i)] = []byte(fmt.Sprintf("value of key %d", i))
+	}
+	_ = store.PutAll(values)
+	store.Close()
+
+	// Reopen the store, so that values are read from the file
+	store = New(TestStoreFile)
+	defer store.Close()
+	got, err := store.GetMany([]string{"key-099", "key-000", "missing", "key-050", "key-000"})
+	if err != nil {
+		t.Fatalf("Expected GetMany to succeed, got %v instead", err)
+	}
+	if len(got) != 3 {
+		t.Errorf("Expected 3 values, got %d instead", len(got))
+	}
+	for _, k := range []string{"key-000", "key-050", "key-099"} {
+		if string(got[k]) != string(values[k]) {
+			t.Errorf("Expected key %s to have value %s, got %s instead", k, values[k], got[k])
+		}
+	}
+	if _, ok := got["missing"]; ok {
+		t.Error("Expected missing key to be left out")
+	}
+
+	keys := store.Keys()
+	if got, err := store.GetMany(keys); err != nil || len(got) != len(values) {
+		t.Errorf("Expected %d values, got %d (%v) instead", len(values), len(got), err)
+	}
+}
+
+func TestSunduk_GetManyCorrupted(t *testing.T) {
+	mirror := New(TestMirrorFile)
+	defer deleteStoreFile(TestMirrorFile)
+	defer mirror.Close()
+	_ = mirror.Put("key", []byte("value"))
+
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"key": []byte("value"), "other": []byte("other value")})
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	store = New(TestStoreFile)
+	if _, err := store.GetMany([]string{"key", "other"}); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum for a corrupted entry, got %v instead", err)
+	}
+	store.Close()
+
+	store = New(TestStoreFile, WithRepairSource(mirror))
+	defer store.Close()
+	got, err := store.GetMany([]string{"key", "other"})
+	if err != nil || string(got["key"]) != "value" || string(got["other"]) != "other value" {
+		t.Errorf("Expected corrupted entry to be repaired, got %q (%v) instead", got, err)
+	}
+}
+
+func TestSunduk_Preload(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"assets/logo.png": []byte("logo"), "assets/app.js": []byte("app"), "data": []byte("data")})
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	if err := store.Preload("data", "missing"); err != nil {
+		t.Fatalf("Expected Preload to succeed, got %v instead", err)
+	}
+	if err := store.PreloadPrefix("assets/"); err != nil {
+		t.Fatalf("Expected PreloadPrefix to succeed, got %v instead", err)
+	}
+	if m := store.Metrics(); m.Gets != 0 || m.CacheMisses != 0 {
+		t.Errorf("Expected preloading not to count as reads, got %d gets and %d misses instead", m.Gets, m.CacheMisses)
+	}
+	checkValueForKey(t, store, "assets/logo.png", []byte("logo"))
+	checkValueForKey(t, store, "assets/app.js", []byte("app"))
+	checkValueForKey(t, store, "data", []byte("data"))
+	if m := store.Metrics(); m.CacheHits != 3 || m.CacheMisses != 0 {
+		t.Errorf("Expected preloaded values to be read from the cache, got %d hits and %d misses instead", m.CacheHits, m.CacheMisses)
+	}
+}
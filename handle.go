@@ -0,0 +1,35 @@
+package sunduk
+
+import (
+	"sunduk/internal/vfs"
+	"sync/atomic"
+)
+
+// handle is a store file shared by the store and its readers. The file is closed once the store has dropped it
+// and every reader has released it, so a compaction replacing the file never closes it in the middle of a read
+type handle struct {
+	vfs.File
+	dict *dictionary // dict is the compression dictionary of the file
+	hash uint64      // hash is the hash of the digests of the entries of the file, see WithHash
+	refs int32
+}
+
+func newHandle(file vfs.File, dict *dictionary) *handle {
+	return &handle{File: file, dict: dict, refs: 1}
+}
+
+// acquire takes a reference to the handle. It must be called while the handle is referenced by the store
+func (h *handle) acquire() *handle {
+	if h != nil {
+		atomic.AddInt32(&h.refs, 1)
+	}
+	return h
+}
+
+// release drops a reference to the handle, closing the file with the last one. It returns the error closing the file
+func (h *handle) release() error {
+	if h != nil && atomic.AddInt32(&h.refs, -1) == 0 {
+		return h.File.Close()
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package sunduk
+
+import (
+	"fmt"
+	"sunduk/internal/format"
+)
+
+// Hash is the hash of the digests verifying values on top of their checksums, see WithHash
+type Hash int
+
+const (
+	// HashNone verifies values by their CRC-32C checksum only, which catches damaged files but not tampering
+	HashNone Hash = format.HashNone
+	// HashXXH64 verifies values by their 64-bit xxHash too, fast but not collision resistant
+	HashXXH64 Hash = format.HashXXH64
+	// HashSHA256 verifies values by their SHA-256 digest too, for deployments requiring a cryptographic hash
+	HashSHA256 Hash = format.HashSHA256
+)
+
+// String returns the name of the hash
+func (h Hash) String() string {
+	switch h {
+	case HashNone:
+		return "none"
+	case HashXXH64:
+		return "xxh64"
+	case HashSHA256:
+		return "sha256"
+	default:
+		return fmt.Sprintf("unknown hash %d", int(h))
+	}
+}
+
+// WithHash records the digest of every value written with h in the index, and verifies values read against their
+// digests as well as their checksums, HashNone by default. The hash is recorded in the store file: opening a store
+// file recording another hash fails with ErrHash, and store files recording a hash are opened with it without
+// WithHash. Values written before the store file recorded a hash get their digest once compaction rewrites them.
+// Appended values are written in full, as digests can't be extended like checksums, and values streamed by
+// Value.Reader and read from lookup tables, see WithLazyIndex, are verified by their checksum only
+func WithHash(h Hash) Option {
+	return func(o *options) {
+		o.hash = h
+	}
+}
+
+// useHash sets the hash of the store from the hash recorded by the store file. It fails with ErrHash if it isn't
+// the hash of the store, or the file records an unknown hash
+func (store *Sunduk) useHash(hash uint64) error {
+	switch {
+	case hash == format.HashNone:
+		// Files recording no hash record the hash of the store on the next write
+		hash = store.enc.hash
+	case format.DigestSize(hash) == 0:
+		return fmt.Errorf("%w: %v", ErrHash, Hash(hash))
+	case store.enc.hash != format.HashNone && store.enc.hash != hash:
+		return fmt.Errorf("%w: %v instead of %v", ErrHash, Hash(hash), Hash(store.enc.hash))
+	}
+	store.enc.hash = hash
+	store.file.hash = hash
+	return nil
+}
+
+// checkDigest returns true if value matches digest with the hash of the file, or if there is no digest
+func (h *handle) checkDigest(value []byte, digest string) bool {
+	return digest == "" || string(format.Digest(h.hash, value)) == digest
+}
@@ -0,0 +1,55 @@
+package sunduk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSunduk_WithHash(t *testing.T) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	store := New(TestStoreFile)
+	_ = store.Put("old", []byte("old value"))
+	store.Close()
+
+	store, err := Open(TestStoreFile, WithHash(HashSHA256))
+	if err != nil {
+		t.Fatalf("Expected store file without hash to open with SHA-256, got %v instead", err)
+	}
+	_ = store.Put("new", []byte("new value"))
+	_ = store.Append("new", []byte(" appended"))
+	if store.index["old"].Digest != "" || len(store.index["new"].Digest) != 32 {
+		t.Errorf("Expected only new values to have digests")
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.index["old"].Digest) != 32 {
+		t.Errorf("Expected compaction to digest old values")
+	}
+	store.Close()
+
+	if _, err := Open(TestStoreFile, WithHash(HashXXH64)); !errors.Is(err, ErrHash) {
+		t.Errorf("Expected ErrHash for another hash, got %v instead", err)
+	}
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkValueForKey(t, store, "new", []byte("new value appended"))
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected values to match their digests, got %v instead", err)
+	}
+
+	// Values matching their checksum but not their digest fail verification
+	store.mu.Lock()
+	e := store.index["old"]
+	e.Digest = string(make([]byte, 32))
+	store.index = map[string]entry{"old": e}
+	store.cache.clear()
+	store.mu.Unlock()
+	if _, ok := store.Get("old"); ok {
+		t.Error("Expected value not matching its digest to be rejected")
+	}
+	if err := store.Verify(); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum, got %v instead", err)
+	}
+}
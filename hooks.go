@@ -0,0 +1,153 @@
+package sunduk
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Hooks are callbacks called on changes of the store, e.g. for auditing, cache invalidation or replication.
+// They are called with writers blocked, in the order of changes, so they must not write to the store.
+// Any of them may be nil
+type Hooks struct {
+	// OnBeforePut is called for every value before it is put by Put or PutAll. An error aborts the whole put
+	// and is returned to the caller
+	OnBeforePut func(key string, value []byte) error
+	// OnAfterPut is called for every value once it is put
+	OnAfterPut func(key string, value []byte)
+	// OnDelete is called once a key is deleted
+	OnDelete func(key string)
+	// OnRename is called once the value of oldKey is moved to newKey by Rename
+	OnRename func(oldKey, newKey string)
+	// OnCopy is called once the value of srcKey is copied to dstKey by Copy
+	OnCopy func(srcKey, dstKey string)
+	// OnAppend is called once data is appended to the value of key by Append without rewriting the value
+	OnAppend func(key string, data []byte)
+	// OnEvict is called once the entry of key is evicted by compaction, see TTL and WithSizeCap
+	OnEvict func(key string)
+	// OnExpire is called once the value of key expires, or ahead of it with WithExpiryNotice, see TTL. Unlike
+	// other hooks it is called by a background goroutine without blocking writers, so it may write to the store,
+	// such as to refresh the value. It is called once for every expiry time of a key, and for the values which
+	// expired before the store was opened
+	OnExpire func(key string)
+	// OnFlush is called once changes are written to the store file
+	OnFlush func(FlushInfo)
+}
+
+// FlushInfo describes changes written to the store file
+type FlushInfo struct {
+	Puts     int // Puts is the count of values put, copies included
+	Deletes  int // Deletes is the count of keys deleted
+	Renames  int // Renames is the count of keys renamed
+	Duration time.Duration
+}
+
+// WithHooks registers hooks called on changes of the store. Hooks registered by several options are
+// called in the order of options
+func WithHooks(h Hooks) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, h)
+	}
+}
+
+// write commits values and deleted keys, or stages them with WithWriteBuffer, and calls hooks around the commit.
+// It must be called with writeMu held
+func (store *Sunduk) write(values map[string][]byte, deleted []string, po putOptions) error {
+	_, err := store.apply(values, deleted, po)
+	return err
+}
+
+// apply writes values and deleted keys like write, and returns true if they were applied, committed or staged,
+// even if writing the write buffer failed afterwards
+func (store *Sunduk) apply(values map[string][]byte, deleted []string, po putOptions) (bool, error) {
+	if err := store.writable(); err != nil {
+		return false, err
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, h := range store.opts.hooks {
+		if h.OnBeforePut == nil {
+			continue
+		}
+		for _, k := range keys {
+			if err := h.OnBeforePut(k, values[k]); err != nil {
+				return false, fmt.Errorf("put of key %q rejected: %w", k, err)
+			}
+		}
+	}
+
+	buffered := store.opts.writeBuffer > 0
+	var info FlushInfo
+	if buffered {
+		if err := store.admit(values, deleted, po); err != nil {
+			return false, err
+		}
+		store.stage(values, deleted, po)
+	} else {
+		var err error
+		if info, err = store.flush(values, deleted, po); err != nil {
+			return false, err
+		}
+	}
+	atomic.AddUint64(&store.counters.puts, uint64(len(values)))
+	atomic.AddUint64(&store.counters.deletes, uint64(len(deleted)))
+
+	for _, h := range store.opts.hooks {
+		if h.OnAfterPut != nil {
+			for _, k := range keys {
+				h.OnAfterPut(k, values[k])
+			}
+		}
+		if h.OnDelete != nil {
+			for _, k := range deleted {
+				h.OnDelete(k)
+			}
+		}
+	}
+
+	// The put filling the buffer writes it, so writers wait for pending writes to be written
+	if buffered {
+		if store.pending.size < store.opts.writeBuffer {
+			return true, nil
+		}
+		return true, store.flushPending()
+	}
+	store.notifyFlush(info)
+	return true, nil
+}
+
+// flush commits values, deleted keys and pending writes, and returns what was written. It must be called with writeMu held
+func (store *Sunduk) flush(values map[string][]byte, deleted []string, po putOptions) (FlushInfo, error) {
+	info := FlushInfo{Puts: len(store.data) + len(values) + len(po.chunks), Deletes: len(store.pending.deleted) + len(deleted) - len(po.renames), Renames: len(po.renames)}
+	if err := store.admit(values, deleted, po); err != nil {
+		return info, err
+	}
+	start := time.Now()
+	if err := store.commit(values, deleted, po); err != nil {
+		return info, err
+	}
+	info.Duration = time.Since(start)
+	return info, nil
+}
+
+// admit returns an error if the authorizer denies writing values, deleting deleted keys, or writing the chunks and
+// the renames of po, or if they exceed a quota. It must be called with writeMu held
+func (store *Sunduk) admit(values map[string][]byte, deleted []string, po putOptions) error {
+	if err := store.authorizeWrite(values, deleted, po); err != nil {
+		return err
+	}
+	return store.checkQuotas(values, deleted, po)
+}
+
+// notifyFlush calls OnFlush hooks
+func (store *Sunduk) notifyFlush(info FlushInfo) {
+	for _, h := range store.opts.hooks {
+		if h.OnFlush != nil {
+			h.OnFlush(info)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package sunduk
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSunduk_Hooks(t *testing.T) {
+	var events []string
+	hooks := Hooks{
+		OnBeforePut: func(key string, value []byte) error {
+			if key == "forbidden" {
+				return errors.New("forbidden key")
+			}
+			events = append(events, "before "+key)
+			return nil
+		},
+		OnAfterPut: func(key string, value []byte) {
+			events = append(events, "after "+key+"="+string(value))
+		},
+		OnDelete: func(key string) {
+			events = append(events, "delete "+key)
+		},
+		OnFlush: func(info FlushInfo) {
+			if info.Duration <= 0 {
+				t.Error("Expected the duration of the flush to be set")
+			}
+			events = append(events, "flush")
+		},
+	}
+	store := New(TestStoreFile, WithHooks(hooks))
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"b": []byte("2"), "a": []byte("1")})
+	_ = store.Delete("a")
+	_ = store.Delete("missing")
+	if err := store.PutAll(map[string][]byte{"c": []byte("3"), "forbidden": nil}); err == nil {
+		t.Error("Expected an error of OnBeforePut to abort the put")
+	}
+	checkKeyNotExists(t, store, "c")
+	store.Close()
+
+	want := []string{"before a", "before b", "after a=1", "after b=2", "flush", "delete a", "flush", "before c"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Expected events %v, got %v instead", want, events)
+	}
+}
@@ -0,0 +1,121 @@
+package sunduk
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sunduk/internal/format"
+)
+
+// hasIndexFile returns true if the index of the store file is in an index file
+func (store *Sunduk) hasIndexFile() bool {
+	return store.fileID != format.FileID{}
+}
+
+// writeFilePreamble writes the preamble of a new store file, followed by a new file ID with WithIndexFile.
+// It returns the ID, zero for files holding their index
+func (store *Sunduk) writeFilePreamble(w io.Writer) (format.FileID, error) {
+	if !store.opts.indexFile {
+		return format.FileID{}, writePreamble(w)
+	}
+	id, err := store.newFileID()
+	if err != nil {
+		return id, err
+	}
+	return id, format.WriteDataPreamble(w, id)
+}
+
+// readIndexFile reads the index of the store file with id, of size bytes, from its index file. An index file
+// left next to it by a compaction interrupted before putting it in place is recovered if it holds the index
+// of the store file
+func (store *Sunduk) readIndexFile(id format.FileID, size int64) error {
+	path := store.indexPath()
+	index, info, err := store.loadIndexFile(path, id, size)
+	if err != nil {
+		var nerr error
+		if index, info, nerr = store.loadIndexFile(path+".new", id, size); nerr != nil {
+			return err
+		}
+		if !store.opts.readOnly {
+			if info, err = store.replaceIndexFile(); err != nil {
+				return fmt.Errorf("unable to recover index file %s: %v", path, err)
+			}
+			store.opts.logger.Warn("recovered index file", "file", path)
+		}
+	}
+	if err := store.useIndex(index); err != nil {
+		return err
+	}
+	store.indexInfo = info
+	store.size = index.Data.Size
+	store.tail = 0
+	return nil
+}
+
+// loadIndexFile reads the index file at path, checking that it holds the index of the store file with id, of size bytes
+func (store *Sunduk) loadIndexFile(path string, id format.FileID, size int64) (format.Index, os.FileInfo, error) {
+	file, err := fsys.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return format.Index{}, nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return format.Index{}, nil, fmt.Errorf("unable to stat index file: %v", err)
+	}
+	index, err := store.opts.indexLimits.ReadIndexFile(file, info.Size())
+	if err != nil {
+		return format.Index{}, nil, err
+	}
+	if index.Data.ID != id || index.Data.Size > size {
+		return format.Index{}, nil, fmt.Errorf("%w: %s isn't the index of %s", ErrCorruptHeader, path, store.FilePath)
+	}
+	return index, info, nil
+}
+
+// writeIndexFile writes the entries of index with the sections of idx to a new index file next to the index file,
+// synced if the durability level requires it, which replaceIndexFile puts in place of the index file
+func (store *Sunduk) writeIndexFile(enc encoder, keys OrderedKeys, index map[string]entry, idx format.Index) (int64, error) {
+	path := store.indexPath() + ".new"
+	file, err := store.createFile(path, store.FilePath)
+	if err != nil {
+		return 0, err
+	}
+	w := &offsetWriter{file: file}
+	err = writePreamble(w)
+	if err == nil {
+		idx.Entries = indexEntries(keys, index)
+		idx.Offset = w.offset
+		err = format.WriteIndex(w, idx, enc.windowBits)
+	}
+	if err == nil {
+		err = store.syncData(file)
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = fsys.Remove(path)
+		return 0, fmt.Errorf("unable to write index file %s: %v", path, err)
+	}
+	return w.offset, nil
+}
+
+// replaceIndexFile puts the new index file written by writeIndexFile in place of the index file. It returns
+// the info of the index file, nil if it can't be stat'ed once it is in place
+func (store *Sunduk) replaceIndexFile() (os.FileInfo, error) {
+	path := store.indexPath()
+	if err := fsys.Rename(path+".new", path); err != nil {
+		return nil, err
+	}
+	info, _ := os.Stat(path)
+	return info, nil
+}
+
+// removeIndexFile removes the index file left by a store file replaced by a file holding its index
+func (store *Sunduk) removeIndexFile() {
+	if err := fsys.Remove(store.indexPath()); err != nil && !os.IsNotExist(err) {
+		// The index file isn't read along with a store file holding its index, it only takes space
+		store.opts.logger.Warn("unable to remove index file", "file", store.indexPath(), "err", err)
+	}
+}
@@ -0,0 +1,118 @@
+package sunduk
+
+import (
+	"bytes"
+	"os"
+	"sunduk/internal/format"
+	"testing"
+)
+
+func TestSunduk_IndexFile(t *testing.T) {
+	store := New(TestStoreFile, WithIndexFile())
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": []byte("first value"), "b": []byte("second value")})
+	if !store.DebugInfo().Index.IndexFile {
+		t.Fatal("Expected store file with an index file")
+	}
+	info, _ := os.Stat(TestStoreFile)
+	size := info.Size()
+	value := bytes.Repeat([]byte("third value "), 100)
+	_ = store.Put("c", value)
+	info, _ = os.Stat(TestStoreFile)
+	if n := info.Size() - size; n > 200 {
+		t.Errorf("Expected only the value to be appended to the store file, got %d bytes appended instead", n)
+	}
+	_ = store.Delete("a")
+	store.Close()
+
+	file, _ := os.Open(TestStoreFile)
+	info, _ = file.Stat()
+	if _, err := format.ReadIndex(file, info.Size()); err == nil {
+		t.Error("Expected store file without an index of its own")
+	}
+	file.Close()
+
+	// Store files with an index file are read without the option
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "b", []byte("second value"))
+	checkValueForKey(t, store, "c", value)
+	if _, ok := store.Get("a"); ok {
+		t.Error("Expected deleted key to be missing")
+	}
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected store with an index file to verify, got %v instead", err)
+	}
+	_ = store.Put("d", []byte("fourth value"))
+	if !store.DebugInfo().Index.IndexFile {
+		t.Error("Expected commits to keep the index file")
+	}
+
+	// Compaction without the option moves the index back into the store file
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "d", []byte("fourth value"))
+	if store.DebugInfo().Index.IndexFile {
+		t.Error("Expected compaction without the option to write the index into the store file")
+	}
+	if _, err := os.Stat(TestStoreFile + ".idx"); !os.IsNotExist(err) {
+		t.Errorf("Expected index file to be removed, got %v instead", err)
+	}
+	store.Close()
+}
+
+func TestSunduk_IndexFileCompaction(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": []byte("first value"), "b": []byte("second value")})
+	store.Close()
+
+	store = New(TestStoreFile, WithIndexFile())
+	_ = store.Put("a", []byte("new value"))
+	if store.DebugInfo().Index.IndexFile {
+		t.Error("Expected existing store file to keep its index until compaction")
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	info := store.DebugInfo().Index
+	if !info.IndexFile || info.DeadBytes != 0 {
+		t.Errorf("Expected compacted store file with an index file and no dead bytes, got %+v instead", info)
+	}
+	_ = store.Put("c", []byte("third value"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkValueForKey(t, store, "a", []byte("new value"))
+	checkValueForKey(t, store, "b", []byte("second value"))
+	checkValueForKey(t, store, "c", []byte("third value"))
+}
+
+func TestSunduk_IndexFileRecovery(t *testing.T) {
+	store := New(TestStoreFile, WithIndexFile())
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+	stale, _ := os.ReadFile(TestStoreFile + ".idx")
+
+	// A compaction interrupted after the store file is replaced leaves the new index file next to the stale one
+	store = New(TestStoreFile, WithIndexFile())
+	_ = store.Put("other", []byte("other value"))
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	if err := os.Rename(TestStoreFile+".idx", TestStoreFile+".idx.new"); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(TestStoreFile+".idx", stale, 0666)
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkValueForKey(t, store, "key", []byte("value"))
+	checkValueForKey(t, store, "other", []byte("other value"))
+	if _, err := os.Stat(TestStoreFile + ".idx.new"); !os.IsNotExist(err) {
+		t.Errorf("Expected new index file to be put in place, got %v instead", err)
+	}
+}
@@ -0,0 +1,81 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Access holds the access statistics of a key, counted by the store
+type Access struct {
+	Key   string
+	Reads uint64 // Reads is the count of reads of the key
+	Last  int64  // Last is the time of the last read in unix nanoseconds
+
+	Written int64 // Written is the time the value was last written in unix nanoseconds, 0 if unknown
+}
+
+// encodeAccess marshals the access statistics section, stats must be in bytewise ascending key order. Write times
+// follow the statistics of every key, if any is known, so that readers unaware of them ignore them
+func encodeAccess(stats []Access) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(stats)))])
+	for _, a := range stats {
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(a.Key)))])
+		buf.WriteString(a.Key)
+		buf.Write(vb[:binary.PutUvarint(vb[:], a.Reads)])
+		buf.Write(vb[:binary.PutVarint(vb[:], a.Last)])
+	}
+	for _, a := range stats {
+		if a.Written != 0 {
+			for _, a := range stats {
+				buf.Write(vb[:binary.PutVarint(vb[:], a.Written)])
+			}
+			break
+		}
+	}
+	return buf.Bytes()
+}
+
+// decodeAccess unmarshals the access statistics section
+func decodeAccess(section []byte) ([]Access, error) {
+	r := bytes.NewReader(section)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	// Every key takes at least three bytes for its length, its count of reads and its last read time
+	if count > uint64(r.Len())/3 {
+		return nil, fmt.Errorf("invalid count of access statistics %d", count)
+	}
+	stats := make([]Access, count)
+	for i := range stats {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		key := make([]byte, n)
+		_, _ = r.Read(key)
+		stats[i].Key = string(key)
+		if stats[i].Reads, err = binary.ReadUvarint(r); err != nil {
+			return nil, err
+		}
+		if stats[i].Last, err = binary.ReadVarint(r); err != nil {
+			return nil, err
+		}
+	}
+	if r.Len() == 0 {
+		return stats, nil
+	}
+	for i := range stats {
+		if stats[i].Written, err = binary.ReadVarint(r); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
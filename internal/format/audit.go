@@ -0,0 +1,153 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Audit records flags
+const (
+	// AuditOld flags audit records of keys which had a value before the mutation, with its checksum
+	AuditOld = 1 << iota
+	// AuditNew flags audit records of keys which have a value after the mutation, with its checksum
+	AuditNew
+)
+
+// AuditRecord records a mutation of the value of a key, see EncodeAudit
+type AuditRecord struct {
+	Time       int64  // Time is the time of the mutation in unix nanoseconds
+	Generation uint64 // Generation is the generation of the store once the mutation was applied
+	Actor      string
+	Key        string
+	Flags      uint64 // Flags tell which checksums the record holds, AuditOld and AuditNew
+	OldSum     uint32 // OldSum is the checksum of the value before the mutation
+	NewSum     uint32 // NewSum is the checksum of the value after the mutation
+}
+
+// EncodeAudit marshals audit records for an audit chunk:
+//
+//	uvarint count of records
+//	varint time | uvarint generation | uvarint actor length | actor | uvarint key length | key | uvarint flags
+//	uint32 old checksum | uint32 new checksum
+//	...
+func EncodeAudit(records []AuditRecord) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(records)))])
+	for _, a := range records {
+		buf.Write(vb[:binary.PutVarint(vb[:], a.Time)])
+		buf.Write(vb[:binary.PutUvarint(vb[:], a.Generation)])
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(a.Actor)))])
+		buf.WriteString(a.Actor)
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(a.Key)))])
+		buf.WriteString(a.Key)
+		buf.Write(vb[:binary.PutUvarint(vb[:], a.Flags)])
+		binary.LittleEndian.PutUint32(vb[:], a.OldSum)
+		binary.LittleEndian.PutUint32(vb[4:], a.NewSum)
+		buf.Write(vb[:8])
+	}
+	return buf.Bytes()
+}
+
+// DecodeAudit unmarshals the audit records of an audit chunk
+func DecodeAudit(data []byte) ([]AuditRecord, error) {
+	r := bytes.NewReader(data)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	// Every record takes at least thirteen bytes
+	if count > uint64(r.Len())/13 {
+		return nil, fmt.Errorf("invalid count of audit records %d", count)
+	}
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return "", err
+		}
+		if n > uint64(r.Len()) {
+			return "", io.ErrUnexpectedEOF
+		}
+		b := make([]byte, n)
+		_, _ = r.Read(b)
+		return string(b), nil
+	}
+	records := make([]AuditRecord, count)
+	for i := range records {
+		a := &records[i]
+		if a.Time, err = binary.ReadVarint(r); err != nil {
+			return nil, err
+		}
+		if a.Generation, err = binary.ReadUvarint(r); err != nil {
+			return nil, err
+		}
+		if a.Actor, err = readString(); err != nil {
+			return nil, err
+		}
+		if a.Key, err = readString(); err != nil {
+			return nil, err
+		}
+		if a.Flags, err = binary.ReadUvarint(r); err != nil {
+			return nil, err
+		}
+		var sb [8]byte
+		if _, err := io.ReadFull(r, sb[:]); err != nil {
+			return nil, err
+		}
+		a.OldSum, a.NewSum = binary.LittleEndian.Uint32(sb[:]), binary.LittleEndian.Uint32(sb[4:])
+	}
+	return records, nil
+}
+
+// encodeAuditChunks marshals the audit section
+func encodeAuditChunks(chunks []Base) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(chunks)))])
+	for _, c := range chunks {
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(c.Offset))])
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(c.Size))])
+		buf.Write(vb[:binary.PutUvarint(vb[:], c.Flags)])
+		binary.LittleEndian.PutUint32(vb[:], c.Sum)
+		buf.Write(vb[:4])
+	}
+	return buf.Bytes()
+}
+
+// decodeAuditChunks unmarshals the audit section, checking that audit chunks lie inside [PreambleSize, end)
+func decodeAuditChunks(section []byte, end int64) ([]Base, error) {
+	r := bytes.NewReader(section)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	// Every chunk takes at least seven bytes
+	if count > uint64(r.Len())/7 {
+		return nil, fmt.Errorf("invalid count of audit chunks %d", count)
+	}
+	chunks := make([]Base, count)
+	for i := range chunks {
+		var fields [3]uint64
+		for j := range fields {
+			if fields[j], err = binary.ReadUvarint(r); err != nil {
+				return nil, err
+			}
+		}
+		var sb [4]byte
+		if _, err := io.ReadFull(r, sb[:]); err != nil {
+			return nil, err
+		}
+		c := Base{Offset: int64(fields[0]), Size: int64(fields[1]), Flags: fields[2], Sum: binary.LittleEndian.Uint32(sb[:])}
+		if !inBounds(c.Offset, c.Size, end) {
+			return nil, errors.New("audit chunk is out of data bounds")
+		}
+		if c.Flags&^FlagRaw != 0 {
+			return nil, fmt.Errorf("invalid flags %#x of audit chunk", c.Flags)
+		}
+		chunks[i] = c
+	}
+	return chunks, nil
+}
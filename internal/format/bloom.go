@@ -0,0 +1,92 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+)
+
+// maxBloomHashes is the maximum count of hash functions of a bloom filter
+const maxBloomHashes = 30
+
+// Bloom is a bloom filter of the keys of a file, it is empty if the file has none. It holds bits set
+// by Hashes hash functions of every key, derived from the 64-bit FNV-1a hash of the key by double hashing
+type Bloom struct {
+	Hashes int
+	Bits   []byte
+}
+
+// NewBloom returns a bloom filter of keys with bitsPerKey bits for every key, which false positive rate
+// is about 0.6185^bitsPerKey
+func NewBloom(keys []string, bitsPerKey int) Bloom {
+	if len(keys) == 0 || bitsPerKey <= 0 {
+		return Bloom{}
+	}
+	hashes := int(math.Round(float64(bitsPerKey) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	} else if hashes > maxBloomHashes {
+		hashes = maxBloomHashes
+	}
+	b := Bloom{Hashes: hashes, Bits: make([]byte, (len(keys)*bitsPerKey+7)/8)}
+	for _, k := range keys {
+		b.probe(k, func(bit uint64) bool {
+			b.Bits[bit/8] |= 1 << (bit % 8)
+			return true
+		})
+	}
+	return b
+}
+
+// Empty returns true if the filter holds no keys
+func (b Bloom) Empty() bool {
+	return len(b.Bits) == 0
+}
+
+// MayContain returns false if key certainly isn't among the keys of the filter. It returns true for an empty filter
+func (b Bloom) MayContain(key string) bool {
+	if b.Empty() {
+		return true
+	}
+	return b.probe(key, func(bit uint64) bool {
+		return b.Bits[bit/8]&(1<<(bit%8)) != 0
+	})
+}
+
+// probe calls fn for the bits of key until fn returns false, and returns false if it does
+func (b Bloom) probe(key string, fn func(bit uint64) bool) bool {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+	delta := sum>>33 | sum<<31
+	n := uint64(len(b.Bits)) * 8
+	for i := 0; i < b.Hashes; i++ {
+		if !fn(sum % n) {
+			return false
+		}
+		sum += delta
+	}
+	return true
+}
+
+// encodeBloom marshals the bloom filter section
+func encodeBloom(b Bloom) []byte {
+	var vb [binary.MaxVarintLen64]byte
+	data := append([]byte(nil), vb[:binary.PutUvarint(vb[:], uint64(b.Hashes))]...)
+	return append(data, b.Bits...)
+}
+
+// decodeBloom unmarshals the bloom filter section
+func decodeBloom(section []byte) (Bloom, error) {
+	r := bytes.NewReader(section)
+	hashes, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Bloom{}, err
+	}
+	if hashes < 1 || hashes > maxBloomHashes || r.Len() == 0 {
+		return Bloom{}, errors.New("invalid bloom filter")
+	}
+	return Bloom{Hashes: int(hashes), Bits: section[len(section)-r.Len():]}, nil
+}
@@ -0,0 +1,53 @@
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestBloom(t *testing.T) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	b := NewBloom(keys, 10)
+	for _, k := range keys {
+		if !b.MayContain(k) {
+			t.Fatalf("Expected bloom filter to contain %s", k)
+		}
+	}
+	positives := 0
+	for i := 0; i < 10000; i++ {
+		if b.MayContain(fmt.Sprintf("absent%d", i)) {
+			positives++
+		}
+	}
+	if positives > 300 {
+		t.Errorf("Expected about 1%% false positives, got %d of 10000 instead", positives)
+	}
+	if !(Bloom{}).MayContain("key") {
+		t.Error("Expected empty bloom filter to contain every key")
+	}
+}
+
+func TestDecodeIndex_Bloom(t *testing.T) {
+	b := NewBloom([]string{"a", "b"}, 10)
+	index, err := DecodeIndex(EncodeIndex(Index{Bloom: b}), PreambleSize, Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index.Bloom, b) {
+		t.Errorf("Expected bloom filter %+v, got %+v instead", b, index.Bloom)
+	}
+	if index, err := DecodeIndex(EncodeIndex(Index{}), PreambleSize, Version); err != nil || !index.Bloom.Empty() {
+		t.Errorf("Expected no bloom filter without bloom filter section, got %+v (%v) instead", index.Bloom, err)
+	}
+	data := append(append(uvarint(0), uvarint(0)...), uvarint(1)...)
+	data = append(data, uvarint(sectionBloom)...)
+	data = append(data, uvarint(2)...)
+	data = append(data, 0, 0xff)
+	if _, err := DecodeIndex(data, PreambleSize, Version); err == nil {
+		t.Error("Expected bloom filter without hash functions to be rejected")
+	}
+}
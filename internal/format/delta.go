@@ -0,0 +1,135 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// deltaBlockSize is the length of the blocks of the base matched in the target by Diff
+const deltaBlockSize = 32
+
+// deltaPrime is the multiplier of the rolling hash of blocks
+const deltaPrime = 16777619
+
+// Diff returns the instructions rebuilding target from base, as a sequence of
+//
+//	uvarint length << 1 | 0 | bytes        insert bytes
+//	uvarint length << 1 | 1 | uvarint offset   copy length bytes of base at offset
+//
+// Blocks of the base are found in the target by a rolling hash and extended both ways, so content moved
+// or shifted between versions, as in successive builds of a binary, is copied rather than inserted
+func Diff(base, target []byte) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) {
+		buf.Write(vb[:binary.PutUvarint(vb[:], v)])
+	}
+	insert := func(data []byte) {
+		if len(data) > 0 {
+			putUvarint(uint64(len(data)) << 1)
+			buf.Write(data)
+		}
+	}
+
+	if len(base) < deltaBlockSize || len(target) < deltaBlockSize {
+		insert(target)
+		return buf.Bytes()
+	}
+	blocks := make(map[uint32]int, len(base)/deltaBlockSize)
+	for i := 0; i+deltaBlockSize <= len(base); i += deltaBlockSize {
+		h := blockHash(base[i : i+deltaBlockSize])
+		if _, ok := blocks[h]; !ok {
+			blocks[h] = i
+		}
+	}
+	// pow is deltaPrime to the power of the block size minus one, to roll the first byte out of the hash
+	pow := uint32(1)
+	for i := 1; i < deltaBlockSize; i++ {
+		pow *= deltaPrime
+	}
+
+	lit := 0
+	i := 0
+	h := blockHash(target[:deltaBlockSize])
+	for i+deltaBlockSize <= len(target) {
+		if j, ok := blocks[h]; ok && bytes.Equal(base[j:j+deltaBlockSize], target[i:i+deltaBlockSize]) {
+			start, from, n := i, j, deltaBlockSize
+			for from+n < len(base) && start+n < len(target) && base[from+n] == target[start+n] {
+				n++
+			}
+			for start > lit && from > 0 && base[from-1] == target[start-1] {
+				start, from, n = start-1, from-1, n+1
+			}
+			insert(target[lit:start])
+			putUvarint(uint64(n)<<1 | 1)
+			putUvarint(uint64(from))
+			i, lit = start+n, start+n
+			if i+deltaBlockSize <= len(target) {
+				h = blockHash(target[i : i+deltaBlockSize])
+			}
+			continue
+		}
+		if i+deltaBlockSize < len(target) {
+			h = (h-uint32(target[i])*pow)*deltaPrime + uint32(target[i+deltaBlockSize])
+		}
+		i++
+	}
+	insert(target[lit:])
+	return buf.Bytes()
+}
+
+// blockHash returns the rolling hash of a block
+func blockHash(block []byte) uint32 {
+	var h uint32
+	for _, b := range block {
+		h = h*deltaPrime + uint32(b)
+	}
+	return h
+}
+
+// Patch rebuilds the target of a delta made by Diff from base
+func Patch(base, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+	var target []byte
+	for r.Len() > 0 {
+		op, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		n := op >> 1
+		if op&1 == 0 {
+			if n > uint64(r.Len()) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			start := len(target)
+			target = append(target, make([]byte, n)...)
+			_, _ = r.Read(target[start:])
+			continue
+		}
+		offset, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if offset > uint64(len(base)) || n > uint64(len(base))-offset {
+			return nil, errors.New("delta copies out of base bounds")
+		}
+		target = append(target, base[offset:offset+n]...)
+	}
+	return target, nil
+}
+
+// CompressDelta returns the chunk of a delta rebuilding target from base, compressed with brotli with a window of windowBits
+func CompressDelta(base, target []byte, windowBits int) ([]byte, error) {
+	return Compress(Diff(base, target), windowBits)
+}
+
+// DecodeDelta returns the value held by a chunk flagged with FlagDelta, base is the value of the base chunk
+func DecodeDelta(data, base []byte) ([]byte, error) {
+	delta, err := Decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	return Patch(base, delta)
+}
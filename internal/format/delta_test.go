@@ -0,0 +1,97 @@
+package format
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestDiff_Patch(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	base := make([]byte, 64<<10)
+	rnd.Read(base)
+	// A new build: a few patched bytes, an inserted section and a removed one
+	target := append([]byte(nil), base[:1000]...)
+	target = append(target, []byte("inserted section")...)
+	target = append(target, base[1000:30000]...)
+	target = append(target, base[31000:]...)
+	target[5000] ^= 0xff
+	target[50000] ^= 0xff
+
+	cases := map[string][2][]byte{
+		"similar":     {base, target},
+		"equal":       {base, base},
+		"empty base":  {nil, target},
+		"empty value": {base, nil},
+		"short":       {[]byte("short"), []byte("shorter")},
+		"unrelated":   {base[:1000], []byte(string(bytes.Repeat([]byte("x"), 5000)))},
+	}
+	for name, c := range cases {
+		delta := Diff(c[0], c[1])
+		value, err := Patch(c[0], delta)
+		if err != nil || !bytes.Equal(value, c[1]) {
+			t.Errorf("%s: Expected Patch to rebuild the target, got %v instead", name, err)
+		}
+	}
+	if delta := Diff(base, target); len(delta) > 1000 {
+		t.Errorf("Expected small delta between similar values, got %d bytes instead", len(delta))
+	}
+}
+
+func TestPatch_OutOfBounds(t *testing.T) {
+	delta := Diff([]byte("0123456789abcdef0123456789abcdef"), []byte("0123456789abcdef0123456789abcdef!"))
+	if _, err := Patch([]byte("0123"), delta); err == nil {
+		t.Error("Expected Patch to reject copies out of base bounds")
+	}
+	if _, err := Patch(nil, []byte{0x10, 'a'}); err == nil {
+		t.Error("Expected Patch to reject truncated inserts")
+	}
+}
+
+func TestDecodeIndex_Base(t *testing.T) {
+	base := Base{Offset: PreambleSize, Size: 10, Flags: FlagRaw, Sum: 42}
+	entries := []Entry{
+		{Key: "a", Offset: PreambleSize, Size: 10, Flags: FlagRaw},
+		{Key: "b", Offset: PreambleSize + 10, Size: 5, Flags: FlagDelta, Base: base},
+	}
+	index, err := DecodeIndex(EncodeIndex(Index{Entries: entries}), PreambleSize+15, Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Entries[1].Base != base || index.Entries[0].Base != (Base{}) {
+		t.Errorf("Expected base %+v of delta entry, got %+v instead", base, index.Entries[1].Base)
+	}
+
+	entries[1].Base.Flags = FlagDelta
+	if _, err := DecodeIndex(EncodeIndex(Index{Entries: entries}), PreambleSize+15, Version); err == nil {
+		t.Error("Expected base flagged as delta to be rejected")
+	}
+	entries[1].Base = Base{Offset: PreambleSize, Size: 100}
+	if _, err := DecodeIndex(EncodeIndex(Index{Entries: entries}), PreambleSize+15, Version); err == nil {
+		t.Error("Expected base out of data bounds to be rejected")
+	}
+}
+
+func TestDecodeIndex_AppendedBase(t *testing.T) {
+	base := Base{Offset: PreambleSize, Size: 10, Flags: FlagRaw, Sum: 42}
+	entries := []Entry{{Key: "log", Offset: PreambleSize + 10, Size: 5, Flags: FlagAppend | FlagRaw, Base: base}}
+	index, err := DecodeIndex(EncodeIndex(Index{Entries: entries}), PreambleSize+15, Version)
+	if err != nil || index.Entries[0].Base != base {
+		t.Errorf("Expected base %+v of appended entry, got %+v (%v) instead", base, index.Entries[0].Base, err)
+	}
+
+	entries[0].Flags = FlagAppend | FlagDelta
+	if _, err := DecodeIndex(EncodeIndex(Index{Entries: entries}), PreambleSize+15, Version); err == nil {
+		t.Error("Expected entry flagged both as delta and appended to be rejected")
+	}
+	entries[0].Flags, entries[0].Base.Flags = FlagAppend, FlagAppend
+	if _, err := DecodeIndex(EncodeIndex(Index{Entries: entries}), PreambleSize+15, Version); err == nil {
+		t.Error("Expected base flagged as appended to be rejected")
+	}
+}
+
+func TestUpdateChecksum(t *testing.T) {
+	if sum := UpdateChecksum(Checksum([]byte("hello ")), []byte("world")); sum != Checksum([]byte("hello world")) {
+		t.Errorf("Expected updated checksum to be the checksum of the whole value, got %#x instead", sum)
+	}
+}
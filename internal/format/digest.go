@@ -0,0 +1,114 @@
+package format
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"github.com/cespare/xxhash/v2"
+	"hash"
+	"io"
+)
+
+const (
+	HashNone   = 0 // HashNone is the hash of indexes without digests, which values are verified by their checksum only
+	HashXXH64  = 1 // HashXXH64 is the 64-bit xxHash, fast but not collision resistant
+	HashSHA256 = 2 // HashSHA256 is SHA-256, collision resistant
+
+	sectionDigests = 15 // sectionDigests is the tag of the digests section
+)
+
+// DigestSize returns the size of the digests of hash, 0 for HashNone and unknown hashes
+func DigestSize(hash uint64) int {
+	switch hash {
+	case HashXXH64:
+		return 8
+	case HashSHA256:
+		return sha256.Size
+	default:
+		return 0
+	}
+}
+
+// Digest returns the digest of data with hash, nil for HashNone and unknown hashes
+func Digest(hash uint64, data []byte) []byte {
+	switch hash {
+	case HashXXH64:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], xxhash.Sum64(data))
+		return b[:]
+	case HashSHA256:
+		sum := sha256.Sum256(data)
+		return sum[:]
+	default:
+		return nil
+	}
+}
+
+// NewDigest returns a hash computing digests with hash like Digest, for values read as streams, nil for HashNone
+// and unknown hashes
+func NewDigest(hash uint64) hash.Hash {
+	switch hash {
+	case HashXXH64:
+		return &xxh64{xxhash.New()}
+	case HashSHA256:
+		return sha256.New()
+	default:
+		return nil
+	}
+}
+
+// xxh64 is a hash.Hash of the 64-bit xxHash, which sums are little endian like the digests of Digest
+type xxh64 struct {
+	*xxhash.Digest
+}
+
+func (d *xxh64) Sum(b []byte) []byte {
+	var s [8]byte
+	binary.LittleEndian.PutUint64(s[:], d.Sum64())
+	return append(b, s[:]...)
+}
+
+// encodeDigests marshals the digests section: the hash, then the digest of every entry in the order of entries,
+// empty for entries without digest
+func encodeDigests(hash uint64, entries []Entry) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	buf.Write(vb[:binary.PutUvarint(vb[:], hash)])
+	for _, e := range entries {
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(e.Digest)))])
+		buf.WriteString(e.Digest)
+	}
+	return buf.Bytes()
+}
+
+// decodeDigests unmarshals the digests section into the entries of index. Digests of known hashes must have
+// the size of the hash
+func decodeDigests(section []byte, index *Index) error {
+	r := bytes.NewReader(section)
+	hash, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	size := DigestSize(hash)
+	for i := range index.Entries {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		if n > uint64(r.Len()) {
+			return io.ErrUnexpectedEOF
+		}
+		if n > 0 && size > 0 && n != uint64(size) {
+			return fmt.Errorf("invalid digest of %d bytes of key %q", n, index.Entries[i].Key)
+		}
+		digest := make([]byte, n)
+		_, _ = r.Read(digest)
+		index.Entries[i].Digest = string(digest)
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%d bytes of digests of no entry", r.Len())
+	}
+	index.Hash = hash
+	return nil
+}
@@ -0,0 +1,57 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Expiry holds the expiry time of the value of a key
+type Expiry struct {
+	Key  string
+	Time int64 // Time is the expiry time in unix nanoseconds
+}
+
+// encodeExpiry marshals the expiry section, expiry times must be in bytewise ascending key order
+func encodeExpiry(expiry []Expiry) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(expiry)))])
+	for _, e := range expiry {
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(e.Key)))])
+		buf.WriteString(e.Key)
+		buf.Write(vb[:binary.PutVarint(vb[:], e.Time)])
+	}
+	return buf.Bytes()
+}
+
+// decodeExpiry unmarshals the expiry section
+func decodeExpiry(section []byte) ([]Expiry, error) {
+	r := bytes.NewReader(section)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	// Every key takes at least two bytes for its length and its expiry time
+	if count > uint64(r.Len())/2 {
+		return nil, fmt.Errorf("invalid count of expiry times %d", count)
+	}
+	expiry := make([]Expiry, count)
+	for i := range expiry {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		key := make([]byte, n)
+		_, _ = r.Read(key)
+		expiry[i].Key = string(key)
+		if expiry[i].Time, err = binary.ReadVarint(r); err != nil {
+			return nil, err
+		}
+	}
+	return expiry, nil
+}
@@ -0,0 +1,1008 @@
+// Package format encodes and decodes the on-disk layout of sunduk store files.
+//
+// Layout of format version 3:
+//
+//	preamble  magic "SNDK" | uint16 version | uint16 flags (reserved)
+//	chunks    values, brotli-compressed unless flagged raw, back to back
+//	index     brotli-compressed index block
+//	trailer   uint64 index offset | uint32 index size | uint32 index checksum | magic "SNDK"
+//
+// The index block is
+//
+//	uvarint required features
+//	uvarint count of entries
+//	uvarint key length | key | uvarint offset | uvarint size | uvarint raw size | uint32 checksum | uvarint flags
+//	[uvarint base offset | uvarint base size | uvarint base flags | uint32 base checksum]
+//	...
+//	uvarint count of sections
+//	uvarint tag | uvarint length | section
+//	...
+//
+// Required features flag additions to the layout that readers can't skip, such as new flags of entries or sections
+// that readers must not ignore. Readers reject index blocks with features they don't know with ErrVersion, so that
+// the layout grows within a version while readers fail cleanly on files they can't read. FeatureNil marks index blocks
+// with entries flagged with FlagNil, among the entries of the index block, of tombstones or of trash. FeatureDigests
+// marks index blocks with a digests section, which readers verifying values must not ignore.
+// Sections hold data of the file other than entries, readers skip sections with unknown tags.
+// The dictionary section locates the compression dictionary, stored raw among the chunks:
+//
+//	uvarint offset | uvarint size | uint32 checksum
+//
+// The generation section holds the count of mutations committed to the store, kept across compactions:
+//
+//	uvarint generation
+//
+// The metadata section holds metadata defined by the application, with values in bytewise ascending key order:
+//
+//	uvarint length | application | varint creation time in unix nanoseconds | uvarint count of values
+//	uvarint key length | key | uvarint value length | value
+//	...
+//
+// The bloom filter section holds a bloom filter of the keys of the index, see Bloom:
+//
+//	uvarint count of hash functions | bits
+//
+// The signature section holds an Ed25519 signature of the keys and the values of the file, made by the application:
+//
+//	signature
+//
+// The seal section marks stores sealed by the application, which aren't written to afterwards:
+//
+//	varint seal time in unix nanoseconds
+//
+// The access statistics section holds the count of reads and the last read time of keys, in bytewise ascending key order,
+// optionally followed by the last write time of every key:
+//
+//	uvarint count of keys
+//	uvarint key length | key | uvarint count of reads | varint last read time in unix nanoseconds
+//	...
+//	[varint last write time in unix nanoseconds]...
+//
+// The secondary index section holds the values keys are indexed under by secondary indexes of the application,
+// with indexes in bytewise ascending name order and keys in bytewise ascending key order:
+//
+//	uvarint count of indexes
+//	uvarint name length | name | uvarint count of keys
+//	uvarint key length | key | uvarint count of values | [uvarint value length | value]...
+//	...
+//
+// The expiry section holds the expiry time of the values of keys which expire, in bytewise ascending key order:
+//
+//	uvarint count of keys
+//	uvarint key length | key | varint expiry time in unix nanoseconds
+//	...
+//
+// The data file section locates the chunks of an index file in its data file, see ReadIndexFile:
+//
+//	data file ID | uvarint data file size
+//
+// The audit section locates the audit chunks among the chunks, which hold the audit records of mutations, see EncodeAudit:
+//
+//	uvarint count of audit chunks
+//	uvarint offset | uvarint size | uvarint flags | uint32 checksum of the records
+//	...
+//
+// The tombstone section holds the entries of soft-deleted keys, laid out like entries of the index block,
+// in bytewise ascending key order:
+//
+//	uvarint count of tombstones
+//	entry | varint deletion time in unix nanoseconds | varint expiry time in unix nanoseconds, 0 if the value doesn't expire
+//	...
+//
+// The trash section holds the entries of the last values overwritten or deleted, laid out like entries of the index
+// block, in the order they were trashed:
+//
+//	uvarint count of values
+//	entry | uvarint generation | varint trash time in unix nanoseconds | varint expiry time in unix nanoseconds
+//	...
+//
+// The collation section holds the name of the collation ordering keys for the application, such as in listings
+// and range scans. Entries and sections stay in bytewise ascending key order regardless:
+//
+//	name
+//
+// The digests section holds the hash of the digests, see HashXXH64 and HashSHA256, and the digest of the
+// uncompressed value of every entry of the index block, in the order of entries, empty for entries without digest:
+//
+//	uvarint hash
+//	uvarint digest length | digest
+//	...
+//
+// Chunks flagged with FlagDict are deflate-compressed with the dictionary as preset dictionary.
+// Chunks flagged with FlagDelta hold a brotli-compressed delta against the value of another chunk, their base,
+// see Diff. Chunks flagged with FlagAppend hold data appended to the value of their base, compressed like other chunks:
+// the value is the value of the base followed by the data. Only entries of delta and appended chunks are followed
+// by the location, the flags and the checksum of the value of the base, and bases are neither deltas nor appended chunks.
+// Chunks flagged with FlagFramed are compressed in independent frames, see Frames.
+// Entries of nil values are flagged with FlagRaw and FlagNil and have empty chunks, entries of other empty values
+// aren't flagged with FlagNil.
+// Version 2 is the same layout without required features, its index blocks start with the count of entries.
+// Version 1 is the same layout without the flags of entries and without sections, all its chunks
+// are brotli-compressed. Entries with unknown flags are rejected.
+// Entries are in bytewise ascending key order, files with unordered keys are rejected.
+// All fixed-size integers are little endian. Checksums are CRC-32 (Castagnoli) of the
+// uncompressed values and of the compressed index block. The index follows the chunks,
+// so a file is written in a single forward pass once every chunk size is known, and
+// new chunks are appended after the last index followed by a new index and trailer.
+// A lookup table of the entries may precede the index block, see Lookup. The index may be kept in a
+// separate index file instead, see ReadIndexFile.
+// Files that don't start with the magic are read as the legacy layout, see ReadLegacyIndex.
+// Files of every version are pinned by the golden files of testdata, which files written in the current
+// version must match byte for byte: changing the layout requires a new version, and go test -update.
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math"
+	"sort"
+)
+
+const (
+	Version      = 3  // Version is the current format version
+	PreambleSize = 8  // PreambleSize is the size of the preamble at the beginning of the file
+	TrailerSize  = 20 // TrailerSize is the size of the trailer at the end of the file
+
+	// MaxEntries is the maximum count of entries in a file
+	MaxEntries = math.MaxUint32
+	// MaxIndexSize is the maximum size of the compressed index block, which size is stored in 32 bits
+	MaxIndexSize = math.MaxUint32
+
+	// minEntrySize is the smallest encoded entry of any version: one byte for each uvarint and the checksum
+	minEntrySize = 8
+)
+
+const (
+	FlagRaw    = 1 << iota // FlagRaw marks chunks holding the value uncompressed
+	FlagDict               // FlagDict marks chunks compressed with the dictionary of the file
+	FlagDelta              // FlagDelta marks chunks holding a delta against the chunk of the base of the entry
+	FlagAppend             // FlagAppend marks chunks holding data appended to the value of the base of the entry
+	FlagFramed             // FlagFramed marks chunks compressed in independent frames, see Frames
+	FlagNil                // FlagNil marks empty raw chunks holding a nil value rather than an empty one
+
+	knownFlags = FlagRaw | FlagDict | FlagDelta | FlagAppend | FlagFramed | FlagNil
+
+	// baseFlags are the flags of entries followed by a base
+	baseFlags = FlagDelta | FlagAppend
+)
+
+const (
+	FeatureNil     = 1 << iota // FeatureNil marks index blocks with entries flagged with FlagNil
+	FeatureDigests             // FeatureDigests marks index blocks with a digests section
+
+	// knownFeatures are the required features readers of this version understand
+	knownFeatures = FeatureNil | FeatureDigests
+)
+
+const (
+	// MaxDictionarySize is the maximum useful size of a dictionary, the size of the deflate window
+	MaxDictionarySize = 32 << 10
+
+	sectionDictionary = 1  // sectionDictionary is the tag of the dictionary section
+	sectionGeneration = 2  // sectionGeneration is the tag of the generation section
+	sectionMeta       = 3  // sectionMeta is the tag of the metadata section
+	sectionBloom      = 4  // sectionBloom is the tag of the bloom filter section
+	sectionSignature  = 5  // sectionSignature is the tag of the signature section
+	sectionSeal       = 6  // sectionSeal is the tag of the seal section
+	sectionAccess     = 7  // sectionAccess is the tag of the access statistics section
+	sectionData       = 8  // sectionData is the tag of the data file section
+	sectionSecondary  = 9  // sectionSecondary is the tag of the secondary index section
+	sectionExpiry     = 10 // sectionExpiry is the tag of the expiry section
+	sectionAudit      = 11 // sectionAudit is the tag of the audit section
+	sectionTombstone  = 12 // sectionTombstone is the tag of the tombstone section
+	sectionTrash      = 13 // sectionTrash is the tag of the trash section
+	sectionCollation  = 14 // sectionCollation is the tag of the collation section
+
+	// SignatureSize is the size of the signature of signed files
+	SignatureSize = 64
+)
+
+var (
+	// Magic starts the preamble and ends the trailer of store files
+	Magic = [4]byte{'S', 'N', 'D', 'K'}
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errDeltaChunk = errors.New("delta chunk can't be decoded without its base")
+
+	// ErrVersion is returned for files in a format version this package can't read, or which require features of
+	// the format it doesn't know, such as files written by newer versions of the package
+	ErrVersion = errors.New("unsupported store format version")
+
+	// ErrCorruptHeader is returned for storage headers that don't hold a valid index, such as headers of truncated
+	// or damaged files and files that aren't store files
+	ErrCorruptHeader = errors.New("corrupt storage header")
+)
+
+// Entry is an entry of the index, describing the chunk that holds the value of a key
+type Entry struct {
+	Key     string
+	Offset  int64  // Offset of compressed chunk in file
+	Size    int64  // Size of compressed chunk
+	RawSize int64  // Size of uncompressed value
+	Sum     uint32 // Checksum of uncompressed value
+	Flags   uint64 // Flags of chunk, such as FlagRaw
+	Base    Base   // Base of chunk flagged with FlagDelta or FlagAppend
+	Digest  string // Digest of uncompressed value with the hash of the index, empty if the entry has none
+}
+
+// Base locates the chunk a delta chunk applies to
+type Base struct {
+	Offset int64
+	Size   int64
+	Flags  uint64 // Flags of base chunk, never FlagDelta nor FlagAppend
+	Sum    uint32 // Checksum of the value of base chunk
+}
+
+// Dictionary locates the compression dictionary in the file, its size is 0 if the file has no dictionary
+type Dictionary struct {
+	Offset int64
+	Size   int64
+	Sum    uint32 // Checksum of dictionary
+}
+
+// Meta is the metadata of a file defined by the application, it is empty if the file has none
+type Meta struct {
+	Application string
+	Created     int64 // Created is the creation time of the store in unix nanoseconds, 0 if unknown
+	Values      map[string]string
+}
+
+// empty returns true if m holds nothing to write
+func (m Meta) empty() bool {
+	return m.Application == "" && m.Created == 0 && len(m.Values) == 0
+}
+
+// Index is the content of an index block
+type Index struct {
+	Entries    []Entry
+	Dictionary Dictionary
+	Generation uint64 // Generation is the count of mutations committed to the store
+	Meta       Meta
+	Bloom      Bloom       // Bloom is a bloom filter of the keys of entries, it may be empty
+	Signature  []byte      // Signature is the signature of the file, nil if the file isn't signed
+	Sealed     int64       // Sealed is the time the store was sealed in unix nanoseconds, 0 if it isn't sealed
+	Access     []Access    // Access holds the access statistics of keys, nil if the store doesn't count reads
+	Secondary  []Secondary // Secondary holds the secondary indexes of keys, nil if the store has none
+	Expiry     []Expiry    // Expiry holds the expiry times of the values of keys which expire, nil if none expires
+	Audit      []Base      // Audit locates the audit chunks of the file in the order they were written, nil if it has none
+	Tombstones []Tombstone // Tombstones holds the entries of soft-deleted keys, nil if there are none
+	Trash      []Trashed   // Trash holds the entries of the last values overwritten or deleted, nil if there are none
+	Collation  string      // Collation is the name of the collation ordering keys, empty for bytewise order
+	Hash       uint64      // Hash is the hash of the digests of entries, HashNone if they have none
+	Data       DataFile    // Data locates the chunks of an index file, its size is 0 for indexes of store files
+	Offset     int64       // Offset of index block in file
+}
+
+// Checksum returns the checksum of data as it is recorded in the index
+func Checksum(data []byte) uint32 {
+	return crc32.Checksum(data, crcTable)
+}
+
+// NewChecksum returns a hash computing checksums as they are recorded in the index, for values read as streams
+func NewChecksum() hash.Hash32 {
+	return crc32.New(crcTable)
+}
+
+// UpdateChecksum returns the checksum of a value which checksum is sum followed by data
+func UpdateChecksum(sum uint32, data []byte) uint32 {
+	return crc32.Update(sum, crcTable, data)
+}
+
+// Compress returns data compressed with brotli at default quality, using a window of 1<<windowBits bytes
+func Compress(data []byte, windowBits int) ([]byte, error) {
+	zb := getBuffer()
+	defer putBuffer(zb)
+	zw := getBrotliWriter(zb, windowBits)
+	defer putBrotliWriter(zw, windowBits)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), zb.Bytes()...), nil
+}
+
+// CompressStream writes the data read from r to w compressed with brotli like Compress, without holding it in
+// memory, and returns the count of bytes read
+func CompressStream(w io.Writer, r io.Reader, windowBits int) (int64, error) {
+	zw := getBrotliWriter(w, windowBits)
+	defer putBrotliWriter(zw, windowBits)
+	n, err := io.Copy(zw, r)
+	if err != nil {
+		return n, err
+	}
+	return n, zw.Close()
+}
+
+// Decompress returns brotli-compressed data uncompressed
+func Decompress(data []byte) ([]byte, error) {
+	return readAll(bytes.NewReader(data), 0, nil)
+}
+
+// CompressDict returns data deflate-compressed with dict as preset dictionary
+func CompressDict(data, dict []byte) ([]byte, error) {
+	zb := getBuffer()
+	defer putBuffer(zb)
+	zw, err := getFlateWriter(zb, dict)
+	if err != nil {
+		return nil, err
+	}
+	defer putFlateWriter(zw)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), zb.Bytes()...), nil
+}
+
+// chunkReader returns a reader of the value held by a chunk with flags, dict is the dictionary of the file
+func chunkReader(data []byte, flags uint64, dict []byte) io.Reader {
+	return NewChunkReader(bytes.NewReader(data), flags, dict)
+}
+
+// NewChunkReader returns a reader of the value held by the chunk read from r, so that values are read
+// partially without reading and decompressing the whole chunk. Flags are those of the chunk, as with DecodeChunk
+func NewChunkReader(r io.Reader, flags uint64, dict []byte) io.Reader {
+	switch {
+	case flags&FlagFramed != 0:
+		return &framedReader{r: &countingByteReader{r: bufio.NewReader(r)}}
+	case flags&FlagRaw != 0:
+		return r
+	case flags&FlagDict != 0:
+		return flate.NewReaderDict(r, dict)
+	default:
+		return brotli.NewReader(r)
+	}
+}
+
+// DecodeChunk returns the value held by a chunk with flags, dict is the dictionary of the file.
+// Chunks flagged with FlagDelta are decoded with DecodeDelta, and chunks flagged with FlagAppend hold the appended data
+func DecodeChunk(data []byte, flags uint64, dict []byte) ([]byte, error) {
+	if flags&FlagDelta != 0 {
+		return nil, errDeltaChunk
+	}
+	if flags&FlagNil != 0 {
+		return nil, nil
+	}
+	if flags&FlagRaw != 0 {
+		return data, nil
+	}
+	if flags&FlagFramed != 0 {
+		return io.ReadAll(chunkReader(data, flags, dict))
+	}
+	return readAll(bytes.NewReader(data), flags, dict)
+}
+
+// ChunkSum decompresses a chunk without retaining the value and returns the size and the checksum of the value
+func ChunkSum(data []byte, flags uint64, dict []byte) (rawSize int64, sum uint32, err error) {
+	if flags&FlagDelta != 0 {
+		return 0, 0, errDeltaChunk
+	}
+	h := crc32.New(crcTable)
+	if flags&(FlagRaw|FlagFramed) != 0 {
+		rawSize, err = io.Copy(h, chunkReader(data, flags, dict))
+	} else {
+		err = decompress(bytes.NewReader(data), flags, dict, func(r io.Reader) (err error) {
+			rawSize, err = io.Copy(h, r)
+			return err
+		})
+	}
+	return rawSize, h.Sum32(), err
+}
+
+// WritePreamble writes the magic and the format version at the beginning of the file
+func WritePreamble(w io.Writer) error {
+	var b [PreambleSize]byte
+	copy(b[:], Magic[:])
+	binary.LittleEndian.PutUint16(b[4:], Version)
+	_, err := w.Write(b[:])
+	return err
+}
+
+// ReadVersion returns the format version of the file, 0 for files in legacy layout
+func ReadVersion(r io.ReaderAt) (int, error) {
+	var pb [PreambleSize]byte
+	n, err := r.ReadAt(pb[:], 0)
+	if n < len(Magic) || !bytes.Equal(pb[:len(Magic)], Magic[:]) {
+		return 0, nil
+	}
+	if n < PreambleSize {
+		return 0, fmt.Errorf("unable to read storage preamble: %v", err)
+	}
+	return int(binary.LittleEndian.Uint16(pb[4:])), nil
+}
+
+// WriteIndex compresses the index, which entries must be in key order, and writes it
+// at the offset of the index followed by the trailer
+func WriteIndex(w io.Writer, index Index, windowBits int) error {
+	if int64(len(index.Entries)) > MaxEntries {
+		return fmt.Errorf("too many entries: %d, maximum is %d", len(index.Entries), uint64(MaxEntries))
+	}
+	data, err := Compress(EncodeIndex(index), windowBits)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > MaxIndexSize {
+		return fmt.Errorf("index block of %d bytes exceeds maximum of %d bytes", len(data), int64(MaxIndexSize))
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	var tb [TrailerSize]byte
+	binary.LittleEndian.PutUint64(tb[0:], uint64(index.Offset))
+	binary.LittleEndian.PutUint32(tb[8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(tb[12:], Checksum(data))
+	copy(tb[16:], Magic[:])
+	_, err = w.Write(tb[:])
+	return err
+}
+
+// EncodeIndex marshals the entries, in their order, and the sections of index in the current version
+func EncodeIndex(index Index) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) {
+		buf.Write(vb[:binary.PutUvarint(vb[:], v)])
+	}
+
+	putUvarint(index.features())
+	putUvarint(uint64(len(index.Entries)))
+	for _, e := range index.Entries {
+		encodeEntry(&buf, e)
+	}
+	buf.Write(encodeSections(index))
+	return buf.Bytes()
+}
+
+// features returns the required features of index
+func (index Index) features() uint64 {
+	var features uint64
+	for _, e := range index.Entries {
+		features |= nilFeature(e)
+	}
+	for _, t := range index.Tombstones {
+		features |= nilFeature(t.Entry)
+	}
+	for _, t := range index.Trash {
+		features |= nilFeature(t.Entry)
+	}
+	if index.Hash != HashNone {
+		features |= FeatureDigests
+	}
+	return features
+}
+
+// nilFeature returns FeatureNil if e is flagged with FlagNil, 0 otherwise
+func nilFeature(e Entry) uint64 {
+	if e.Flags&FlagNil != 0 {
+		return FeatureNil
+	}
+	return 0
+}
+
+// checkFeatures returns an error wrapping ErrVersion if features has features readers of this version don't know
+func checkFeatures(features uint64) error {
+	if features&^knownFeatures != 0 {
+		return fmt.Errorf("%w %d with features %#x, the known features are %#x", ErrVersion, Version, features, knownFeatures)
+	}
+	return nil
+}
+
+// encodeEntry marshals an entry of an index block
+func encodeEntry(buf *bytes.Buffer, e Entry) {
+	var vb [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) {
+		buf.Write(vb[:binary.PutUvarint(vb[:], v)])
+	}
+	putUvarint(uint64(len(e.Key)))
+	buf.WriteString(e.Key)
+	putUvarint(uint64(e.Offset))
+	putUvarint(uint64(e.Size))
+	putUvarint(uint64(e.RawSize))
+	binary.LittleEndian.PutUint32(vb[:], e.Sum)
+	buf.Write(vb[:4])
+	putUvarint(e.Flags)
+	if e.Flags&baseFlags != 0 {
+		putUvarint(uint64(e.Base.Offset))
+		putUvarint(uint64(e.Base.Size))
+		putUvarint(e.Base.Flags)
+		binary.LittleEndian.PutUint32(vb[:], e.Base.Sum)
+		buf.Write(vb[:4])
+	}
+}
+
+// encodeSections marshals the sections of index, preceded by their count
+func encodeSections(index Index) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) {
+		buf.Write(vb[:binary.PutUvarint(vb[:], v)])
+	}
+	type section struct {
+		tag  uint64
+		data []byte
+	}
+	var sections []section
+	if d := index.Dictionary; d.Size > 0 {
+		var data []byte
+		data = append(data, vb[:binary.PutUvarint(vb[:], uint64(d.Offset))]...)
+		data = append(data, vb[:binary.PutUvarint(vb[:], uint64(d.Size))]...)
+		binary.LittleEndian.PutUint32(vb[:], d.Sum)
+		data = append(data, vb[:4]...)
+		sections = append(sections, section{tag: sectionDictionary, data: data})
+	}
+	if index.Generation > 0 {
+		data := append([]byte(nil), vb[:binary.PutUvarint(vb[:], index.Generation)]...)
+		sections = append(sections, section{tag: sectionGeneration, data: data})
+	}
+	if !index.Meta.empty() {
+		sections = append(sections, section{tag: sectionMeta, data: encodeMeta(index.Meta)})
+	}
+	if !index.Bloom.Empty() {
+		sections = append(sections, section{tag: sectionBloom, data: encodeBloom(index.Bloom)})
+	}
+	if index.Signature != nil {
+		sections = append(sections, section{tag: sectionSignature, data: index.Signature})
+	}
+	if index.Sealed != 0 {
+		data := append([]byte(nil), vb[:binary.PutVarint(vb[:], index.Sealed)]...)
+		sections = append(sections, section{tag: sectionSeal, data: data})
+	}
+	if index.Access != nil {
+		sections = append(sections, section{tag: sectionAccess, data: encodeAccess(index.Access)})
+	}
+	if index.Secondary != nil {
+		sections = append(sections, section{tag: sectionSecondary, data: encodeSecondary(index.Secondary)})
+	}
+	if index.Expiry != nil {
+		sections = append(sections, section{tag: sectionExpiry, data: encodeExpiry(index.Expiry)})
+	}
+	if index.Audit != nil {
+		sections = append(sections, section{tag: sectionAudit, data: encodeAuditChunks(index.Audit)})
+	}
+	if index.Tombstones != nil {
+		sections = append(sections, section{tag: sectionTombstone, data: encodeTombstones(index.Tombstones)})
+	}
+	if index.Trash != nil {
+		sections = append(sections, section{tag: sectionTrash, data: encodeTrash(index.Trash)})
+	}
+	if index.Collation != "" {
+		sections = append(sections, section{tag: sectionCollation, data: []byte(index.Collation)})
+	}
+	if index.Hash != HashNone {
+		sections = append(sections, section{tag: sectionDigests, data: encodeDigests(index.Hash, index.Entries)})
+	}
+	if d := index.Data; d.Size > 0 {
+		data := append(append([]byte(nil), d.ID[:]...), vb[:binary.PutUvarint(vb[:], uint64(d.Size))]...)
+		sections = append(sections, section{tag: sectionData, data: data})
+	}
+	putUvarint(uint64(len(sections)))
+	for _, s := range sections {
+		putUvarint(s.tag)
+		putUvarint(uint64(len(s.data)))
+		buf.Write(s.data)
+	}
+	return buf.Bytes()
+}
+
+// DecodeIndex unmarshals an index block of a file in format version, checking that keys are in order
+// and that every chunk lies inside [PreambleSize, end)
+func DecodeIndex(data []byte, end int64, version int) (Index, error) {
+	return Limits{}.decodeIndex(bytes.NewReader(data), end, version)
+}
+
+// decodeIndex unmarshals an index block read from r like DecodeIndex, within the limits
+func (l Limits) decodeIndex(r indexReader, end int64, version int) (Index, error) {
+	var index Index
+	if version > 2 {
+		features, err := binary.ReadUvarint(r)
+		if err != nil {
+			return index, err
+		}
+		if err := checkFeatures(features); err != nil {
+			return index, err
+		}
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return index, err
+	}
+	if count > MaxEntries || count > uint64(r.Len())/minEntrySize {
+		return index, fmt.Errorf("invalid count of keys %d", count)
+	}
+	if err := l.checkEntries(count); err != nil {
+		return index, err
+	}
+
+	capacity := count
+	if capacity > maxPreallocEntries {
+		capacity = maxPreallocEntries
+	}
+	index.Entries = make([]Entry, 0, capacity)
+	for i := uint64(0); i < count; i++ {
+		e, err := decodeEntry(r, end, version)
+		if err != nil {
+			return index, err
+		}
+		if i > 0 && e.Key <= index.Entries[i-1].Key {
+			return index, fmt.Errorf("key %q is out of order", e.Key)
+		}
+		index.Entries = append(index.Entries, e)
+	}
+
+	if version > 1 {
+		if err := decodeSections(r, end, &index); err != nil {
+			return index, err
+		}
+	}
+	return index, nil
+}
+
+// decodeEntry unmarshals an entry of an index block of a file in format version,
+// checking that its chunk lies inside [PreambleSize, end)
+func decodeEntry(r indexReader, end int64, version int) (Entry, error) {
+	kl, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Entry{}, err
+	}
+	key, err := readBytes(r, kl)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	var fields [3]uint64
+	for j := range fields {
+		if fields[j], err = binary.ReadUvarint(r); err != nil {
+			return Entry{}, err
+		}
+	}
+	var sb [4]byte
+	if _, err := io.ReadFull(r, sb[:]); err != nil {
+		return Entry{}, err
+	}
+	var flags uint64
+	if version > 1 {
+		if flags, err = binary.ReadUvarint(r); err != nil {
+			return Entry{}, err
+		}
+		if flags&^knownFlags != 0 {
+			return Entry{}, fmt.Errorf("unknown flags %#x of key %q", flags, key)
+		}
+		if flags&baseFlags == baseFlags || flags&FlagFramed != 0 && flags&(FlagRaw|FlagDict|FlagDelta) != 0 ||
+			flags&FlagNil != 0 && (flags != FlagRaw|FlagNil || fields[1] != 0) {
+			return Entry{}, fmt.Errorf("invalid flags %#x of key %q", flags, key)
+		}
+	}
+	var base Base
+	if flags&baseFlags != 0 {
+		if base, err = decodeBase(r, end); err != nil {
+			return Entry{}, fmt.Errorf("invalid base of key %q: %v", key, err)
+		}
+	}
+
+	e := Entry{
+		Key:     string(key),
+		Offset:  int64(fields[0]),
+		Size:    int64(fields[1]),
+		RawSize: int64(fields[2]),
+		Sum:     binary.LittleEndian.Uint32(sb[:]),
+		Flags:   flags,
+		Base:    base,
+	}
+	if e.Offset < PreambleSize || e.Size < 0 || e.Offset+e.Size > end {
+		return Entry{}, fmt.Errorf("chunk of key %q is out of data bounds", key)
+	}
+	return e, nil
+}
+
+// decodeBase unmarshals the base of an entry flagged with FlagDelta or FlagAppend, checking that it lies inside [PreambleSize, end)
+func decodeBase(r indexReader, end int64) (Base, error) {
+	var fields [3]uint64
+	for i := range fields {
+		var err error
+		if fields[i], err = binary.ReadUvarint(r); err != nil {
+			return Base{}, err
+		}
+	}
+	var sb [4]byte
+	if _, err := io.ReadFull(r, sb[:]); err != nil {
+		return Base{}, err
+	}
+	b := Base{Offset: int64(fields[0]), Size: int64(fields[1]), Flags: fields[2], Sum: binary.LittleEndian.Uint32(sb[:])}
+	if b.Offset < PreambleSize || b.Size < 0 || b.Offset+b.Size > end {
+		return Base{}, errors.New("base is out of data bounds")
+	}
+	if b.Flags&^knownFlags != 0 || b.Flags&baseFlags != 0 || b.Flags&FlagFramed != 0 && b.Flags&(FlagRaw|FlagDict) != 0 {
+		return Base{}, fmt.Errorf("invalid flags %#x of base", b.Flags)
+	}
+	return b, nil
+}
+
+// decodeSections unmarshals the sections following the entries of an index block
+func decodeSections(r indexReader, end int64, index *Index) error {
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < count; i++ {
+		tag, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		section, err := readBytes(r, size)
+		if err != nil {
+			return err
+		}
+		switch tag {
+		case sectionDictionary:
+			if index.Dictionary, err = decodeDictionary(section, end); err != nil {
+				return err
+			}
+		case sectionGeneration:
+			if index.Generation, err = binary.ReadUvarint(bytes.NewReader(section)); err != nil {
+				return err
+			}
+		case sectionMeta:
+			if index.Meta, err = decodeMeta(section); err != nil {
+				return err
+			}
+		case sectionBloom:
+			if index.Bloom, err = decodeBloom(section); err != nil {
+				return err
+			}
+		case sectionSignature:
+			if len(section) != SignatureSize {
+				return fmt.Errorf("invalid signature of %d bytes", len(section))
+			}
+			index.Signature = section
+		case sectionSeal:
+			if index.Sealed, err = binary.ReadVarint(bytes.NewReader(section)); err != nil {
+				return err
+			}
+		case sectionAccess:
+			if index.Access, err = decodeAccess(section); err != nil {
+				return err
+			}
+		case sectionSecondary:
+			if index.Secondary, err = decodeSecondary(section); err != nil {
+				return err
+			}
+		case sectionExpiry:
+			if index.Expiry, err = decodeExpiry(section); err != nil {
+				return err
+			}
+		case sectionData:
+			if index.Data, err = decodeDataFile(section); err != nil {
+				return err
+			}
+		case sectionAudit:
+			if index.Audit, err = decodeAuditChunks(section, end); err != nil {
+				return err
+			}
+		case sectionTombstone:
+			if index.Tombstones, err = decodeTombstones(section, end); err != nil {
+				return err
+			}
+		case sectionTrash:
+			if index.Trash, err = decodeTrash(section, end); err != nil {
+				return err
+			}
+		case sectionCollation:
+			index.Collation = string(section)
+		case sectionDigests:
+			if err := decodeDigests(section, index); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeDictionary unmarshals the dictionary section, checking that the dictionary lies inside [PreambleSize, end)
+func decodeDictionary(section []byte, end int64) (Dictionary, error) {
+	r := bytes.NewReader(section)
+	offset, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Dictionary{}, err
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Dictionary{}, err
+	}
+	var sb [4]byte
+	if _, err := io.ReadFull(r, sb[:]); err != nil {
+		return Dictionary{}, err
+	}
+	d := Dictionary{Offset: int64(offset), Size: int64(size), Sum: binary.LittleEndian.Uint32(sb[:])}
+	if d.Offset < PreambleSize || d.Size < 0 || d.Size > MaxDictionarySize || d.Offset+d.Size > end {
+		return Dictionary{}, errors.New("dictionary is out of data bounds")
+	}
+	return d, nil
+}
+
+// encodeMeta marshals the metadata section
+func encodeMeta(m Meta) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	putString := func(s string) {
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(s)))])
+		buf.WriteString(s)
+	}
+	putString(m.Application)
+	buf.Write(vb[:binary.PutVarint(vb[:], m.Created)])
+	keys := make([]string, 0, len(m.Values))
+	for k := range m.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(keys)))])
+	for _, k := range keys {
+		putString(k)
+		putString(m.Values[k])
+	}
+	return buf.Bytes()
+}
+
+// decodeMeta unmarshals the metadata section
+func decodeMeta(section []byte) (Meta, error) {
+	r := bytes.NewReader(section)
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return "", err
+		}
+		if n > uint64(r.Len()) {
+			return "", io.ErrUnexpectedEOF
+		}
+		s := make([]byte, n)
+		_, _ = r.Read(s)
+		return string(s), nil
+	}
+	var m Meta
+	var err error
+	if m.Application, err = readString(); err != nil {
+		return Meta{}, err
+	}
+	if m.Created, err = binary.ReadVarint(r); err != nil {
+		return Meta{}, err
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Meta{}, err
+	}
+	// Every value takes at least two bytes for the lengths of its key and its value
+	if count > uint64(r.Len())/2 {
+		return Meta{}, fmt.Errorf("invalid count of metadata values %d", count)
+	}
+	if count > 0 {
+		m.Values = make(map[string]string, count)
+	}
+	for i := uint64(0); i < count; i++ {
+		k, err := readString()
+		if err != nil {
+			return Meta{}, err
+		}
+		if m.Values[k], err = readString(); err != nil {
+			return Meta{}, err
+		}
+	}
+	return m, nil
+}
+
+// ReadIndex reads the trailer at the end of a file of size bytes, then reads, verifies and unmarshalls the index
+func ReadIndex(r io.ReaderAt, size int64) (Index, error) {
+	return Limits{}.ReadIndex(r, size)
+}
+
+// ReadIndex reads the index like the function ReadIndex, within the limits. The index block is unmarshalled
+// as it is decompressed
+func (l Limits) ReadIndex(r io.ReaderAt, size int64) (Index, error) {
+	if _, ok, err := ReadFileID(r); err != nil || ok {
+		if err == nil {
+			err = headerError("read", errors.New("the index of the file is in an index file"))
+		}
+		return Index{}, err
+	}
+	version, offset, data, err := readIndexBlock(r, size)
+	if err != nil {
+		return Index{}, err
+	}
+	index, err := l.decodeBlock(data, offset, version)
+	if err != nil {
+		return Index{}, headerError("decode", err)
+	}
+	index.Offset = offset
+	return index, nil
+}
+
+// readIndexBlock reads the trailer at the end of a file of size bytes, then reads and verifies the compressed
+// index block, returning the format version of the file and the offset of the index block
+func readIndexBlock(r io.ReaderAt, size int64) (version int, offset int64, data []byte, err error) {
+	makeErr := func(action string, err error) error {
+		return headerError(action, err)
+	}
+
+	if version, err = ReadVersion(r); err != nil {
+		return 0, 0, nil, err
+	}
+	if version < 1 || version > Version {
+		return 0, 0, nil, fmt.Errorf("%w %d, the latest supported version is %d", ErrVersion, version, Version)
+	}
+	offset, isize, sum, err := readTrailer(r, size)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	// Read and verify compressed index
+	data = make([]byte, isize)
+	if _, err := r.ReadAt(data, offset); err != nil {
+		return 0, 0, nil, makeErr("read", err)
+	}
+	if Checksum(data) != sum {
+		return 0, 0, nil, makeErr("verify", errors.New("checksum mismatch"))
+	}
+	return version, offset, data, nil
+}
+
+// headerError returns the error of action on the storage header failing with err. Errors other than
+// I/O errors of the file, ErrIndexLimit and ErrVersion wrap ErrCorruptHeader, truncated files included
+func headerError(action string, err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) || errors.Is(err, ErrIndexLimit) || errors.Is(err, ErrVersion) {
+		return fmt.Errorf("unable to %s storage header: %w", action, err)
+	}
+	return fmt.Errorf("unable to %s storage header: %w: %v", action, ErrCorruptHeader, err)
+}
+
+// readTrailer reads the trailer at the end of a file of size bytes, returning the offset, the size and the checksum of the index block
+func readTrailer(r io.ReaderAt, size int64) (offset, isize int64, sum uint32, err error) {
+	makeErr := func(action string, err error) error {
+		return headerError(action, err)
+	}
+	if size < PreambleSize+TrailerSize {
+		return 0, 0, 0, makeErr("read", io.ErrUnexpectedEOF)
+	}
+	var tb [TrailerSize]byte
+	if _, err := r.ReadAt(tb[:], size-TrailerSize); err != nil {
+		return 0, 0, 0, makeErr("read trailer of", err)
+	}
+	if !bytes.Equal(tb[16:], Magic[:]) {
+		return 0, 0, 0, makeErr("find trailer of", errors.New("bad magic"))
+	}
+	offset = int64(binary.LittleEndian.Uint64(tb[0:]))
+	isize = int64(binary.LittleEndian.Uint32(tb[8:]))
+	if offset < PreambleSize || offset+isize != size-TrailerSize {
+		return 0, 0, 0, makeErr("locate", fmt.Errorf("index block is out of file bounds"))
+	}
+	return offset, isize, binary.LittleEndian.Uint32(tb[12:]), nil
+}
+
+// ReadDictionary reads the dictionary located by d and verifies its checksum
+func ReadDictionary(r io.ReaderAt, d Dictionary) ([]byte, error) {
+	dict := make([]byte, d.Size)
+	if _, err := r.ReadAt(dict, d.Offset); err != nil {
+		return nil, fmt.Errorf("unable to read dictionary: %v", err)
+	}
+	if Checksum(dict) != d.Sum {
+		return nil, errors.New("unable to verify dictionary: checksum mismatch")
+	}
+	return dict, nil
+}
@@ -0,0 +1,418 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFile returns a complete file with a chunk for every key, the value of a key being the key itself
+func writeFile(t *testing.T, keys ...string) []byte {
+	return writeFileWith(t, Index{}, keys...)
+}
+
+// writeFileWith returns a complete file like writeFile, which index has the sections of sections
+func writeFileWith(t *testing.T, sections Index, keys ...string) []byte {
+	var buf bytes.Buffer
+	if err := WritePreamble(&buf); err != nil {
+		t.Fatal(err)
+	}
+	entries := make([]Entry, len(keys))
+	for i, k := range keys {
+		data, err := Compress([]byte(k), 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[i] = Entry{Key: k, Offset: int64(buf.Len()), Size: int64(len(data)), RawSize: int64(len(k)), Sum: Checksum([]byte(k)),
+			Digest: string(Digest(sections.Hash, []byte(k)))}
+		buf.Write(data)
+	}
+	sections.Entries, sections.Offset = entries, int64(buf.Len())
+	if err := WriteIndex(&buf, sections, 16); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadIndex_ZeroEntries(t *testing.T) {
+	file := writeFile(t)
+	if version, err := ReadVersion(bytes.NewReader(file)); version != Version || err != nil {
+		t.Errorf("Expected version %d, got %d (%v) instead", Version, version, err)
+	}
+	index, err := ReadIndex(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Entries) != 0 || index.Offset != PreambleSize {
+		t.Errorf("Expected no entries and index at %d, got %d entries and index at %d instead", PreambleSize, len(index.Entries), index.Offset)
+	}
+}
+
+func TestReadIndex_SingleEntry(t *testing.T) {
+	file := writeFile(t, "key")
+	index, err := ReadIndex(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := index.Entries
+	if len(entries) != 1 || entries[0].Key != "key" || entries[0].Offset != PreambleSize {
+		t.Fatalf("Expected a single entry for 'key' at %d, got %v instead", PreambleSize, entries)
+	}
+	value, _ := Decompress(file[entries[0].Offset : entries[0].Offset+entries[0].Size])
+	if string(value) != "key" || Checksum(value) != entries[0].Sum {
+		t.Errorf("Expected chunk to hold 'key', got '%s' instead", value)
+	}
+}
+
+func TestReadIndex_Truncated(t *testing.T) {
+	file := writeFile(t, "a", "b")
+	for _, size := range []int{0, PreambleSize, PreambleSize + TrailerSize - 1, len(file) - 1} {
+		if _, err := ReadIndex(bytes.NewReader(file[:size]), int64(size)); err == nil {
+			t.Errorf("Expected file truncated to %d bytes to be rejected", size)
+		}
+	}
+}
+
+func TestDecodeIndex_Boundaries(t *testing.T) {
+	scenarios := map[string][]byte{
+		"no count":            {},
+		"count above maximum": uvarint(MaxEntries + 1),
+		"count above size":    append(uvarint(1000), make([]byte, 100)...),
+		"missing entry":       uvarint(1),
+		"unordered keys": EncodeIndex(Index{Entries: []Entry{
+			{Key: "b", Offset: PreambleSize},
+			{Key: "a", Offset: PreambleSize},
+		}}),
+		"chunk out of bounds":      EncodeIndex(Index{Entries: []Entry{{Key: "a", Offset: PreambleSize, Size: 1000}}}),
+		"chunk in preamble":        EncodeIndex(Index{Entries: []Entry{{Key: "a", Offset: 0}}}),
+		"unknown flags":            EncodeIndex(Index{Entries: []Entry{{Key: "a", Offset: PreambleSize, Flags: 1 << 7}}}),
+		"nil chunk not raw":        EncodeIndex(Index{Entries: []Entry{{Key: "a", Offset: PreambleSize, Flags: FlagNil}}}),
+		"nil chunk with data":      EncodeIndex(Index{Entries: []Entry{{Key: "a", Offset: PreambleSize, Size: 1, Flags: FlagRaw | FlagNil}}}),
+		"dictionary out of bounds": EncodeIndex(Index{Dictionary: Dictionary{Offset: PreambleSize, Size: 1000}}),
+		"missing sections":         append(uvarint(0), uvarint(0)...),
+	}
+	for name, data := range scenarios {
+		if _, err := DecodeIndex(data, 100, Version); err == nil {
+			t.Errorf("Expected index with %s to be rejected", name)
+		}
+	}
+}
+
+func TestDecodeChunk_Nil(t *testing.T) {
+	if data, err := DecodeChunk([]byte{}, FlagRaw|FlagNil, nil); data != nil || err != nil {
+		t.Errorf("Expected nil chunk to hold nil, got %q (%v) instead", data, err)
+	}
+	if data, err := DecodeChunk([]byte{}, FlagRaw, nil); data == nil || err != nil {
+		t.Errorf("Expected empty chunk to hold an empty value, got nil (%v) instead", err)
+	}
+}
+
+func TestDecodeIndex_Version1(t *testing.T) {
+	// Entries of version 1 have no flags
+	data := append(uvarint(1), uvarint(1)...)
+	data = append(data, 'a')
+	data = append(data, uvarint(PreambleSize)...)
+	data = append(data, uvarint(5)...)
+	data = append(data, uvarint(3)...)
+	data = append(data, 1, 2, 3, 4)
+
+	index, err := DecodeIndex(data, 100, 1)
+	want := Entry{Key: "a", Offset: PreambleSize, Size: 5, RawSize: 3, Sum: 0x04030201}
+	if err != nil || len(index.Entries) != 1 || index.Entries[0] != want {
+		t.Errorf("Expected version 1 index to hold %v, got %v (%v) instead", want, index.Entries, err)
+	}
+	if _, err := DecodeIndex(data, 100, Version); err == nil {
+		t.Error("Expected version 1 index to be rejected as the current version")
+	}
+}
+
+func TestDecodeChunk_Raw(t *testing.T) {
+	value := []byte("raw value")
+	if data, err := DecodeChunk(value, FlagRaw, nil); err != nil || !bytes.Equal(data, value) {
+		t.Errorf("Expected raw chunk to hold %q, got %q (%v) instead", value, data, err)
+	}
+	if size, sum, err := ChunkSum(value, FlagRaw, nil); err != nil || size != int64(len(value)) || sum != Checksum(value) {
+		t.Errorf("Expected checksum of raw chunk to be the checksum of its value, got %d, %x (%v) instead", size, sum, err)
+	}
+}
+
+func TestReadLegacyIndex_ZeroEntries(t *testing.T) {
+	keys, _ := Compress(nil, 16)
+	var buf bytes.Buffer
+	var sb [4]byte
+	buf.Write(sb[:]) // count of keys
+	binary.LittleEndian.PutUint32(sb[:], uint32(len(keys)))
+	buf.Write(sb[:])
+	buf.Write(keys)
+
+	entries, err := ReadLegacyIndex(bytes.NewReader(buf.Bytes()))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected legacy file without keys to have no entries, got %v (%v) instead", entries, err)
+	}
+}
+
+func TestReadVersion_Legacy(t *testing.T) {
+	for _, data := range []string{"", "SN", "\x02\x00\x00\x00rest of legacy file"} {
+		if version, err := ReadVersion(io.NewSectionReader(strings.NewReader(data), 0, int64(len(data)))); version != 0 || err != nil {
+			t.Errorf("Expected %q to be read as legacy file, got version %d (%v) instead", data, version, err)
+		}
+	}
+}
+
+func uvarint(v uint64) []byte {
+	var vb [binary.MaxVarintLen64]byte
+	return vb[:binary.PutUvarint(vb[:], v)]
+}
+
+func TestReadIndex_Dictionary(t *testing.T) {
+	dict := []byte("dictionary of common words")
+	value := []byte("common words")
+	var buf bytes.Buffer
+	_ = WritePreamble(&buf)
+	d := Dictionary{Offset: int64(buf.Len()), Size: int64(len(dict)), Sum: Checksum(dict)}
+	buf.Write(dict)
+	data, err := CompressDict(value, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := Entry{Key: "key", Offset: int64(buf.Len()), Size: int64(len(data)), RawSize: int64(len(value)), Sum: Checksum(value), Flags: FlagDict}
+	buf.Write(data)
+	if err := WriteIndex(&buf, Index{Entries: []Entry{e}, Dictionary: d, Offset: int64(buf.Len())}, 16); err != nil {
+		t.Fatal(err)
+	}
+
+	file := buf.Bytes()
+	index, err := ReadIndex(bytes.NewReader(file), int64(len(file)))
+	if err != nil || index.Dictionary != d {
+		t.Fatalf("Expected dictionary %v, got %v (%v) instead", d, index.Dictionary, err)
+	}
+	read, err := ReadDictionary(bytes.NewReader(file), index.Dictionary)
+	if err != nil || !bytes.Equal(read, dict) {
+		t.Fatalf("Expected dictionary %q, got %q (%v) instead", dict, read, err)
+	}
+	if decoded, err := DecodeChunk(data, FlagDict, read); err != nil || !bytes.Equal(decoded, value) {
+		t.Errorf("Expected chunk to hold %q, got %q (%v) instead", value, decoded, err)
+	}
+	if size, sum, err := ChunkSum(data, FlagDict, read); err != nil || size != e.RawSize || sum != e.Sum {
+		t.Errorf("Expected checksum of chunk to be the checksum of its value, got %d, %x (%v) instead", size, sum, err)
+	}
+}
+
+func TestDecodeIndex_SkipsUnknownSections(t *testing.T) {
+	data := append(append(uvarint(0), uvarint(0)...), uvarint(1)...) // no features, no entries, one section
+	data = append(data, uvarint(100)...)
+	data = append(data, uvarint(3)...)
+	data = append(data, 1, 2, 3)
+	if _, err := DecodeIndex(data, 100, Version); err != nil {
+		t.Errorf("Expected unknown section to be skipped, got %v instead", err)
+	}
+}
+
+func TestDecodeIndex_UnknownFeatures(t *testing.T) {
+	data := append(append(uvarint(1<<40), uvarint(0)...), uvarint(0)...)
+	if _, err := DecodeIndex(data, 100, Version); !errors.Is(err, ErrVersion) {
+		t.Errorf("Expected index with unknown features to be rejected with ErrVersion, got %v instead", err)
+	}
+	// Index blocks of version 2 have no features
+	if _, err := DecodeIndex(data[len(uvarint(1<<40)):], 100, 2); err != nil {
+		t.Errorf("Expected version 2 index without features to be read, got %v instead", err)
+	}
+}
+
+func TestEncodeIndex_FeatureNil(t *testing.T) {
+	nilEntry := Entry{Key: "a", Offset: PreambleSize, Flags: FlagRaw | FlagNil}
+	indexes := map[string]Index{
+		"entry":     {Entries: []Entry{nilEntry}},
+		"tombstone": {Tombstones: []Tombstone{{Entry: nilEntry, Deleted: 1}}},
+		"trash":     {Trash: []Trashed{{Entry: nilEntry, Generation: 1, Time: 1}}},
+	}
+	for name, index := range indexes {
+		data := EncodeIndex(index)
+		if features, _ := binary.Uvarint(data); features != FeatureNil {
+			t.Errorf("Expected %s flagged nil to require FeatureNil, got features %#x instead", name, features)
+		}
+		if _, err := DecodeIndex(data, PreambleSize, Version); err != nil {
+			t.Errorf("Expected index with %s flagged nil to be read, got %v instead", name, err)
+		}
+	}
+	if features, _ := binary.Uvarint(EncodeIndex(Index{Entries: []Entry{{Key: "a", Offset: PreambleSize, Flags: FlagRaw}}})); features != 0 {
+		t.Errorf("Expected index without nil entries to require no features, got %#x instead", features)
+	}
+}
+
+func TestEncodeIndex_FeatureDigests(t *testing.T) {
+	entries := []Entry{{Key: "a", Offset: PreambleSize, Size: 1, Digest: string(Digest(HashXXH64, []byte("a")))}}
+	data := EncodeIndex(Index{Entries: entries, Hash: HashXXH64})
+	if features, _ := binary.Uvarint(data); features != FeatureDigests {
+		t.Errorf("Expected index with digests to require FeatureDigests, got features %#x instead", features)
+	}
+	if index, err := DecodeIndex(data, PreambleSize+1, Version); err != nil || index.Hash != HashXXH64 {
+		t.Errorf("Expected index with digests to be read, got hash %d (%v) instead", index.Hash, err)
+	}
+}
+
+func TestDecodeIndex_Generation(t *testing.T) {
+	index, err := DecodeIndex(EncodeIndex(Index{Generation: 42}), PreambleSize, Version)
+	if err != nil || index.Generation != 42 {
+		t.Errorf("Expected generation 42, got %d (%v) instead", index.Generation, err)
+	}
+	if index, err := DecodeIndex(EncodeIndex(Index{}), PreambleSize, Version); err != nil || index.Generation != 0 {
+		t.Errorf("Expected generation 0 without generation section, got %d (%v) instead", index.Generation, err)
+	}
+}
+
+func TestDecodeIndex_Meta(t *testing.T) {
+	meta := Meta{Application: "app", Created: -42, Values: map[string]string{"b": "2", "a": "", "": "empty"}}
+	index, err := DecodeIndex(EncodeIndex(Index{Meta: meta}), PreambleSize, Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index.Meta, meta) {
+		t.Errorf("Expected metadata %+v, got %+v instead", meta, index.Meta)
+	}
+	if index, err := DecodeIndex(EncodeIndex(Index{}), PreambleSize, Version); err != nil || !index.Meta.empty() {
+		t.Errorf("Expected no metadata without metadata section, got %+v (%v) instead", index.Meta, err)
+	}
+	data := EncodeIndex(Index{Meta: meta})
+	if _, err := DecodeIndex(data[:len(data)-1], PreambleSize, Version); err == nil {
+		t.Error("Expected truncated metadata section to be rejected")
+	}
+}
+
+func TestDecodeIndex_Signature(t *testing.T) {
+	signature := bytes.Repeat([]byte{7}, SignatureSize)
+	index, err := DecodeIndex(EncodeIndex(Index{Signature: signature}), PreambleSize, Version)
+	if err != nil || !bytes.Equal(index.Signature, signature) {
+		t.Errorf("Expected signature to be decoded, got %x (%v) instead", index.Signature, err)
+	}
+	if _, err := DecodeIndex(EncodeIndex(Index{Signature: signature[1:]}), PreambleSize, Version); err == nil {
+		t.Error("Expected signature of invalid size to be rejected")
+	}
+}
+
+func TestDecodeIndex_Access(t *testing.T) {
+	stats := []Access{{Key: "a", Reads: 3, Last: 1700000000000000000}, {Key: "b", Reads: 1, Last: -1}}
+	index, err := DecodeIndex(EncodeIndex(Index{Access: stats}), PreambleSize, Version)
+	if err != nil || !reflect.DeepEqual(index.Access, stats) {
+		t.Errorf("Expected access statistics %v, got %v (%v) instead", stats, index.Access, err)
+	}
+	if index, _ := DecodeIndex(EncodeIndex(Index{Access: []Access{}}), PreambleSize, Version); index.Access == nil {
+		t.Error("Expected empty access statistics to be kept")
+	}
+
+	written := []Access{{Key: "a", Reads: 3, Last: 2, Written: 1}, {Key: "b"}}
+	section := encodeAccess(written)
+	if got, err := decodeAccess(section); err != nil || !reflect.DeepEqual(got, written) {
+		t.Errorf("Expected write times %v, got %v (%v) instead", written, got, err)
+	}
+	if _, err := decodeAccess(section[:len(section)-1]); err == nil {
+		t.Error("Expected truncated write times to be rejected")
+	}
+}
+
+func TestDecodeIndex_Sealed(t *testing.T) {
+	index, err := DecodeIndex(EncodeIndex(Index{Sealed: 1700000000000000000}), PreambleSize, Version)
+	if err != nil || index.Sealed != 1700000000000000000 {
+		t.Errorf("Expected seal time to be decoded, got %d (%v) instead", index.Sealed, err)
+	}
+	if index, _ := DecodeIndex(EncodeIndex(Index{}), PreambleSize, Version); index.Sealed != 0 {
+		t.Errorf("Expected unsealed index, got seal time %d instead", index.Sealed)
+	}
+}
+
+func TestDecodeIndex_Secondary(t *testing.T) {
+	indexes := []Secondary{{Name: "tags", Keys: []SecondaryKey{{Key: "a", Values: []string{"tcp", "udp"}}, {Key: "b", Values: []string{"udp"}}}}}
+	index, err := DecodeIndex(EncodeIndex(Index{Secondary: indexes}), PreambleSize, Version)
+	if err != nil || !reflect.DeepEqual(index.Secondary, indexes) {
+		t.Errorf("Expected secondary indexes %v, got %v (%v) instead", indexes, index.Secondary, err)
+	}
+	section := encodeSecondary(indexes)
+	if _, err := decodeSecondary(section[:len(section)-2]); err == nil {
+		t.Error("Expected truncated secondary index section to be rejected")
+	}
+}
+
+func TestDecodeIndex_Expiry(t *testing.T) {
+	expiry := []Expiry{{Key: "a", Time: 1700000000000000000}, {Key: "b", Time: 1}}
+	index, err := DecodeIndex(EncodeIndex(Index{Expiry: expiry}), PreambleSize, Version)
+	if err != nil || !reflect.DeepEqual(index.Expiry, expiry) {
+		t.Errorf("Expected expiry times %v, got %v (%v) instead", expiry, index.Expiry, err)
+	}
+	section := encodeExpiry(expiry)
+	if _, err := decodeExpiry(section[:len(section)-1]); err == nil {
+		t.Error("Expected truncated expiry section to be rejected")
+	}
+}
+
+func TestDecodeIndex_Audit(t *testing.T) {
+	records := []AuditRecord{
+		{Time: 1700000000000000000, Generation: 1, Actor: "alice", Key: "a", Flags: AuditNew, NewSum: 42},
+		{Time: 1700000000000000001, Generation: 2, Key: "a", Flags: AuditOld, OldSum: 42},
+	}
+	decoded, err := DecodeAudit(EncodeAudit(records))
+	if err != nil || !reflect.DeepEqual(decoded, records) {
+		t.Errorf("Expected audit records %v, got %v (%v) instead", records, decoded, err)
+	}
+	data := EncodeAudit(records)
+	if _, err := DecodeAudit(data[:len(data)-1]); err == nil {
+		t.Error("Expected truncated audit records to be rejected")
+	}
+	chunks := []Base{{Offset: PreambleSize, Size: 10, Sum: 7}}
+	index, err := DecodeIndex(EncodeIndex(Index{Audit: chunks, Offset: PreambleSize + 10}), PreambleSize+10, Version)
+	if err != nil || !reflect.DeepEqual(index.Audit, chunks) {
+		t.Errorf("Expected audit chunks %v, got %v (%v) instead", chunks, index.Audit, err)
+	}
+}
+
+func TestDecodeIndex_Tombstones(t *testing.T) {
+	tombstones := []Tombstone{
+		{Entry: Entry{Key: "a", Offset: PreambleSize, Size: 4, RawSize: 4, Sum: 1, Flags: FlagRaw}, Deleted: 1700000000000000000},
+		{Entry: Entry{Key: "b", Offset: PreambleSize + 4, Size: 4, RawSize: 8}, Deleted: 1700000000000000001, Expires: 1800000000000000000},
+	}
+	index, err := DecodeIndex(EncodeIndex(Index{Tombstones: tombstones}), PreambleSize+8, Version)
+	if err != nil || !reflect.DeepEqual(index.Tombstones, tombstones) {
+		t.Errorf("Expected tombstones %v, got %v (%v) instead", tombstones, index.Tombstones, err)
+	}
+	if _, err := DecodeIndex(EncodeIndex(Index{Tombstones: tombstones}), PreambleSize+4, Version); err == nil {
+		t.Error("Expected tombstone out of data bounds to be rejected")
+	}
+}
+
+func TestDecodeIndex_Trash(t *testing.T) {
+	trash := []Trashed{
+		{Entry: Entry{Key: "b", Offset: PreambleSize, Size: 4, RawSize: 4, Flags: FlagRaw}, Generation: 2, Time: 1700000000000000000},
+		{Entry: Entry{Key: "a", Offset: PreambleSize, Size: 4, RawSize: 4, Flags: FlagRaw}, Generation: 3, Time: 1700000000000000001, Expires: 1},
+	}
+	index, err := DecodeIndex(EncodeIndex(Index{Trash: trash}), PreambleSize+4, Version)
+	if err != nil || !reflect.DeepEqual(index.Trash, trash) {
+		t.Errorf("Expected trash %v, got %v (%v) instead", trash, index.Trash, err)
+	}
+}
+
+func TestDecodeIndex_Collation(t *testing.T) {
+	index, err := DecodeIndex(EncodeIndex(Index{Collation: "natural"}), PreambleSize, Version)
+	if err != nil || index.Collation != "natural" {
+		t.Errorf("Expected collation natural, got %q (%v) instead", index.Collation, err)
+	}
+}
+
+func TestDecodeIndex_Digests(t *testing.T) {
+	digest := string(Digest(HashSHA256, []byte("a")))
+	entries := []Entry{{Key: "a", Offset: PreambleSize, Size: 1, Digest: digest}, {Key: "b", Offset: PreambleSize, Size: 1}}
+	index, err := DecodeIndex(EncodeIndex(Index{Entries: entries, Hash: HashSHA256}), PreambleSize+1, Version)
+	if err != nil || index.Hash != HashSHA256 || !reflect.DeepEqual(index.Entries, entries) {
+		t.Errorf("Expected entries %v hashed with SHA-256, got %v hashed with %d (%v) instead", entries, index.Entries, index.Hash, err)
+	}
+
+	entries[0].Digest = digest[:8]
+	if _, err := DecodeIndex(EncodeIndex(Index{Entries: entries, Hash: HashSHA256}), PreambleSize+1, Version); err == nil {
+		t.Error("Expected digest of another size to be rejected")
+	}
+	if size := len(Digest(HashXXH64, nil)); size != DigestSize(HashXXH64) {
+		t.Errorf("Expected digests of %d bytes, got %d instead", DigestSize(HashXXH64), size)
+	}
+}
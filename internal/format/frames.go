@@ -0,0 +1,223 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Chunks flagged with FlagFramed hold a value compressed with brotli in independent frames, so that parts of
+// the value are read and verified without decompressing the whole value:
+//
+//	uvarint raw size of frames | uvarint count of frames
+//	uvarint frame size | uint32 frame checksum
+//	...
+//	frames
+//
+// Every frame but the last holds the raw size of frames of the value, the checksums are CRC-32 (Castagnoli)
+// of the uncompressed frames. Framed chunks are neither raw, compressed with the dictionary nor deltas.
+
+// errFrameChecksum is returned for frames that don't match their checksum
+var errFrameChecksum = errors.New("frame checksum mismatch")
+
+// Frames is the frame index of a framed chunk
+type Frames struct {
+	Size   int64 // Size is the raw size of every frame but the last
+	Frames []Frame
+}
+
+// Frame locates a frame of a framed chunk
+type Frame struct {
+	Offset int64  // Offset of the frame from the beginning of the chunk
+	Size   int64  // Size of the compressed frame
+	Sum    uint32 // Sum is the checksum of the uncompressed frame
+}
+
+// Find returns the indexes of the first and the last frame holding the length bytes of the value from off on
+func (f Frames) Find(off, length int64) (first, last int) {
+	if f.Size <= 0 || len(f.Frames) == 0 {
+		return 0, -1
+	}
+	first, last = int(off/f.Size), int((off+length-1)/f.Size)
+	if last >= len(f.Frames) {
+		last = len(f.Frames) - 1
+	}
+	return first, last
+}
+
+// CompressFrames returns the framed chunk of value, compressed in frames of frameSize raw bytes with brotli
+// with a window of windowBits
+func CompressFrames(value []byte, frameSize, windowBits int) ([]byte, error) {
+	if frameSize <= 0 {
+		return nil, fmt.Errorf("invalid frame size %d", frameSize)
+	}
+	var frames [][]byte
+	var header bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	putUvarint := func(v uint64) {
+		header.Write(vb[:binary.PutUvarint(vb[:], v)])
+	}
+	putUvarint(uint64(frameSize))
+	putUvarint(uint64((len(value) + frameSize - 1) / frameSize))
+	for off := 0; off < len(value); off += frameSize {
+		end := off + frameSize
+		if end > len(value) {
+			end = len(value)
+		}
+		frame, err := Compress(value[off:end], windowBits)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+		putUvarint(uint64(len(frame)))
+		binary.LittleEndian.PutUint32(vb[:], Checksum(value[off:end]))
+		header.Write(vb[:4])
+	}
+	for _, frame := range frames {
+		header.Write(frame)
+	}
+	return header.Bytes(), nil
+}
+
+// ReadFrames reads the frame index of the framed chunk of size bytes read from r
+func ReadFrames(r io.Reader, size int64) (Frames, error) {
+	br := &countingByteReader{r: bufio.NewReaderSize(r, 512)}
+	f, err := readFrames(br, size)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return f, err
+}
+
+// readFrames reads the frame index of a framed chunk of size bytes from r, size is negative if it isn't known
+func readFrames(r *countingByteReader, size int64) (Frames, error) {
+	frameSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Frames{}, err
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Frames{}, err
+	}
+	// Every frame takes at least 5 bytes of the index, frames are appended as they are read so that
+	// the index of a chunk of unknown size takes no more memory than it reads
+	if size >= 0 && count > uint64(size)/5 || frameSize == 0 && count > 0 || frameSize > math.MaxInt32 {
+		return Frames{}, errors.New("invalid frame index")
+	}
+	f := Frames{Size: int64(frameSize)}
+	var sb [4]byte
+	for i := uint64(0); i < count; i++ {
+		fs, err := binary.ReadUvarint(r)
+		if err != nil {
+			return Frames{}, err
+		}
+		// Brotli expands incompressible data by a few bytes only
+		if fs > frameSize+frameSize/16+64 {
+			return Frames{}, errors.New("invalid frame size")
+		}
+		for j := range sb {
+			if sb[j], err = r.ReadByte(); err != nil {
+				return Frames{}, err
+			}
+		}
+		f.Frames = append(f.Frames, Frame{Size: int64(fs), Sum: binary.LittleEndian.Uint32(sb[:])})
+	}
+	offset := r.n
+	for i := range f.Frames {
+		f.Frames[i].Offset = offset
+		offset += f.Frames[i].Size
+	}
+	if size >= 0 && offset != size {
+		return Frames{}, errors.New("frames are out of chunk bounds")
+	}
+	return f, nil
+}
+
+// DecodeFrame returns the value held by the frame f, verifying its checksum
+func DecodeFrame(data []byte, f Frame) ([]byte, error) {
+	value, err := Decompress(data)
+	if err != nil {
+		return nil, err
+	}
+	if Checksum(value) != f.Sum {
+		return nil, errFrameChecksum
+	}
+	return value, nil
+}
+
+// countingByteReader counts the bytes read from r
+type countingByteReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (r *countingByteReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
+
+func (r *countingByteReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// framedReader reads the value of a framed chunk frame by frame, verifying every frame
+type framedReader struct {
+	r      *countingByteReader
+	frames []Frame
+	value  []byte
+	err    error
+	read   bool // read is true once the frame index is read
+}
+
+func (fr *framedReader) Read(p []byte) (int, error) {
+	for len(fr.value) == 0 && fr.err == nil {
+		fr.next()
+	}
+	if len(fr.value) == 0 {
+		return 0, fr.err
+	}
+	n := copy(p, fr.value)
+	fr.value = fr.value[n:]
+	return n, nil
+}
+
+// next decodes the next frame
+func (fr *framedReader) next() {
+	if !fr.read {
+		fr.read = true
+		f, err := readFrames(fr.r, -1)
+		if err != nil {
+			fr.err = unexpected(err)
+			return
+		}
+		fr.frames = f.Frames
+	}
+	if len(fr.frames) == 0 {
+		fr.err = io.EOF
+		return
+	}
+	data := make([]byte, fr.frames[0].Size)
+	if _, err := io.ReadFull(fr.r, data); err != nil {
+		fr.err = unexpected(err)
+		return
+	}
+	fr.value, fr.err = DecodeFrame(data, fr.frames[0])
+	fr.frames = fr.frames[1:]
+}
+
+// unexpected returns io.ErrUnexpectedEOF for io.EOF, as values end with their last frame
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package format
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestCompressFrames(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	value := make([]byte, 10000)
+	rnd.Read(value[:5000])
+
+	for _, v := range [][]byte{value, value[:4096], nil} {
+		data, err := CompressFrames(v, 4096, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := DecodeChunk(data, FlagFramed, nil); err != nil || !bytes.Equal(got, v) {
+			t.Errorf("Expected framed chunk of %d bytes to decode, got %d bytes and %v instead", len(v), len(got), err)
+		}
+		if rawSize, sum, err := ChunkSum(data, FlagFramed, nil); err != nil || rawSize != int64(len(v)) || sum != Checksum(v) {
+			t.Errorf("Expected checksum of framed chunk to match, got %v instead", err)
+		}
+	}
+
+	data, _ := CompressFrames(value, 4096, 16)
+	frames, err := ReadFrames(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frames.Size != 4096 || len(frames.Frames) != 3 {
+		t.Fatalf("Expected 3 frames of 4096 bytes, got %d frames of %d bytes instead", len(frames.Frames), frames.Size)
+	}
+	if first, last := frames.Find(5000, 4000); first != 1 || last != 2 {
+		t.Errorf("Expected range to span frames 1 to 2, got %d to %d instead", first, last)
+	}
+	f := frames.Frames[1]
+	if frame, err := DecodeFrame(data[f.Offset:f.Offset+f.Size], f); err != nil || !bytes.Equal(frame, value[4096:8192]) {
+		t.Errorf("Expected frame to decode, got %v instead", err)
+	}
+}
+
+func TestReadFrames_Corrupted(t *testing.T) {
+	value := bytes.Repeat([]byte("framed value "), 1000)
+	data, _ := CompressFrames(value, 4096, 16)
+	if _, err := ReadFrames(bytes.NewReader(data), int64(len(data))+1); err == nil {
+		t.Error("Expected ReadFrames to reject frames out of chunk bounds")
+	}
+	if _, err := ReadFrames(bytes.NewReader(data[:3]), 3); err == nil {
+		t.Error("Expected ReadFrames to reject truncated frame index")
+	}
+
+	frames, _ := ReadFrames(bytes.NewReader(data), int64(len(data)))
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	if _, err := DecodeChunk(corrupted, FlagFramed, nil); err == nil {
+		t.Error("Expected DecodeChunk to reject corrupted frame")
+	}
+	f := frames.Frames[0]
+	f.Sum++
+	if _, err := DecodeFrame(data[f.Offset:f.Offset+f.Size], f); err == nil {
+		t.Error("Expected DecodeFrame to reject frame not matching its checksum")
+	}
+}
+
+func TestDecodeIndex_FramedFlags(t *testing.T) {
+	entries := []Entry{{Key: "a", Offset: PreambleSize, Size: 10, Flags: FlagFramed | FlagRaw}}
+	if _, err := DecodeIndex(EncodeIndex(Index{Entries: entries}), PreambleSize+10, Version); err == nil {
+		t.Error("Expected DecodeIndex to reject raw framed chunks")
+	}
+}
@@ -0,0 +1,115 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// legacyFile returns a legacy file holding values under keys
+func legacyFile(t testing.TB, keys []string, values [][]byte) []byte {
+	var buf bytes.Buffer
+	var sb [4]byte
+	binary.LittleEndian.PutUint32(sb[:], uint32(len(keys)))
+	buf.Write(sb[:])
+	var joined []byte
+	for i, k := range keys {
+		if i > 0 {
+			joined = append(joined, '#')
+		}
+		joined = append(joined, k...)
+	}
+	zkeys, err := Compress(joined, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint32(sb[:], uint32(len(zkeys)))
+	buf.Write(sb[:])
+	for _, v := range values {
+		binary.LittleEndian.PutUint32(sb[:], uint32(len(v)))
+		buf.Write(sb[:])
+	}
+	buf.Write(zkeys)
+	for _, v := range values {
+		buf.Write(v)
+	}
+	return buf.Bytes()
+}
+
+func TestReadLegacyIndex_Bounds(t *testing.T) {
+	file := legacyFile(t, []string{"a", "b"}, [][]byte{[]byte("value a"), []byte("value b")})
+	entries, err := ReadLegacyIndex(bytes.NewReader(file))
+	if err != nil || len(entries) != 2 || entries[1].Key != "b" || entries[1].Size != 7 {
+		t.Fatalf("Expected 2 entries, got %v (%v) instead", entries, err)
+	}
+
+	huge := append([]byte(nil), file...)
+	binary.LittleEndian.PutUint32(huge, 0xffffffff)
+	truncated := file[:len(file)-1]
+	oversized := append([]byte(nil), file...)
+	binary.LittleEndian.PutUint32(oversized[8:], 1<<20)
+	for name, data := range map[string][]byte{"huge count": huge, "truncated": truncated, "oversized chunk": oversized, "empty": nil} {
+		if _, err := ReadLegacyIndex(bytes.NewReader(data)); !errors.Is(err, ErrCorruptHeader) {
+			t.Errorf("%s: Expected ErrCorruptHeader, got %v instead", name, err)
+		}
+	}
+}
+
+func TestReadIndex_Corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	_ = WritePreamble(&buf)
+	_ = WriteIndex(&buf, Index{Offset: PreambleSize}, 16)
+	data := buf.Bytes()
+	data[PreambleSize] ^= 0xff
+	if _, err := ReadIndex(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrCorruptHeader) {
+		t.Errorf("Expected ErrCorruptHeader for a damaged index, got %v instead", err)
+	}
+}
+
+func FuzzReadLegacyIndex(f *testing.F) {
+	f.Add(legacyFile(f, []string{"a", "b"}, [][]byte{[]byte("value a"), []byte("value b")}))
+	f.Add(legacyFile(f, nil, nil))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entries, err := ReadLegacyIndex(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			if e.Offset < 0 || e.Size < 0 || e.Offset+e.Size > int64(len(data)) {
+				t.Fatalf("Entry %q is out of file bounds", e.Key)
+			}
+		}
+	})
+}
+
+func FuzzReadIndex(f *testing.F) {
+	var buf bytes.Buffer
+	_ = WritePreamble(&buf)
+	buf.WriteString("value")
+	index := Index{
+		Entries:    []Entry{{Key: "key", Offset: PreambleSize, Size: 5, RawSize: 5, Sum: Checksum([]byte("value")), Flags: FlagRaw}},
+		Generation: 1,
+		Offset:     int64(buf.Len()),
+	}
+	_ = WriteIndex(&buf, index, 16)
+	f.Add(buf.Bytes())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		index, err := ReadIndex(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		for _, e := range index.Entries {
+			if e.Offset < PreambleSize || e.Size < 0 || e.Offset+e.Size > index.Offset {
+				t.Fatalf("Entry %q is out of data bounds", e.Key)
+			}
+		}
+	})
+}
+
+func FuzzDecodeIndex(f *testing.F) {
+	f.Add(EncodeIndex(Index{Entries: []Entry{{Key: "a", Offset: PreambleSize, Size: 10, Flags: FlagRaw}}, Generation: 3}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = DecodeIndex(data, 1<<20, Version)
+	})
+}
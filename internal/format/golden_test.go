@@ -0,0 +1,176 @@
+package format
+
+import (
+	"bytes"
+	"embed"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// golden holds the golden files of testdata, named after their format version and their content
+//
+//go:embed testdata/*.golden
+var golden embed.FS
+
+// goldenFiles are the golden files, which pin the layout of store files: files in every version must stay readable,
+// and files in the current version must be written byte for byte the same. The value of every key is the key itself
+var goldenFiles = []struct {
+	version  int
+	name     string
+	keys     []string
+	sections Index
+}{
+	{version: 0, name: "empty"},
+	{version: 0, name: "many", keys: []string{"a", "b/c", "long key with spaces"}},
+	{version: 1, name: "empty"},
+	{version: 1, name: "many", keys: []string{"", "a", "b/c", "long key with spaces", "\xff\x00binary"}},
+	{version: 2, name: "empty"},
+	{version: 2, name: "single", keys: []string{"key"}},
+	{version: 2, name: "many", keys: []string{"", "a", "b/c", "long key with spaces", "\xff\x00binary"}},
+	{version: 2, name: "sections", keys: []string{"expiring", "key"}, sections: Index{
+		Generation: 42,
+		Meta:       Meta{Application: "golden", Created: 1600000000000000000, Values: map[string]string{"k": "v"}},
+		Expiry:     []Expiry{{Key: "expiring", Time: 1700000000000000000}},
+		Collation:  "natural",
+	}},
+	{version: 3, name: "empty"},
+	{version: 3, name: "single", keys: []string{"key"}},
+	{version: 3, name: "many", keys: []string{"", "a", "b/c", "long key with spaces", "\xff\x00binary"}},
+	{version: 3, name: "sections", keys: []string{"expiring", "key"}, sections: Index{
+		Generation: 42,
+		Meta:       Meta{Application: "golden", Created: 1600000000000000000, Values: map[string]string{"k": "v"}},
+		Expiry:     []Expiry{{Key: "expiring", Time: 1700000000000000000}},
+		Collation:  "natural",
+	}},
+	{version: 3, name: "nil", keys: []string{"key"}, sections: Index{
+		Tombstones: []Tombstone{{Entry: Entry{Key: "gone", Offset: PreambleSize, Flags: FlagRaw | FlagNil}, Deleted: 1700000000000000000}},
+	}},
+	{version: 3, name: "digests", keys: []string{"a", "key"}, sections: Index{Hash: HashSHA256}},
+}
+
+// version1File returns a complete file in format version 1 like writeFile, which entries have no flags
+// and which index has no sections
+func version1File(t *testing.T, keys ...string) []byte {
+	var buf bytes.Buffer
+	if err := WritePreamble(&buf); err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint16(buf.Bytes()[4:], 1)
+	index := uvarint(uint64(len(keys)))
+	for _, k := range keys {
+		data, err := Compress([]byte(k), 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index = append(index, uvarint(uint64(len(k)))...)
+		index = append(index, k...)
+		index = append(index, uvarint(uint64(buf.Len()))...)
+		index = append(index, uvarint(uint64(len(data)))...)
+		index = append(index, uvarint(uint64(len(k)))...)
+		var sb [4]byte
+		binary.LittleEndian.PutUint32(sb[:], Checksum([]byte(k)))
+		index = append(index, sb[:]...)
+		buf.Write(data)
+	}
+	offset := buf.Len()
+	data, err := Compress(index, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(data)
+	var tb [TrailerSize]byte
+	binary.LittleEndian.PutUint64(tb[0:], uint64(offset))
+	binary.LittleEndian.PutUint32(tb[8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(tb[12:], Checksum(data))
+	copy(tb[16:], Magic[:])
+	buf.Write(tb[:])
+	return buf.Bytes()
+}
+
+// goldenFile returns the file of a golden file written by the writer of its version, nil for versions which writer
+// is gone, which golden files are kept as they were written
+func goldenFile(t *testing.T, version int, keys []string, sections Index) []byte {
+	switch version {
+	case 0:
+		values := make([][]byte, len(keys))
+		for i, k := range keys {
+			data, err := Compress([]byte(k), 16)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values[i] = data
+		}
+		return legacyFile(t, keys, values)
+	case 1:
+		return version1File(t, keys...)
+	case Version:
+		return writeFileWith(t, sections, keys...)
+	default:
+		return nil
+	}
+}
+
+func TestGoldenFiles(t *testing.T) {
+	for _, g := range goldenFiles {
+		g := g
+		name := fmt.Sprintf("v%d-%s.golden", g.version, g.name)
+		t.Run(name, func(t *testing.T) {
+			if *update {
+				file := goldenFile(t, g.version, g.keys, g.sections)
+				if file == nil {
+					return
+				}
+				if err := os.WriteFile(filepath.Join("testdata", name), file, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			file, err := golden.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.version == Version && !bytes.Equal(goldenFile(t, g.version, g.keys, g.sections), file) {
+				t.Errorf("Expected file written in version %d to match %s", Version, name)
+			}
+
+			if version, err := ReadVersion(bytes.NewReader(file)); version != g.version || err != nil {
+				t.Fatalf("Expected version %d, got %d (%v) instead", g.version, version, err)
+			}
+			var index Index
+			if g.version == 0 {
+				index.Entries, err = ReadLegacyIndex(bytes.NewReader(file))
+			} else {
+				index, err = ReadIndex(bytes.NewReader(file), int64(len(file)))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(index.Entries) != len(g.keys) {
+				t.Fatalf("Expected %d entries, got %d instead", len(g.keys), len(index.Entries))
+			}
+			for i, e := range index.Entries {
+				value, err := DecodeChunk(file[e.Offset:e.Offset+e.Size], e.Flags, nil)
+				if err != nil || e.Key != g.keys[i] || string(value) != e.Key {
+					t.Errorf("Expected entry %d to hold %q, got %q holding %q (%v) instead", i, g.keys[i], e.Key, value, err)
+				}
+				if g.version > 0 && (e.RawSize != int64(len(value)) || e.Sum != Checksum(value)) {
+					t.Errorf("Expected entry %d to record the size and the checksum of its value", i)
+				}
+				if e.Digest != string(Digest(index.Hash, value)) {
+					t.Errorf("Expected entry %d to record the digest of its value", i)
+				}
+			}
+			index.Entries, index.Offset = nil, 0
+			if !reflect.DeepEqual(index, g.sections) {
+				t.Errorf("Expected sections %+v, got %+v instead", g.sections, index)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package format
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Store files may keep their index in a separate index file, so that the data file is only ever appended to.
+// The data file is flagged with PreambleIndexFile and the preamble is followed by the ID of the data file:
+//
+//	preamble  magic "SNDK" | uint16 version | uint16 flags, with PreambleIndexFile
+//	file ID   random ID of the data file
+//	chunks    values, back to back
+//
+// The index file is laid out like a store file without chunks, its index block holds the data file section,
+// which ties the index to the ID of its data file and records the size of the data file it indexes:
+//
+//	preamble  magic "SNDK" | uint16 version | uint16 flags (reserved)
+//	index     brotli-compressed index block
+//	trailer   uint64 index offset | uint32 index size | uint32 index checksum | magic "SNDK"
+//
+// Chunks appended to the data file past the recorded size aren't part of the store until a new index file
+// records them, and index files that don't hold the ID of the data file aren't its index.
+
+const (
+	// PreambleIndexFile flags the preamble of data files which index is in an index file
+	PreambleIndexFile = 1 << iota
+
+	// FileIDSize is the size of the ID of a data file
+	FileIDSize = 16
+)
+
+// FileID identifies a data file, it is chosen at random when the data file is created
+type FileID [FileIDSize]byte
+
+// DataFile locates the chunks of an index file
+type DataFile struct {
+	ID   FileID
+	Size int64 // Size is the size of the data file holding the chunks of the index
+}
+
+// NewFileID returns a random file ID
+func NewFileID() (FileID, error) {
+	var id FileID
+	_, err := rand.Read(id[:])
+	return id, err
+}
+
+// WriteDataPreamble writes the preamble of a data file flagged with PreambleIndexFile followed by its ID
+func WriteDataPreamble(w io.Writer, id FileID) error {
+	var b [PreambleSize + FileIDSize]byte
+	copy(b[:], Magic[:])
+	binary.LittleEndian.PutUint16(b[4:], Version)
+	binary.LittleEndian.PutUint16(b[6:], PreambleIndexFile)
+	copy(b[PreambleSize:], id[:])
+	_, err := w.Write(b[:])
+	return err
+}
+
+// ReadFileID returns the ID of a data file, ok is false for files that aren't flagged with PreambleIndexFile
+func ReadFileID(r io.ReaderAt) (id FileID, ok bool, err error) {
+	var b [PreambleSize + FileIDSize]byte
+	n, err := r.ReadAt(b[:], 0)
+	if n < PreambleSize || !bytes.Equal(b[:len(Magic)], Magic[:]) {
+		return id, false, nil
+	}
+	if binary.LittleEndian.Uint16(b[6:])&PreambleIndexFile == 0 {
+		return id, false, nil
+	}
+	if n < len(b) {
+		return id, false, headerError("read", fmt.Errorf("truncated data file ID: %v", err))
+	}
+	copy(id[:], b[PreambleSize:])
+	return id, true, nil
+}
+
+// ReadIndexFile reads the trailer at the end of an index file of size bytes, then reads, verifies and unmarshalls
+// the index, checking that every chunk lies inside the data file recorded by the index
+func ReadIndexFile(r io.ReaderAt, size int64) (Index, error) {
+	return Limits{}.ReadIndexFile(r, size)
+}
+
+// ReadIndexFile reads an index file like the function ReadIndexFile, within the limits
+func (l Limits) ReadIndexFile(r io.ReaderAt, size int64) (Index, error) {
+	version, offset, data, err := readIndexBlock(r, size)
+	if err != nil {
+		return Index{}, err
+	}
+	// Index files were introduced in version 2
+	if version < 2 {
+		return Index{}, fmt.Errorf("%w %d of index file", ErrVersion, version)
+	}
+	index, err := l.decodeBlock(data, math.MaxInt64, version)
+	if err == nil {
+		err = index.checkBounds()
+	}
+	if err != nil {
+		return Index{}, headerError("decode", err)
+	}
+	index.Offset = offset
+	return index, nil
+}
+
+// checkBounds checks that the chunks, the bases and the dictionary of an index file lie inside its data file
+func (index Index) checkBounds() error {
+	end := index.Data.Size
+	if end < PreambleSize+FileIDSize {
+		return errors.New("missing data file section")
+	}
+	for _, e := range index.Entries {
+		if !inBounds(e.Offset, e.Size, end) || e.Flags&baseFlags != 0 && !inBounds(e.Base.Offset, e.Base.Size, end) {
+			return fmt.Errorf("chunk of key %q is out of data bounds", e.Key)
+		}
+	}
+	if d := index.Dictionary; d.Size > 0 && !inBounds(d.Offset, d.Size, end) {
+		return errors.New("dictionary is out of data bounds")
+	}
+	for _, t := range index.Tombstones {
+		if !inBounds(t.Offset, t.Size, end) || t.Flags&baseFlags != 0 && !inBounds(t.Base.Offset, t.Base.Size, end) {
+			return fmt.Errorf("tombstone of key %q is out of data bounds", t.Key)
+		}
+	}
+	for _, t := range index.Trash {
+		if !inBounds(t.Offset, t.Size, end) || t.Flags&baseFlags != 0 && !inBounds(t.Base.Offset, t.Base.Size, end) {
+			return fmt.Errorf("trashed value of key %q is out of data bounds", t.Key)
+		}
+	}
+	for _, a := range index.Audit {
+		if !inBounds(a.Offset, a.Size, end) {
+			return errors.New("audit chunk is out of data bounds")
+		}
+	}
+	return nil
+}
+
+// inBounds returns true if size bytes at offset lie inside [PreambleSize, end)
+func inBounds(offset, size, end int64) bool {
+	return offset >= PreambleSize && size >= 0 && offset <= end && size <= end-offset
+}
+
+// decodeDataFile unmarshals the data file section
+func decodeDataFile(section []byte) (DataFile, error) {
+	var d DataFile
+	if len(section) < FileIDSize {
+		return d, io.ErrUnexpectedEOF
+	}
+	copy(d.ID[:], section)
+	size, err := binary.ReadUvarint(bytes.NewReader(section[FileIDSize:]))
+	if err != nil {
+		return d, err
+	}
+	if size > math.MaxInt64 {
+		return d, fmt.Errorf("invalid data file size %d", size)
+	}
+	d.Size = int64(size)
+	return d, nil
+}
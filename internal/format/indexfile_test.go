@@ -0,0 +1,56 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// indexFile returns an index file holding index
+func indexFile(t *testing.T, index Index) []byte {
+	var buf bytes.Buffer
+	index.Offset = PreambleSize
+	if err := WritePreamble(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteIndex(&buf, index, 16); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadIndexFile(t *testing.T) {
+	id, err := NewFileID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data bytes.Buffer
+	_ = WriteDataPreamble(&data, id)
+	data.WriteString("value")
+	if got, ok, err := ReadFileID(bytes.NewReader(data.Bytes())); err != nil || !ok || got != id {
+		t.Errorf("Expected ID of data file, got %v and %v instead", ok, err)
+	}
+
+	entries := []Entry{{Key: "a", Offset: PreambleSize + FileIDSize, Size: 5, RawSize: 5, Sum: Checksum([]byte("value")), Flags: FlagRaw}}
+	file := indexFile(t, Index{Entries: entries, Generation: 3, Data: DataFile{ID: id, Size: int64(data.Len())}})
+	index, err := ReadIndexFile(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Data.ID != id || index.Data.Size != int64(data.Len()) || len(index.Entries) != 1 || index.Generation != 3 {
+		t.Errorf("Expected index of data file, got %+v instead", index)
+	}
+	if _, err := ReadIndex(bytes.NewReader(data.Bytes()), int64(data.Len())); !errors.Is(err, ErrCorruptHeader) {
+		t.Errorf("Expected ReadIndex to reject data file, got %v instead", err)
+	}
+
+	for name, index := range map[string]Index{
+		"missing data file section": {Entries: entries},
+		"chunk out of data file":    {Entries: entries, Data: DataFile{ID: id, Size: PreambleSize + FileIDSize + 4}},
+	} {
+		file := indexFile(t, index)
+		if _, err := ReadIndexFile(bytes.NewReader(file), int64(len(file))); !errors.Is(err, ErrCorruptHeader) {
+			t.Errorf("Expected ReadIndexFile to reject index with %s, got %v instead", name, err)
+		}
+	}
+}
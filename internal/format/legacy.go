@@ -0,0 +1,128 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/brotli"
+	"io"
+)
+
+// maxLegacyKeySize bounds the average size of the keys of legacy files, so that a corrupt keys chunk can't
+// decompress to more than the keys of its count could take
+const maxLegacyKeySize = 64 << 10
+
+// maxLegacyKeysSize bounds the size of the decompressed keys of legacy files
+const maxLegacyKeysSize = 1 << 30
+
+// ReadLegacyIndex read, decompress and unmarshall storage header written before format versioning.
+// Entries of legacy files have neither raw sizes nor checksums.
+// Legacy header format is
+// uint32 Count						- count of data chunks
+// uint32 Size of keys chunk		- compressed size of keys chunk
+// uint32 Size of first data chunk  - compressed size of data chunk
+// ...
+// uint32 Size of last  data chunk  - compressed size of data chunk
+// Compressed keys joined with "#", followed by compressed data chunks
+// Counts and sizes are checked against the size of the file before anything is allocated,
+// headers that don't fit the file fail with ErrCorruptHeader
+func ReadLegacyIndex(file io.ReadSeeker) ([]Entry, error) {
+	return Limits{}.ReadLegacyIndex(file)
+}
+
+// ReadLegacyIndex reads the header of a legacy file like the function ReadLegacyIndex, within the limits.
+// Keys are split as they are decompressed, so that the joined keys are never held in memory
+func (l Limits) ReadLegacyIndex(file io.ReadSeeker) ([]Entry, error) {
+	makeErr := func(action string, err error) error {
+		return headerError(action, err)
+	}
+
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, makeErr("seek position in", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, makeErr("seek position in", err)
+	}
+
+	// Read count of keys in storage and compressed size of keys
+	var sb [8]byte
+	if _, err := io.ReadFull(file, sb[:]); err != nil {
+		return nil, makeErr("read count of keys in", err)
+	}
+	kc := int64(binary.LittleEndian.Uint32(sb[:4]))
+	ks := int64(binary.LittleEndian.Uint32(sb[4:]))
+	offset := int64(len(sb)) + 4*kc + ks
+	if offset > size {
+		return nil, makeErr("locate", fmt.Errorf("%d keys of %d bytes are out of file bounds", kc, ks))
+	}
+	if err := l.checkEntries(uint64(kc)); err != nil {
+		return nil, err
+	}
+
+	// Read compressed sizes of data chunks
+	sizes := make([]byte, 4*kc)
+	if _, err := io.ReadFull(file, sizes); err != nil {
+		return nil, makeErr("read size of data chunk in", err)
+	}
+
+	// Read compressed header content
+	data := make([]byte, ks)
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, makeErr("read", err)
+	}
+
+	// Decompress header, bounded by the size the keys could take and by the limits
+	limit := (kc + 1) * maxLegacyKeySize
+	if limit > maxLegacyKeysSize {
+		limit = maxLegacyKeysSize
+	}
+	limited := l.IndexSize > 0 && l.IndexSize < limit
+	if limited {
+		limit = l.IndexSize
+	}
+	keys := bufio.NewReader(io.LimitReader(brotli.NewReader(bytes.NewReader(data)), limit+1))
+
+	// Unmarshall header data as it is decompressed, an empty store has no keys rather than a single empty key
+	capacity := kc
+	if capacity > maxPreallocEntries {
+		capacity = maxPreallocEntries
+	}
+	entries := make([]Entry, 0, capacity)
+	var read int64
+	for more := true; more; {
+		key, err := keys.ReadString('#')
+		read += int64(len(key))
+		switch {
+		case read > limit && limited:
+			return nil, fmt.Errorf("%w: keys exceed %d bytes", ErrIndexLimit, limit)
+		case read > limit:
+			return nil, makeErr("decompress", fmt.Errorf("keys exceed %d bytes", limit))
+		case err == io.EOF:
+			more = false
+			if read == 0 && kc == 0 {
+				continue
+			}
+		case err != nil:
+			return nil, makeErr("decompress", err)
+		default:
+			key = key[:len(key)-1]
+		}
+		if int64(len(entries)) == kc {
+			return nil, makeErr("decode keys in", fmt.Errorf("more than %d keys", kc))
+		}
+		i := len(entries)
+		entries = append(entries, Entry{Key: key, Offset: offset, Size: int64(binary.LittleEndian.Uint32(sizes[4*i:]))})
+		offset += entries[i].Size
+	}
+	if int64(len(entries)) != kc {
+		return nil, makeErr("decode keys in", fmt.Errorf("%d keys instead of %d", len(entries), kc))
+	}
+	if offset > size {
+		return nil, makeErr("locate", errors.New("data chunks are out of file bounds"))
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,120 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrIndexLimit is returned for indexes exceeding the Limits they are read with
+var ErrIndexLimit = errors.New("index exceeds limits")
+
+// maxInt is the largest int
+const maxInt = int(^uint(0) >> 1)
+
+// maxPreallocEntries bounds the entries allocated ahead of decoding them, so that a corrupt count of entries
+// fails with the end of the index block instead of allocating them
+const maxPreallocEntries = 1 << 16
+
+// Limits bound the indexes read from files, so that files with more entries or larger indexes than an application
+// expects fail with ErrIndexLimit instead of exhausting memory. Zero fields don't limit indexes further than the format
+type Limits struct {
+	Entries   int64 // Entries is the maximum count of entries of an index
+	IndexSize int64 // IndexSize is the maximum size of an index block once decompressed, or of the keys of a legacy file
+}
+
+// indexReader reads index blocks, Len bounds the count of bytes left to read
+type indexReader interface {
+	io.Reader
+	io.ByteReader
+	Len() int
+}
+
+// blockReader reads an index block as it is decompressed, so that the block is never held in memory decompressed.
+// It fails with ErrIndexLimit past limit bytes, unless limit is 0. The size of the block isn't known, so Len doesn't bound it
+type blockReader struct {
+	r     *bufio.Reader
+	limit int64
+	read  int64
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	if b.limit > 0 {
+		if b.read >= b.limit {
+			return 0, b.exceeded()
+		}
+		if rest := b.limit - b.read; int64(len(p)) > rest {
+			p = p[:rest]
+		}
+	}
+	n, err := b.r.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *blockReader) ReadByte() (byte, error) {
+	if b.limit > 0 && b.read >= b.limit {
+		return 0, b.exceeded()
+	}
+	c, err := b.r.ReadByte()
+	if err == nil {
+		b.read++
+	}
+	return c, err
+}
+
+func (b *blockReader) Len() int {
+	return maxInt
+}
+
+// exceeded returns io.EOF at the end of a block of limit bytes, and ErrIndexLimit for larger blocks
+func (b *blockReader) exceeded() error {
+	if _, err := b.r.Peek(1); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: index block exceeds %d bytes", ErrIndexLimit, b.limit)
+}
+
+// readBytes reads n bytes from r. Large byte strings are read into a growing buffer, so that a corrupt length
+// fails with the end of the block instead of allocating it
+func readBytes(r indexReader, n uint64) ([]byte, error) {
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	var err error
+	if n <= 64<<10 {
+		b := make([]byte, n)
+		if _, err = io.ReadFull(r, b); err == nil {
+			return b, nil
+		}
+	} else {
+		var buf bytes.Buffer
+		if _, err = io.CopyN(&buf, r, int64(n)); err == nil {
+			return buf.Bytes(), nil
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, err
+}
+
+// checkEntries returns ErrIndexLimit if count entries exceed the limits
+func (l Limits) checkEntries(count uint64) error {
+	if l.Entries > 0 && count > uint64(l.Entries) {
+		return fmt.Errorf("%w: %d entries exceed the limit of %d", ErrIndexLimit, count, l.Entries)
+	}
+	return nil
+}
+
+// decodeBlock decompresses and unmarshals a compressed index block of a file in format version, decoding it as
+// it is decompressed
+func (l Limits) decodeBlock(data []byte, end int64, version int) (index Index, err error) {
+	err = decompress(bytes.NewReader(data), 0, nil, func(zr io.Reader) error {
+		index, err = l.decodeIndex(&blockReader{r: bufio.NewReader(zr), limit: l.IndexSize}, end, version)
+		return err
+	})
+	return index, err
+}
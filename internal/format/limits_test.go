@@ -0,0 +1,61 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestLimits_ReadIndex(t *testing.T) {
+	file := writeFile(t, "a", "b", "c")
+	for _, l := range []Limits{{}, {Entries: 3}, {IndexSize: 1 << 10}} {
+		if index, err := l.ReadIndex(bytes.NewReader(file), int64(len(file))); err != nil || len(index.Entries) != 3 {
+			t.Errorf("Expected 3 entries within %+v, got %d (%v) instead", l, len(index.Entries), err)
+		}
+	}
+	for _, l := range []Limits{{Entries: 2}, {IndexSize: 16}} {
+		_, err := l.ReadIndex(bytes.NewReader(file), int64(len(file)))
+		if !errors.Is(err, ErrIndexLimit) || errors.Is(err, ErrCorruptHeader) {
+			t.Errorf("Expected ErrIndexLimit within %+v, got %v instead", l, err)
+		}
+	}
+}
+
+func TestLimits_ReadIndexFile(t *testing.T) {
+	file := indexFile(t, Index{Entries: []Entry{{Key: "a", Size: 1}, {Key: "b", Size: 1}}})
+	if _, err := (Limits{Entries: 1}).ReadIndexFile(bytes.NewReader(file), int64(len(file))); !errors.Is(err, ErrIndexLimit) {
+		t.Errorf("Expected ErrIndexLimit, got %v instead", err)
+	}
+}
+
+func TestLimits_ReadLegacyIndex(t *testing.T) {
+	file := legacyFile(t, []string{"a", "bb", "ccc"}, [][]byte{{1}, {2}, {3}})
+	entries, err := Limits{IndexSize: 8}.ReadLegacyIndex(bytes.NewReader(file))
+	if err != nil || len(entries) != 3 || entries[2].Key != "ccc" || entries[2].Offset != entries[1].Offset+1 {
+		t.Fatalf("Expected 3 entries, got %v (%v) instead", entries, err)
+	}
+	for _, l := range []Limits{{Entries: 2}, {IndexSize: 7}} {
+		if _, err := l.ReadLegacyIndex(bytes.NewReader(file)); !errors.Is(err, ErrIndexLimit) {
+			t.Errorf("Expected ErrIndexLimit within %+v, got %v instead", l, err)
+		}
+	}
+}
+
+func TestReadIndex_ManyEntries(t *testing.T) {
+	keys := make([]string, 200000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%07d", i)
+	}
+	file := writeFile(t, keys...)
+	index, err := ReadIndex(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Entries) != len(keys) || index.Entries[len(keys)-1].Key != keys[len(keys)-1] {
+		t.Errorf("Expected %d entries, got %d instead", len(keys), len(index.Entries))
+	}
+	if _, err := (Limits{Entries: int64(len(keys)) - 1}).ReadIndex(bytes.NewReader(file), int64(len(file))); !errors.Is(err, ErrIndexLimit) {
+		t.Errorf("Expected ErrIndexLimit, got %v instead", err)
+	}
+}
@@ -0,0 +1,194 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// LookupFooterSize is the size of the footer ending a lookup table
+const LookupFooterSize = 24
+
+var (
+	// LookupMagic ends the footer of lookup tables
+	LookupMagic = [4]byte{'S', 'N', 'D', 'L'}
+
+	// ErrNoLookup is returned by ReadLookup for files without a lookup table
+	ErrNoLookup = errors.New("file has no lookup table")
+)
+
+// Lookup is a lookup table of a file, written right before the index block by WriteLookup. It holds the entries
+// and the sections of the index uncompressed, so that entries are binary-searched in the file:
+//
+//	records   entry, encoded as in the index block, in key order
+//	...
+//	offsets   uint64 offset of every record from the first one
+//	sections  uvarint required features, then sections, encoded as in the index block
+//	footer    uint64 table offset | uint32 count of records | uint32 size of sections | uint32 checksum | magic "SNDL"
+//
+// The checksum is the checksum of the offsets and the sections. Readers unaware of lookup tables see them as dead bytes
+type Lookup struct {
+	Index   Index // Index holds the sections of the index, without entries
+	Offset  int64 // Offset of lookup table in file
+	offsets []uint64
+	end     int64 // end is the end of the records
+}
+
+// WriteLookup writes the lookup table of index, which entries must be in key order, at index.Offset.
+// It returns the size of the table, the index block goes right after it
+func WriteLookup(w io.Writer, index Index) (int64, error) {
+	if int64(len(index.Entries)) > MaxEntries {
+		return 0, fmt.Errorf("too many entries: %d, maximum is %d", len(index.Entries), uint64(MaxEntries))
+	}
+	var buf bytes.Buffer
+	offsets := make([]byte, 8*len(index.Entries))
+	for i, e := range index.Entries {
+		binary.LittleEndian.PutUint64(offsets[8*i:], uint64(buf.Len()))
+		encodeEntry(&buf, e)
+	}
+	var vb [binary.MaxVarintLen64]byte
+	sections := append(vb[:binary.PutUvarint(vb[:], index.features())], encodeSections(index)...)
+	buf.Write(offsets)
+	buf.Write(sections)
+
+	var fb [LookupFooterSize]byte
+	binary.LittleEndian.PutUint64(fb[0:], uint64(index.Offset))
+	binary.LittleEndian.PutUint32(fb[8:], uint32(len(index.Entries)))
+	binary.LittleEndian.PutUint32(fb[12:], uint32(len(sections)))
+	binary.LittleEndian.PutUint32(fb[16:], Checksum(buf.Bytes()[buf.Len()-len(offsets)-len(sections):]))
+	copy(fb[20:], LookupMagic[:])
+	buf.Write(fb[:])
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// ReadLookup reads the trailer at the end of a file of size bytes, then reads and verifies the offsets and the
+// sections of the lookup table before the index block. It returns ErrNoLookup if there is no lookup table
+func ReadLookup(r io.ReaderAt, size int64) (Lookup, error) {
+	makeErr := func(action string, err error) error {
+		return fmt.Errorf("unable to %s lookup table: %v", action, err)
+	}
+	version, err := ReadVersion(r)
+	if err != nil {
+		return Lookup{}, err
+	}
+	if version != Version {
+		return Lookup{}, ErrNoLookup
+	}
+	offset, _, _, err := readTrailer(r, size)
+	if err != nil {
+		return Lookup{}, err
+	}
+	if offset < PreambleSize+LookupFooterSize {
+		return Lookup{}, ErrNoLookup
+	}
+	var fb [LookupFooterSize]byte
+	if _, err := r.ReadAt(fb[:], offset-LookupFooterSize); err != nil {
+		return Lookup{}, makeErr("read footer of", err)
+	}
+	if !bytes.Equal(fb[20:], LookupMagic[:]) {
+		return Lookup{}, ErrNoLookup
+	}
+
+	l := Lookup{Offset: int64(binary.LittleEndian.Uint64(fb[0:]))}
+	count := int64(binary.LittleEndian.Uint32(fb[8:]))
+	ssize := int64(binary.LittleEndian.Uint32(fb[12:]))
+	l.end = offset - LookupFooterSize - ssize - 8*count
+	if l.Offset < PreambleSize || l.end < l.Offset {
+		return Lookup{}, makeErr("locate", errors.New("lookup table is out of file bounds"))
+	}
+	data := make([]byte, 8*count+ssize)
+	if _, err := r.ReadAt(data, l.end); err != nil {
+		return Lookup{}, makeErr("read", err)
+	}
+	if Checksum(data) != binary.LittleEndian.Uint32(fb[16:]) {
+		return Lookup{}, makeErr("verify", errors.New("checksum mismatch"))
+	}
+	l.offsets = make([]uint64, count)
+	for i := range l.offsets {
+		l.offsets[i] = binary.LittleEndian.Uint64(data[8*i:])
+		if l.offsets[i] > uint64(l.end-l.Offset) || i > 0 && l.offsets[i] <= l.offsets[i-1] {
+			return Lookup{}, makeErr("decode", fmt.Errorf("record %d is out of bounds", i))
+		}
+	}
+	sr := bytes.NewReader(data[8*count:])
+	features, err := binary.ReadUvarint(sr)
+	if err == nil {
+		err = checkFeatures(features)
+	}
+	if errors.Is(err, ErrVersion) {
+		return Lookup{}, err
+	}
+	if err == nil {
+		err = decodeSections(sr, l.Offset, &l.Index)
+	}
+	if err != nil {
+		return Lookup{}, makeErr("decode", err)
+	}
+	l.Index.Offset = offset
+	return l, nil
+}
+
+// Len returns the count of entries of the table
+func (l Lookup) Len() int {
+	return len(l.offsets)
+}
+
+// Entries reads the entries [i, i+n) of the table from r, checking that their chunks lie inside [PreambleSize, l.Offset)
+func (l Lookup) Entries(r io.ReaderAt, i, n int) ([]Entry, error) {
+	if i < 0 || n < 0 || i+n > len(l.offsets) {
+		return nil, fmt.Errorf("entries [%d, %d) are out of lookup table bounds", i, i+n)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	start, end := l.Offset+int64(l.offsets[i]), l.end
+	if i+n < len(l.offsets) {
+		end = l.Offset + int64(l.offsets[i+n])
+	}
+	data := make([]byte, end-start)
+	if _, err := r.ReadAt(data, start); err != nil {
+		return nil, fmt.Errorf("unable to read lookup table: %v", err)
+	}
+	entries := make([]Entry, n)
+	br := bytes.NewReader(data)
+	for j := range entries {
+		var err error
+		if entries[j], err = decodeEntry(br, l.Offset, Version); err != nil {
+			return nil, fmt.Errorf("unable to decode lookup table: %v", err)
+		}
+	}
+	return entries, nil
+}
+
+// Search returns the index of the first entry which key isn't less than key, reading keys from r
+func (l Lookup) Search(r io.ReaderAt, key string) (int, error) {
+	var err error
+	i := sort.Search(len(l.offsets), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var entries []Entry
+		if entries, err = l.Entries(r, i, 1); err != nil {
+			return true
+		}
+		return entries[0].Key >= key
+	})
+	return i, err
+}
+
+// Find returns the entry of key, reading it from r
+func (l Lookup) Find(r io.ReaderAt, key string) (Entry, bool, error) {
+	i, err := l.Search(r, key)
+	if err != nil || i == len(l.offsets) {
+		return Entry{}, false, err
+	}
+	entries, err := l.Entries(r, i, 1)
+	if err != nil || entries[0].Key != key {
+		return Entry{}, false, err
+	}
+	return entries[0], true, nil
+}
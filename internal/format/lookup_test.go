@@ -0,0 +1,87 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// writeLookupFile returns a complete file with a chunk for every key and a lookup table before the index
+func writeLookupFile(t *testing.T, keys ...string) []byte {
+	var buf bytes.Buffer
+	if err := WritePreamble(&buf); err != nil {
+		t.Fatal(err)
+	}
+	entries := make([]Entry, len(keys))
+	for i, k := range keys {
+		entries[i] = Entry{Key: k, Offset: int64(buf.Len()), Size: int64(len(k)), RawSize: int64(len(k)), Sum: Checksum([]byte(k)), Flags: FlagRaw}
+		buf.WriteString(k)
+	}
+	index := Index{Entries: entries, Generation: 7, Offset: int64(buf.Len())}
+	n, err := WriteLookup(&buf, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Offset += n
+	if err := WriteIndex(&buf, index, 16); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadLookup(t *testing.T) {
+	var keys []string
+	for i := 0; i < 100; i++ {
+		keys = append(keys, fmt.Sprintf("key%03d", i))
+	}
+	file := writeLookupFile(t, keys...)
+	r := bytes.NewReader(file)
+	l, err := ReadLookup(r, int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Len() != 100 || l.Index.Generation != 7 {
+		t.Fatalf("Expected 100 entries of generation 7, got %d entries of generation %d instead", l.Len(), l.Index.Generation)
+	}
+	e, ok, err := l.Find(r, "key042")
+	if err != nil || !ok || e.Key != "key042" || string(file[e.Offset:e.Offset+e.Size]) != "key042" {
+		t.Errorf("Expected to find key042, got %+v, %v (%v) instead", e, ok, err)
+	}
+	if _, ok, err := l.Find(r, "key042a"); ok || err != nil {
+		t.Errorf("Expected not to find absent key, got %v (%v) instead", ok, err)
+	}
+	if i, err := l.Search(r, "key05"); err != nil || i != 50 {
+		t.Errorf("Expected key05 to be searched at 50, got %d (%v) instead", i, err)
+	}
+	entries, err := l.Entries(r, 98, 2)
+	if err != nil || len(entries) != 2 || entries[1].Key != "key099" {
+		t.Errorf("Expected the last 2 entries, got %+v (%v) instead", entries, err)
+	}
+
+	// The index block still holds every entry
+	index, err := ReadIndex(r, int64(len(file)))
+	if err != nil || len(index.Entries) != 100 {
+		t.Errorf("Expected index of 100 entries, got %d (%v) instead", len(index.Entries), err)
+	}
+}
+
+func TestReadLookup_NoLookup(t *testing.T) {
+	file := writeFile(t, "a", "b")
+	if _, err := ReadLookup(bytes.NewReader(file), int64(len(file))); !errors.Is(err, ErrNoLookup) {
+		t.Errorf("Expected ErrNoLookup, got %v instead", err)
+	}
+}
+
+func TestReadLookup_Corrupted(t *testing.T) {
+	file := writeLookupFile(t, "a", "b")
+	index, err := ReadIndex(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Corrupt the sections, right before the footer
+	file[index.Offset-LookupFooterSize-1] ^= 0xff
+	if _, err := ReadLookup(bytes.NewReader(file), int64(len(file))); err == nil || errors.Is(err, ErrNoLookup) {
+		t.Errorf("Expected corrupted lookup table to be rejected, got %v instead", err)
+	}
+}
@@ -0,0 +1,174 @@
+package format
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Compressor state takes from kilobytes to tens of megabytes to allocate, more than compressing small values
+// takes, so brotli writers, brotli and deflate readers, and the buffers compressed data is written to are pooled.
+
+// maxPooledBufferSize is the capacity of buffers above which they aren't pooled, so that compressing a large
+// value doesn't keep its memory
+const maxPooledBufferSize = 4 << 20
+
+// maxWindowBits is the largest brotli window
+const maxWindowBits = 24
+
+var (
+	brotliWriters [maxWindowBits + 1]sync.Pool // brotliWriters holds brotli writers by window bits
+	brotliReaders sync.Pool
+	flateWriters  sync.Pool
+	flateReaders  sync.Pool
+	buffers       sync.Pool
+)
+
+// emptyReader is the source of pooled readers, so that they don't keep the data they read
+var emptyReader = bytes.NewReader(nil)
+
+// getBrotliWriter returns a brotli writer at default quality with a window of 1<<windowBits bytes writing to w
+func getBrotliWriter(w io.Writer, windowBits int) *brotli.Writer {
+	if windowBits < 0 || windowBits > maxWindowBits {
+		return brotli.NewWriterOptions(w, brotli.WriterOptions{Quality: brotli.DefaultCompression, LGWin: windowBits})
+	}
+	if zw, ok := brotliWriters[windowBits].Get().(*brotli.Writer); ok {
+		zw.Reset(w)
+		return zw
+	}
+	return brotli.NewWriterOptions(w, brotli.WriterOptions{Quality: brotli.DefaultCompression, LGWin: windowBits})
+}
+
+// putBrotliWriter returns a writer got from getBrotliWriter to the pool
+func putBrotliWriter(zw *brotli.Writer, windowBits int) {
+	if windowBits < 0 || windowBits > maxWindowBits {
+		return
+	}
+	zw.Reset(io.Discard)
+	brotliWriters[windowBits].Put(zw)
+}
+
+// getBrotliReader returns a brotli reader of r
+func getBrotliReader(r io.Reader) *brotli.Reader {
+	if zr, ok := brotliReaders.Get().(*brotli.Reader); ok && zr.Reset(r) == nil {
+		return zr
+	}
+	return brotli.NewReader(r)
+}
+
+// putBrotliReader returns a reader got from getBrotliReader to the pool
+func putBrotliReader(zr *brotli.Reader) {
+	if zr.Reset(emptyReader) == nil {
+		brotliReaders.Put(zr)
+	}
+}
+
+// flateWriter is a pooled deflate writer. It holds its dictionary, so that a dictionary at the same address
+// is the same dictionary
+type flateWriter struct {
+	*flate.Writer
+	dict []byte
+}
+
+// getFlateWriter returns a deflate writer at best compression with dict as preset dictionary writing to w
+func getFlateWriter(w io.Writer, dict []byte) (*flateWriter, error) {
+	if zw, ok := flateWriters.Get().(*flateWriter); ok && sameBytes(zw.dict, dict) {
+		zw.Reset(w)
+		return zw, nil
+	}
+	zw, err := flate.NewWriterDict(w, flate.BestCompression, dict)
+	if err != nil {
+		return nil, err
+	}
+	return &flateWriter{Writer: zw, dict: dict}, nil
+}
+
+// putFlateWriter returns a writer got from getFlateWriter to the pool
+func putFlateWriter(zw *flateWriter) {
+	zw.Reset(io.Discard)
+	flateWriters.Put(zw)
+}
+
+// sameBytes returns true if a and b are the same slice
+func sameBytes(a, b []byte) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// getFlateReader returns a deflate reader of r with dict as preset dictionary
+func getFlateReader(r io.Reader, dict []byte) io.ReadCloser {
+	if zr, ok := flateReaders.Get().(io.ReadCloser); ok && zr.(flate.Resetter).Reset(r, dict) == nil {
+		return zr
+	}
+	return flate.NewReaderDict(r, dict)
+}
+
+// putFlateReader returns a reader got from getFlateReader to the pool
+func putFlateReader(zr io.ReadCloser) {
+	if zr.(flate.Resetter).Reset(emptyReader, nil) == nil {
+		flateReaders.Put(zr)
+	}
+}
+
+// getBuffer returns an empty buffer
+func getBuffer() *bytes.Buffer {
+	if b, ok := buffers.Get().(*bytes.Buffer); ok {
+		return b
+	}
+	return new(bytes.Buffer)
+}
+
+// putBuffer returns a buffer got from getBuffer to the pool, unless it grew too large
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() <= maxPooledBufferSize {
+		b.Reset()
+		buffers.Put(b)
+	}
+}
+
+// decompress calls fn with a pooled reader of the value held by the chunk read from r, compressed with flags.
+// Flags are neither FlagRaw nor FlagFramed. Readers are pooled again only once fn succeeds and they are read to
+// the end, brotli readers keep the input left
+func decompress(r io.Reader, flags uint64, dict []byte, fn func(r io.Reader) error) error {
+	if flags&FlagDict != 0 {
+		zr := getFlateReader(r, dict)
+		err := fn(zr)
+		if err == nil && atEOF(zr) {
+			putFlateReader(zr)
+		}
+		return err
+	}
+	zr := getBrotliReader(r)
+	err := fn(zr)
+	if err == nil && atEOF(zr) {
+		putBrotliReader(zr)
+	}
+	return err
+}
+
+// atEOF returns true if r is read to the end
+func atEOF(r io.Reader) bool {
+	var b [1]byte
+	n, err := r.Read(b[:])
+	return n == 0 && err == io.EOF
+}
+
+// ReadChunk calls fn with a reader of the value held by the chunk read from r, as NewChunkReader returns, but
+// reusing decompressor state, so fn must not keep the reader
+func ReadChunk(r io.Reader, flags uint64, dict []byte, fn func(r io.Reader) error) error {
+	if flags&(FlagRaw|FlagFramed) != 0 {
+		return fn(NewChunkReader(r, flags, dict))
+	}
+	return decompress(r, flags, dict, fn)
+}
+
+// readAll reads the value held by the chunk read from r, compressed with flags, see decompress
+func readAll(r io.Reader, flags uint64, dict []byte) (value []byte, err error) {
+	err = decompress(r, flags, dict, func(r io.Reader) error {
+		value, err = io.ReadAll(r)
+		return err
+	})
+	return value, err
+}
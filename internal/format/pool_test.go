@@ -0,0 +1,38 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompress_Pooled(t *testing.T) {
+	values := [][]byte{[]byte("small value"), bytes.Repeat([]byte("larger value "), 1000), {}}
+	for i := 0; i < 3; i++ {
+		for _, windowBits := range []int{10, 16, 22} {
+			for _, value := range values {
+				data, err := Compress(value, windowBits)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// A corrupted chunk fails with the pooled reader and leaves it out of the pool
+				if _, err := Decompress(data[:len(data)/2]); err == nil && len(value) > 0 {
+					t.Error("Expected truncated chunk to fail decompression")
+				}
+				if got, err := Decompress(data); err != nil || !bytes.Equal(got, value) {
+					t.Errorf("Expected %d bytes with window bits %d, got %d (%v) instead", len(value), windowBits, len(got), err)
+				}
+			}
+		}
+		for _, dict := range [][]byte{[]byte("small larger value"), []byte("another dictionary")} {
+			for _, value := range values {
+				data, err := CompressDict(value, dict)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := DecodeChunk(data, FlagDict, dict); err != nil || !bytes.Equal(got, value) {
+					t.Errorf("Expected %d bytes with dictionary %q, got %d (%v) instead", len(value), dict, len(got), err)
+				}
+			}
+		}
+	}
+}
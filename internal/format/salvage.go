@@ -0,0 +1,94 @@
+package format
+
+import (
+	"bytes"
+	"github.com/andybalholm/brotli"
+	"io"
+)
+
+// salvageBlock is the size of the blocks files are scanned in for trailers
+const salvageBlock = 1 << 20
+
+// maxSalvageSize bounds the size of values decoded by ScanChunks
+const maxSalvageSize = 1 << 30
+
+// excessiveInput is the message of the error of the brotli decoder for streams followed by other data
+const excessiveInput = "brotli: excessive input"
+
+// FindTrailers returns the end of every trailer candidate in a file of size bytes, that is every position
+// following the magic, newest first. Every commit appends an index and a trailer, so a file whose last index
+// is damaged holds the indexes of earlier commits, read with ReadIndex up to the end of their trailers
+func FindTrailers(r io.ReaderAt, size int64) []int64 {
+	var ends []int64
+	block := make([]byte, salvageBlock+len(Magic))
+	for end := size; end > PreambleSize; end -= salvageBlock {
+		start := end - salvageBlock
+		if start < 0 {
+			start = 0
+		}
+		// Blocks overlap by the size of the magic, so magics across blocks are found
+		stop := end + int64(len(Magic)) - 1
+		if stop > size {
+			stop = size
+		}
+		b := block[:stop-start]
+		if _, err := r.ReadAt(b, start); err != nil && err != io.EOF {
+			return ends
+		}
+		// Only magics starting in the block are taken, those starting in the overlap belong to the next block
+		for i := bytes.LastIndex(b, Magic[:]); i >= 0; i = bytes.LastIndex(b[:i+len(Magic)-1], Magic[:]) {
+			if int64(i) < end-start {
+				ends = append(ends, start+int64(i+len(Magic)))
+			}
+		}
+	}
+	return ends
+}
+
+// ScanChunks calls fn for every brotli stream found between start and end, with its offset, its size and
+// its value. Streams are searched at every offset and the search resumes after every stream found, so that
+// brotli-compressed chunks are recovered without an index. Raw chunks and chunks compressed with
+// the dictionary can't be told from other data and aren't found
+func ScanChunks(r io.ReaderAt, start, end int64, fn func(offset, size int64, value []byte)) {
+	for off := start; off < end; off++ {
+		value, err := decodeStream(r, off, end-off)
+		if err != nil || len(value) == 0 {
+			continue
+		}
+		size := streamSize(r, off, end-off, len(value))
+		fn(off, size, value)
+		off += size - 1
+	}
+}
+
+// decodeStream decodes the brotli stream at off, within n bytes. Data following the stream is ignored
+func decodeStream(r io.ReaderAt, off, n int64) ([]byte, error) {
+	value, err := io.ReadAll(io.LimitReader(brotli.NewReader(io.NewSectionReader(r, off, n)), maxSalvageSize))
+	// The decoder fails on data following a complete stream, which is the next chunk
+	if err != nil && err.Error() == excessiveInput {
+		err = nil
+	}
+	return value, err
+}
+
+// streamSize returns the size of the brotli stream at off holding a value of rawSize bytes, the smallest
+// prefix of the n bytes at off holding the whole stream
+func streamSize(r io.ReaderAt, off, n int64, rawSize int) int64 {
+	lo, hi := int64(1), n
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if value, err := decodeStream(r, off, mid); err == nil && len(value) == rawSize {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo
+}
+
+// LocateIndex returns the offset of the index block of a file of size bytes as told by its trailer,
+// even if the index block is damaged
+func LocateIndex(r io.ReaderAt, size int64) (int64, error) {
+	offset, _, _, err := readTrailer(r, size)
+	return offset, err
+}
@@ -0,0 +1,41 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScanChunks(t *testing.T) {
+	a, _ := Compress(bytes.Repeat([]byte("first chunk "), 100), 16)
+	b, _ := Compress(bytes.Repeat([]byte("second chunk "), 100), 16)
+	var buf bytes.Buffer
+	buf.Write(a)
+	buf.Write(b)
+	data := buf.Bytes()
+
+	var offsets []int64
+	ScanChunks(bytes.NewReader(data), 0, int64(len(data)), func(offset, size int64, value []byte) {
+		offsets = append(offsets, offset)
+	})
+	if len(offsets) != 2 || offsets[0] != 0 || offsets[1] != int64(len(a)) {
+		t.Errorf("Expected chunks at 0 and %d, got %v instead", len(a), offsets)
+	}
+}
+
+func TestFindTrailers(t *testing.T) {
+	var buf bytes.Buffer
+	_ = WritePreamble(&buf)
+	_ = WriteIndex(&buf, Index{Offset: PreambleSize, Generation: 1}, 16)
+	first := int64(buf.Len())
+	offset := int64(buf.Len())
+	_ = WriteIndex(&buf, Index{Offset: offset, Generation: 2}, 16)
+	data := append(buf.Bytes(), "damaged trailer"...)
+
+	ends := FindTrailers(bytes.NewReader(data), int64(len(data)))
+	if len(ends) != 3 || ends[0] != int64(buf.Len()) || ends[1] != first || ends[2] != 4 {
+		t.Fatalf("Expected trailers ending at %d, %d and the preamble, got %v instead", buf.Len(), first, ends)
+	}
+	if index, err := ReadIndex(bytes.NewReader(data), ends[0]); err != nil || index.Generation != 2 {
+		t.Errorf("Expected last index to be read, got %v instead", err)
+	}
+}
@@ -0,0 +1,102 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Secondary is a secondary index of the keys of a file, it maps keys to values computed by the application
+type Secondary struct {
+	Name string
+	Keys []SecondaryKey // Keys are in bytewise ascending key order
+}
+
+// SecondaryKey holds the values a key is indexed under by a secondary index
+type SecondaryKey struct {
+	Key    string
+	Values []string // Values are in bytewise ascending order
+}
+
+// encodeSecondary marshals the secondary index section, indexes must be in bytewise ascending name order
+func encodeSecondary(indexes []Secondary) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	putString := func(s string) {
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(s)))])
+		buf.WriteString(s)
+	}
+	buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(indexes)))])
+	for _, s := range indexes {
+		putString(s.Name)
+		buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(s.Keys)))])
+		for _, k := range s.Keys {
+			putString(k.Key)
+			buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(k.Values)))])
+			for _, v := range k.Values {
+				putString(v)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// decodeSecondary unmarshals the secondary index section
+func decodeSecondary(section []byte) ([]Secondary, error) {
+	r := bytes.NewReader(section)
+	// Every index, key and value takes at least one byte for its length or count, so counts
+	// larger than the bytes left are rejected before allocating
+	readCount := func() (uint64, error) {
+		n, err := binary.ReadUvarint(r)
+		if err == nil && n > uint64(r.Len()) {
+			err = fmt.Errorf("invalid count %d of secondary index section", n)
+		}
+		return n, err
+	}
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return "", err
+		}
+		if n > uint64(r.Len()) {
+			return "", io.ErrUnexpectedEOF
+		}
+		b := make([]byte, n)
+		_, _ = r.Read(b)
+		return string(b), nil
+	}
+
+	count, err := readCount()
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]Secondary, count)
+	for i := range indexes {
+		if indexes[i].Name, err = readString(); err != nil {
+			return nil, err
+		}
+		keys, err := readCount()
+		if err != nil {
+			return nil, err
+		}
+		indexes[i].Keys = make([]SecondaryKey, keys)
+		for j := range indexes[i].Keys {
+			k := &indexes[i].Keys[j]
+			if k.Key, err = readString(); err != nil {
+				return nil, err
+			}
+			values, err := readCount()
+			if err != nil {
+				return nil, err
+			}
+			k.Values = make([]string, values)
+			for l := range k.Values {
+				if k.Values[l], err = readString(); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,57 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Tombstone holds the entry of a soft-deleted key, kept until it is purged
+type Tombstone struct {
+	Entry
+	Deleted int64 // Deleted is the deletion time in unix nanoseconds
+	Expires int64 // Expires is the expiry time of the value in unix nanoseconds, 0 if it doesn't expire
+}
+
+// encodeTombstones marshals the tombstone section, tombstones must be in bytewise ascending key order
+func encodeTombstones(tombstones []Tombstone) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(tombstones)))])
+	for _, t := range tombstones {
+		encodeEntry(&buf, t.Entry)
+		buf.Write(vb[:binary.PutVarint(vb[:], t.Deleted)])
+		buf.Write(vb[:binary.PutVarint(vb[:], t.Expires)])
+	}
+	return buf.Bytes()
+}
+
+// decodeTombstones unmarshals the tombstone section, checking that keys are in order
+// and that every chunk lies inside [PreambleSize, end)
+func decodeTombstones(section []byte, end int64) ([]Tombstone, error) {
+	r := bytes.NewReader(section)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(r.Len())/minEntrySize {
+		return nil, fmt.Errorf("invalid count of tombstones %d", count)
+	}
+	tombstones := make([]Tombstone, count)
+	for i := range tombstones {
+		t := &tombstones[i]
+		if t.Entry, err = decodeEntry(r, end, Version); err != nil {
+			return nil, err
+		}
+		if i > 0 && t.Key <= tombstones[i-1].Key {
+			return nil, fmt.Errorf("tombstone of key %q is out of order", t.Key)
+		}
+		if t.Deleted, err = binary.ReadVarint(r); err != nil {
+			return nil, err
+		}
+		if t.Expires, err = binary.ReadVarint(r); err != nil {
+			return nil, err
+		}
+	}
+	return tombstones, nil
+}
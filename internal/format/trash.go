@@ -0,0 +1,58 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Trashed holds the entry of a value overwritten or deleted, kept in the trash of the store
+type Trashed struct {
+	Entry
+	Generation uint64 // Generation is the generation of the store once the value was overwritten or deleted
+	Time       int64  // Time is the time the value was overwritten or deleted in unix nanoseconds
+	Expires    int64  // Expires is the expiry time of the value in unix nanoseconds, 0 if it doesn't expire
+}
+
+// encodeTrash marshals the trash section, values must be in the order they were trashed
+func encodeTrash(trash []Trashed) []byte {
+	var buf bytes.Buffer
+	var vb [binary.MaxVarintLen64]byte
+	buf.Write(vb[:binary.PutUvarint(vb[:], uint64(len(trash)))])
+	for _, t := range trash {
+		encodeEntry(&buf, t.Entry)
+		buf.Write(vb[:binary.PutUvarint(vb[:], t.Generation)])
+		buf.Write(vb[:binary.PutVarint(vb[:], t.Time)])
+		buf.Write(vb[:binary.PutVarint(vb[:], t.Expires)])
+	}
+	return buf.Bytes()
+}
+
+// decodeTrash unmarshals the trash section, checking that every chunk lies inside [PreambleSize, end)
+func decodeTrash(section []byte, end int64) ([]Trashed, error) {
+	r := bytes.NewReader(section)
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(r.Len())/minEntrySize {
+		return nil, fmt.Errorf("invalid count of trashed values %d", count)
+	}
+	trash := make([]Trashed, count)
+	for i := range trash {
+		t := &trash[i]
+		if t.Entry, err = decodeEntry(r, end, Version); err != nil {
+			return nil, err
+		}
+		if t.Generation, err = binary.ReadUvarint(r); err != nil {
+			return nil, err
+		}
+		if t.Time, err = binary.ReadVarint(r); err != nil {
+			return nil, err
+		}
+		if t.Expires, err = binary.ReadVarint(r); err != nil {
+			return nil, err
+		}
+	}
+	return trash, nil
+}
@@ -0,0 +1,177 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	// ErrFault is the error of the operations FaultFS fails
+	ErrFault = errors.New("injected fault")
+	// ErrCrashed is the error of every operation after FaultFS crashed, as if the process had died
+	ErrCrashed = errors.New("crashed")
+)
+
+// Faults are the faults injected by FaultFS. Writes are counted across the files opened by the FaultFS, and so
+// are renames, from the time the faults are injected
+type Faults struct {
+	// Fail fails the operations it returns true for with ErrFault. op is open, rename, remove, write, sync or
+	// truncate, name is the name of the file or from -> to for renames
+	Fail func(op, name string) bool
+	// FailWrite fails the FailWrite-th write with ErrFault, writing nothing, 0 for none
+	FailWrite int
+	// ShortWrite writes only the first half of the data of the ShortWrite-th write, which fails with
+	// io.ErrShortWrite, 0 for none
+	ShortWrite int
+	// CrashWrite crashes on the CrashWrite-th write after writing the first half of its data, 0 for none
+	CrashWrite int
+	// CrashRename crashes on the CrashRename-th rename, after renaming if RenameBeforeCrash, 0 for none
+	CrashRename       int
+	RenameBeforeCrash bool
+}
+
+// FaultFS is an FS injecting faults into the operations of another FS, for testing crash safety. Once crashed,
+// every operation fails with ErrCrashed and nothing more reaches the files, so tests reopen the files with the
+// other FS to check what a restarted process recovers
+type FaultFS struct {
+	fs      FS
+	mu      sync.Mutex
+	faults  Faults
+	writes  int
+	renames int
+	crashed bool
+}
+
+// NewFaultFS returns a FaultFS of the files of fs, injecting no faults until Inject
+func NewFaultFS(fs FS) *FaultFS {
+	return &FaultFS{fs: fs}
+}
+
+// Inject replaces the faults injected, and restarts counting writes and renames
+func (f *FaultFS) Inject(faults Faults) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults, f.writes, f.renames = faults, 0, 0
+}
+
+// Crashed returns true once the FaultFS crashed
+func (f *FaultFS) Crashed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.crashed
+}
+
+// check returns the error of op on name, ErrCrashed once crashed
+func (f *FaultFS) check(op, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.crashed {
+		return ErrCrashed
+	}
+	if f.faults.Fail != nil && f.faults.Fail(op, name) {
+		return ErrFault
+	}
+	return nil
+}
+
+// OpenFile opens the file at name, counting its writes
+func (f *FaultFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if err := f.check("open", name); err != nil {
+		return nil, err
+	}
+	file, err := f.fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultFile{File: file, fs: f}, nil
+}
+
+// Rename renames from to to, or crashes on the CrashRename-th rename
+func (f *FaultFS) Rename(from, to string) error {
+	if err := f.check("rename", from+" -> "+to); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.renames++
+	crash := f.renames == f.faults.CrashRename
+	before := f.faults.RenameBeforeCrash
+	f.mu.Unlock()
+	if crash {
+		if before {
+			_ = f.fs.Rename(from, to)
+		}
+		f.crash()
+		return ErrCrashed
+	}
+	return f.fs.Rename(from, to)
+}
+
+// Remove removes the file at name
+func (f *FaultFS) Remove(name string) error {
+	if err := f.check("remove", name); err != nil {
+		return err
+	}
+	return f.fs.Remove(name)
+}
+
+func (f *FaultFS) crash() {
+	f.mu.Lock()
+	f.crashed = true
+	f.mu.Unlock()
+}
+
+// write writes p with fn, injecting the faults of the write
+func (f *FaultFS) write(name string, p []byte, fn func(p []byte) (int, error)) (int, error) {
+	if err := f.check("write", name); err != nil {
+		return 0, err
+	}
+	f.mu.Lock()
+	f.writes++
+	n, faults := f.writes, f.faults
+	f.mu.Unlock()
+	switch n {
+	case faults.FailWrite:
+		return 0, ErrFault
+	case faults.ShortWrite:
+		written, err := fn(p[:len(p)/2])
+		if err == nil {
+			err = io.ErrShortWrite
+		}
+		return written, err
+	case faults.CrashWrite:
+		written, _ := fn(p[:len(p)/2])
+		f.crash()
+		return written, ErrCrashed
+	}
+	return fn(p)
+}
+
+// faultFile is a file opened by a FaultFS
+type faultFile struct {
+	File
+	fs *FaultFS
+}
+
+func (f *faultFile) Write(p []byte) (int, error) {
+	return f.fs.write(f.Name(), p, f.File.Write)
+}
+
+func (f *faultFile) WriteAt(p []byte, off int64) (int, error) {
+	return f.fs.write(f.Name(), p, func(p []byte) (int, error) { return f.File.WriteAt(p, off) })
+}
+
+func (f *faultFile) Sync() error {
+	if err := f.fs.check("sync", f.Name()); err != nil {
+		return err
+	}
+	return f.File.Sync()
+}
+
+func (f *faultFile) Truncate(size int64) error {
+	if err := f.fs.check("truncate", f.Name()); err != nil {
+		return err
+	}
+	return f.File.Truncate(size)
+}
@@ -0,0 +1,74 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// osFS is the file system of the OS
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFS) Rename(from, to string) error {
+	return os.Rename(from, to)
+}
+
+func (osFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+const TestFile = "vfs.data"
+
+func TestFaultFS_Writes(t *testing.T) {
+	defer os.Remove(TestFile)
+	fs := NewFaultFS(osFS{})
+	file, err := fs.OpenFile(TestFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	fs.Inject(Faults{FailWrite: 1, ShortWrite: 2, CrashWrite: 3})
+	if _, err := file.Write([]byte("fail")); err != ErrFault {
+		t.Errorf("Expected ErrFault, got %v instead", err)
+	}
+	if n, err := file.Write([]byte("short")); n != 2 || err != io.ErrShortWrite {
+		t.Errorf("Expected 2 bytes written and io.ErrShortWrite, got %d and %v instead", n, err)
+	}
+	if n, err := file.WriteAt([]byte("crash"), 2); n != 2 || err != ErrCrashed || !fs.Crashed() {
+		t.Errorf("Expected 2 bytes written and ErrCrashed, got %d and %v instead", n, err)
+	}
+	if err := file.Sync(); err != ErrCrashed {
+		t.Errorf("Expected ErrCrashed after the crash, got %v instead", err)
+	}
+	if data, _ := os.ReadFile(TestFile); string(data) != "shcr" {
+		t.Errorf("Expected 'shcr' written, got '%s' instead", data)
+	}
+}
+
+func TestFaultFS_Renames(t *testing.T) {
+	defer os.Remove(TestFile)
+	defer os.Remove(TestFile + ".new")
+	_ = os.WriteFile(TestFile, []byte("data"), 0666)
+	fs := NewFaultFS(osFS{})
+	fs.Inject(Faults{CrashRename: 2, RenameBeforeCrash: true, Fail: func(op, name string) bool { return op == "remove" }})
+	if err := fs.Remove(TestFile); err != ErrFault {
+		t.Errorf("Expected ErrFault, got %v instead", err)
+	}
+	if err := fs.Rename(TestFile, TestFile+".new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Rename(TestFile+".new", TestFile); !errors.Is(err, ErrCrashed) {
+		t.Errorf("Expected ErrCrashed, got %v instead", err)
+	}
+	if _, err := os.Stat(TestFile); err != nil {
+		t.Errorf("Expected the rename to be done before the crash, got %v instead", err)
+	}
+	if _, err := fs.OpenFile(TestFile, os.O_RDONLY, 0); err != ErrCrashed {
+		t.Errorf("Expected ErrCrashed after the crash, got %v instead", err)
+	}
+}
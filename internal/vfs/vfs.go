@@ -0,0 +1,34 @@
+// Package vfs abstracts the file operations stores commit and recover with, so tests replace them with
+// implementations injecting faults, see FaultFS
+package vfs
+
+import (
+	"io"
+	"os"
+)
+
+// File is an open file, implemented by *os.File
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.WriterAt
+	io.Seeker
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+	Sync() error
+	Truncate(size int64) error
+	Chmod(mode os.FileMode) error
+	Chown(uid, gid int) error
+}
+
+// FS opens, renames and removes files
+type FS interface {
+	// OpenFile opens the file at name like os.OpenFile
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	// Rename renames from to to, atomically replacing to if it exists
+	Rename(from, to string) error
+	// Remove removes the file at name
+	Remove(name string) error
+}
@@ -0,0 +1,146 @@
+package sunduk
+
+import (
+	"errors"
+	"strings"
+	"sunduk/internal/format"
+)
+
+// lookupBatch is the count of entries read at once from lookup tables by scans
+const lookupBatch = 4096
+
+// readLookup reads the lookup table of the store file instead of its index, see WithLazyIndex.
+// It returns false if the file has no lookup table
+func (store *Sunduk) readLookup() (bool, error) {
+	info, err := store.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	l, err := format.ReadLookup(store.file, info.Size())
+	if errors.Is(err, format.ErrNoLookup) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := store.readDictionary(l.Index.Dictionary); err != nil {
+		return false, err
+	}
+	if err := store.useCollation(l.Index.Collation); err != nil {
+		return false, err
+	}
+	store.lazy = &l
+	store.index = make(map[string]entry)
+	store.setHeader(l.Index)
+	store.access.load(l.Index.Access)
+	store.size = info.Size()
+	store.tail = info.Size() - l.Offset
+	return true, nil
+}
+
+// lookup returns the entry of key from pending writes, or else from the index, or from the lookup table of the
+// store file if the index isn't loaded. It must be called with mu held
+func (store *Sunduk) lookup(key string) (entry, bool) {
+	if value, ok := store.data[key]; ok {
+		return pendingEntry(value), true
+	}
+	if store.pending.deleted[key] {
+		return entry{}, false
+	}
+	return store.committed(key)
+}
+
+// committed returns the entry of key from the index, or from the lookup table of the store file if the index
+// isn't loaded, regardless of pending writes. It must be called with mu held
+func (store *Sunduk) committed(key string) (entry, bool) {
+	if store.lazy == nil {
+		e, ok := store.index[key]
+		return e, ok
+	}
+	if store.file == nil {
+		return entry{}, false
+	}
+	e, ok, err := store.lazy.Find(store.file, key)
+	if err != nil {
+		store.opts.logger.Error("unable to look up entry", "file", store.FilePath, "key", key, "err", err)
+	}
+	return newEntry(e), ok
+}
+
+// count returns the count of entries, pending writes included. It must be called with mu held
+func (store *Sunduk) count() int {
+	if store.lazy != nil {
+		return store.lazy.Len() + store.pending.count
+	}
+	return len(store.index) + store.pending.count
+}
+
+// scan calls fn for every entry which key starts with prefix, the entries of pending writes last. Entries of the
+// lookup table are read in key order if the index isn't loaded, entries of the index in no particular order.
+// It must be called with mu held
+func (store *Sunduk) scan(prefix string, fn func(key string, e entry)) {
+	if !store.staged() {
+		store.scanCommitted(prefix, fn)
+		return
+	}
+	store.scanCommitted(prefix, func(k string, e entry) {
+		if _, pending := store.data[k]; !pending && !store.pending.deleted[k] {
+			fn(k, e)
+		}
+	})
+	for k, v := range store.data {
+		if strings.HasPrefix(k, prefix) {
+			fn(k, pendingEntry(v))
+		}
+	}
+}
+
+// scanCommitted calls fn for every entry of the index or of the lookup table which key starts with prefix,
+// regardless of pending writes. It must be called with mu held
+func (store *Sunduk) scanCommitted(prefix string, fn func(key string, e entry)) {
+	if store.lazy == nil {
+		for k, e := range store.index {
+			if strings.HasPrefix(k, prefix) {
+				fn(k, e)
+			}
+		}
+		return
+	}
+	if store.file == nil {
+		return
+	}
+	i, err := store.lazy.Search(store.file, prefix)
+	for err == nil && i < store.lazy.Len() {
+		n := store.lazy.Len() - i
+		if n > lookupBatch {
+			n = lookupBatch
+		}
+		var entries []format.Entry
+		if entries, err = store.lazy.Entries(store.file, i, n); err != nil {
+			break
+		}
+		for _, e := range entries {
+			if !strings.HasPrefix(e.Key, prefix) {
+				return
+			}
+			fn(e.Key, newEntry(e))
+		}
+		i += n
+	}
+	if err != nil {
+		store.opts.logger.Error("unable to read lookup table", "file", store.FilePath, "err", err)
+	}
+}
+
+// loadIndex returns the index with the entries of pending writes, reading every entry of the lookup table if
+// the index isn't loaded. It must be called with mu held
+func (store *Sunduk) loadIndex() map[string]entry {
+	if store.lazy == nil && !store.staged() {
+		return store.index
+	}
+	index := make(map[string]entry, store.count())
+	store.scan("", func(k string, e entry) {
+		index[k] = e
+	})
+	return index
+}
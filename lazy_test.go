@@ -0,0 +1,81 @@
+package sunduk
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSunduk_LazyIndex(t *testing.T) {
+	writer, err := Open(TestStoreFile, WithLazyIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	defer writer.Close()
+	values := make(map[string][]byte)
+	for i := 0; i < 5000; i++ {
+		values[fmt.Sprintf("key%04d", i)] = []byte(fmt.Sprintf("value %d", i))
+	}
+	_ = writer.PutAll(values)
+	_ = writer.Delete("key0000")
+
+	reader, err := Open(TestStoreFile, WithReadOnly(), WithLazyIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if reader.lazy == nil || len(reader.index) != 0 {
+		t.Fatalf("Expected entries to be looked up in the lookup table, got %d entries loaded instead", len(reader.index))
+	}
+	checkValueForKey(t, reader, "key4242", []byte("value 4242"))
+	checkKeyNotExists(t, reader, "key0000")
+	checkKeyNotExists(t, reader, "absent")
+	if n := reader.Count(); n != 4999 {
+		t.Errorf("Expected 4999 keys, got %d instead", n)
+	}
+	if n := reader.CountPrefix("key01"); n != 100 {
+		t.Errorf("Expected 100 keys with prefix key01, got %d instead", n)
+	}
+	if keys := reader.Keys(Prefix("key1"), Limit(2)); !reflect.DeepEqual(keys, []string{"key1000", "key1001"}) {
+		t.Errorf("Expected first 2 keys with prefix key1, got %v instead", keys)
+	}
+	if keys := reader.OrderedKeys(); keys.Len() != 4999 || keys.At(0) != "key0001" {
+		t.Errorf("Expected 4999 ordered keys from key0001, got %d keys from %q instead", keys.Len(), keys.At(0))
+	}
+	if err := reader.Verify(); err != nil {
+		t.Errorf("Expected Verify to succeed, got %v instead", err)
+	}
+	if info := reader.DebugInfo(); info.Index.Entries != 4999 || info.Index.LastKey != "key4999" || !info.Config.LazyIndex {
+		t.Errorf("Expected debug info of 4999 entries up to key4999, got %+v instead", info.Index)
+	}
+	if dead, _ := reader.garbage(); dead != writer.DebugInfo().Index.DeadBytes {
+		t.Errorf("Expected reader to count the dead bytes of the writer, got %d instead of %d", dead, writer.DebugInfo().Index.DeadBytes)
+	}
+
+	_ = writer.Put("key0000", []byte("value 0"))
+	if err := reader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, reader, "key0000", []byte("value 0"))
+	if reader.lazy == nil {
+		t.Error("Expected reload to look up entries in the lookup table")
+	}
+}
+
+func TestSunduk_LazyIndexWithoutLookupTable(t *testing.T) {
+	writer := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = writer.Put("a", []byte("apple"))
+	writer.Close()
+
+	reader, err := Open(TestStoreFile, WithReadOnly(), WithLazyIndex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if reader.lazy != nil {
+		t.Error("Expected the index to be loaded from a file without lookup table")
+	}
+	checkValueForKey(t, reader, "a", []byte("apple"))
+}
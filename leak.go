@@ -0,0 +1,124 @@
+package sunduk
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// WithLeakDetection reports stores garbage collected without Close, to track down leaks of file handles and locks
+// in large applications. Leaked stores are logged as errors with the stack trace of the Open call, and if panics
+// is true the process panics instead. Stores running background compaction, reloads or expiry notifications are
+// referenced by them until Close, so they are never collected and never reported. It is a debugging aid, as
+// capturing the stack trace slows down Open
+func WithLeakDetection(panics bool) Option {
+	return func(o *options) {
+		o.leakDetection = true
+		o.leakPanics = panics
+	}
+}
+
+// leakTracker holds the store file and the lock of a store detecting leaks, so that they are released if the store
+// is garbage collected without Close, or destroyed by Destroy. It holds no reference to the store, so that openStores
+// holds the tracker rather than the store and doesn't keep it from being collected. The store tracks its file and
+// its lock whenever it replaces them
+type leakTracker struct {
+	mu       sync.Mutex
+	file     *handle
+	lock     *os.File
+	released bool // released is true once the file and the lock are released, or detached by Close
+}
+
+// leakSentinel is referenced by its store only, so it becomes unreachable along with the store and its finalizer
+// reports the leak
+type leakSentinel struct {
+	tracker *leakTracker
+}
+
+// newLeakTracker returns the tracker of a store detecting leaks, nil for other stores
+func newLeakTracker(opts options) *leakTracker {
+	if !opts.leakDetection {
+		return nil
+	}
+	return &leakTracker{}
+}
+
+// track records the store file and the lock of the store, unless they are released already
+func (t *leakTracker) track(file *handle, lock *os.File) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.released {
+		t.file, t.lock = file, lock
+	}
+}
+
+// release releases the tracked file and lock, and returns false if they were released or detached already
+func (t *leakTracker) release() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return false
+	}
+	t.released = true
+	if t.file != nil {
+		_ = t.file.release()
+	}
+	if t.lock != nil {
+		_ = t.lock.Close()
+	}
+	t.file, t.lock = nil, nil
+	return true
+}
+
+// detach stops tracking the file and the lock once Close releases them, and returns false if they were released
+// by Destroy already, so that Close doesn't release them again. It returns true for stores not detecting leaks
+func (t *leakTracker) detach() bool {
+	if t == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.released {
+		return false
+	}
+	t.released = true
+	t.file, t.lock = nil, nil
+	return true
+}
+
+// destroyed returns true if the file and the lock were released by Destroy, so the store is closed
+func (t *leakTracker) destroyed() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.released
+}
+
+// detectLeak sets a finalizer on a sentinel of the store reporting the store with the stack trace of its opening if
+// it is collected without Close. The finalizer releases the store file and the lock of leaked stores, pending writes
+// are lost
+func (store *Sunduk) detectLeak() {
+	if store.leak == nil {
+		return
+	}
+	stack := string(debug.Stack())
+	entry, logger, panics := newRegistered(store), store.opts.logger, store.opts.leakPanics
+	store.sentinel = &leakSentinel{tracker: store.leak}
+	runtime.SetFinalizer(store.sentinel, func(s *leakSentinel) {
+		unregister(entry)
+		if !s.tracker.release() {
+			return
+		}
+		if panics {
+			panic(fmt.Sprintf("sunduk: store %s garbage collected without Close, opened at\n%s", entry.path, stack))
+		}
+		logger.Error("store garbage collected without Close", "file", entry.path, "opened", stack)
+	})
+}
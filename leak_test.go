@@ -0,0 +1,72 @@
+package sunduk
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSunduk_WithLeakDetection(t *testing.T) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	logger := &recordingLogger{}
+	closed := New(TestStoreFile, WithLeakDetection(false), WithLogger(logger))
+	closed.Close()
+	closed = nil
+	func() {
+		_ = New(TestStoreFile, WithLeakDetection(false), WithLogger(logger))
+	}()
+
+	for i := 0; i < 100 && !logger.has("ERROR store garbage collected without Close"); i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	logger.mu.Lock()
+	count := 0
+	for _, e := range logger.events {
+		if e == "ERROR store garbage collected without Close" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected the leaked store to be reported once, got %v instead", logger.events)
+	}
+	logger.mu.Unlock()
+
+	// The leaked store was removed from the stores open in the process
+	store, err := Open(TestStoreFile)
+	if err != nil {
+		t.Fatalf("Expected Open to succeed once the leaked store is collected, got %v instead", err)
+	}
+	store.Close()
+}
+
+func TestSunduk_WithLeakDetectionDestroy(t *testing.T) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	logger := &recordingLogger{}
+	store := New(TestStoreFile, WithLeakDetection(false), WithLogger(logger))
+	_ = store.Put("key", []byte("value"))
+	if err := Destroy(TestStoreFile); err != nil {
+		t.Fatalf("Expected Destroy to succeed, got %v instead", err)
+	}
+	if err := store.Put("key", []byte("value")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected writes to a destroyed store to fail with ErrClosed, got %v instead", err)
+	}
+	if err := store.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected Close of a destroyed store to fail with ErrClosed, got %v instead", err)
+	}
+	store = nil
+
+	reopened := New(TestStoreFile, WithLeakDetection(false), WithLogger(logger))
+	checkKeyNotExists(t, reopened, "key")
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if logger.has("ERROR store garbage collected without Close") {
+		t.Error("Expected the destroyed store not to be reported as leaked")
+	}
+	reopened.Close()
+}
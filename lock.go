@@ -0,0 +1,56 @@
+package sunduk
+
+import "os"
+
+// acquireLock locks the store file, shared for read-only stores and exclusive otherwise. The lock is held
+// on a lock file next to the store file, as compaction replaces the store file. It fails with ErrLocked
+// if another store holds a conflicting lock
+func (store *Sunduk) acquireLock() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.lock != nil || store.opts.unlocked {
+		return nil
+	}
+	name := store.FilePath + ".lock"
+	_, err := os.Stat(name)
+	created := os.IsNotExist(err)
+	file, err := os.OpenFile(name, store.openFlag()|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	if created {
+		if err := store.setPerm(file, store.FilePath); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err := lockFile(file, !store.opts.readOnly); err != nil {
+		_ = file.Close()
+		return err
+	}
+	store.lock = file
+	return nil
+}
+
+// releaseLock unlocks the store file
+func (store *Sunduk) releaseLock() {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.lock == nil {
+		return
+	}
+	// Closing the lock file releases the lock. The lock file is kept, removing it would let
+	// another store lock a new lock file while a third one still holds the removed one
+	if err := store.lock.Close(); err != nil {
+		store.opts.logger.Warn("unable to release lock", "file", store.lock.Name(), "err", err)
+	}
+	store.lock = nil
+}
+
+// openFlag returns the flag the store file is opened with
+func (store *Sunduk) openFlag() int {
+	if store.opts.readOnly {
+		return os.O_RDONLY
+	}
+	return os.O_RDWR
+}
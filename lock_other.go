@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package sunduk
+
+import "os"
+
+// lockFile does nothing on platforms without file locks, stores aren't protected from each other there
+func lockFile(file *os.File, exclusive bool) error {
+	return nil
+}
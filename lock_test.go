@@ -0,0 +1,98 @@
+package sunduk
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSunduk_OpenLockedStore(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+
+	if _, err := Open(TestStoreFile); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked opening a locked store, got %v instead", err)
+	}
+	if _, err := Open(TestStoreFile, WithSharedReadLock()); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked opening a locked store read-only, got %v instead", err)
+	}
+
+	store.Close()
+	other, err := Open(TestStoreFile)
+	if err != nil {
+		t.Fatalf("Expected Open to succeed after Close, got %v instead", err)
+	}
+	other.Close()
+}
+
+func TestSunduk_SharedReadLock(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+
+	first, err := Open(TestStoreFile, WithSharedReadLock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := Open(TestStoreFile, WithSharedReadLock())
+	if err != nil {
+		t.Fatalf("Expected two read-only opens to succeed, got %v instead", err)
+	}
+	defer second.Close()
+	checkValueForKey(t, first, "key", []byte("value"))
+	checkValueForKey(t, second, "key", []byte("value"))
+
+	if _, err := Open(TestStoreFile); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked opening a store opened read-only, got %v instead", err)
+	}
+	if err := first.Put("key", []byte("other")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly on Put to a read-only store, got %v instead", err)
+	}
+	if err := first.Delete("key"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly on Delete from a read-only store, got %v instead", err)
+	}
+	if err := first.Compact(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly on Compact of a read-only store, got %v instead", err)
+	}
+	checkValueForKey(t, second, "key", []byte("value"))
+}
+
+func TestSunduk_SharedReadLockMissingFile(t *testing.T) {
+	defer deleteTestStoreFile()
+	if _, err := Open(TestStoreFile, WithSharedReadLock()); err == nil {
+		t.Error("Expected read-only Open of a missing file to fail")
+	}
+}
+
+func TestSunduk_OpenTwiceInProcess(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+
+	// The store file is opened by another path to it, and the lock file is removed so that it doesn't lock it
+	_ = os.Remove(TestStoreFile + ".lock")
+	_, err := Open("./" + TestStoreFile)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Expected ErrLocked opening a store open in the process, got %v instead", err)
+	}
+	if !strings.Contains(err.Error(), "already open in this process") {
+		t.Errorf("Expected error naming the store open in the process, got %q instead", err)
+	}
+
+	reader, err := Open(TestStoreFile, WithReadOnly())
+	if err != nil {
+		t.Fatalf("Expected read-only Open of a store open in the process to succeed, got %v instead", err)
+	}
+	reader.Close()
+
+	store.Close()
+	other, err := Open("./" + TestStoreFile)
+	if err != nil {
+		t.Fatalf("Expected Open to succeed after Close, got %v instead", err)
+	}
+	checkValueForKey(t, other, "key", []byte("value"))
+	other.Close()
+}
@@ -0,0 +1,52 @@
+package sunduk
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger receives the significant events of a store: opening, flushes, compactions, detected corruption,
+// repairs and failures of cleanups. Arguments following the message are alternating keys and values.
+// The method set is that of *slog.Logger, which can be used as a Logger directly
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// stdLogger is the default logger, it writes warnings and errors with the standard logger
+type stdLogger struct{}
+
+func (stdLogger) Debug(string, ...interface{}) {}
+func (stdLogger) Info(string, ...interface{})  {}
+
+func (stdLogger) Warn(msg string, args ...interface{}) {
+	log.Print(formatEvent("WARN", msg, args))
+}
+
+func (stdLogger) Error(msg string, args ...interface{}) {
+	log.Print(formatEvent("ERROR", msg, args))
+}
+
+// formatEvent formats an event as a line of text with key=value pairs
+func formatEvent(level, msg string, args []interface{}) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "sunduk: %s %s", level, msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%q", args[i], fmt.Sprint(args[i+1]))
+	}
+	if len(args)%2 != 0 {
+		fmt.Fprintf(&b, " %q", fmt.Sprint(args[len(args)-1]))
+	}
+	return b.String()
+}
+
+// nopLogger discards events
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
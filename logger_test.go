@@ -0,0 +1,82 @@
+package sunduk
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger records events as "LEVEL msg"
+type recordingLogger struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *recordingLogger) record(level, msg string) {
+	l.mu.Lock()
+	l.events = append(l.events, level+" "+msg)
+	l.mu.Unlock()
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.record("DEBUG", msg) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.record("INFO", msg) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.record("WARN", msg) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.record("ERROR", msg) }
+
+func (l *recordingLogger) has(event string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range l.events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSunduk_Logger(t *testing.T) {
+	logger := &recordingLogger{}
+	store := New(TestStoreFile, WithLogger(logger))
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	_ = store.Compact()
+	store.Close()
+	for _, event := range []string{
+		"INFO opened store", "DEBUG flush started", "DEBUG flush finished", "INFO compaction started", "INFO compaction finished",
+	} {
+		if !logger.has(event) {
+			t.Errorf("Expected event '%s' to be logged, got %v instead", event, logger.events)
+		}
+	}
+}
+
+func TestSunduk_LoggerOfCorruption(t *testing.T) {
+	store := New(TestStoreFile, WithLogger(nil))
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	mirror := New(TestMirrorFile, WithLogger(nil))
+	defer deleteStoreFile(TestMirrorFile)
+	_ = mirror.Put("key", []byte("value"))
+	logger := &recordingLogger{}
+	store = New(TestStoreFile, WithLogger(logger), WithRepairSource(mirror))
+	store.Get("key")
+	store.Close()
+	mirror.Close()
+	if !logger.has("ERROR corrupted entry detected") || !logger.has("WARN repaired entry from repair source") {
+		t.Errorf("Expected corruption and repair to be logged, got %v instead", logger.events)
+	}
+}
+
+func TestFormatEvent(t *testing.T) {
+	line := formatEvent("WARN", "unable to remove backup file", []interface{}{"file", "sunduk.data.bak", "err", fmt.Errorf("busy")})
+	if want := `sunduk: WARN unable to remove backup file file="sunduk.data.bak" err="busy"`; line != want {
+		t.Errorf("Expected '%s', got '%s' instead", want, line)
+	}
+	if line := formatEvent("ERROR", "odd", []interface{}{"key"}); !strings.HasSuffix(line, ` "key"`) {
+		t.Errorf("Expected a dangling argument to be kept, got '%s' instead", line)
+	}
+}
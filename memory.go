@@ -0,0 +1,90 @@
+package sunduk
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryStore is a Store keeping its values in memory only, for data that needn't outlive the process and for
+// tests of code written against Store. Put options are ignored. It is safe for concurrent use
+type MemoryStore struct {
+	mu     sync.RWMutex
+	values map[string][]byte
+	closed bool
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte)}
+}
+
+// Get returns a copy of the value of key and true, or false if the store has no value for key or is closed
+func (m *MemoryStore) Get(key string) ([]byte, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.values[key]
+	if !ok || m.closed {
+		return nil, false
+	}
+	return cloneValue(value), true
+}
+
+// Put sets a copy of value as the value of key
+func (m *MemoryStore) Put(key string, value []byte, _ ...PutOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.values[key] = cloneValue(value)
+	return nil
+}
+
+// Delete removes key
+func (m *MemoryStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	delete(m.values, key)
+	return nil
+}
+
+// Keys returns the keys selected by opts, in bytewise ascending order, see Sunduk.Keys
+func (m *MemoryStore) Keys(opts ...KeysOption) []string {
+	m.mu.RLock()
+	keys := make([]string, 0, len(m.values))
+	for k := range m.values {
+		keys = append(keys, k)
+	}
+	m.mu.RUnlock()
+	sort.Strings(keys)
+	return SelectKeys(keys, opts...)
+}
+
+// Count returns the number of entries of the store
+func (m *MemoryStore) Count() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.values)
+}
+
+// Close drops the values of the store, later writes fail with ErrClosed
+func (m *MemoryStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrClosed
+	}
+	m.closed, m.values = true, map[string][]byte{}
+	return nil
+}
+
+// cloneValue returns a copy of value, nil if value is nil so that nil values stay apart from empty ones
+func cloneValue(value []byte) []byte {
+	if value == nil {
+		return nil
+	}
+	return append([]byte{}, value...)
+}
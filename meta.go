@@ -0,0 +1,63 @@
+package sunduk
+
+import (
+	"sunduk/internal/format"
+	"time"
+)
+
+// Meta is metadata of a store defined by the application. It is kept in the index of the store file, so tools
+// can tell what a store holds without reading its values
+type Meta struct {
+	Version     int       // Version is the format version of the store file, it is ignored by SetMeta
+	Application string    // Application is the name of the application writing the store
+	Created     time.Time // Created is the creation time of the store, zero for files created without it
+	Sealed      time.Time // Sealed is the time the store was sealed, zero for stores not sealed. It is ignored by SetMeta
+	Values      map[string]string
+}
+
+// GetMeta returns the metadata of the store
+func (store *Sunduk) GetMeta() Meta {
+	store.mu.RLock()
+	m, sealed := store.meta, store.sealed
+	file := store.file.acquire()
+	store.mu.RUnlock()
+
+	meta := Meta{Application: m.Application, Values: make(map[string]string, len(m.Values))}
+	if m.Created != 0 {
+		meta.Created = time.Unix(0, m.Created)
+	}
+	if sealed != 0 {
+		meta.Sealed = time.Unix(0, sealed)
+	}
+	for k, v := range m.Values {
+		meta.Values[k] = v
+	}
+	if file != nil {
+		meta.Version, _ = format.ReadVersion(file)
+		file.release()
+	}
+	return meta
+}
+
+// SetMeta replaces the application name and the values of the metadata of the store and commits them to the store
+// file, along with pending writes. The creation time is replaced only if meta has one. It bumps the generation
+func (store *Sunduk) SetMeta(meta Meta) error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	store.mu.RLock()
+	m := format.Meta{Application: meta.Application, Created: store.meta.Created}
+	store.mu.RUnlock()
+	if !meta.Created.IsZero() {
+		m.Created = meta.Created.UnixNano()
+	}
+	if len(meta.Values) > 0 {
+		m.Values = make(map[string]string, len(meta.Values))
+		for k, v := range meta.Values {
+			m.Values[k] = v
+		}
+	}
+	return store.commit(nil, nil, putOptions{meta: &m})
+}
@@ -0,0 +1,77 @@
+package sunduk
+
+import (
+	"reflect"
+	"sunduk/internal/format"
+	"testing"
+	"time"
+)
+
+func TestSunduk_Meta(t *testing.T) {
+	before := time.Now()
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	meta := store.GetMeta()
+	if meta.Version != format.Version {
+		t.Errorf("Expected format version %d, got %d instead", format.Version, meta.Version)
+	}
+	if meta.Created.Before(before.Add(-time.Second)) || meta.Created.After(time.Now()) {
+		t.Errorf("Expected creation time of a new store, got %v instead", meta.Created)
+	}
+	created := meta.Created
+
+	values := map[string]string{"schema": "v2", "owner": "billing"}
+	if err := store.SetMeta(Meta{Application: "app", Values: values}); err != nil {
+		t.Fatalf("Expected SetMeta to succeed, got %v instead", err)
+	}
+	values["schema"] = "v3"
+	if store.Generation() != 1 {
+		t.Errorf("Expected SetMeta to bump the generation to 1, got %d instead", store.Generation())
+	}
+	_ = store.Put("key", []byte("value"))
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	meta = store.GetMeta()
+	if meta.Application != "app" || !meta.Created.Equal(created) {
+		t.Errorf("Expected application app created at %v, got %s created at %v instead", created, meta.Application, meta.Created)
+	}
+	if expected := map[string]string{"schema": "v2", "owner": "billing"}; !reflect.DeepEqual(meta.Values, expected) {
+		t.Errorf("Expected values %v, got %v instead", expected, meta.Values)
+	}
+	meta.Values["schema"] = "changed"
+	if store.GetMeta().Values["schema"] != "v2" {
+		t.Error("Expected GetMeta to return a copy of the values")
+	}
+
+	created = time.Unix(1, 0)
+	if err := store.SetMeta(Meta{Created: created}); err != nil {
+		t.Fatal(err)
+	}
+	if meta := store.GetMeta(); !meta.Created.Equal(created) || meta.Application != "" || len(meta.Values) != 0 {
+		t.Errorf("Expected SetMeta to replace the metadata, got %+v instead", meta)
+	}
+	store.Close()
+}
+
+func TestSunduk_MetaReadOnly(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.SetMeta(Meta{Application: "app"})
+	store.Close()
+
+	store, err := Open(TestStoreFile, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if store.GetMeta().Application != "app" {
+		t.Error("Expected read-only store to read the metadata")
+	}
+	if err := store.SetMeta(Meta{}); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v instead", err)
+	}
+}
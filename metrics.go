@@ -0,0 +1,64 @@
+package sunduk
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a snapshot of the counters of a store since it was opened
+type Metrics struct {
+	Gets    uint64 // Gets is the count of Get calls
+	Puts    uint64 // Puts is the count of values put by Put and PutAll
+	Deletes uint64 // Deletes is the count of keys removed by Delete
+
+	CacheHits   uint64 // CacheHits is the count of values got from memory
+	CacheMisses uint64 // CacheMisses is the count of values read from the store file
+
+	BytesRead    uint64 // BytesRead is the count of bytes of chunks read from the store file
+	BytesWritten uint64 // BytesWritten is the count of bytes written to the store file, including rewrites
+
+	// CompressionRatio is the size of live values divided by the size of their chunks, 0 for an empty store
+	CompressionRatio float64
+
+	LastFlushDuration      time.Duration // LastFlushDuration is the duration of the last write to the store file
+	LastCompactionDuration time.Duration // LastCompactionDuration is the duration of the last compaction
+}
+
+// counters are the counters behind Metrics, updated atomically
+type counters struct {
+	gets, puts, deletes       uint64
+	cacheHits, cacheMisses    uint64
+	bytesRead, bytesWritten   uint64
+	lastFlush, lastCompaction int64
+}
+
+// Metrics returns the counters of the store
+func (store *Sunduk) Metrics() Metrics {
+	c := store.counters
+	m := Metrics{
+		Gets:                   atomic.LoadUint64(&c.gets),
+		Puts:                   atomic.LoadUint64(&c.puts),
+		Deletes:                atomic.LoadUint64(&c.deletes),
+		CacheHits:              atomic.LoadUint64(&c.cacheHits),
+		CacheMisses:            atomic.LoadUint64(&c.cacheMisses),
+		BytesRead:              atomic.LoadUint64(&c.bytesRead),
+		BytesWritten:           atomic.LoadUint64(&c.bytesWritten),
+		LastFlushDuration:      time.Duration(atomic.LoadInt64(&c.lastFlush)),
+		LastCompactionDuration: time.Duration(atomic.LoadInt64(&c.lastCompaction)),
+	}
+
+	// Entries of legacy files have no raw sizes
+	var raw, stored int64
+	store.mu.RLock()
+	store.scan("", func(_ string, e entry) {
+		if e.hasSum {
+			raw += e.RawSize
+			stored += e.Size
+		}
+	})
+	store.mu.RUnlock()
+	if stored > 0 {
+		m.CompressionRatio = float64(raw) / float64(stored)
+	}
+	return m
+}
@@ -0,0 +1,65 @@
+package sunduk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSunduk_Metrics(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	value := bytes.Repeat([]byte("value "), 100)
+	_ = store.Put("key", value)
+	_ = store.PutAll(map[string][]byte{"a": value, "b": value})
+	_ = store.Delete("b")
+	_ = store.Delete("missing")
+	store.Get("key")
+	store.Get("missing")
+	store.Close()
+
+	store = New(TestStoreFile)
+	store.Get("key")
+	m := store.Metrics()
+	if m.Gets != 1 || m.CacheHits != 0 || m.CacheMisses != 1 {
+		t.Errorf("Expected 1 get missing the cache, got %d gets, %d hits and %d misses instead", m.Gets, m.CacheHits, m.CacheMisses)
+	}
+	if m.BytesRead != uint64(store.index["key"].Size) {
+		t.Errorf("Expected %d bytes read, got %d instead", store.index["key"].Size, m.BytesRead)
+	}
+	if m.CompressionRatio <= 1 {
+		t.Errorf("Expected repetitive values to compress, got compression ratio %f instead", m.CompressionRatio)
+	}
+	store.Close()
+}
+
+func TestSunduk_MetricsOfWrites(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	before := store.Metrics().BytesWritten
+	_ = store.Put("key", []byte("value"))
+	_ = store.PutAll(map[string][]byte{"a": []byte("1"), "b": []byte("2")})
+	_ = store.Delete("b")
+	_ = store.Delete("missing")
+	store.Get("key")
+
+	m := store.Metrics()
+	if m.Puts != 3 || m.Deletes != 1 {
+		t.Errorf("Expected 3 puts and 1 delete, got %d and %d instead", m.Puts, m.Deletes)
+	}
+	if m.Gets != 1 || m.CacheHits != 1 {
+		t.Errorf("Expected 1 get from the cache, got %d gets and %d hits instead", m.Gets, m.CacheHits)
+	}
+	if written := m.BytesWritten - before; written != uint64(fileSize(t, TestStoreFile))-before {
+		t.Errorf("Expected appended bytes to be counted, got %d bytes written instead", written)
+	}
+	if m.LastFlushDuration <= 0 {
+		t.Error("Expected the duration of the last flush to be recorded")
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatalf("Expected Compact to succeed, got %v instead", err)
+	}
+	if m := store.Metrics(); m.LastCompactionDuration <= 0 || m.BytesWritten <= before+uint64(fileSize(t, TestStoreFile)) {
+		t.Error("Expected compaction to be recorded")
+	}
+	store.Close()
+}
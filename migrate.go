@@ -0,0 +1,60 @@
+package sunduk
+
+import (
+	"fmt"
+	"io"
+	"sunduk/internal/format"
+)
+
+// Migrate upgrades the store file at path written by older versions of the package to the current format version.
+// The file is rewritten in place, and the original file is kept next to it as path.v<version>.bak. Files in the
+// current version are left as they are, and files in newer versions fail with ErrVersion. Stores open files in
+// older versions too, and upgrade them on the first write, so Migrate is only needed to upgrade files ahead of time.
+// The options are those of Open, the store is opened with them for migration
+func Migrate(path string, opts ...Option) error {
+	store, err := Open(path, opts...)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if err := store.writable(); err != nil {
+		return err
+	}
+
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	version, err := format.ReadVersion(store.file)
+	if err != nil {
+		return err
+	}
+	if version == format.Version && !store.legacy {
+		return nil
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := store.backUp(backup); err != nil {
+		return fmt.Errorf("unable to back up %s to %s before migration: %v", path, backup, err)
+	}
+	store.opts.logger.Info("migrating store file", "file", path, "version", version, "backup", backup)
+	if err := store.commit(nil, nil, putOptions{}); err != nil {
+		return err
+	}
+	store.opts.logger.Info("migrated store file", "file", path, "version", format.Version)
+	return nil
+}
+
+// backUp copies the store file to a new file at name, synced to stable storage. It must be called with writeMu held
+func (store *Sunduk) backUp(name string) error {
+	file, err := store.createFile(name, store.FilePath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, io.NewSectionReader(store.file, 0, store.size)); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,75 @@
+package sunduk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"sunduk/internal/format"
+	"testing"
+)
+
+func TestMigrate_Version1File(t *testing.T) {
+	writeVersion1File(t, TestStoreFile, map[string][]byte{"a": []byte("apple"), "b": []byte("banana")})
+	defer deleteTestStoreFile()
+	backup := TestStoreFile + ".v1.bak"
+	defer os.Remove(backup)
+	original, _ := os.ReadFile(TestStoreFile)
+
+	if err := Migrate(TestStoreFile); err != nil {
+		t.Fatalf("Expected Migrate to succeed, got %v instead", err)
+	}
+	if data, _ := os.ReadFile(backup); !bytes.Equal(data, original) {
+		t.Error("Expected Migrate to keep the original file as backup")
+	}
+	store := New(TestStoreFile)
+	if version := store.GetMeta().Version; version != format.Version {
+		t.Errorf("Expected Migrate to upgrade the file to version %d, got version %d instead", format.Version, version)
+	}
+	if store.Generation() != 0 {
+		t.Errorf("Expected Migrate to keep the generation, got %d instead", store.Generation())
+	}
+	checkValueForKey(t, store, "a", []byte("apple"))
+	checkValueForKey(t, store, "b", []byte("banana"))
+	store.Close()
+
+	// Files in the current version are left as they are
+	migrated, _ := os.ReadFile(TestStoreFile)
+	_ = os.Remove(backup)
+	if err := Migrate(TestStoreFile); err != nil {
+		t.Fatalf("Expected Migrate to succeed on a file in the current version, got %v instead", err)
+	}
+	if data, _ := os.ReadFile(TestStoreFile); !bytes.Equal(data, migrated) {
+		t.Error("Expected Migrate to leave a file in the current version unchanged")
+	}
+	if _, err := os.Stat(backup); !os.IsNotExist(err) {
+		t.Error("Expected Migrate to make no backup of a file in the current version")
+	}
+}
+
+func TestMigrate_NewerVersion(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+	data, _ := os.ReadFile(TestStoreFile)
+	binary.LittleEndian.PutUint16(data[4:], format.Version+1)
+	if err := os.WriteFile(TestStoreFile, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Migrate(TestStoreFile); !errors.Is(err, ErrVersion) {
+		t.Errorf("Expected ErrVersion for a file in a newer version, got %v instead", err)
+	}
+	if _, err := Open(TestStoreFile); !errors.Is(err, ErrVersion) {
+		t.Errorf("Expected Open to fail with ErrVersion for a file in a newer version, got %v instead", err)
+	}
+}
+
+func TestMigrate_ReadOnly(t *testing.T) {
+	writeVersion1File(t, TestStoreFile, map[string][]byte{"a": []byte("apple")})
+	defer deleteTestStoreFile()
+	if err := Migrate(TestStoreFile, WithSharedReadLock()); err != ErrReadOnly {
+		t.Errorf("Expected ErrReadOnly, got %v instead", err)
+	}
+}
@@ -0,0 +1,113 @@
+package sunduk
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// expiryNotifier holds the state of expiry notifications, see Hooks.OnExpire
+type expiryNotifier struct {
+	stopOnce sync.Once
+	stop     chan struct{} // stop is closed to stop notifications
+	done     chan struct{} // done is closed once notifications are stopped
+	wake     chan struct{} // wake is signaled when the expiry times of the store change
+}
+
+// WithExpiryNotice makes the store call OnExpire hooks ahead of the expiry of values by notice, so that
+// applications refresh values before they expire instead of missing them, see TTL
+func WithExpiryNotice(notice time.Duration) Option {
+	return func(o *options) {
+		o.expiryNotice = notice
+	}
+}
+
+// startNotifier starts expiry notifications if a hook is notified of them
+func (store *Sunduk) startNotifier() {
+	notified := false
+	for _, h := range store.opts.hooks {
+		notified = notified || h.OnExpire != nil
+	}
+	if !notified {
+		return
+	}
+	store.notifier.stop = make(chan struct{})
+	store.notifier.done = make(chan struct{})
+	store.notifier.wake = make(chan struct{}, 1)
+	go store.runNotifier()
+}
+
+// stopNotifier stops expiry notifications and waits for running hooks to return
+func (store *Sunduk) stopNotifier() {
+	if store.notifier.stop == nil {
+		return
+	}
+	store.notifier.stopOnce.Do(func() {
+		close(store.notifier.stop)
+	})
+	<-store.notifier.done
+}
+
+// wakeNotifier makes expiry notifications account for changed expiry times
+func (store *Sunduk) wakeNotifier() {
+	select {
+	case store.notifier.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runNotifier calls OnExpire hooks as values expire, once per expiry time of every key
+func (store *Sunduk) runNotifier() {
+	defer close(store.notifier.done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	notified := make(map[string]int64) // notified holds the expiry times keys were notified of
+	for {
+		select {
+		case <-store.notifier.stop:
+			return
+		case <-store.notifier.wake:
+		case <-timer.C:
+		}
+		// The expiry times of the store are never modified in place
+		store.mu.RLock()
+		expiry := store.expiry
+		store.mu.RUnlock()
+		now := time.Now().Add(store.opts.expiryNotice).UnixNano()
+		next := int64(math.MaxInt64)
+		var due []string
+		for k, t := range expiry {
+			switch {
+			case notified[k] == t:
+			case t <= now:
+				due = append(due, k)
+				notified[k] = t
+			case t < next:
+				next = t
+			}
+		}
+		for k, t := range notified {
+			if expiry[k] != t {
+				delete(notified, k)
+			}
+		}
+		sort.Strings(due)
+		for _, k := range due {
+			for _, h := range store.opts.hooks {
+				if h.OnExpire != nil {
+					h.OnExpire(k)
+				}
+			}
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if next != math.MaxInt64 {
+			timer.Reset(time.Duration(next - now))
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package sunduk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunduk_OnExpire(t *testing.T) {
+	expired := make(chan string, 10)
+	hooks := Hooks{OnExpire: func(key string) {
+		expired <- key
+	}}
+	store := New(TestStoreFile, WithHooks(hooks), WithExpiryNotice(50*time.Millisecond))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	start := time.Now()
+	_ = store.Put("a", []byte("1"), TTL(150*time.Millisecond))
+	_ = store.Put("b", []byte("2"))
+
+	select {
+	case k := <-expired:
+		if k != "a" {
+			t.Errorf("Expected expiry of a, got %s instead", k)
+		}
+		if elapsed := time.Since(start); elapsed >= 150*time.Millisecond {
+			t.Errorf("Expected notice ahead of expiry, got it after %v instead", elapsed)
+		}
+		// The hook may refresh the value
+		_ = store.Put("a", []byte("1"), TTL(time.Hour))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected expiry of a to be notified")
+	}
+	select {
+	case k := <-expired:
+		t.Errorf("Expected a single notice, got expiry of %s instead", k)
+	case <-time.After(200 * time.Millisecond):
+	}
+	checkValueForKey(t, store, "a", []byte("1"))
+}
@@ -0,0 +1,457 @@
+package sunduk
+
+import (
+	"os"
+	"sunduk/internal/format"
+	"time"
+)
+
+// Option configures a Sunduk on creation
+type Option func(*options)
+
+type options struct {
+	repair      RepairSource
+	logger      Logger
+	hooks       []Hooks
+	readOnly    bool
+	copies      bool
+	dedup       bool
+	delta       bool
+	unlocked    bool // unlocked is true for read-only stores running alongside a writer
+	force       bool // force is true to write to sealed stores
+	accessStats bool // accessStats is true to count reads of keys
+
+	bloomBits int  // bloomBits is the count of bits of the bloom filter for every key, 0 without bloom filter
+	lazyIndex bool // lazyIndex is true to write lookup tables, and to read them instead of the index when read-only
+	indexFile bool // indexFile is true to write the index of new store files to an index file, see WithIndexFile
+
+	indexLimits format.Limits // indexLimits bound the indexes read from store files, see WithIndexLimits
+
+	indexes map[string]IndexFunc // indexes are the secondary indexes of the store by name, see WithSecondaryIndex
+	quotas  map[string]int64     // quotas holds the size limits of the values of keys by prefix, see WithQuota
+
+	authorizer Authorizer // authorizer decides whether keys may be accessed, nil to allow every access
+	collation  *Collation // collation orders keys, nil for bytewise order
+	hash       Hash       // hash is the hash of the digests of values, see WithHash
+
+	deterministic bool // deterministic is true to write byte-identical files for identical writes, see WithDeterministic
+
+	durability Durability
+
+	reloadInterval time.Duration
+	openTimeout    time.Duration // openTimeout is the time Open waits for the store to load, 0 to wait forever
+	readTimeout    time.Duration // readTimeout is the time reads of values wait for the store file, 0 to wait forever
+	expiryNotice   time.Duration // expiryNotice is the time OnExpire hooks are called ahead of the expiry of values
+
+	tempDir  string
+	fileMode os.FileMode // fileMode is the mode of created files, 0 to create them with 0666 and keep the mode on compaction
+	uid, gid int         // uid and gid are the owner of created files, -1 to keep the default
+
+	spaceHeadroom int64 // spaceHeadroom is the free space left by flushes and compactions, negative to not check free space
+
+	cacheSize   int64
+	writeBuffer int64
+	bufferPool  int64 // bufferPool is the size of the largest chunks read into pooled buffers, 0 to pool none
+
+	sizeCap        int64          // sizeCap is the size of the store file past which entries are evicted, 0 for no limit
+	evictionPolicy EvictionPolicy // evictionPolicy chooses the entries evicted to fit sizeCap
+	changeLog      int            // changeLog is the count of changes kept by the change log, 0 to keep none
+	audit          bool           // audit is true to record mutations in the audit log, see WithAuditLog
+	auditActor     string         // auditActor is the actor of mutations written without Actor
+
+	tombstoneRetention time.Duration // tombstoneRetention is the time soft-deleted values are kept, see SoftDelete
+	trash              int           // trash is the count of values overwritten or deleted kept, see WithTrash
+
+	compactionThreshold float64
+	compactionInterval  time.Duration
+	compactionProgress  func(done, total int)
+	backgroundIO        int64 // backgroundIO is the limit of IO of compactions in bytes per second, 0 for no limit
+
+	progress func(Progress)
+
+	leakDetection bool // leakDetection is true to report stores garbage collected without Close, see WithLeakDetection
+	leakPanics    bool // leakPanics is true to panic on leaked stores instead of logging them
+
+	compressionBudget  int64
+	compressionWorkers int
+	compressionMinSize int
+	frameSize          int // frameSize is the raw size of compression frames, 0 if values are compressed whole
+}
+
+func defaultOptions() options {
+	return options{logger: stdLogger{}, uid: -1, gid: -1, cacheSize: defaultCacheSize, bufferPool: defaultBufferPool, compressionMinSize: defaultCompressionMinSize, evictionPolicy: LRU(), tombstoneRetention: defaultTombstoneRetention, spaceHeadroom: defaultSpaceHeadroom}
+}
+
+// WithRepairSource sets the source of known-good values used to repair entries failing checksum verification
+func WithRepairSource(src RepairSource) Option {
+	return func(o *options) {
+		o.repair = src
+	}
+}
+
+// WithSharedReadLock opens the store read-only with a shared lock, so that several processes can read
+// the store at once. Writes and compactions fail with ErrReadOnly. By default a store is opened with
+// an exclusive lock, and Open fails with ErrLocked while another store holds a conflicting lock
+func WithSharedReadLock() Option {
+	return func(o *options) {
+		o.readOnly = true
+	}
+}
+
+// WithReadOnly opens the store read-only without locking it, so that readers can run alongside the single
+// process writing the store. Readers see the store as it was on Open until it is reloaded, see Reload and WithAutoReload.
+// Writes and compactions fail with ErrReadOnly
+func WithReadOnly() Option {
+	return func(o *options) {
+		o.readOnly = true
+		o.unlocked = true
+	}
+}
+
+// WithAutoReload makes a read-only store check the store file every interval, and reload its index once
+// another process has written to the file or compacted it. Reloads run until the store is closed
+func WithAutoReload(interval time.Duration) Option {
+	return func(o *options) {
+		o.reloadInterval = interval
+	}
+}
+
+// WithForceWrites lets the store write to a sealed store file, see Seal. The file stays sealed,
+// but writes drop its signature
+func WithForceWrites() Option {
+	return func(o *options) {
+		o.force = true
+	}
+}
+
+// WithTempDir sets the directory of the temporary file written by compaction, the directory of the store file
+// by default. The temporary file is renamed over the store file, so dir must be on the same file system,
+// and Open fails with ErrTempDir if it isn't, see CheckTempDir
+func WithTempDir(dir string) Option {
+	return func(o *options) {
+		o.tempDir = dir
+	}
+}
+
+// WithFileMode sets the permission bits of the store file and the lock file, e.g. 0600 for stores holding secrets.
+// They are set on files created by the store regardless of the umask. By default files are created with 0666
+// masked by the umask, and compaction keeps the mode of the store file
+func WithFileMode(mode os.FileMode) Option {
+	return func(o *options) {
+		o.fileMode = mode.Perm()
+	}
+}
+
+// WithFileOwner sets the owner of files created by the store, -1 keeps the default user or group.
+// Changing the owner usually requires privileges, and it isn't supported on Windows
+func WithFileOwner(uid, gid int) Option {
+	return func(o *options) {
+		o.uid = uid
+		o.gid = gid
+	}
+}
+
+// WithValueCopies makes the store copy values kept in memory on Put and on reads, so that callers may modify
+// values they put and values they get. By default values are shared, which saves a copy per call
+func WithValueCopies() Option {
+	return func(o *options) {
+		o.copies = true
+	}
+}
+
+// WithDeduplication makes the store write the chunk of a value once for all keys with equal values, such as
+// duplicated assets. Entries of equal values point at the same chunk, found by the checksum of the value and
+// compared byte for byte. Values are deduplicated if they are compressed the same, and compaction keeps chunks
+// shared. Stores holding shared chunks should be opened with deduplication to account their garbage right
+func WithDeduplication() Option {
+	return func(o *options) {
+		o.dedup = true
+	}
+}
+
+// WithDeltaEncoding makes the store write values overwriting a key as a delta against the previous value,
+// when the delta is smaller than the value compressed. It suits values changing little between versions, such
+// as successive builds of a binary. Values of at least 4 KiB are delta-encoded, and Get rebuilds them from
+// the previous value. Deltas apply to the last value written in full, which is kept until compaction writes
+// values in full again
+func WithDeltaEncoding() Option {
+	return func(o *options) {
+		o.delta = true
+	}
+}
+
+// WithBloomFilter makes the store write a bloom filter of its keys with bitsPerKey bits for every key along
+// with the index, so that Get and Has tell most absent keys without looking them up. 10 bits per key make
+// about 1% of absent keys looked up. The filter is rebuilt on every write, and stores opened without the option
+// use the filter of the file until they write to it
+func WithBloomFilter(bitsPerKey int) Option {
+	return func(o *options) {
+		o.bloomBits = bitsPerKey
+	}
+}
+
+// WithAccessStats makes the store count reads of every key by Get, GetMany and Borrow, and keep the time of the
+// last read, see Stat. Statistics are committed to the store file with writes, by Flush and by Close, unless the
+// store is read-only or sealed. Stores opened without the option drop the statistics of the file on their first write
+func WithAccessStats() Option {
+	return func(o *options) {
+		o.accessStats = true
+	}
+}
+
+// WithLazyIndex makes the store write a lookup table of its entries along with the index, and read-only stores
+// opened with it look entries up in the table of the file instead of loading the index into memory. It makes
+// opening stores of millions of keys fast and lean, at the cost of reading the table to look up keys not cached.
+// Files without a lookup table, such as files written without the option, are loaded as usual.
+// Operations over every entry, such as Verify and Diff, read the whole table
+func WithLazyIndex() Option {
+	return func(o *options) {
+		o.lazyIndex = true
+	}
+}
+
+// WithIndexFile makes the store keep its index in an index file next to the store file, at FilePath + ".idx",
+// so that the store file only holds values and is only ever appended to. Commits append the values to the store
+// file and replace the small index file, instead of appending a new index to the store file each time. Files
+// are created in the layout with the option and switch to it on compaction, and store files with an index
+// file are read with or without the option. Both files make up the store and must be moved together, and
+// lookup tables aren't written to index files, see WithLazyIndex
+func WithIndexFile() Option {
+	return func(o *options) {
+		o.indexFile = true
+	}
+}
+
+// WithIndexLimits bounds the indexes read from store files to entries keys and to indexSize bytes once
+// decompressed, 0 for no limit. Opening or reloading a store file past the limits fails with ErrIndexLimit,
+// rather than exhausting memory on store files far larger than the application expects
+func WithIndexLimits(entries, indexSize int64) Option {
+	return func(o *options) {
+		o.indexLimits = format.Limits{Entries: entries, IndexSize: indexSize}
+	}
+}
+
+// WithDurability sets when writes are synced to disk, DurabilityNone by default. Syncing makes commits
+// and compactions survive a crash of the OS or a power loss, at the cost of slower writes
+func WithDurability(d Durability) Option {
+	return func(o *options) {
+		o.durability = d
+	}
+}
+
+// WithCacheSize limits the total size of values kept in memory once they are written to or read from the store
+// file, 64 MiB by default. The least recently used values are evicted first, and a size of 0 disables the cache
+func WithCacheSize(bytes int64) Option {
+	return func(o *options) {
+		o.cacheSize = bytes
+	}
+}
+
+// WithBufferPool sets the size of the largest chunks read into buffers reused across reads, 1 MiB by default.
+// Get reads compressed chunks into them to decompress them, and Borrow reads uncompressed values into them until
+// they are released. Pooled buffers are freed by the garbage collector, and a size of 0 disables pooling.
+// Compressor state is pooled regardless
+func WithBufferPool(bytes int64) Option {
+	return func(o *options) {
+		o.bufferPool = bytes
+	}
+}
+
+// WithWriteBuffer defers writes: Put, PutAll and Delete are applied in memory, and written to the store file
+// together once pending keys and values reach bytes, on Flush or on Close. The write filling the buffer writes
+// it while other writers wait, which bounds the memory held by pending writes. If writing fails, the error is
+// returned and the writes stay pending. Writes still pending when the process exits are lost
+func WithWriteBuffer(bytes int64) Option {
+	return func(o *options) {
+		o.writeBuffer = bytes
+	}
+}
+
+// WithLogger sets the logger receiving the events of the store. By default warnings and errors are written
+// with the standard logger, a nil logger discards every event
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		if l == nil {
+			l = nopLogger{}
+		}
+		o.logger = l
+	}
+}
+
+// WithAutoCompaction enables background compaction. Every interval the store compares the bytes left by
+// overwritten and deleted entries with the live bytes, and compacts once their ratio reaches threshold.
+// Background compaction runs until the store is closed
+func WithAutoCompaction(threshold float64, interval time.Duration) Option {
+	return func(o *options) {
+		o.compactionThreshold = threshold
+		o.compactionInterval = interval
+	}
+}
+
+// WithBackgroundIOLimit limits the IO of compactions, background ones and those run by Compact and TrainDictionary,
+// to about bytesPerSecond read from the store file and written to the new file. It keeps maintenance from
+// starving Gets of the disk, at the cost of longer compactions. Entries written meanwhile are copied unthrottled
+func WithBackgroundIOLimit(bytesPerSecond int64) Option {
+	return func(o *options) {
+		o.backgroundIO = bytesPerSecond
+	}
+}
+
+// WithCompactionProgress sets a function called during compaction with the count of entries
+// written to the new file and the total count of entries to write. See WithProgress for the bytes processed
+func WithCompactionProgress(fn func(done, total int)) Option {
+	return func(o *options) {
+		o.compactionProgress = fn
+	}
+}
+
+// WithProgress sets a function called after each entry processed by long operations, Compact, TrainDictionary,
+// background compactions and Verify, and by the imports and exports of sundukconvert, so that tools can show
+// progress bars and estimate the time remaining
+func WithProgress(fn func(Progress)) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// WithCompressionMemoryBudget limits the memory used by brotli encoders while compressing values.
+// The count of parallel compression workers is throttled to fit the budget, and if a single encoder
+// with the default 4 MiB window doesn't fit, the window is shrunk at the cost of compression ratio
+func WithCompressionMemoryBudget(bytes int64) Option {
+	return func(o *options) {
+		o.compressionBudget = bytes
+	}
+}
+
+// WithCompressionWorkers sets the count of values compressed in parallel by compaction and PutAll.
+// It defaults to the number of CPUs and is throttled by WithCompressionMemoryBudget
+func WithCompressionWorkers(n int) Option {
+	return func(o *options) {
+		o.compressionWorkers = n
+	}
+}
+
+// WithCompressionMinSize sets the size of values below which they are stored uncompressed, 64 bytes by default
+func WithCompressionMinSize(n int) Option {
+	return func(o *options) {
+		o.compressionMinSize = n
+	}
+}
+
+// WithCompressionFrames makes the store compress values larger than size bytes in independent frames of size
+// bytes, along with an index of the frames, so that GetRange decompresses only the frames holding a range and
+// verifies them against their own checksums. Smaller frames compress worse, 256 KiB frames suit large assets
+// served in ranges. Values are compressed whole by default, and files with framed values can't be read by
+// versions of the package predating frames
+func WithCompressionFrames(size int) Option {
+	return func(o *options) {
+		o.frameSize = size
+	}
+}
+
+// KeysOption selects the keys returned by Keys
+type KeysOption func(*keysOptions)
+
+type keysOptions struct {
+	reverse bool
+	prefix  string
+	offset  int
+	limit   int // limit is the maximum count of keys, 0 for no limit
+}
+
+func newKeysOptions(opts []KeysOption) (ko keysOptions) {
+	for _, opt := range opts {
+		opt(&ko)
+	}
+	return
+}
+
+// SelectKeys selects keys with opts like Keys, for stores implemented outside of the package, such as remote
+// stores. keys must be in bytewise ascending order and are selected in that order, they are modified in place
+func SelectKeys(keys []string, opts ...KeysOption) []string {
+	return newKeysOptions(opts).apply(keys, nil)
+}
+
+// Sorted returns keys in bytewise ascending order
+func Sorted() KeysOption {
+	return func(o *keysOptions) {}
+}
+
+// Reversed returns keys in bytewise descending order
+func Reversed() KeysOption {
+	return func(o *keysOptions) {
+		o.reverse = true
+	}
+}
+
+// Prefix returns only keys starting with prefix
+func Prefix(prefix string) KeysOption {
+	return func(o *keysOptions) {
+		o.prefix = prefix
+	}
+}
+
+// Offset skips the first n keys, in the order of the returned keys, for paginating through a large store
+func Offset(n int) KeysOption {
+	return func(o *keysOptions) {
+		if n > 0 {
+			o.offset = n
+		}
+	}
+}
+
+// Limit returns at most n keys, for paginating through a large store
+func Limit(n int) KeysOption {
+	return func(o *keysOptions) {
+		o.limit = n
+	}
+}
+
+// PutOption configures how Put and PutAll store values
+type PutOption func(*putOptions)
+
+type putOptions struct {
+	compression compressionMode
+	repair      bool                 // repair is true for commits rewriting repaired values, which don't change the store
+	meta        *format.Meta         // meta is the metadata committed by SetMeta, nil to keep the metadata
+	signature   []byte               // signature is the signature committed by Sign, nil to keep the signature while entries don't change
+	seal        bool                 // seal is true for the commit of Seal
+	renames     map[string]string    // renames maps keys renamed by Rename, which are deleted, to their new keys
+	chunks      map[string]chunk     // chunks holds the chunks put as they are by Copy and Append
+	expires     int64                // expires is the expiry time of the values put in unix nanoseconds, 0 if they don't expire
+	actor       string               // actor is who puts the values in the audit log, see Actor
+	tombstones  map[string]tombstone // tombstones holds the tombstones of the keys soft-deleted by SoftDelete
+	undelete    string               // undelete is the key restored by Undelete, which tombstone is dropped
+	restored    map[trashKey]bool    // restored holds the values restored from the trash by Undo and Restore
+	untrashed   bool                 // untrashed is true for commits which don't trash the values they replace
+}
+
+func newPutOptions(opts []PutOption) (po putOptions) {
+	for _, opt := range opts {
+		opt(&po)
+	}
+	return
+}
+
+// compressionMode tells whether values are compressed
+type compressionMode int
+
+const (
+	compressAuto   compressionMode = iota // compressAuto compresses values unless heuristics tell otherwise
+	compressNever                         // compressNever stores values raw
+	compressAlways                        // compressAlways compresses values regardless of heuristics
+)
+
+// Uncompressed stores values without compression, e.g. values known to be compressed already
+func Uncompressed() PutOption {
+	return func(o *putOptions) {
+		o.compression = compressNever
+	}
+}
+
+// Compressed compresses values even if they are small or look already compressed
+func Compressed() PutOption {
+	return func(o *putOptions) {
+		o.compression = compressAlways
+	}
+}
@@ -0,0 +1,169 @@
+package sunduk
+
+import (
+	"fmt"
+	"sort"
+)
+
+// OrderedKeys is an immutable list of keys in bytewise ascending order, which is the order of entries
+// on disk, or in the order of the collation of the store, see WithCollation. The order is guaranteed for
+// every OrderedKeys returned by the store and is the order followed by ForEach, Range and Cursor
+type OrderedKeys struct {
+	keys      []string
+	collation *Collation // collation orders keys, nil for bytewise order
+}
+
+// newOrderedKeys returns the keys of index in bytewise ascending order
+func newOrderedKeys(index map[string]entry) OrderedKeys {
+	keys := make([]string, 0, len(index))
+	for k := range index {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return OrderedKeys{keys: keys}
+}
+
+// Len returns the number of keys
+func (ok OrderedKeys) Len() int {
+	return len(ok.keys)
+}
+
+// At returns the i-th key
+func (ok OrderedKeys) At(i int) string {
+	return ok.keys[i]
+}
+
+// Search returns the position of the first key greater than or equal to key, or Len() if there is none
+func (ok OrderedKeys) Search(key string) int {
+	if ok.collation == nil {
+		return sort.SearchStrings(ok.keys, key)
+	}
+	return sort.Search(len(ok.keys), func(i int) bool {
+		return ok.collation.compare(ok.keys[i], key) >= 0
+	})
+}
+
+// Strings returns a copy of the keys as a slice
+func (ok OrderedKeys) Strings() []string {
+	return append([]string(nil), ok.keys...)
+}
+
+// OrderedKeys returns all keys of the store in bytewise ascending order, or in the order of the collation
+// of the store, see WithCollation
+func (store *Sunduk) OrderedKeys() OrderedKeys {
+	keys, collation := store.orderedKeys()
+	return collation.sort(keys)
+}
+
+// orderedKeys returns all keys of the store in bytewise ascending order, and the collation of the store
+func (store *Sunduk) orderedKeys() (OrderedKeys, *Collation) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if store.lazy == nil && !store.staged() {
+		return newOrderedKeys(store.index), store.collation
+	}
+	keys := make([]string, 0, store.count())
+	store.scan("", func(k string, _ entry) {
+		keys = append(keys, k)
+	})
+	// Keys of pending writes are scanned after the lookup table
+	if store.staged() {
+		sort.Strings(keys)
+	}
+	return OrderedKeys{keys: keys}, store.collation
+}
+
+// ForEach calls fn for every entry in key order. Iteration stops at the first error returned by fn
+func (store *Sunduk) ForEach(fn func(key string, value []byte) error) error {
+	return iterate(store.Get, store.OrderedKeys(), "", "", fn)
+}
+
+// Range calls fn in key order for every entry which key is in [start, end). An empty end means
+// no upper bound. Iteration stops at the first error returned by fn
+func (store *Sunduk) Range(start, end string, fn func(key string, value []byte) error) error {
+	return iterate(store.Get, store.OrderedKeys(), start, end, fn)
+}
+
+// iterate calls fn for the entries got with get of keys in [start, end), an empty end meaning no upper bound
+func iterate(get func(key string) ([]byte, bool), keys OrderedKeys, start, end string, fn func(key string, value []byte) error) error {
+	if end != "" {
+		keys.keys = keys.keys[:keys.Search(end)]
+	}
+	for i := keys.Search(start); i < keys.Len(); i++ {
+		k := keys.At(i)
+		value, ok := get(k)
+		if !ok {
+			return fmt.Errorf("unable to read value for key %q", k)
+		}
+		if err := fn(k, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cursor iterates over the entries of a store in key order. It walks the keys present
+// when the cursor was created
+type Cursor struct {
+	get  func(key string) ([]byte, bool)
+	keys OrderedKeys
+	pos  int
+}
+
+// Cursor returns a cursor over the entries of the store, positioned before the first key
+func (store *Sunduk) Cursor() *Cursor {
+	return &Cursor{get: store.Get, keys: store.OrderedKeys(), pos: -1}
+}
+
+// First moves the cursor to the first key and reports whether there is one
+func (c *Cursor) First() bool {
+	return c.move(0)
+}
+
+// Last moves the cursor to the last key and reports whether there is one
+func (c *Cursor) Last() bool {
+	return c.move(c.keys.Len() - 1)
+}
+
+// Next moves the cursor to the next key and reports whether there is one
+func (c *Cursor) Next() bool {
+	return c.move(c.pos + 1)
+}
+
+// Prev moves the cursor to the previous key and reports whether there is one
+func (c *Cursor) Prev() bool {
+	return c.move(c.pos - 1)
+}
+
+// Seek moves the cursor to the first key greater than or equal to key and reports whether there is one
+func (c *Cursor) Seek(key string) bool {
+	return c.move(c.keys.Search(key))
+}
+
+// Key returns the key at the cursor position
+func (c *Cursor) Key() string {
+	if c.pos < 0 || c.pos >= c.keys.Len() {
+		return ""
+	}
+	return c.keys.At(c.pos)
+}
+
+// Value returns the value at the cursor position as well as a bool that indicates whether it exists
+func (c *Cursor) Value() ([]byte, bool) {
+	if c.pos < 0 || c.pos >= c.keys.Len() {
+		return nil, false
+	}
+	return c.get(c.keys.At(c.pos))
+}
+
+func (c *Cursor) move(pos int) bool {
+	switch {
+	case pos < 0:
+		c.pos = -1
+	case pos > c.keys.Len():
+		c.pos = c.keys.Len()
+	default:
+		c.pos = pos
+	}
+	return c.pos >= 0 && c.pos < c.keys.Len()
+}
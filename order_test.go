@@ -0,0 +1,116 @@
+package sunduk
+
+import (
+	"sort"
+	"testing"
+	"testing/quick"
+)
+
+func TestSunduk_OrderedKeysAcrossReopen(t *testing.T) {
+	property := func(entries map[string][]byte, deleted []string) bool {
+		deleteTestStoreFile()
+		defer deleteTestStoreFile()
+		store := New(TestStoreFile)
+		if err := store.PutAll(entries); err != nil {
+			t.Log(err)
+			return false
+		}
+		for _, k := range deleted {
+			delete(entries, k)
+			_ = store.Delete(k)
+		}
+		store.Close()
+
+		expected := make([]string, 0, len(entries))
+		for k := range entries {
+			expected = append(expected, k)
+		}
+		sort.Strings(expected)
+
+		store = New(TestStoreFile)
+		defer store.Close()
+		return checkOrder(t, store, expected)
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 20}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSunduk_Range(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": nil, "b": nil, "ba": nil, "c": nil, "d": nil})
+
+	var keys []string
+	err := store.Range("b", "d", func(key string, value []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || keys[0] != "b" || keys[1] != "ba" || keys[2] != "c" {
+		t.Errorf("Expected range [b, d) to be [b ba c], got %v instead", keys)
+	}
+	store.Close()
+}
+
+func TestCursor_SeekAndPrev(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": []byte("1"), "c": []byte("3"), "e": []byte("5")})
+
+	c := store.Cursor()
+	if !c.Seek("b") || c.Key() != "c" {
+		t.Errorf("Expected Seek(\"b\") to stop at 'c', got '%s' instead", c.Key())
+	}
+	if value, ok := c.Value(); !ok || string(value) != "3" {
+		t.Errorf("Expected value at 'c' to be '3', got '%s' instead", value)
+	}
+	if !c.Prev() || c.Key() != "a" {
+		t.Errorf("Expected Prev to move to 'a', got '%s' instead", c.Key())
+	}
+	if c.Prev() {
+		t.Error("Expected Prev before the first key to report no key")
+	}
+	if c.Seek("f") {
+		t.Error("Expected Seek past the last key to report no key")
+	}
+	store.Close()
+}
+
+// checkOrder checks that OrderedKeys, ForEach and Cursor all follow expected order
+func checkOrder(t *testing.T, store *Sunduk, expected []string) bool {
+	ordered := store.OrderedKeys().Strings()
+	if len(ordered) != len(expected) {
+		t.Logf("Expected %d ordered keys, got %d instead", len(expected), len(ordered))
+		return false
+	}
+	for i := range expected {
+		if ordered[i] != expected[i] {
+			t.Logf("Expected ordered key #%d to be %q, got %q instead", i, expected[i], ordered[i])
+			return false
+		}
+	}
+
+	var visited []string
+	_ = store.ForEach(func(key string, value []byte) error {
+		visited = append(visited, key)
+		return nil
+	})
+	if len(visited) != len(expected) {
+		t.Logf("Expected ForEach to visit %d keys, got %d instead", len(expected), len(visited))
+		return false
+	}
+
+	c := store.Cursor()
+	i := len(expected) - 1
+	for ok := c.Last(); ok; ok = c.Prev() {
+		if c.Key() != expected[i] || visited[i] != expected[i] {
+			t.Logf("Expected key #%d to be %q, got %q and %q instead", i, expected[i], c.Key(), visited[i])
+			return false
+		}
+		i--
+	}
+	return i == -1
+}
@@ -0,0 +1,28 @@
+package sunduk
+
+// Pin keeps the value of key in memory apart from the cache, so that it is always served without reading the
+// store file regardless of eviction, such as the main library of a plugin bundle. The value is read now if key has
+// an entry, otherwise once key is put. Pinned values don't count in the size of the cache, see WithCacheSize
+func (store *Sunduk) Pin(key string) error {
+	if store.cache.pin(key) {
+		return nil
+	}
+	_, err := store.getMany([]string{key}, true)
+	return err
+}
+
+// Unpin lets the value of a key pinned by Pin be evicted like other cached values
+func (store *Sunduk) Unpin(key string) {
+	store.cache.unpin(key)
+}
+
+// loadPins reads the values of pinned keys which aren't held, once the cache is cleared by a reload
+func (store *Sunduk) loadPins() {
+	keys := store.cache.unheld()
+	if len(keys) == 0 {
+		return
+	}
+	if _, err := store.getMany(keys, true); err != nil {
+		store.opts.logger.Warn("unable to read pinned values", "file", store.FilePath, "err", err)
+	}
+}
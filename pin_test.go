@@ -0,0 +1,42 @@
+package sunduk
+
+import "testing"
+
+func TestSunduk_Pin(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"decoder.dll": []byte("decoder"), "a": []byte("apple"), "b": []byte("banana")})
+	store.Close()
+
+	store = New(TestStoreFile, WithCacheSize(6))
+	defer store.Close()
+	if err := store.Pin("decoder.dll"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Pin("later"); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "a", []byte("apple"))
+	checkValueForKey(t, store, "b", []byte("banana"))
+	checkValueForKey(t, store, "decoder.dll", []byte("decoder"))
+	if m := store.Metrics(); m.CacheHits != 1 || m.CacheMisses != 2 {
+		t.Errorf("Expected pinned value to be served from memory, got %d hits and %d misses instead", m.CacheHits, m.CacheMisses)
+	}
+
+	_ = store.Put("decoder.dll", []byte("decoder v2"))
+	_ = store.Put("later", []byte("pinned once put"))
+	checkValueForKey(t, store, "decoder.dll", []byte("decoder v2"))
+	checkValueForKey(t, store, "later", []byte("pinned once put"))
+	if m := store.Metrics(); m.CacheHits != 3 || m.CacheMisses != 2 {
+		t.Errorf("Expected pinned values to be held once written, got %d hits and %d misses instead", m.CacheHits, m.CacheMisses)
+	}
+	if pinned := store.DebugInfo().Index.PinnedBytes; pinned != 25 {
+		t.Errorf("Expected 25 pinned bytes, got %d instead", pinned)
+	}
+
+	store.Unpin("later")
+	store.Unpin("decoder.dll")
+	if pinned := store.DebugInfo().Index.PinnedBytes; pinned != 0 {
+		t.Errorf("Expected no pinned bytes once unpinned, got %d instead", pinned)
+	}
+}
@@ -0,0 +1,100 @@
+package sunduk
+
+import (
+	"sort"
+	"time"
+)
+
+// EvictionPolicy chooses the entries compaction evicts once the store file outgrows its size cap, see WithSizeCap
+type EvictionPolicy interface {
+	// Evict returns the keys of the candidates to evict, which sizes should add up to at least excess bytes for
+	// the store file to fit its size cap. Candidates are the entries of values that haven't expired, in key order.
+	// It is called with the store locked and must not call its methods
+	Evict(candidates []EvictionCandidate, excess int64) []string
+}
+
+// EvictionCandidate describes an entry compaction may evict, see EvictionPolicy
+type EvictionCandidate struct {
+	Key        string
+	Size       int64     // Size is the estimated size of the entry in the store file
+	Reads      uint64    // Reads is the count of reads of the key
+	LastAccess time.Time // LastAccess is the time of the last read or write, zero if unknown
+	Written    time.Time // Written is the time of the last write, zero if unknown, such as for values written without a size cap
+	Expires    time.Time // Expires is the expiry time of the value, zero if it doesn't expire, see TTL
+}
+
+// EvictionFunc is an eviction policy implemented by a function
+type EvictionFunc func(candidates []EvictionCandidate, excess int64) []string
+
+// Evict calls f
+func (f EvictionFunc) Evict(candidates []EvictionCandidate, excess int64) []string {
+	return f(candidates, excess)
+}
+
+// WithEvictionPolicy sets the policy choosing the entries evicted to fit the size cap, LRU by default
+func WithEvictionPolicy(p EvictionPolicy) Option {
+	return func(o *options) {
+		if p == nil {
+			p = LRU()
+		}
+		o.evictionPolicy = p
+	}
+}
+
+// EvictionOrder returns the eviction policy evicting candidates in the order of less, the first ones first,
+// until excess bytes are freed. Candidates less doesn't tell apart are evicted in key order
+func EvictionOrder(less func(a, b EvictionCandidate) bool) EvictionPolicy {
+	return EvictionFunc(func(candidates []EvictionCandidate, excess int64) []string {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return less(candidates[i], candidates[j])
+		})
+		var keys []string
+		for _, c := range candidates {
+			if excess <= 0 {
+				break
+			}
+			keys = append(keys, c.Key)
+			excess -= c.Size
+		}
+		return keys
+	})
+}
+
+// LRU returns the eviction policy evicting the least recently used entries first
+func LRU() EvictionPolicy {
+	return EvictionOrder(func(a, b EvictionCandidate) bool {
+		return a.LastAccess.Before(b.LastAccess)
+	})
+}
+
+// LFU returns the eviction policy evicting the least frequently read entries first, the least recently used
+// first among entries read as often
+func LFU() EvictionPolicy {
+	return EvictionOrder(func(a, b EvictionCandidate) bool {
+		if a.Reads != b.Reads {
+			return a.Reads < b.Reads
+		}
+		return a.LastAccess.Before(b.LastAccess)
+	})
+}
+
+// FIFO returns the eviction policy evicting the entries written first, regardless of reads
+func FIFO() EvictionPolicy {
+	return EvictionOrder(func(a, b EvictionCandidate) bool {
+		return a.Written.Before(b.Written)
+	})
+}
+
+// TTLFirst returns the eviction policy evicting the entries of values which expire first, the soonest to expire
+// first, then the least recently used entries of values which don't expire
+func TTLFirst() EvictionPolicy {
+	return EvictionOrder(func(a, b EvictionCandidate) bool {
+		switch {
+		case a.Expires.IsZero() != b.Expires.IsZero():
+			return !a.Expires.IsZero()
+		case !a.Expires.Equal(b.Expires):
+			return a.Expires.Before(b.Expires)
+		}
+		return a.LastAccess.Before(b.LastAccess)
+	})
+}
@@ -0,0 +1,33 @@
+package sunduk
+
+// Operations reported by Progress
+const (
+	OperationCompact = "compact"
+	OperationVerify  = "verify"
+	OperationImport  = "import"
+	OperationExport  = "export"
+)
+
+// Progress is the progress of a long operation over the entries of a store, see WithProgress
+type Progress struct {
+	Operation string // Operation is the operation in progress, such as OperationCompact
+	Done      int    // Done is the count of entries processed
+	Total     int    // Total is the count of entries to process, 0 if it isn't known, such as for imports
+	Bytes     int64  // Bytes is the size of the chunks processed as read from the store file, or of the values imported or exported
+}
+
+// progress reports the progress of an operation to the functions set by WithProgress and WithCompactionProgress
+func (store *Sunduk) progress(p Progress) {
+	if store.opts.progress != nil {
+		store.opts.progress(p)
+	}
+	if p.Operation == OperationCompact && store.opts.compactionProgress != nil {
+		store.opts.compactionProgress(p.Done, p.Total)
+	}
+}
+
+// ReportProgress reports the progress of an operation over the entries of the store run outside of the package,
+// such as the imports and exports of sundukconvert, to the function set by WithProgress
+func (store *Sunduk) ReportProgress(p Progress) {
+	store.progress(p)
+}
@@ -0,0 +1,40 @@
+package sunduk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSunduk_Progress(t *testing.T) {
+	var reports []Progress
+	store := New(TestStoreFile, WithProgress(func(p Progress) {
+		reports = append(reports, p)
+	}))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.PutAll(map[string][]byte{"a": bytes.Repeat([]byte("a"), 1000), "b": []byte("banana"), "c": []byte("cherry")})
+
+	for _, operation := range []string{OperationCompact, OperationVerify} {
+		reports = nil
+		var err error
+		if operation == OperationCompact {
+			err = store.Compact()
+		} else {
+			err = store.Verify()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reports) != 3 {
+			t.Fatalf("Expected 3 progress reports of %s, got %d instead", operation, len(reports))
+		}
+		for i, p := range reports {
+			if p.Operation != operation || p.Done != i+1 || p.Total != 3 {
+				t.Errorf("Expected progress %d/3 of %s, got %+v instead", i+1, operation, p)
+			}
+			if i > 0 && p.Bytes <= reports[i-1].Bytes {
+				t.Errorf("Expected bytes processed to grow, got %d after %d instead", p.Bytes, reports[i-1].Bytes)
+			}
+		}
+	}
+}
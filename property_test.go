@@ -0,0 +1,158 @@
+package sunduk
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/quick"
+)
+
+// opKind is the kind of an operation of a property test
+type opKind int
+
+const (
+	opPut opKind = iota
+	opDelete
+	opReopen
+	opCompact
+	opFlush
+	opKinds
+)
+
+// operation is an operation applied to both a store and its model
+type operation struct {
+	kind  opKind
+	key   string
+	value []byte
+}
+
+func (op operation) String() string {
+	switch op.kind {
+	case opPut:
+		return fmt.Sprintf("put(%q, %d bytes)", op.key, len(op.value))
+	case opDelete:
+		return fmt.Sprintf("delete(%q)", op.key)
+	case opReopen:
+		return "reopen"
+	case opCompact:
+		return "compact"
+	default:
+		return "flush"
+	}
+}
+
+// operations is a random sequence of operations. Keys are drawn from a few, so that operations often hit keys
+// written before, and values are empty, small, compressible or incompressible
+type operations []operation
+
+// Generate returns a random sequence of operations, see quick.Generator
+func (operations) Generate(random *rand.Rand, size int) reflect.Value {
+	ops := make(operations, random.Intn(4*size+1))
+	for i := range ops {
+		op := operation{kind: opKind(random.Intn(int(opKinds)))}
+		// Puts and deletes are more frequent than the rest
+		if random.Intn(2) == 0 {
+			op.kind = opKind(random.Intn(int(opDelete) + 1))
+		}
+		op.key = fmt.Sprintf("key-%d", random.Intn(8))
+		if op.kind == opPut {
+			op.value = make([]byte, random.Intn(3)*random.Intn(2000))
+			if random.Intn(2) == 0 {
+				random.Read(op.value)
+			}
+		}
+		ops[i] = op
+	}
+	return reflect.ValueOf(ops)
+}
+
+// checkModel returns an error unless the store holds the entries of model
+func checkModel(store *Sunduk, model map[string][]byte) error {
+	keys := store.Keys(Sorted())
+	want := make([]string, 0, len(model))
+	for k := range model {
+		want = append(want, k)
+	}
+	sort.Strings(want)
+	if len(keys) != len(want) || (len(keys) > 0 && !reflect.DeepEqual(keys, want)) {
+		return fmt.Errorf("keys %v instead of %v", keys, want)
+	}
+	for k, v := range model {
+		if value, ok := store.Get(k); !ok || !bytes.Equal(value, v) {
+			return fmt.Errorf("value of %d bytes (%t) instead of %d bytes for key %q", len(value), ok, len(v), k)
+		}
+	}
+	return nil
+}
+
+// applyOperations applies ops to a new store opened with opts and to a model map, and returns an error once
+// the store doesn't match the model, after every operation and after reopening the store at the end
+func applyOperations(ops operations, opts ...Option) (err error) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	store, err := Open(TestStoreFile, opts...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if store != nil {
+			store.Close()
+		}
+	}()
+	model := make(map[string][]byte)
+	for i, op := range append(ops, operation{kind: opReopen}) {
+		switch op.kind {
+		case opPut:
+			err = store.Put(op.key, op.value)
+			model[op.key] = op.value
+		case opDelete:
+			err = store.Delete(op.key)
+			delete(model, op.key)
+		case opReopen:
+			if err = store.Close(); err == nil {
+				store, err = Open(TestStoreFile, opts...)
+			} else {
+				store = nil
+			}
+		case opCompact:
+			err = store.Compact()
+		case opFlush:
+			err = store.Flush()
+		}
+		if err == nil {
+			err = checkModel(store, model)
+		}
+		if err != nil {
+			return fmt.Errorf("operation %d %v of %v: %w", i, op, ops, err)
+		}
+	}
+	return nil
+}
+
+func TestSunduk_PropertyRoundtrip(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"default":      nil,
+		"write buffer": {WithWriteBuffer(4 << 10)},
+		"dedup":        {WithDeduplication()},
+		"lazy index":   {WithLazyIndex(), WithCacheSize(0)},
+		"index file":   {WithIndexFile()},
+		"hash":         {WithHash(HashXXH64)},
+	} {
+		opts := opts
+		t.Run(name, func(t *testing.T) {
+			property := func(ops operations) bool {
+				if err := applyOperations(ops, opts...); err != nil {
+					t.Log(err)
+					return false
+				}
+				return true
+			}
+			if err := quick.Check(property, &quick.Config{MaxCount: 30, Rand: rand.New(rand.NewSource(1))}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package sunduk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithQuota limits the total size of the values of keys starting with prefix to bytes, so that one tenant of a
+// store shared by several can't take up the whole file. Writes adding to the values under prefix past the quota
+// fail with ErrQuotaExceeded, writes shrinking them succeed even over the quota. Sizes are the uncompressed sizes
+// of values, and the sizes of the values under prefix are summed for every write to it. Quotas of nested prefixes
+// all apply
+func WithQuota(prefix string, bytes int64) Option {
+	return func(o *options) {
+		if o.quotas == nil {
+			o.quotas = make(map[string]int64)
+		}
+		o.quotas[prefix] = bytes
+	}
+}
+
+// checkQuotas returns ErrQuotaExceeded if values, deleted keys, and the chunks and the renames of po grow the values
+// under the prefix of a quota past it, see WithQuota. It must be called with writeMu held
+func (store *Sunduk) checkQuotas(values map[string][]byte, deleted []string, po putOptions) error {
+	if len(store.opts.quotas) == 0 || len(values)+len(po.chunks)+len(po.renames) == 0 {
+		return nil
+	}
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	size := func(key string) int64 {
+		e, _ := store.lookup(key)
+		return e.RawSize
+	}
+	// sizes holds the sizes of the values of keys once written, 0 for deleted keys
+	sizes := make(map[string]int64, len(values)+len(deleted)+len(po.chunks)+len(po.renames))
+	for _, k := range deleted {
+		sizes[k] = 0
+	}
+	for from, to := range po.renames {
+		sizes[to] = size(from)
+	}
+	for k, v := range values {
+		sizes[k] = int64(len(v))
+	}
+	for k, c := range po.chunks {
+		if c.data != nil {
+			sizes[k] = c.rawSize
+		} else {
+			sizes[k] = int64(len(c.value))
+		}
+	}
+	for prefix, quota := range store.opts.quotas {
+		var grown int64
+		for k, s := range sizes {
+			if strings.HasPrefix(k, prefix) {
+				grown += s - size(k)
+			}
+		}
+		if grown <= 0 {
+			continue
+		}
+		var used int64
+		store.scan(prefix, func(_ string, e entry) {
+			used += e.RawSize
+		})
+		if used+grown > quota {
+			return fmt.Errorf("%w: %d bytes used of %d under prefix %q", ErrQuotaExceeded, used+grown, quota, prefix)
+		}
+	}
+	return nil
+}
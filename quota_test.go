@@ -0,0 +1,42 @@
+package sunduk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSunduk_Quota(t *testing.T) {
+	store := New(TestStoreFile, WithQuota("tenant1/", 10))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	if err := store.Put("tenant1/a", []byte("12345678")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("tenant1/b", []byte("123")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v instead", err)
+	}
+	if store.Has("tenant1/b") {
+		t.Error("Expected value over quota not to be put")
+	}
+	if err := store.Put("tenant2/b", []byte("12345678901234567890")); err != nil {
+		t.Errorf("Expected other prefixes to have no quota, got %v instead", err)
+	}
+	if err := store.Put("tenant1/a", []byte("1234567890")); err != nil {
+		t.Errorf("Expected overwrite within quota to succeed, got %v instead", err)
+	}
+	if err := store.Copy("tenant2/b", "tenant1/c"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded for copy, got %v instead", err)
+	}
+	if err := store.Rename("tenant2/b", "tenant1/c"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded for rename, got %v instead", err)
+	}
+	if err := store.Append("tenant1/a", []byte("1")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded for append, got %v instead", err)
+	}
+	if err := store.Delete("tenant1/a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put("tenant1/b", []byte("123")); err != nil {
+		t.Errorf("Expected put within quota once deleted, got %v instead", err)
+	}
+}
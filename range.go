@@ -0,0 +1,156 @@
+package sunduk
+
+import (
+	"fmt"
+	"io"
+	"sunduk/internal/format"
+	"sync/atomic"
+)
+
+// GetRange returns length bytes of the value of key from offset off on, such as to serve HTTP range requests.
+// Ranges past the end of the value are cut short. Only the part of the chunk up to the end of the range is read
+// and decompressed, uncompressed values are read from off on, and values compressed in frames are read from the
+// first frame holding the range on, see WithCompressionFrames, so ranges of large values are served without
+// reading whole values. Partial reads can't be verified against the checksum of the value, only frames are
+// verified against their own checksums, unless the read fails and the whole value is read instead. Values read
+// aren't cached. It returns ErrNotFound if key has no entry
+func (store *Sunduk) GetRange(key string, off, length int64) ([]byte, error) {
+	if off < 0 || length < 0 {
+		return nil, ErrRange
+	}
+	if err := store.authorize(key, OperationRead); err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&store.counters.gets, 1)
+	store.mu.RLock()
+	if !store.mayContain(key) {
+		store.mu.RUnlock()
+		return nil, ErrNotFound
+	}
+	value, ok := store.data[key]
+	if !ok {
+		value, ok = store.cache.get(key)
+	}
+	if ok {
+		store.mu.RUnlock()
+		atomic.AddUint64(&store.counters.cacheHits, 1)
+		store.access.record(key)
+		return cut(value, off, length), nil
+	}
+	e, ok := store.lookup(key)
+	if !ok {
+		store.mu.RUnlock()
+		return nil, ErrNotFound
+	}
+	atomic.AddUint64(&store.counters.cacheMisses, 1)
+	file := store.file.acquire()
+	store.mu.RUnlock()
+	defer file.release()
+
+	if file != nil && e.hasSum && e.Flags&format.FlagDelta == 0 {
+		if value, err := store.readRange(file, e, off, length); err == nil {
+			store.access.record(key)
+			return value, nil
+		}
+	}
+	// Deltas and values of legacy files are read in full, as are values which partial reads fail to be repaired
+	value, repaired, err := store.fetch(file, key, e)
+	if err != nil {
+		return nil, err
+	}
+	if repaired {
+		store.rewriteRepaired(key, e, value)
+	}
+	store.access.record(key)
+	return cut(value, off, length), nil
+}
+
+// readRange reads length bytes of the value of e from off on, decompressing its chunk up to the end of the range.
+// The value of an appended chunk is read from its base followed by the chunk
+func (store *Sunduk) readRange(file *handle, e entry, off, length int64) ([]byte, error) {
+	if off >= e.RawSize {
+		return []byte{}, nil
+	}
+	if length > e.RawSize-off {
+		length = e.RawSize - off
+	}
+	var r io.Reader
+	switch {
+	case e.Flags&format.FlagFramed != 0 && e.Flags&format.FlagAppend == 0:
+		return store.readFrames(file, e, off, length)
+	case e.Flags&format.FlagAppend != 0:
+		r = io.MultiReader(store.chunkReader(file, e.Base.Offset, e.Base.Size, e.Base.Flags), store.chunkReader(file, e.Offset, e.Size, e.Flags))
+	case e.Flags&format.FlagRaw != 0:
+		r, off = store.chunkReader(file, e.Offset+off, e.Size-off, e.Flags), 0
+	default:
+		r = store.chunkReader(file, e.Offset, e.Size, e.Flags)
+	}
+	if _, err := io.CopyN(io.Discard, r, off); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrChecksum, err)
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrChecksum, err)
+	}
+	return value, nil
+}
+
+// readFrames reads length bytes of the value of the framed chunk of e from off on, decompressing and
+// verifying only the frames holding the range
+func (store *Sunduk) readFrames(file *handle, e entry, off, length int64) ([]byte, error) {
+	frames, err := format.ReadFrames(store.chunkSection(file, e.Offset, e.Size), e.Size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrChecksum, err)
+	}
+	first, last := frames.Find(off, length)
+	if first > last {
+		return nil, fmt.Errorf("%w: range is out of frames", ErrChecksum)
+	}
+	start, end := frames.Frames[first].Offset, frames.Frames[last].Offset+frames.Frames[last].Size
+	data, err := store.readChunk(file, entry{Offset: e.Offset + start, Size: end - start})
+	if err != nil {
+		return nil, err
+	}
+	value := make([]byte, 0, length)
+	for _, f := range frames.Frames[first : last+1] {
+		frame, err := format.DecodeFrame(data[f.Offset-start:f.Offset-start+f.Size], f)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrChecksum, err)
+		}
+		value = append(value, frame...)
+	}
+	return cut(value, off-int64(first)*frames.Size, length), nil
+}
+
+// chunkSection returns a reader of the size bytes at offset in file, counting the bytes read
+func (store *Sunduk) chunkSection(file *handle, offset, size int64) io.Reader {
+	return &countingReader{r: io.NewSectionReader(file, offset, size), n: &store.counters.bytesRead}
+}
+
+// chunkReader returns a reader of the value held by the chunk of size bytes at offset in file
+func (store *Sunduk) chunkReader(file *handle, offset, size int64, flags uint64) io.Reader {
+	return format.NewChunkReader(store.chunkSection(file, offset, size), flags, file.dict.bytes())
+}
+
+// countingReader counts the bytes read from r in n
+type countingReader struct {
+	r io.Reader
+	n *uint64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddUint64(r.n, uint64(n))
+	return n, err
+}
+
+// cut returns a copy of length bytes of value from off on, cut short at the end of value
+func cut(value []byte, off, length int64) []byte {
+	if off >= int64(len(value)) {
+		return []byte{}
+	}
+	if length > int64(len(value))-off {
+		length = int64(len(value)) - off
+	}
+	return append([]byte{}, value[off:off+length]...)
+}
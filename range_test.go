@@ -0,0 +1,103 @@
+package sunduk
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestSunduk_GetRange(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	text := bytes.Repeat([]byte("0123456789abcdef"), 4<<10)
+	blob := make([]byte, 256<<10)
+	_, _ = rand.Read(blob)
+	_ = store.PutAll(map[string][]byte{"text": text, "blob": blob})
+	_ = store.Append("text", []byte("tail"))
+	text = append(text, "tail"...)
+
+	for _, c := range []struct {
+		key         string
+		value       []byte
+		off, length int64
+	}{
+		{"text", text, 0, 10},
+		{"text", text, 1000, 5000},
+		{"text", text, int64(len(text)) - 6, 100},
+		{"text", text, int64(len(text)) + 1, 10},
+		{"blob", blob, 100 << 10, 1000},
+		{"blob", blob, 0, 0},
+	} {
+		got, err := store.GetRange(c.key, c.off, c.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := cut(c.value, c.off, c.length); !bytes.Equal(got, want) {
+			t.Errorf("Expected %d bytes of %q from %d, got %d bytes instead", len(want), c.key, c.off, len(got))
+		}
+	}
+
+	read := store.Metrics().BytesRead
+	if _, err := store.GetRange("blob", 200<<10, 100); err != nil {
+		t.Fatal(err)
+	}
+	if n := store.Metrics().BytesRead - read; n > 1<<10 {
+		t.Errorf("Expected only the range of an uncompressed value to be read, got %d bytes read instead", n)
+	}
+
+	if _, err := store.GetRange("missing", 0, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing key, got %v instead", err)
+	}
+	if _, err := store.GetRange("text", -1, 10); !errors.Is(err, ErrRange) {
+		t.Errorf("Expected ErrRange for a negative offset, got %v instead", err)
+	}
+}
+
+func TestSunduk_GetRangeCorrupted(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	value := bytes.Repeat([]byte("corrupted value "), 1<<10)
+	_ = store.Put("key", value)
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	if _, err := store.GetRange("key", 0, int64(len(value))); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum for a corrupted value, got %v instead", err)
+	}
+}
+
+func TestSunduk_GetRangeFrames(t *testing.T) {
+	store := New(TestStoreFile, WithCompressionFrames(16<<10))
+	defer deleteTestStoreFile()
+	value := make([]byte, 1<<20)
+	for i := range value {
+		value[i] = byte(i / 1000)
+	}
+	_ = store.Put("asset", value)
+	if n := store.DebugInfo().Index.FramedEntries; n != 1 {
+		t.Errorf("Expected 1 framed entry, got %d instead", n)
+	}
+	checkValueForKey(t, store, "asset", value)
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	read := store.Metrics().BytesRead
+	got, err := store.GetRange("asset", 500<<10, 20<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, value[500<<10:520<<10]) {
+		t.Errorf("Expected range of framed value, got %d bytes instead", len(got))
+	}
+	if n := store.Metrics().BytesRead - read; n > 16<<10 {
+		t.Errorf("Expected only frames holding the range to be read, got %d bytes read instead", n)
+	}
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected store with framed values to verify, got %v instead", err)
+	}
+}
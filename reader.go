@@ -0,0 +1,171 @@
+package sunduk
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sunduk/internal/format"
+	"sunduk/internal/vfs"
+	"time"
+)
+
+// Reader reads a store file without any of the write machinery of Sunduk: no lock, no cache, no pending writes
+// and no background work. It holds the entries of the index in a sorted slice rather than a map, and reads
+// values from the file on every Get, for embedded consumers that only ever read distribution bundles. The file
+// is read as it was on OpenReader, and must not be compacted while it is read. Get and Keys may be called
+// concurrently, but not along with Close
+type Reader struct {
+	file    vfs.File
+	entries []format.Entry // entries are the entries of the index in bytewise ascending key order
+	expiry  []format.Expiry
+	dict    *dictionary
+	hash    uint64
+	legacy  bool // legacy is true for files in the legacy layout, which entries have no checksums
+}
+
+// OpenReader opens the store file at filePath for reading
+func OpenReader(filePath string) (*Reader, error) {
+	file, err := fsys.OpenFile(filePath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{file: file}
+	if err := r.readIndex(filePath); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// readIndex reads the index of the store file at path in any format version
+func (r *Reader) readIndex(path string) error {
+	info, err := r.file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	version, err := format.ReadVersion(r.file)
+	if err != nil {
+		return err
+	}
+	var index format.Index
+	switch version {
+	case 0:
+		r.legacy = true
+		index.Entries, err = format.ReadLegacyIndex(r.file)
+	case 1, 2, format.Version:
+		var id format.FileID
+		var ok bool
+		if id, ok, err = format.ReadFileID(r.file); err == nil && ok {
+			index, err = readReaderIndexFile(path+".idx", id, info.Size())
+		} else if err == nil {
+			index, err = format.ReadIndex(r.file, info.Size())
+		}
+	default:
+		return fmt.Errorf("%w %d, the latest supported version is %d", ErrVersion, version, format.Version)
+	}
+	if err != nil {
+		return err
+	}
+	if d := index.Dictionary; d.Size > 0 {
+		data, err := format.ReadDictionary(r.file, d)
+		if err != nil {
+			return err
+		}
+		r.dict = &dictionary{data: data, offset: d.Offset}
+	}
+	r.entries, r.expiry, r.hash = index.Entries, index.Expiry, index.Hash
+	return nil
+}
+
+// readReaderIndexFile reads the index file at path, checking that it holds the index of the store file with id,
+// of size bytes
+func readReaderIndexFile(path string, id format.FileID, size int64) (format.Index, error) {
+	file, err := fsys.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return format.Index{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return format.Index{}, err
+	}
+	index, err := format.ReadIndexFile(file, info.Size())
+	if err == nil && (index.Data.ID != id || index.Data.Size > size) {
+		err = fmt.Errorf("%w: %s isn't the index of the store file", ErrCorruptHeader, path)
+	}
+	return index, err
+}
+
+// Get returns the value of key and true, or false if the store file has no value for key, it expired or it
+// can't be read or fails verification
+func (r *Reader) Get(key string) ([]byte, bool) {
+	i := sort.Search(len(r.entries), func(i int) bool { return r.entries[i].Key >= key })
+	if i == len(r.entries) || r.entries[i].Key != key || r.expired(key) {
+		return nil, false
+	}
+	value, err := r.read(r.entries[i])
+	return value, err == nil
+}
+
+// expired returns true if the value of key expired
+func (r *Reader) expired(key string) bool {
+	i := sort.Search(len(r.expiry), func(i int) bool { return r.expiry[i].Key >= key })
+	return i < len(r.expiry) && r.expiry[i].Key == key && r.expiry[i].Time <= time.Now().UnixNano()
+}
+
+// read reads, decodes and verifies the value of e
+func (r *Reader) read(e format.Entry) ([]byte, error) {
+	if r.file == nil {
+		return nil, ErrClosed
+	}
+	data := make([]byte, e.Size)
+	if _, err := r.file.ReadAt(data, e.Offset); err != nil {
+		return nil, err
+	}
+	var value []byte
+	var err error
+	if e.Flags&(format.FlagDelta|format.FlagAppend) != 0 {
+		base := make([]byte, e.Base.Size)
+		if _, err := r.file.ReadAt(base, e.Base.Offset); err != nil {
+			return nil, err
+		}
+		if e.Flags&format.FlagDelta != 0 {
+			value, err = decodeDelta(data, base, e.Base, r.dict)
+		} else {
+			value, err = decodeAppended(data, base, e.Flags, e.Base, r.dict)
+		}
+	} else {
+		value, err = decodeChunk(data, e.Flags, r.dict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !r.legacy && (int64(len(value)) != e.RawSize || checksum(value) != e.Sum) {
+		return nil, ErrChecksum
+	}
+	if e.Digest != "" && string(format.Digest(r.hash, value)) != e.Digest {
+		return nil, ErrChecksum
+	}
+	return value, nil
+}
+
+// Keys returns the keys of the store file which values haven't expired, in bytewise ascending order
+func (r *Reader) Keys() []string {
+	keys := make([]string, 0, len(r.entries))
+	for _, e := range r.entries {
+		if len(r.expiry) == 0 || !r.expired(e.Key) {
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys
+}
+
+// Close closes the store file. Later reads fail
+func (r *Reader) Close() error {
+	if r.file == nil {
+		return ErrClosed
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
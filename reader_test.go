@@ -0,0 +1,46 @@
+package sunduk
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	store := New(TestStoreFile, WithDeltaEncoding(), WithHash(HashXXH64))
+	large := make([]byte, 8<<10)
+	_ = store.Put("b", []byte("value b"))
+	_ = store.Put("a", []byte("value a"))
+	_ = store.Put("large", large)
+	large[0] = 1
+	_ = store.Put("large", large)
+	_ = store.Put("expired", []byte("gone"), TTL(time.Nanosecond))
+	store.Close()
+
+	r, err := OpenReader(TestStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := r.Keys(); !reflect.DeepEqual(keys, []string{"a", "b", "large"}) {
+		t.Errorf("Expected keys [a b large], got %v instead", keys)
+	}
+	if value, ok := r.Get("a"); !ok || string(value) != "value a" {
+		t.Errorf("Expected 'value a', got %q (%t) instead", value, ok)
+	}
+	if value, ok := r.Get("large"); !ok || !reflect.DeepEqual(value, large) {
+		t.Errorf("Expected delta-encoded value, got %d bytes (%t) instead", len(value), ok)
+	}
+	for _, k := range []string{"missing", "expired"} {
+		if _, ok := r.Get(k); ok {
+			t.Errorf("Expected no value for %q", k)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Get("a"); ok {
+		t.Error("Expected closed reader to fail")
+	}
+}
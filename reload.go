@@ -0,0 +1,145 @@
+package sunduk
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reloader holds the state of background reloads
+type reloader struct {
+	stopOnce sync.Once
+	stop     chan struct{} // stop is closed to stop background reloads
+	done     chan struct{} // done is closed once background reloads are stopped
+}
+
+// Reload reads the index of the store file again and swaps it in atomically, so that a long-running reader
+// picks up changes made by another process, such as a writer or the command line tool. Nothing is read if
+// the file didn't change since it was loaded. On failure the store keeps serving the index it had
+func (store *Sunduk) Reload() error {
+	_, err := store.reload()
+	return err
+}
+
+// changed returns true if the store file was written or replaced since it was loaded. Commits always
+// grow the file and compactions replace it, so the identity and the size of the file tell its generation.
+// Commits to stores with an index file replace the index file, so its identity tells the generation instead
+func (store *Sunduk) changed() (bool, error) {
+	info, err := os.Stat(store.FilePath)
+	if err != nil {
+		return false, err
+	}
+	store.mu.RLock()
+	file := store.file.acquire()
+	size := store.size
+	indexFile, indexInfo := store.hasIndexFile(), store.indexInfo
+	store.mu.RUnlock()
+	if file == nil {
+		return true, nil
+	}
+	defer file.release()
+	loaded, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !indexFile || !os.SameFile(info, loaded) {
+		return !os.SameFile(info, loaded) || info.Size() != size, nil
+	}
+	current, err := os.Stat(store.indexPath())
+	if err != nil {
+		return false, err
+	}
+	return indexInfo == nil || !os.SameFile(current, indexInfo), nil
+}
+
+// reload reads the index of the store file again if the file changed since it was loaded, and swaps it in.
+// The store is left as it was if the file can't be read, e.g. while another process is in the middle of a commit
+func (store *Sunduk) reload() (bool, error) {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if atomic.LoadInt32(&store.closed) != 0 {
+		return false, ErrClosed
+	}
+	if changed, err := store.changed(); err != nil || !changed {
+		return false, err
+	}
+	if err := store.acquireLock(); err != nil {
+		return false, err
+	}
+	file, err := fsys.OpenFile(store.FilePath, store.openFlag(), 0)
+	if err != nil {
+		return false, err
+	}
+	next := &Sunduk{FilePath: store.FilePath, index: make(map[string]entry), file: newHandle(file, nil), opts: store.opts, enc: store.enc, access: newAccessStats(store.opts)}
+	if err := next.readFormat(); err != nil {
+		next.file.release()
+		return false, err
+	}
+
+	store.mu.Lock()
+	previous := store.file
+	store.file = next.file
+	store.leak.track(store.file, store.lock)
+	store.data = make(map[string][]byte)
+	store.pending = newPending()
+	store.cache.clear()
+	store.index = next.index
+	store.lazy = next.lazy
+	store.size = next.size
+	store.tail = next.tail
+	store.indexInfo = next.indexInfo
+	store.legacy = next.legacy
+	store.dict = next.dict
+	if next.collation != nil {
+		store.collation = next.collation
+	}
+	store.setHeader(next.header())
+	store.access.replace(next.access)
+	store.chunks = nil
+	store.mu.Unlock()
+	previous.release()
+	store.loadPins()
+	store.opts.logger.Info("reloaded store", "file", store.FilePath, "entries", len(next.index), "size", next.size)
+	return true, nil
+}
+
+// startReloader starts background reloads if they are enabled
+func (store *Sunduk) startReloader() {
+	if store.opts.reloadInterval <= 0 || !store.opts.readOnly {
+		return
+	}
+	store.reloader.stop = make(chan struct{})
+	store.reloader.done = make(chan struct{})
+	go store.runReloader(store.opts.reloadInterval)
+}
+
+// stopReloader stops background reloads and waits for a running reload to finish
+func (store *Sunduk) stopReloader() {
+	if store.reloader.stop == nil {
+		return
+	}
+	store.reloader.stopOnce.Do(func() {
+		close(store.reloader.stop)
+	})
+	<-store.reloader.done
+}
+
+// runReloader reloads the store every interval if the store file changed
+func (store *Sunduk) runReloader(interval time.Duration) {
+	defer close(store.reloader.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-store.reloader.stop:
+			return
+		case <-ticker.C:
+		}
+		// Failures, such as reading a commit which isn't finished yet, are retried on the next tick
+		if _, err := store.reload(); err != nil {
+			store.opts.logger.Debug("unable to reload store", "file", store.FilePath, "err", err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package sunduk
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// waitForValue polls store until key has value, failing the test after a second
+func waitForValue(t *testing.T, store *Sunduk, key string, value []byte) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if v, ok := store.Get(key); ok && string(v) == string(value) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Expected key %s to be reloaded with value %s", key, value)
+}
+
+func TestSunduk_ReadOnlyAlongsideWriter(t *testing.T) {
+	writer := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer writer.Close()
+	_ = writer.Put("a", []byte("apple"))
+
+	reader, err := Open(TestStoreFile, WithReadOnly(), WithAutoReload(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Expected read-only Open to succeed alongside a writer, got %v instead", err)
+	}
+	defer reader.Close()
+	checkValueForKey(t, reader, "a", []byte("apple"))
+	if err := reader.Put("b", []byte("banana")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly on Put to a read-only store, got %v instead", err)
+	}
+
+	_ = writer.Put("b", []byte("banana"))
+	waitForValue(t, reader, "b", []byte("banana"))
+
+	_ = writer.Delete("a")
+	_ = writer.Put("c", []byte("cherry"))
+	if err := writer.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	waitForValue(t, reader, "c", []byte("cherry"))
+	checkKeyNotExists(t, reader, "a")
+	checkValueForKey(t, reader, "b", []byte("banana"))
+}
+
+func TestSunduk_ReloadUnchangedFile(t *testing.T) {
+	writer := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer writer.Close()
+	_ = writer.Put("a", []byte("apple"))
+
+	reader, err := Open(TestStoreFile, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if reloaded, err := reader.reload(); err != nil || reloaded {
+		t.Errorf("Expected unchanged file not to be reloaded, got %v, %v instead", reloaded, err)
+	}
+	_ = writer.Put("b", []byte("banana"))
+	if reloaded, err := reader.reload(); err != nil || !reloaded {
+		t.Errorf("Expected changed file to be reloaded, got %v, %v instead", reloaded, err)
+	}
+	checkValueForKey(t, reader, "b", []byte("banana"))
+}
+
+func TestSunduk_Reload(t *testing.T) {
+	writer := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer writer.Close()
+	_ = writer.PutAll(map[string][]byte{"a": []byte("apple"), "b": []byte("banana")})
+
+	reader, err := Open(TestStoreFile, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	_ = writer.Put("a", []byte("apricot"))
+	_ = writer.Delete("b")
+	checkValueForKey(t, reader, "a", []byte("apple"))
+	checkValueForKey(t, reader, "b", []byte("banana"))
+
+	if err := reader.Reload(); err != nil {
+		t.Fatalf("Expected Reload to succeed, got %v instead", err)
+	}
+	checkValueForKey(t, reader, "a", []byte("apricot"))
+	checkKeyNotExists(t, reader, "b")
+	if reader.Count() != 1 {
+		t.Errorf("Expected 1 entry after Reload, got %d instead", reader.Count())
+	}
+}
+
+func TestSunduk_ReloadKeepsIndexOnFailure(t *testing.T) {
+	writer := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = writer.Put("a", []byte("apple"))
+
+	reader, err := Open(TestStoreFile, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	writer.Close()
+	// A partial commit leaves no valid trailer at the end of the file
+	file, err := os.OpenFile(TestStoreFile, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.Write([]byte("partial chunk"))
+	_ = file.Close()
+
+	if err := reader.Reload(); err == nil {
+		t.Error("Expected Reload of a partially written file to fail")
+	}
+	checkValueForKey(t, reader, "a", []byte("apple"))
+}
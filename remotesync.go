@@ -0,0 +1,345 @@
+package sunduk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sunduk/internal/format"
+)
+
+// Kinds of the records of a sync stream, see ApplySync
+const (
+	syncEnd    = iota // syncEnd ends the stream
+	syncChunk         // syncChunk puts a compressed chunk as it is
+	syncValue         // syncValue puts a value
+	syncDelete        // syncDelete deletes a key
+)
+
+// syncBatchSize is the size of the values and chunks ApplySync commits at once
+const syncBatchSize = 64 << 20
+
+// SyncSum identifies the value of a key by its checksum and size, see SyncTo
+type SyncSum struct {
+	Key  string `json:"key"`
+	Sum  uint32 `json:"sum"`
+	Size int64  `json:"size"`
+}
+
+// SyncTo makes the store at url, served by the sundukhttp package, hold the same values as the store. It compares
+// the checksums of values with the remote store and only sends the values that differ, and deletes the keys the
+// store doesn't have. Compressed chunks are sent as they are, values compressed with the dictionary of the store
+// or chained to another chunk are sent whole. The remote store commits the values in batches as they arrive,
+// so an interrupted sync leaves it partly synced, and the next sync sends what is left
+func (store *Sunduk) SyncTo(url string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	var remote []SyncSum
+	if err = checkResponse(resp); err == nil {
+		err = json.NewDecoder(resp.Body).Decode(&remote)
+	}
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("unable to read checksums of %s: %w", url, err)
+	}
+
+	store.mu.RLock()
+	file, index := store.file.acquire(), store.loadIndex()
+	data := make(map[string][]byte, len(store.data))
+	for k, v := range store.data {
+		data[k] = v
+	}
+	store.mu.RUnlock()
+	defer file.release()
+	sums, err := store.sums(file, index, data)
+	if err != nil {
+		return err
+	}
+	var send, deleted []string
+	remoteSums := make(map[string]valueSum, len(remote))
+	for _, s := range remote {
+		remoteSums[s.Key] = valueSum{s.Sum, s.Size}
+		if _, ok := sums[s.Key]; !ok {
+			deleted = append(deleted, s.Key)
+		}
+	}
+	for k, s := range sums {
+		if rs, ok := remoteSums[k]; !ok || rs != s {
+			send = append(send, k)
+		}
+	}
+	sort.Strings(send)
+	if len(send)+len(deleted) == 0 {
+		return nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		err := func() error {
+			for _, k := range deleted {
+				if err := writeSyncRecord(w, syncDelete, k, chunk{}); err != nil {
+					return err
+				}
+			}
+			for _, k := range send {
+				e := index[k]
+				c, err := store.copyChunk(file, k, index, data, e.Flags&format.FlagDict != 0)
+				if err != nil {
+					return err
+				}
+				kind := byte(syncChunk)
+				if c.data == nil {
+					kind = syncValue
+				}
+				if err := writeSyncRecord(w, kind, k, c); err != nil {
+					return err
+				}
+			}
+			if err := writeSyncRecord(w, syncEnd, "", chunk{}); err != nil {
+				return err
+			}
+			return w.Flush()
+		}()
+		pw.CloseWithError(err)
+	}()
+	resp, err = http.Post(url, "application/octet-stream", pr)
+	pr.Close()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return fmt.Errorf("unable to sync %s: %w", url, err)
+	}
+	store.opts.logger.Info("synced store", "file", store.FilePath, "url", url, "sent", len(send), "deleted", len(deleted))
+	return nil
+}
+
+// SyncSums returns the checksums of the values of the store in key order, for SyncTo to tell the values to send
+func (store *Sunduk) SyncSums() []SyncSum {
+	store.mu.RLock()
+	file, index := store.file.acquire(), store.loadIndex()
+	data := make(map[string][]byte, len(store.data))
+	for k, v := range store.data {
+		data[k] = v
+	}
+	store.mu.RUnlock()
+	defer file.release()
+	// Values which can't be read are left out, so that the sync sends them again
+	sums, _ := store.sums(file, index, data)
+	list := make([]SyncSum, 0, len(sums))
+	for k, s := range sums {
+		list = append(list, SyncSum{Key: k, Sum: s.Sum, Size: s.Size})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// ApplySync applies the values and the deletions sent by SyncTo, read from r. Values are committed in batches
+// as they are read, chunks are checked against their checksums before they are committed
+func (store *Sunduk) ApplySync(r io.Reader) error {
+	br := bufio.NewReader(r)
+	values := make(map[string][]byte)
+	chunks := make(map[string]chunk)
+	var deleted []string
+	var size int64
+	commit := func() error {
+		if len(values)+len(chunks)+len(deleted) == 0 {
+			return nil
+		}
+		if err := store.writable(); err != nil {
+			return err
+		}
+		store.writeMu.Lock()
+		defer store.writeMu.Unlock()
+		if err := store.checkOpen(); err != nil {
+			return err
+		}
+		info, err := store.flush(values, deleted, putOptions{chunks: chunks})
+		if err != nil {
+			return err
+		}
+		store.notifyFlush(info)
+		values, chunks, deleted, size = make(map[string][]byte), make(map[string]chunk), nil, 0
+		return nil
+	}
+	for {
+		kind, key, c, err := readSyncRecord(br)
+		if err != nil {
+			return fmt.Errorf("unable to read sync stream: %w", err)
+		}
+		switch kind {
+		case syncEnd:
+			return commit()
+		case syncDelete:
+			deleted = append(deleted, key)
+		case syncValue:
+			values[key] = c.value
+		case syncChunk:
+			chunks[key] = c
+		}
+		if size += int64(len(c.value) + len(c.data)); size >= syncBatchSize {
+			if err := commit(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sums returns the checksums of the values of index and of data. Values which checksum isn't in the index,
+// such as values of legacy files, are read to compute it. Values which can't be read are left out, and the
+// error of the first one is returned
+func (store *Sunduk) sums(file *handle, index map[string]entry, data map[string][]byte) (map[string]valueSum, error) {
+	sums := make(map[string]valueSum, len(index))
+	var ferr error
+	for k, e := range index {
+		value, ok := data[k]
+		if !ok && e.hasSum {
+			sums[k] = valueSum{e.Sum, e.RawSize}
+			continue
+		}
+		if !ok {
+			var err error
+			if value, _, err = store.fetch(file, k, e); err != nil {
+				if ferr == nil {
+					ferr = fmt.Errorf("unable to read value for key %q: %w", k, err)
+				}
+				continue
+			}
+		}
+		sums[k] = valueSum{checksum(value), int64(len(value))}
+	}
+	return sums, ferr
+}
+
+// writeSyncRecord writes a record of a sync stream: the kind and the key, followed for chunks by the data, the
+// flags, the size of the value and its checksum of c, and for values by the value of c
+func writeSyncRecord(w *bufio.Writer, kind byte, key string, c chunk) error {
+	var vb [binary.MaxVarintLen64]byte
+	uvarint := func(v uint64) {
+		_, _ = w.Write(vb[:binary.PutUvarint(vb[:], v)])
+	}
+	_ = w.WriteByte(kind)
+	uvarint(uint64(len(key)))
+	_, _ = w.WriteString(key)
+	switch kind {
+	case syncValue:
+		uvarint(uint64(len(c.value)))
+		_, _ = w.Write(c.value)
+	case syncChunk:
+		uvarint(uint64(len(c.data)))
+		_, _ = w.Write(c.data)
+		uvarint(c.flags)
+		uvarint(uint64(c.rawSize))
+		uvarint(uint64(c.sum))
+	}
+	// Errors of the buffered writer stick, the last write reports them
+	_, err := w.Write(nil)
+	return err
+}
+
+// readSyncRecord reads a record written by writeSyncRecord, returning the value or the chunk it puts
+func readSyncRecord(r *bufio.Reader) (kind byte, key string, c chunk, err error) {
+	if kind, err = r.ReadByte(); err != nil {
+		return kind, key, c, eofUnexpected(err)
+	}
+	if kind > syncDelete {
+		return kind, key, c, fmt.Errorf("invalid record kind %d", kind)
+	}
+	b, err := readSyncBytes(r)
+	if err != nil {
+		return kind, key, c, err
+	}
+	key = string(b)
+	switch kind {
+	case syncValue:
+		c.value, err = readSyncBytes(r)
+		if c.value == nil {
+			c.value = []byte{}
+		}
+	case syncChunk:
+		if c.data, err = readSyncBytes(r); err != nil {
+			return kind, key, c, err
+		}
+		// Chunks are written as they are, so empty chunks aren't taken for values to compress
+		if c.data == nil {
+			c.data = []byte{}
+		}
+		var rawSize, sum uint64
+		if c.flags, err = binary.ReadUvarint(r); err == nil {
+			if rawSize, err = binary.ReadUvarint(r); err == nil {
+				sum, err = binary.ReadUvarint(r)
+			}
+		}
+		if err != nil {
+			return kind, key, c, eofUnexpected(err)
+		}
+		c.rawSize, c.sum = int64(rawSize), uint32(sum)
+		err = checkSyncChunk(key, c)
+	}
+	return kind, key, c, err
+}
+
+// checkSyncChunk checks that a chunk received by ApplySync stands on its own and holds the value it claims
+func checkSyncChunk(key string, c chunk) error {
+	if c.flags&(format.FlagDict|format.FlagDelta|format.FlagAppend) != 0 {
+		return fmt.Errorf("chunk of key %q depends on data of another store", key)
+	}
+	rawSize, sum, err := chunkSum(c.data, c.flags, nil)
+	if err == nil && (rawSize != c.rawSize || sum != c.sum) {
+		err = ErrChecksum
+	}
+	if err != nil {
+		return fmt.Errorf("chunk of key %q is corrupted: %w", key, err)
+	}
+	return nil
+}
+
+// readSyncBytes reads a length-prefixed byte string. The buffer grows with the bytes read, so that a corrupted
+// length fails with the end of the stream instead of allocating it
+func readSyncBytes(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, eofUnexpected(err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if n > math.MaxInt64 {
+		return nil, fmt.Errorf("invalid length %d", n)
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), nil
+}
+
+// eofUnexpected returns io.ErrUnexpectedEOF for io.EOF, as streams end with a record
+func eofUnexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// checkResponse returns an error holding the body of responses which status isn't successful
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,36 @@
+package sunduk
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSunduk_ApplySyncCorrupted(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+
+	var stream bytes.Buffer
+	w := bufio.NewWriter(&stream)
+	c := chunk{value: bytes.Repeat([]byte("synced value "), 100)}
+	_ = store.enc.encode(&c)
+	c.sum++
+	_ = writeSyncRecord(w, syncChunk, "key", c)
+	_ = writeSyncRecord(w, syncEnd, "", chunk{})
+	_ = w.Flush()
+	if err := store.ApplySync(&stream); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum for a corrupted chunk, got %v instead", err)
+	}
+
+	stream.Reset()
+	_ = writeSyncRecord(w, syncValue, "key", chunk{value: []byte("value")})
+	_ = w.Flush()
+	if err := store.ApplySync(&stream); err == nil {
+		t.Error("Expected truncated stream to be rejected")
+	}
+	if store.Has("key") {
+		t.Error("Expected values of a rejected stream not to be committed")
+	}
+}
@@ -0,0 +1,44 @@
+package sunduk
+
+// Rename moves the value of oldKey to newKey, replacing the value of newKey if it has one. Only the index
+// changes, the value is neither read nor compressed again, and both keys change in a single commit along with
+// pending writes. It returns ErrNotFound if oldKey has no entry. OnBeforePut and OnAfterPut hooks aren't called,
+// as the value isn't read, OnRename hooks are called instead
+func (store *Sunduk) Rename(oldKey, newKey string) error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	// Pending values have no chunk to point the entry of newKey to, and legacy files are converted
+	// before renaming, as converting copies chunks by key
+	if err := store.flushPending(); err != nil {
+		return err
+	}
+	if store.legacy {
+		if err := store.commit(nil, nil, putOptions{}); err != nil {
+			return err
+		}
+	}
+	store.mu.RLock()
+	_, ok := store.index[oldKey]
+	store.mu.RUnlock()
+	if !ok {
+		return ErrNotFound
+	}
+	if oldKey == newKey {
+		return nil
+	}
+
+	info, err := store.flush(nil, []string{oldKey}, putOptions{renames: map[string]string{oldKey: newKey}})
+	if err != nil {
+		return err
+	}
+	for _, h := range store.opts.hooks {
+		if h.OnRename != nil {
+			h.OnRename(oldKey, newKey)
+		}
+	}
+	store.notifyFlush(info)
+	return nil
+}
@@ -0,0 +1,63 @@
+package sunduk
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSunduk_Rename(t *testing.T) {
+	var renamed []string
+	hooks := Hooks{OnRename: func(oldKey, newKey string) {
+		renamed = append(renamed, oldKey+">"+newKey)
+	}}
+	store := New(TestStoreFile, WithHooks(hooks))
+	defer deleteTestStoreFile()
+	blob := bytes.Repeat([]byte("large blob "), 1000)
+	_ = store.PutAll(map[string][]byte{"tmp/upload": blob, "current": []byte("old")})
+	checkValueForKey(t, store, "current", []byte("old"))
+	written := store.Metrics().BytesWritten
+
+	if err := store.Rename("tmp/upload", "current"); err != nil {
+		t.Fatal(err)
+	}
+	checkKeyNotExists(t, store, "tmp/upload")
+	checkValueForKey(t, store, "current", blob)
+	if n := store.Metrics().BytesWritten - written; n > 1000 {
+		t.Errorf("Expected only the index to be written, got %d bytes written instead", n)
+	}
+	if len(renamed) != 1 || renamed[0] != "tmp/upload>current" {
+		t.Errorf("Expected OnRename hook to be called once, got %v instead", renamed)
+	}
+	if err := store.Rename("missing", "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing key, got %v instead", err)
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkKeyNotExists(t, store, "tmp/upload")
+	checkValueForKey(t, store, "current", blob)
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected renamed store to verify, got %v instead", err)
+	}
+}
+
+func TestSunduk_RenamePending(t *testing.T) {
+	store, err := Open(TestStoreFile, WithWriteBuffer(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	_ = store.Put("a", []byte("apple"))
+	if err := store.Rename("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	checkKeyNotExists(t, store, "a")
+	checkValueForKey(t, store, "b", []byte("apple"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkValueForKey(t, store, "b", []byte("apple"))
+}
@@ -0,0 +1,34 @@
+package sunduk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RepairSource provides known-good copies of values, e.g. a mirror, replica or backup of the store.
+// A *Sunduk opened on a mirror file satisfies it
+type RepairSource interface {
+	Get(key string) (value []byte, ok bool)
+}
+
+// fetch reads the value of an entry. If the stored chunk fails checksum verification
+// and a repair source is configured, the good copy is fetched from it and repaired is true
+func (store *Sunduk) fetch(file *handle, key string, e entry) (value []byte, repaired bool, err error) {
+	value, err = store.readValue(file, e)
+	if err != nil && errors.Is(err, ErrChecksum) {
+		store.opts.logger.Error("corrupted entry detected", "file", store.FilePath, "key", key, "err", err)
+	}
+	if err == nil || !errors.Is(err, ErrChecksum) || store.opts.repair == nil {
+		return
+	}
+
+	good, ok := store.opts.repair.Get(key)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: key %q is not found in repair source", err, key)
+	}
+	if int64(len(good)) != e.RawSize || checksum(good) != e.Sum {
+		return nil, false, fmt.Errorf("%w: repair source holds a different value for key %q", err, key)
+	}
+	store.opts.logger.Warn("repaired entry from repair source", "file", store.FilePath, "key", key)
+	return good, true, nil
+}
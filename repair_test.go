@@ -0,0 +1,90 @@
+package sunduk
+
+import (
+	"os"
+	"testing"
+)
+
+const (
+	TestMirrorFile = "sunduk.mirror.data"
+)
+
+func TestSunduk_GetRepairsFromMirror(t *testing.T) {
+	mirror := New(TestMirrorFile)
+	defer deleteStoreFile(TestMirrorFile)
+	_ = mirror.Put("key", []byte("value"))
+	_ = mirror.Put("other", []byte("other value"))
+
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	_ = store.Put("other", []byte("other value"))
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	store = New(TestStoreFile, WithRepairSource(mirror))
+	checkValueForKey(t, store, "key", []byte("value"))
+	checkValueForKey(t, store, "other", []byte("other value"))
+	store.Close()
+	mirror.Close()
+
+	// The repaired chunk should have been rewritten locally
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "key", []byte("value"))
+	store.Close()
+}
+
+func TestSunduk_GetCorruptedWithoutRepairSource(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	store = New(TestStoreFile)
+	checkKeyNotExists(t, store, "key")
+	store.Close()
+}
+
+func TestSunduk_GetRepairSourceWithDifferentValue(t *testing.T) {
+	mirror := New(TestMirrorFile)
+	defer deleteStoreFile(TestMirrorFile)
+	_ = mirror.Put("key", []byte("another value"))
+
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	store = New(TestStoreFile, WithRepairSource(mirror))
+	checkKeyNotExists(t, store, "key")
+	store.Close()
+	mirror.Close()
+}
+
+// corruptEntry flips the bytes of the chunk of key in the store file
+func corruptEntry(t *testing.T, filePath, key string) {
+	store := New(filePath)
+	e, ok := store.index[key]
+	store.Close()
+	if !ok {
+		t.Fatalf("[%s] Expected key '%s' to exist", t.Name(), key)
+	}
+
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	data := make([]byte, e.Size)
+	if _, err := file.ReadAt(data, e.Offset); err != nil {
+		t.Fatal(err)
+	}
+	for i := range data {
+		data[i] ^= 0x5a
+	}
+	if _, err := file.WriteAt(data, e.Offset); err != nil {
+		t.Fatal(err)
+	}
+}
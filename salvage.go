@@ -0,0 +1,102 @@
+package sunduk
+
+import (
+	"fmt"
+	"os"
+	"sunduk/internal/format"
+)
+
+// Salvage recovers as many values as possible from the damaged store file at path, such as a file which index
+// can't be read by Open or which chunks are partly corrupt. Values are read with the newest readable index of the
+// file, and the values of entries which chunks are corrupt are left out. Chunks written after that index, whose
+// keys are lost along with the index, are found by scanning the file for brotli-compressed data and returned under
+// keys of the form "#offset", with the offset of the chunk in the file. Values found by scanning aren't verified.
+// The values of store files with an index file are read with the index file, see WithIndexFile.
+// Salvage reads the file only, recovered values are to be put into a new store
+func Salvage(path string) (map[string][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	h := newHandle(file, nil)
+	defer h.release()
+	store := &Sunduk{FilePath: path, opts: defaultOptions(), counters: &counters{}}
+	size := info.Size()
+	version, err := format.ReadVersion(file)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte)
+	start := int64(format.PreambleSize)
+	id, indexFile, _ := format.ReadFileID(file)
+	if version == 0 {
+		start = 0
+		if entries, err := format.ReadLegacyIndex(file); err == nil {
+			store.salvageEntries(h, entries, false, values)
+			return values, nil
+		}
+	} else if indexFile {
+		start += format.FileIDSize
+		for _, name := range []string{store.indexPath(), store.indexPath() + ".new"} {
+			if index, _, err := store.loadIndexFile(name, id, size); err == nil {
+				store.salvageIndex(h, index, values)
+				start = index.Data.Size
+				break
+			}
+		}
+	} else {
+		for _, end := range format.FindTrailers(file, size) {
+			index, err := format.ReadIndex(file, end)
+			if err != nil {
+				continue
+			}
+			store.salvageIndex(h, index, values)
+			start = end
+			break
+		}
+	}
+
+	// A damaged last index is left out of the scan if its trailer still locates it
+	end := size
+	if offset, err := format.LocateIndex(file, size); err == nil && version > 0 && !indexFile && offset >= start {
+		end = offset
+	}
+	format.ScanChunks(file, start, end, func(offset, _ int64, value []byte) {
+		// Index blocks of broken trailers are compressed like chunks
+		if _, err := format.DecodeIndex(value, size, version); err == nil {
+			return
+		}
+		values[fmt.Sprintf("#%d", offset)] = value
+	})
+	return values, nil
+}
+
+// salvageIndex adds the values of the entries of index read from file to values, with the dictionary of the index if it is readable
+func (store *Sunduk) salvageIndex(file *handle, index format.Index, values map[string][]byte) {
+	if index.Dictionary.Size > 0 {
+		if data, err := format.ReadDictionary(file, index.Dictionary); err == nil {
+			file.dict = &dictionary{data: data, offset: index.Dictionary.Offset}
+		}
+	}
+	store.salvageEntries(file, index.Entries, true, values)
+}
+
+// salvageEntries adds the values of entries read from file to values, leaving out unreadable values.
+// Entries of legacy files have no checksums
+func (store *Sunduk) salvageEntries(file *handle, entries []format.Entry, hasSum bool, values map[string][]byte) {
+	for _, e := range entries {
+		en := entry{Offset: e.Offset, Size: e.Size}
+		if hasSum {
+			en = newEntry(e)
+		}
+		if value, err := store.readValue(file, en); err == nil {
+			values[e.Key] = value
+		}
+	}
+}
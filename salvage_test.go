@@ -0,0 +1,74 @@
+package sunduk
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSalvage(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	first := bytes.Repeat([]byte("first value "), 100)
+	broken := bytes.Repeat([]byte("broken value "), 100)
+	_ = store.PutAll(map[string][]byte{"first": first, "broken": broken})
+	last := bytes.Repeat([]byte("last value "), 100)
+	_ = store.Put("last", last)
+	size := store.size
+	indexOffset := size - store.tail
+	e := store.index["broken"]
+	store.Close()
+
+	// Damage the chunk of a key and the last index
+	file, err := os.OpenFile(TestStoreFile, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteAt([]byte("damage"), e.Offset+e.Size/2)
+	_, _ = file.WriteAt([]byte("damage"), indexOffset+2)
+	file.Close()
+	if _, err := Open(TestStoreFile); err == nil {
+		t.Fatal("Expected store with damaged index not to open")
+	}
+
+	values, err := Salvage(TestStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(values["first"], first) {
+		t.Errorf("Expected intact value to be recovered, got %q instead", values["first"])
+	}
+	if _, ok := values["broken"]; ok {
+		t.Error("Expected damaged value to be left out")
+	}
+	found := false
+	for k, v := range values {
+		if strings.HasPrefix(k, "#") && bytes.Equal(v, last) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected value committed after the last readable index to be found by scanning, got %d values instead", len(values))
+	}
+	if len(values) != 2 {
+		t.Errorf("Expected 2 values, got %d instead", len(values))
+	}
+}
+
+func TestSalvage_Garbage(t *testing.T) {
+	defer deleteTestStoreFile()
+	data := make([]byte, 64<<10)
+	_, _ = rand.Read(data)
+	copy(data, "SNDK\x02\x00\x00\x00")
+	if err := os.WriteFile(TestStoreFile, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Salvage(TestStoreFile); err != nil {
+		t.Errorf("Expected garbage to be scanned, got %v instead", err)
+	}
+	if _, err := Salvage(TestStoreFile + ".missing"); err == nil {
+		t.Error("Expected Salvage to fail for a missing file")
+	}
+}
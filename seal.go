@@ -0,0 +1,65 @@
+package sunduk
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"sync/atomic"
+)
+
+// Seal commits pending writes and marks the store file immutable, signing it with key unless key is nil, see Sign.
+// Stores opened on a sealed file refuse writes and compactions with ErrSealed, unless they are opened with
+// WithForceWrites. Applications seal stores once they are complete, such as datasets handed over to readers
+func (store *Sunduk) Seal(key ed25519.PrivateKey) error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	if key != nil && len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid private key of %d bytes", len(key))
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if err := store.checkOpen(); err != nil {
+		return err
+	}
+	if err := store.flushPending(); err != nil {
+		return err
+	}
+	po := putOptions{seal: true}
+	if key != nil {
+		store.mu.RLock()
+		file, index := store.file.acquire(), store.index
+		store.mu.RUnlock()
+		digest, err := store.manifest(file, index, nil)
+		file.release()
+		if err != nil {
+			return err
+		}
+		po.signature = ed25519.Sign(key, digest)
+	}
+	return store.commit(nil, nil, po)
+}
+
+// Sealed reports whether the store file is sealed, see Seal
+func (store *Sunduk) Sealed() bool {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	return store.sealed != 0
+}
+
+// writable returns ErrClosed for closed stores, ErrReadOnly for read-only stores and ErrSealed for sealed stores
+// opened without WithForceWrites
+func (store *Sunduk) writable() error {
+	if atomic.LoadInt32(&store.closed) != 0 {
+		return ErrClosed
+	}
+	if store.opts.readOnly {
+		return ErrReadOnly
+	}
+	store.mu.RLock()
+	sealed := store.sealed != 0
+	store.mu.RUnlock()
+	if sealed && !store.opts.force {
+		return ErrSealed
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package sunduk
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestSunduk_Seal(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(nil)
+	store, err := Open(TestStoreFile, WithWriteBuffer(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	_ = store.Put("a", []byte("apple"))
+	if err := store.Seal(private); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Sealed() {
+		t.Error("Expected store to be sealed")
+	}
+	if err := store.Put("b", []byte("banana")); !errors.Is(err, ErrSealed) {
+		t.Errorf("Expected ErrSealed for Put to a sealed store, got %v instead", err)
+	}
+	store.Close()
+
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "a", []byte("apple"))
+	if err := store.Delete("a"); !errors.Is(err, ErrSealed) {
+		t.Errorf("Expected ErrSealed for Delete from a reopened sealed store, got %v instead", err)
+	}
+	if err := store.Compact(); !errors.Is(err, ErrSealed) {
+		t.Errorf("Expected ErrSealed for Compact of a sealed store, got %v instead", err)
+	}
+	if err := store.VerifySignature(public); err != nil {
+		t.Errorf("Expected sealed store to be signed, got %v instead", err)
+	}
+	if store.GetMeta().Sealed.IsZero() || !store.DebugInfo().Index.Sealed {
+		t.Error("Expected metadata and debug info to tell the store is sealed")
+	}
+	store.Close()
+
+	store, err = Open(TestStoreFile, WithForceWrites())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.Put("b", []byte("banana")); err != nil {
+		t.Fatalf("Expected Put with WithForceWrites to succeed, got %v instead", err)
+	}
+	if !store.Sealed() {
+		t.Error("Expected forced writes to keep the seal")
+	}
+	if err := store.VerifySignature(public); !errors.Is(err, ErrSignature) {
+		t.Errorf("Expected forced writes to drop the signature, got %v instead", err)
+	}
+}
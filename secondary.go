@@ -0,0 +1,218 @@
+package sunduk
+
+import (
+	"sort"
+	"sunduk/internal/format"
+)
+
+// IndexFunc returns the values the value of key is indexed under by a secondary index, such as its content type
+// or its tags, nil to leave key out of the index
+type IndexFunc func(key string, value []byte) []string
+
+// WithSecondaryIndex defines the secondary index name, which indexes keys under the values fn returns for their
+// values, see QueryIndex. Indexes are kept up to date on commit and persisted in the index of the store file, so
+// keys are found without reading values. Indexes missing from the store file, such as indexes added to an existing
+// store, are built on open by reading every value. Indexes of the store file that aren't defined stay queryable
+// until the first write, which drops them as they can't be kept up to date
+func WithSecondaryIndex(name string, fn IndexFunc) Option {
+	return func(o *options) {
+		if o.indexes == nil {
+			o.indexes = make(map[string]IndexFunc)
+		}
+		o.indexes[name] = fn
+	}
+}
+
+// QueryIndex returns the keys indexed under value by the secondary index name in ascending order, nil if the store
+// has no such index. Pending writes are indexed once they are written to the store file, see WithWriteBuffer
+func (store *Sunduk) QueryIndex(name, value string) []string {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	keys := store.secondary[name].keys[value]
+	if keys == nil {
+		return nil
+	}
+	return append([]string(nil), keys...)
+}
+
+// secondaryIndex is a secondary index of the store, it is replaced as a whole on commit
+type secondaryIndex struct {
+	values map[string][]string // values maps keys to the values they are indexed under, in ascending order
+	keys   map[string][]string // keys maps values to the keys indexed under them, in ascending order
+}
+
+// newSecondaryIndex returns the secondary index of keys indexed under values
+func newSecondaryIndex(values map[string][]string) secondaryIndex {
+	s := secondaryIndex{values: values, keys: make(map[string][]string)}
+	for k, vs := range values {
+		for _, v := range vs {
+			s.keys[v] = append(s.keys[v], k)
+		}
+	}
+	for _, keys := range s.keys {
+		sort.Strings(keys)
+	}
+	return s
+}
+
+// indexValues returns the values fn indexes key under for value, sorted and without duplicates
+func indexValues(fn IndexFunc, key string, value []byte) []string {
+	values := fn(key, value)
+	if len(values) == 0 {
+		return nil
+	}
+	values = append([]string(nil), values...)
+	sort.Strings(values)
+	n := 1
+	for _, v := range values[1:] {
+		if v != values[n-1] {
+			values[n] = v
+			n++
+		}
+	}
+	return values[:n]
+}
+
+// nextSecondary returns the secondary indexes of the store once chunks are committed along with deleted and renamed
+// keys. Values of chunks put as they are, by Copy and Append, are decoded to be indexed. It must be called with
+// writeMu held
+func (store *Sunduk) nextSecondary(chunks map[string]chunk, deleted []string, renames map[string]string) (map[string]secondaryIndex, error) {
+	if len(store.opts.indexes) == 0 {
+		return nil, nil
+	}
+	store.mu.RLock()
+	current := store.secondary
+	store.mu.RUnlock()
+	values := make(map[string][]byte, len(chunks))
+	for k, c := range chunks {
+		if c.data == nil {
+			values[k] = c.value
+			continue
+		}
+		value, err := store.decodeValue(store.file, c.data, entry{Size: int64(len(c.data)), RawSize: c.rawSize, Sum: c.sum, Flags: c.flags, Base: c.base, hasSum: true})
+		if err != nil {
+			return nil, err
+		}
+		values[k] = value
+	}
+
+	next := make(map[string]secondaryIndex, len(store.opts.indexes))
+	for name, fn := range store.opts.indexes {
+		s, ok := current[name]
+		if !ok {
+			var err error
+			if s, err = store.buildSecondary(fn); err != nil {
+				return nil, err
+			}
+		}
+		indexed := make(map[string][]string, len(s.values)+len(values))
+		for k, vs := range s.values {
+			indexed[k] = vs
+		}
+		for _, k := range deleted {
+			delete(indexed, k)
+		}
+		for from, to := range renames {
+			if vs, ok := s.values[from]; ok {
+				indexed[to] = vs
+			} else {
+				delete(indexed, to)
+			}
+		}
+		for k, v := range values {
+			if vs := indexValues(fn, k, v); vs != nil {
+				indexed[k] = vs
+			} else {
+				delete(indexed, k)
+			}
+		}
+		next[name] = newSecondaryIndex(indexed)
+	}
+	return next, nil
+}
+
+// buildSecondary returns the secondary index of fn over the committed values of the store, read from the store file
+func (store *Sunduk) buildSecondary(fn IndexFunc) (secondaryIndex, error) {
+	store.mu.RLock()
+	file := store.file.acquire()
+	var keys []string
+	entries := make(map[string]entry)
+	store.scan("", func(k string, e entry) {
+		keys = append(keys, k)
+		entries[k] = e
+	})
+	store.mu.RUnlock()
+	defer file.release()
+	indexed := make(map[string][]string)
+	for _, k := range keys {
+		value, err := store.readValue(file, entries[k])
+		if err != nil {
+			return secondaryIndex{}, err
+		}
+		if vs := indexValues(fn, k, value); vs != nil {
+			indexed[k] = vs
+		}
+	}
+	return newSecondaryIndex(indexed), nil
+}
+
+// loadSecondary builds the secondary indexes defined for the store that are missing from the store file
+func (store *Sunduk) loadSecondary() error {
+	for name, fn := range store.opts.indexes {
+		store.mu.RLock()
+		_, ok := store.secondary[name]
+		store.mu.RUnlock()
+		if ok {
+			continue
+		}
+		s, err := store.buildSecondary(fn)
+		if err != nil {
+			return err
+		}
+		store.mu.Lock()
+		if store.secondary == nil {
+			store.secondary = make(map[string]secondaryIndex)
+		}
+		store.secondary[name] = s
+		store.mu.Unlock()
+	}
+	return nil
+}
+
+// encodeSecondary returns the secondary indexes of the index of the store file for the secondary indexes s
+func encodeSecondary(s map[string]secondaryIndex) []format.Secondary {
+	if len(s) == 0 {
+		return nil
+	}
+	indexes := make([]format.Secondary, 0, len(s))
+	for name, idx := range s {
+		keys := make([]format.SecondaryKey, 0, len(idx.values))
+		for k, vs := range idx.values {
+			keys = append(keys, format.SecondaryKey{Key: k, Values: vs})
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].Key < keys[j].Key
+		})
+		indexes = append(indexes, format.Secondary{Name: name, Keys: keys})
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+	return indexes
+}
+
+// decodeSecondary returns the secondary indexes of the store for the secondary indexes of the index of the store file
+func decodeSecondary(indexes []format.Secondary) map[string]secondaryIndex {
+	if len(indexes) == 0 {
+		return nil
+	}
+	s := make(map[string]secondaryIndex, len(indexes))
+	for _, idx := range indexes {
+		values := make(map[string][]string, len(idx.Keys))
+		for _, k := range idx.Keys {
+			values[k.Key] = k.Values
+		}
+		s[idx.Name] = newSecondaryIndex(values)
+	}
+	return s
+}
@@ -0,0 +1,55 @@
+package sunduk
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tags indexes values under their words
+func tags(_ string, value []byte) []string {
+	return strings.Fields(string(value))
+}
+
+func TestSunduk_QueryIndex(t *testing.T) {
+	store := New(TestStoreFile, WithSecondaryIndex("tags", tags))
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": []byte("tcp udp"), "b": []byte("udp"), "c": []byte("serial")})
+	checkQuery := func(value string, want []string) {
+		t.Helper()
+		if got := store.QueryIndex("tags", value); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected keys %v under %q, got %v instead", want, value, got)
+		}
+	}
+	checkQuery("udp", []string{"a", "b"})
+	checkQuery("serial", []string{"c"})
+	checkQuery("can", nil)
+	if got := store.QueryIndex("missing", "udp"); got != nil {
+		t.Errorf("Expected no keys for a missing index, got %v instead", got)
+	}
+
+	_ = store.Delete("b")
+	_ = store.Rename("c", "d")
+	_ = store.Copy("a", "e")
+	_ = store.Append("d", []byte(" udp"))
+	checkQuery("udp", []string{"a", "d", "e"})
+	checkQuery("serial", []string{"d"})
+	store.Close()
+
+	// Indexes are read from the store file, and indexes added later are built on open
+	store = New(TestStoreFile, WithSecondaryIndex("tags", tags), WithSecondaryIndex("first", func(_ string, value []byte) []string {
+		return tags("", value)[:1]
+	}))
+	checkQuery("udp", []string{"a", "d", "e"})
+	if got := store.QueryIndex("first", "tcp"); !reflect.DeepEqual(got, []string{"a", "e"}) {
+		t.Errorf("Expected index added to an existing store to be built, got %v instead", got)
+	}
+	store.Close()
+
+	// Indexes of the store file stay queryable without their definition until the first write
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkQuery("udp", []string{"a", "d", "e"})
+	_ = store.Put("f", []byte("udp"))
+	checkQuery("udp", nil)
+}
@@ -0,0 +1,240 @@
+package sunduk
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// SegmentedStore writes values into segment files of about the same size, path.000, path.001 and so on, and keeps
+// a unified index of the segment holding every key in the file at path. Values are written to the last segment
+// only, once it reaches the segment size a new one is started and it is never written again, except by Compact.
+// Overwritten and deleted values take space in their segments until Compact rewrites them, one at a time
+type SegmentedStore struct {
+	FilePath string // FilePath is the path of the index, the segments are named after it
+
+	mu       sync.RWMutex // mu guards segments, which writers append to
+	writeMu  sync.Mutex   // writeMu serializes writers
+	index    *Sunduk      // index maps keys to the numbers of their segments
+	segments []*Sunduk
+	size     int64
+	opts     []Option
+}
+
+// OpenSegmented opens a store of segments of segmentSize bytes, creating the index and the first segment if
+// there are none. Options apply to the index and to every segment
+func OpenSegmented(path string, segmentSize int64, opts ...Option) (*SegmentedStore, error) {
+	if segmentSize <= 0 {
+		return nil, fmt.Errorf("invalid segment size %d", segmentSize)
+	}
+	index, err := Open(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s := &SegmentedStore{FilePath: path, index: index, size: segmentSize, opts: opts}
+	for {
+		name := segmentPath(path, len(s.segments))
+		if _, err := os.Stat(name); os.IsNotExist(err) && len(s.segments) > 0 {
+			break
+		}
+		segment, err := Open(name, opts...)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.segments = append(s.segments, segment)
+	}
+	return s, nil
+}
+
+// segmentPath returns the path of the i-th segment
+func segmentPath(path string, i int) string {
+	return fmt.Sprintf("%s.%03d", path, i)
+}
+
+// Segments returns the stores of the segments, the last one being written to
+func (s *SegmentedStore) Segments() []*Sunduk {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*Sunduk(nil), s.segments...)
+}
+
+// Close closes the index and every segment, even if closing one fails, and returns the first error
+func (s *SegmentedStore) Close() error {
+	first := s.index.Close()
+	for _, segment := range s.segments {
+		if err := segment.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// segment returns the number of the segment holding key
+func (s *SegmentedStore) segment(key string) (int, bool) {
+	value, ok := s.index.Get(key)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(string(value))
+	return i, err == nil && i < len(s.segments)
+}
+
+// Get returns the value of key, see Sunduk.Get
+func (s *SegmentedStore) Get(key string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i, ok := s.segment(key)
+	if !ok {
+		return nil, false
+	}
+	return s.segments[i].Get(key)
+}
+
+// Has returns true if an entry exists for key, without reading its value
+func (s *SegmentedStore) Has(key string) bool {
+	return s.index.Has(key)
+}
+
+// Put sets the value of key, see Sunduk.Put
+func (s *SegmentedStore) Put(key string, value []byte, opts ...PutOption) error {
+	return s.PutAll(map[string][]byte{key: value}, opts...)
+}
+
+// PutAll writes entries to the last segment, then records their segment in the index.
+// Entries are visible once the index is written
+func (s *SegmentedStore) PutAll(entries map[string][]byte, opts ...PutOption) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	last, err := s.lastSegment()
+	if err != nil {
+		return err
+	}
+	if err := s.segments[last].PutAll(entries, opts...); err != nil {
+		return err
+	}
+	moved := make(map[string][]byte)
+	for k := range entries {
+		if i, ok := s.segment(k); !ok || i != last {
+			moved[k] = []byte(strconv.Itoa(last))
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+	return s.index.PutAll(moved)
+}
+
+// lastSegment returns the number of the segment to write to, starting a new one if the last one is full.
+// It must be called with writeMu held
+func (s *SegmentedStore) lastSegment() (int, error) {
+	last := s.segments[len(s.segments)-1]
+	last.mu.RLock()
+	full := last.size >= s.size
+	last.mu.RUnlock()
+	if !full {
+		return len(s.segments) - 1, nil
+	}
+	segment, err := Open(segmentPath(s.FilePath, len(s.segments)), s.opts...)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.segments = append(s.segments, segment)
+	s.mu.Unlock()
+	return len(s.segments) - 1, nil
+}
+
+// Delete removes key from the index, its value is dropped from its segment by Compact
+func (s *SegmentedStore) Delete(key string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.index.Delete(key)
+}
+
+// Count returns the total number of entries in the store
+func (s *SegmentedStore) Count() int {
+	return s.index.Count()
+}
+
+// CountPrefix returns the number of entries which keys start with prefix
+func (s *SegmentedStore) CountPrefix(prefix string) int {
+	return s.index.CountPrefix(prefix)
+}
+
+// Keys returns a list of keys, see Sunduk.Keys
+func (s *SegmentedStore) Keys(opts ...KeysOption) []string {
+	return s.index.Keys(opts...)
+}
+
+// OrderedKeys returns all keys of the store in bytewise ascending order, or in the order of the collation
+// of the store, see Sunduk.OrderedKeys
+func (s *SegmentedStore) OrderedKeys() OrderedKeys {
+	return s.index.OrderedKeys()
+}
+
+// ForEach calls fn for every entry in key order, see Sunduk.ForEach
+func (s *SegmentedStore) ForEach(fn func(key string, value []byte) error) error {
+	return iterate(s.Get, s.OrderedKeys(), "", "", fn)
+}
+
+// Range calls fn in key order for every entry which key is in [start, end), see Sunduk.Range
+func (s *SegmentedStore) Range(start, end string, fn func(key string, value []byte) error) error {
+	return iterate(s.Get, s.OrderedKeys(), start, end, fn)
+}
+
+// RangePrefix calls fn in key order for every entry which key starts with prefix, see Sunduk.RangePrefix
+func (s *SegmentedStore) RangePrefix(prefix string, fn func(key string, value []byte) error) error {
+	return iterate(s.Get, OrderedKeys{keys: s.Keys(Prefix(prefix), Sorted())}, "", "", fn)
+}
+
+// Cursor returns a cursor over the entries of the store, positioned before the first key
+func (s *SegmentedStore) Cursor() *Cursor {
+	return &Cursor{get: s.Get, keys: s.OrderedKeys(), pos: -1}
+}
+
+// Flush writes the pending writes of the segments, then of the index, see Sunduk.Flush
+func (s *SegmentedStore) Flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	for _, segment := range s.segments {
+		if err := segment.Flush(); err != nil {
+			return err
+		}
+	}
+	return s.index.Flush()
+}
+
+// Compact drops overwritten and deleted values from the segments and rewrites the segments holding any,
+// one at a time, then compacts the index. Segments without dropped values are left untouched
+func (s *SegmentedStore) Compact() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	for i, segment := range s.segments {
+		var dropped []string
+		for _, k := range segment.Keys() {
+			if j, ok := s.segment(k); !ok || j != i {
+				dropped = append(dropped, k)
+			}
+		}
+		if len(dropped) > 0 {
+			if err := s.drop(segment, dropped); err != nil {
+				return err
+			}
+		}
+		if dead, _ := segment.garbage(); dead > 0 {
+			if err := segment.Compact(); err != nil {
+				return err
+			}
+		}
+	}
+	return s.index.Compact()
+}
+
+// drop removes keys from segment in a single commit. The index doesn't map keys to segment, so Get doesn't look for them there
+func (s *SegmentedStore) drop(segment *Sunduk, keys []string) error {
+	segment.writeMu.Lock()
+	defer segment.writeMu.Unlock()
+	return segment.write(nil, keys, putOptions{})
+}
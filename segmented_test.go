@@ -0,0 +1,96 @@
+package sunduk
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// deleteSegmentedStoreFiles removes the index and the segments of the test store
+func deleteSegmentedStoreFiles() {
+	deleteTestStoreFile()
+	for i := 0; ; i++ {
+		name := segmentPath(TestStoreFile, i)
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return
+		}
+		deleteStoreFile(name)
+	}
+}
+
+func TestSegmentedStore(t *testing.T) {
+	store, err := OpenSegmented(TestStoreFile, 4<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteSegmentedStoreFiles()
+	random := rand.New(rand.NewSource(1))
+	values := make(map[string][]byte)
+	for i := 0; i < 20; i++ {
+		value := make([]byte, 1000)
+		random.Read(value)
+		values[fmt.Sprintf("key%02d", i)] = value
+		if err := store.Put(fmt.Sprintf("key%02d", i), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(store.Segments()); n < 4 {
+		t.Fatalf("Expected values to be written into several segments, got %d segments instead", n)
+	}
+
+	// Writes go to the last segment only
+	first, err := os.ReadFile(segmentPath(TestStoreFile, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Put("key00", []byte("new value"))
+	_ = store.Delete("key01")
+	checkSegmentedValue(t, store, "key00", []byte("new value"))
+	checkSegmentedValue(t, store, "key02", values["key02"])
+	if _, ok := store.Get("key01"); ok {
+		t.Error("Expected deleted key to not exist")
+	}
+	if data, _ := os.ReadFile(segmentPath(TestStoreFile, 0)); !bytes.Equal(data, first) {
+		t.Error("Expected the first segment to be left untouched by writes")
+	}
+	if n := store.Count(); n != 19 {
+		t.Errorf("Expected 19 keys, got %d instead", n)
+	}
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if size := fileSize(t, segmentPath(TestStoreFile, 0)); size >= int64(len(first)) {
+		t.Errorf("Expected compaction to drop overwritten and deleted values from the first segment, got %d bytes instead of %d", size, len(first))
+	}
+	last := store.Segments()[len(store.Segments())-1]
+	store.Close()
+
+	store, err = OpenSegmented(TestStoreFile, 4<<10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if store.Segments()[len(store.Segments())-1].FilePath != last.FilePath {
+		t.Errorf("Expected all segments to be opened, got %d segments instead", len(store.Segments()))
+	}
+	checkSegmentedValue(t, store, "key00", []byte("new value"))
+	checkSegmentedValue(t, store, "key19", values["key19"])
+	n := 0
+	_ = store.ForEach(func(key string, value []byte) error {
+		n++
+		return nil
+	})
+	if n != 19 {
+		t.Errorf("Expected ForEach to visit 19 keys, got %d instead", n)
+	}
+}
+
+func checkSegmentedValue(t *testing.T, store *SegmentedStore, key string, expected []byte) {
+	t.Helper()
+	if value, ok := store.Get(key); !ok || !bytes.Equal(value, expected) {
+		t.Errorf("Expected value of key %s to be %.20q, got %.20q instead", key, expected, value)
+	}
+}
@@ -0,0 +1,392 @@
+package sunduk
+
+import (
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ShardedStore partitions keys by hash across several stores, each with its own file, so that compaction
+// rewrites one shard at a time and large stores stay manageable. It has the methods of Sunduk, applied
+// to the shard of a key or to every shard. Writes of several keys, such as PutAll, are committed shard
+// by shard, so a failure may leave them applied to some shards only
+type ShardedStore struct {
+	FilePath string // FilePath is the path the files of the shards are named after
+
+	shards []*Sunduk
+}
+
+// OpenSharded opens a store of n shards, stored in files named path.<i>-of-<n>, creating missing ones.
+// Options apply to every shard. A store must be opened with the count of shards it was created with,
+// OpenSharded fails with ErrShards if files of another count of shards exist
+func OpenSharded(path string, n int, opts ...Option) (*ShardedStore, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid count of shards %d", n)
+	}
+	if err := checkShards(path, n); err != nil {
+		return nil, err
+	}
+	s := &ShardedStore{FilePath: path, shards: make([]*Sunduk, n)}
+	for i := range s.shards {
+		shard, err := Open(shardPath(path, i, n), opts...)
+		if err != nil {
+			s.shards = s.shards[:i]
+			s.Close()
+			return nil, err
+		}
+		s.shards[i] = shard
+	}
+	return s, nil
+}
+
+// shardPath returns the path of the file of the i-th of n shards
+func shardPath(path string, i, n int) string {
+	return fmt.Sprintf("%s.%d-of-%d", path, i, n)
+}
+
+// checkShards fails with ErrShards if there are files of shards of path for a count of shards other than n
+func checkShards(path string, n int) error {
+	matches, err := filepath.Glob(path + ".*-of-*")
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		// Lock files and backups of shards don't parse as numbers, they follow the files of shards
+		parts := strings.SplitN(strings.TrimPrefix(m, path+"."), "-of-", 2)
+		if _, err := strconv.Atoi(parts[0]); err != nil {
+			continue
+		}
+		if count, err := strconv.Atoi(parts[1]); err == nil && count != n {
+			return fmt.Errorf("%w: found %s opening %d shards", ErrShards, m, n)
+		}
+	}
+	return nil
+}
+
+// shard returns the store holding key
+func (s *ShardedStore) shard(key string) *Sunduk {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// Shards returns the stores of the shards, e.g. for their DebugInfo
+func (s *ShardedStore) Shards() []*Sunduk {
+	return append([]*Sunduk(nil), s.shards...)
+}
+
+// each calls fn for every shard and returns the first error
+func (s *ShardedStore) each(fn func(shard *Sunduk) error) error {
+	for _, shard := range s.shards {
+		if err := fn(shard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every shard, even if closing one fails, and returns the first error
+func (s *ShardedStore) Close() error {
+	var first error
+	for _, shard := range s.shards {
+		if err := shard.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Get returns the value of key, see Sunduk.Get
+func (s *ShardedStore) Get(key string) ([]byte, bool) {
+	return s.shard(key).Get(key)
+}
+
+// Has returns true if an entry exists for key, see Sunduk.Has
+func (s *ShardedStore) Has(key string) bool {
+	return s.shard(key).Has(key)
+}
+
+// Borrow returns the value of key without copying it, see Sunduk.Borrow
+func (s *ShardedStore) Borrow(key string) (*Borrowed, bool) {
+	return s.shard(key).Borrow(key)
+}
+
+// GetLazy returns the value of key without reading it, see Sunduk.GetLazy
+func (s *ShardedStore) GetLazy(key string) (*Value, bool) {
+	return s.shard(key).GetLazy(key)
+}
+
+// ExtractTo writes the value of key to the file at destPath, see Sunduk.ExtractTo
+func (s *ShardedStore) ExtractTo(key, destPath string) error {
+	return s.shard(key).ExtractTo(key, destPath)
+}
+
+// GetInto reads the value of key into buf, see Sunduk.GetInto
+func (s *ShardedStore) GetInto(key string, buf []byte) (int, error) {
+	return s.shard(key).GetInto(key, buf)
+}
+
+// GetAppend appends the value of key to buf, see Sunduk.GetAppend
+func (s *ShardedStore) GetAppend(key string, buf []byte) ([]byte, error) {
+	return s.shard(key).GetAppend(key, buf)
+}
+
+// GetMany returns the values of the keys found in the store, see Sunduk.GetMany
+func (s *ShardedStore) GetMany(keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for shard, keys := range s.split(keys) {
+		found, err := shard.GetMany(keys)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range found {
+			values[k] = v
+		}
+	}
+	return values, nil
+}
+
+// split groups keys by shard
+func (s *ShardedStore) split(keys []string) map[*Sunduk][]string {
+	groups := make(map[*Sunduk][]string)
+	for _, k := range keys {
+		shard := s.shard(k)
+		groups[shard] = append(groups[shard], k)
+	}
+	return groups
+}
+
+// Put sets the value of key, see Sunduk.Put
+func (s *ShardedStore) Put(key string, value []byte, opts ...PutOption) error {
+	return s.shard(key).Put(key, value, opts...)
+}
+
+// PutAll sets the values of entries shard by shard, see Sunduk.PutAll
+func (s *ShardedStore) PutAll(entries map[string][]byte, opts ...PutOption) error {
+	groups := make(map[*Sunduk]map[string][]byte)
+	for k, v := range entries {
+		shard := s.shard(k)
+		if groups[shard] == nil {
+			groups[shard] = make(map[string][]byte)
+		}
+		groups[shard][k] = v
+	}
+	return s.each(func(shard *Sunduk) error {
+		if group, ok := groups[shard]; ok {
+			return shard.PutAll(group, opts...)
+		}
+		return nil
+	})
+}
+
+// Delete removes key, see Sunduk.Delete
+func (s *ShardedStore) Delete(key string) error {
+	return s.shard(key).Delete(key)
+}
+
+// Clear removes every entry of the store, shard by shard, see Sunduk.Clear
+func (s *ShardedStore) Clear() error {
+	return s.each((*Sunduk).Clear)
+}
+
+// Count returns the total number of entries in the store
+func (s *ShardedStore) Count() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Count()
+	}
+	return n
+}
+
+// CountPrefix returns the number of entries which keys start with prefix
+func (s *ShardedStore) CountPrefix(prefix string) int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.CountPrefix(prefix)
+	}
+	return n
+}
+
+// SizePrefix returns the total size of the values which keys start with prefix, see Sunduk.SizePrefix
+func (s *ShardedStore) SizePrefix(prefix string) (compressed, raw int64) {
+	for _, shard := range s.shards {
+		c, r := shard.SizePrefix(prefix)
+		compressed += c
+		raw += r
+	}
+	return compressed, raw
+}
+
+// Keys returns a list of keys, see Sunduk.Keys
+func (s *ShardedStore) Keys(opts ...KeysOption) []string {
+	var keys []string
+	for _, shard := range s.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	if len(opts) == 0 {
+		return keys
+	}
+	sort.Strings(keys)
+	_, collation := s.shards[0].orderedKeys()
+	return newKeysOptions(opts).apply(keys, collation)
+}
+
+// OrderedKeys returns all keys of the store in bytewise ascending order, or in the order of the collation
+// of the store, see Sunduk.OrderedKeys
+func (s *ShardedStore) OrderedKeys() OrderedKeys {
+	var keys []string
+	var collation *Collation
+	for _, shard := range s.shards {
+		ok, c := shard.orderedKeys()
+		keys, collation = append(keys, ok.keys...), c
+	}
+	sort.Strings(keys)
+	return collation.sort(OrderedKeys{keys: keys})
+}
+
+// ForEach calls fn for every entry in key order, see Sunduk.ForEach
+func (s *ShardedStore) ForEach(fn func(key string, value []byte) error) error {
+	return iterate(s.Get, s.OrderedKeys(), "", "", fn)
+}
+
+// Range calls fn in key order for every entry which key is in [start, end), see Sunduk.Range
+func (s *ShardedStore) Range(start, end string, fn func(key string, value []byte) error) error {
+	return iterate(s.Get, s.OrderedKeys(), start, end, fn)
+}
+
+// RangePrefix calls fn in key order for every entry which key starts with prefix, see Sunduk.RangePrefix
+func (s *ShardedStore) RangePrefix(prefix string, fn func(key string, value []byte) error) error {
+	return iterate(s.Get, OrderedKeys{keys: s.Keys(Prefix(prefix), Sorted())}, "", "", fn)
+}
+
+// Cursor returns a cursor over the entries of the store, positioned before the first key
+func (s *ShardedStore) Cursor() *Cursor {
+	return &Cursor{get: s.Get, keys: s.OrderedKeys(), pos: -1}
+}
+
+// Flush writes the pending writes of every shard, see Sunduk.Flush
+func (s *ShardedStore) Flush() error {
+	return s.each((*Sunduk).Flush)
+}
+
+// Compact compacts the shards one by one, see Sunduk.Compact
+func (s *ShardedStore) Compact() error {
+	return s.each((*Sunduk).Compact)
+}
+
+// PauseCompaction pauses compaction of every shard, see Sunduk.PauseCompaction
+func (s *ShardedStore) PauseCompaction() {
+	for _, shard := range s.shards {
+		shard.PauseCompaction()
+	}
+}
+
+// ResumeCompaction resumes compaction of every shard
+func (s *ShardedStore) ResumeCompaction() {
+	for _, shard := range s.shards {
+		shard.ResumeCompaction()
+	}
+}
+
+// TrainDictionary trains a dictionary for every shard from its values, see Sunduk.TrainDictionary
+func (s *ShardedStore) TrainDictionary(size int) error {
+	return s.each(func(shard *Sunduk) error {
+		return shard.TrainDictionary(size)
+	})
+}
+
+// Freeze freezes every shard, see Sunduk.Freeze. If a shard fails to freeze, the frozen ones are thawed
+func (s *ShardedStore) Freeze() error {
+	for i, shard := range s.shards {
+		if err := shard.Freeze(); err != nil {
+			for _, frozen := range s.shards[:i] {
+				frozen.Thaw()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Thaw resumes writers of every shard blocked by Freeze
+func (s *ShardedStore) Thaw() {
+	for _, shard := range s.shards {
+		shard.Thaw()
+	}
+}
+
+// Reload reloads every shard, see Sunduk.Reload
+func (s *ShardedStore) Reload() error {
+	return s.each((*Sunduk).Reload)
+}
+
+// Verify verifies every shard, see Sunduk.Verify
+func (s *ShardedStore) Verify() error {
+	return s.each((*Sunduk).Verify)
+}
+
+// Generation returns the sum of the generations of the shards, which grows with every committed mutation
+func (s *ShardedStore) Generation() uint64 {
+	var g uint64
+	for _, shard := range s.shards {
+		g += shard.Generation()
+	}
+	return g
+}
+
+// ETag returns an entity tag of the current state of the store, see Sunduk.ETag
+func (s *ShardedStore) ETag() string {
+	return strconv.Quote(strconv.FormatUint(s.Generation(), 16))
+}
+
+// GetMeta returns the metadata of the store, kept by every shard
+func (s *ShardedStore) GetMeta() Meta {
+	return s.shards[0].GetMeta()
+}
+
+// SetMeta sets the metadata of every shard, see Sunduk.SetMeta
+func (s *ShardedStore) SetMeta(meta Meta) error {
+	return s.each(func(shard *Sunduk) error {
+		return shard.SetMeta(meta)
+	})
+}
+
+// Metrics returns the sum of the counters of the shards, the durations are the longest of the shards
+func (s *ShardedStore) Metrics() Metrics {
+	var m Metrics
+	for _, shard := range s.shards {
+		sm := shard.Metrics()
+		m.Gets += sm.Gets
+		m.Puts += sm.Puts
+		m.Deletes += sm.Deletes
+		m.CacheHits += sm.CacheHits
+		m.CacheMisses += sm.CacheMisses
+		m.BytesRead += sm.BytesRead
+		m.BytesWritten += sm.BytesWritten
+		if sm.LastFlushDuration > m.LastFlushDuration {
+			m.LastFlushDuration = sm.LastFlushDuration
+		}
+		if sm.LastCompactionDuration > m.LastCompactionDuration {
+			m.LastCompactionDuration = sm.LastCompactionDuration
+		}
+	}
+	if compressed, raw := s.SizePrefix(""); compressed > 0 {
+		m.CompressionRatio = float64(raw) / float64(compressed)
+	}
+	return m
+}
+
+// DedupStats returns the sum of the deduplication statistics of the shards
+func (s *ShardedStore) DedupStats() DedupStats {
+	var stats DedupStats
+	for _, shard := range s.shards {
+		ss := shard.DedupStats()
+		stats.SharedChunks += ss.SharedChunks
+		stats.SharedEntries += ss.SharedEntries
+		stats.SavedBytes += ss.SavedBytes
+	}
+	return stats
+}
@@ -0,0 +1,102 @@
+package sunduk
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// deleteShardedStoreFiles removes the files of n shards of the test store
+func deleteShardedStoreFiles(n int) {
+	for i := 0; i < n; i++ {
+		deleteStoreFile(shardPath(TestStoreFile, i, n))
+	}
+}
+
+func TestShardedStore(t *testing.T) {
+	store, err := OpenSharded(TestStoreFile, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteShardedStoreFiles(4)
+	values := make(map[string][]byte)
+	for i := 0; i < 100; i++ {
+		values[fmt.Sprintf("key%02d", i)] = []byte(fmt.Sprintf("value %d", i))
+	}
+	if err := store.PutAll(values); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Put("extra", []byte("extra value"))
+	_ = store.Delete("key00")
+
+	for _, shard := range store.Shards() {
+		if n := shard.Count(); n == 0 || n == store.Count() {
+			t.Errorf("Expected keys to be spread across shards, got %d keys of %d in a shard instead", n, store.Count())
+		}
+	}
+	if n := store.Count(); n != 100 {
+		t.Errorf("Expected 100 keys, got %d instead", n)
+	}
+	if n := store.CountPrefix("key"); n != 99 {
+		t.Errorf("Expected 99 keys with prefix, got %d instead", n)
+	}
+	if value, ok := store.Get("key42"); !ok || string(value) != "value 42" {
+		t.Errorf("Expected value 42 for key42, got %q instead", value)
+	}
+	if _, ok := store.Get("key00"); ok {
+		t.Error("Expected deleted key to not exist")
+	}
+	found, err := store.GetMany([]string{"key01", "key02", "missing"})
+	if err != nil || len(found) != 2 || string(found["key02"]) != "value 2" {
+		t.Errorf("Expected 2 values from GetMany, got %v, %v instead", found, err)
+	}
+	if keys := store.Keys(Prefix("key"), Limit(3)); !reflect.DeepEqual(keys, []string{"key01", "key02", "key03"}) {
+		t.Errorf("Expected first 3 keys in order, got %v instead", keys)
+	}
+
+	var keys []string
+	_ = store.Range("key97", "", func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if !reflect.DeepEqual(keys, []string{"key97", "key98", "key99"}) {
+		t.Errorf("Expected keys from key97 in order, got %v instead", keys)
+	}
+	c := store.Cursor()
+	if !c.Next() || c.Key() != "extra" {
+		t.Errorf("Expected cursor to start at key extra, got %q instead", c.Key())
+	}
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = OpenSharded(TestStoreFile, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	all := store.Keys()
+	sort.Strings(all)
+	if !reflect.DeepEqual(all, store.OrderedKeys().Strings()) || len(all) != 100 {
+		t.Errorf("Expected 100 keys after reopening, got %d instead", len(all))
+	}
+}
+
+func TestShardedStore_OtherCountOfShards(t *testing.T) {
+	store, err := OpenSharded(TestStoreFile, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteShardedStoreFiles(2)
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+
+	if _, err := OpenSharded(TestStoreFile, 3); !errors.Is(err, ErrShards) {
+		t.Errorf("Expected ErrShards opening with another count of shards, got %v instead", err)
+	}
+	defer deleteShardedStoreFiles(3)
+}
@@ -0,0 +1,102 @@
+package sunduk
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// manifestContext starts the manifest of signed stores, so that signatures of stores can't be taken for other signatures
+const manifestContext = "sunduk manifest v1\n"
+
+// Sign signs the keys and the values of the store with key and commits the signature to the store file, along
+// with pending writes. Applications distributing stores, such as plugin bundles, sign them so that receivers can
+// authenticate them with VerifySignature. The signature is kept by compactions and dropped by any later write
+func (store *Sunduk) Sign(key ed25519.PrivateKey) error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("invalid private key of %d bytes", len(key))
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if err := store.checkOpen(); err != nil {
+		return err
+	}
+	if err := store.flushPending(); err != nil {
+		return err
+	}
+	store.mu.RLock()
+	file, index := store.file.acquire(), store.index
+	store.mu.RUnlock()
+	defer file.release()
+
+	digest, err := store.manifest(file, index, nil)
+	if err != nil {
+		return err
+	}
+	return store.commit(nil, nil, putOptions{signature: ed25519.Sign(key, digest)})
+}
+
+// VerifySignature reads every value of the store and checks the signature of the store file against key.
+// It returns an error wrapping ErrSignature if the store isn't signed, or if its keys or values aren't the ones
+// signed with the private key of key. Pending writes are verified too, so they fail verification
+func (store *Sunduk) VerifySignature(key ed25519.PublicKey) error {
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key of %d bytes", len(key))
+	}
+	store.mu.RLock()
+	file, index, signature := store.file.acquire(), store.loadIndex(), store.signature
+	data := make(map[string][]byte, len(store.data))
+	for k, v := range store.data {
+		data[k] = v
+	}
+	store.mu.RUnlock()
+	defer file.release()
+
+	if signature == nil {
+		return fmt.Errorf("%w: store isn't signed", ErrSignature)
+	}
+	digest, err := store.manifest(file, index, data)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, digest, signature) {
+		return fmt.Errorf("%w: signature doesn't match the content of the store", ErrSignature)
+	}
+	return nil
+}
+
+// signed returns the signature of the store once chunks and deleted keys are committed. Changed entries void
+// the signature, unless they are repaired to their signed values
+func (store *Sunduk) signed(chunks map[string]chunk, deleted []string, po putOptions) []byte {
+	switch {
+	case po.signature != nil:
+		return po.signature
+	case len(chunks)+len(deleted) > 0 && !po.repair:
+		return nil
+	default:
+		return store.signature
+	}
+}
+
+// manifest returns the digest of the content of the store that is signed: the SHA-256 hash of the keys in order,
+// each followed by the SHA-256 hash of its value. Checksums of the index aren't signed, as they are easily forged
+func (store *Sunduk) manifest(file *handle, index map[string]entry, data map[string][]byte) ([]byte, error) {
+	h := sha256.New()
+	h.Write([]byte(manifestContext))
+	var vb [binary.MaxVarintLen64]byte
+	for _, k := range newOrderedKeys(index).keys {
+		value, err := store.load(file, k, index, data)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(value)
+		h.Write(vb[:binary.PutUvarint(vb[:], uint64(len(k)))])
+		h.Write([]byte(k))
+		h.Write(sum[:])
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,71 @@
+package sunduk
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestSunduk_Sign(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, _ := ed25519.GenerateKey(nil)
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"plugin.so": []byte("code"), "manifest.json": []byte("{}")})
+	if err := store.VerifySignature(public); !errors.Is(err, ErrSignature) {
+		t.Errorf("Expected ErrSignature for an unsigned store, got %v instead", err)
+	}
+	if err := store.Sign(private); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.VerifySignature(public); err != nil {
+		t.Errorf("Expected signature to be verified, got %v instead", err)
+	}
+	if err := store.VerifySignature(other); !errors.Is(err, ErrSignature) {
+		t.Errorf("Expected ErrSignature for another key, got %v instead", err)
+	}
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	reader, err := Open(TestStoreFile, WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.VerifySignature(public); err != nil {
+		t.Errorf("Expected signature to be kept by compaction, got %v instead", err)
+	}
+	if !reader.DebugInfo().Index.Signed {
+		t.Error("Expected debug info to tell the store is signed")
+	}
+	reader.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	_ = store.Put("plugin.so", []byte("malicious code"))
+	if err := store.VerifySignature(public); !errors.Is(err, ErrSignature) {
+		t.Errorf("Expected ErrSignature once the store is written to, got %v instead", err)
+	}
+}
+
+func TestSunduk_VerifySignatureOfChangedValues(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(nil)
+	store, err := Open(TestStoreFile, WithWriteBuffer(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.Put("a", []byte("apple"))
+	if err := store.Sign(private); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Put("a", []byte("apricot"))
+	if err := store.VerifySignature(public); !errors.Is(err, ErrSignature) {
+		t.Errorf("Expected pending writes to fail verification, got %v instead", err)
+	}
+}
@@ -0,0 +1,42 @@
+package sunduk
+
+// SizeInfo holds the sizes of the value of an entry, see EntrySizes
+type SizeInfo struct {
+	RawSize int64   // RawSize is the size of the value
+	Size    int64   // Size is the size of the chunk of the value in the store file, bases of deltas and appended data excluded. It is 0 for pending writes
+	Ratio   float64 // Ratio is RawSize divided by Size, 0 for pending writes and empty chunks
+}
+
+// EntrySizes returns the sizes of the values of all entries by key, so that applications tell which entries
+// dominate the store file and how well they compress. Chunks shared by deduplicated entries are counted for
+// every entry
+func (store *Sunduk) EntrySizes() map[string]SizeInfo {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	sizes := make(map[string]SizeInfo, store.count())
+	store.scan("", func(k string, e entry) {
+		info := SizeInfo{RawSize: e.RawSize, Size: e.Size}
+		if e.Size > 0 {
+			info.Ratio = float64(e.RawSize) / float64(e.Size)
+		}
+		sizes[k] = info
+	})
+	return sizes
+}
+
+// indexEntryEstimate is the size of the index entry of a key, the key excluded, counted for pending writes
+const indexEntryEstimate = 32
+
+// EstimateFileSize predicts the size of the store file once pending writes are committed and the file is
+// compacted, from the sizes of committed chunks and of pending values, so callers check free disk space before
+// committing a large import. Pending values are counted uncompressed along with the index entries of their keys,
+// so the estimate errs on the large side while there are pending writes, see WithWriteBuffer
+func (store *Sunduk) EstimateFileSize() int64 {
+	_, size := store.garbage()
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	for k, v := range store.data {
+		size += int64(len(k)+len(v)) + indexEntryEstimate
+	}
+	return size
+}
@@ -0,0 +1,48 @@
+package sunduk
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestSunduk_EntrySizes(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	text := bytes.Repeat([]byte("compressible "), 1000)
+	_ = store.PutAll(map[string][]byte{"text": text, "small": []byte("raw")})
+
+	sizes := store.EntrySizes()
+	if len(sizes) != 2 {
+		t.Fatalf("Expected sizes of 2 entries, got %d instead", len(sizes))
+	}
+	if s := sizes["text"]; s.RawSize != int64(len(text)) || s.Size >= s.RawSize || s.Ratio <= 1 {
+		t.Errorf("Expected compressed size and ratio of text, got %+v instead", s)
+	}
+	if s := sizes["small"]; s.RawSize != 3 || s.Size != 3 || s.Ratio != 1 {
+		t.Errorf("Expected raw size of small value, got %+v instead", s)
+	}
+}
+
+func TestSunduk_EstimateFileSize(t *testing.T) {
+	store := New(TestStoreFile, WithWriteBuffer(1<<20))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	text := bytes.Repeat([]byte("compressible "), 1000)
+	_ = store.PutAll(map[string][]byte{"a": text, "b": []byte("raw")})
+	_ = store.Flush()
+	_ = store.Put("a", []byte("overwritten"))
+	_ = store.Put("c", bytes.Repeat([]byte{1}, 500))
+
+	estimate := store.EstimateFileSize()
+	_ = store.Flush()
+	_ = store.Compact()
+	info, _ := os.Stat(TestStoreFile)
+	if estimate < info.Size() || estimate > info.Size()+1024 {
+		t.Errorf("Expected an estimate of at least %d bytes of pending writes, got %d instead", info.Size(), estimate)
+	}
+	if estimate = store.EstimateFileSize(); estimate != info.Size() {
+		t.Errorf("Expected an estimate of %d bytes without pending writes, got %d instead", info.Size(), estimate)
+	}
+}
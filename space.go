@@ -0,0 +1,51 @@
+package sunduk
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// defaultSpaceHeadroom is the free space left on the file system after a flush or a compaction by default
+const defaultSpaceHeadroom = 1 << 20
+
+// freeSpace returns the bytes available to the process on the file system holding dir, and false if it isn't
+// known, replaced by tests to simulate full disks
+var freeSpace = diskFree
+
+// WithSpaceHeadroom sets the free space in bytes that flushes and compactions leave on the file system, 1 MiB by
+// default. They check the free space before writing and fail early with ErrNoSpace if the file written and the
+// headroom don't fit, rather than failing halfway. A negative headroom disables the checks
+func WithSpaceHeadroom(bytes int64) Option {
+	return func(o *options) {
+		o.spaceHeadroom = bytes
+	}
+}
+
+// checkSpace returns an error wrapping ErrNoSpace if the file system holding path has no room for need bytes
+// and the headroom. Nothing is checked if the free space isn't known
+func (store *Sunduk) checkSpace(path string, need int64) error {
+	if store.opts.spaceHeadroom < 0 {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	free, ok := freeSpace(dir)
+	if !ok || need+store.opts.spaceHeadroom <= free {
+		return nil
+	}
+	return fmt.Errorf("%w in %s: %d bytes free, %d bytes needed along with %d bytes of headroom", ErrNoSpace, dir, free, need, store.opts.spaceHeadroom)
+}
+
+// commitSize estimates the bytes appended to the store file by committing chunks: the data of chunks loaded with
+// their data, the uncompressed values of others, and a new index as large as the last one grown by the entries of
+// chunks
+func (store *Sunduk) commitSize(chunks map[string]chunk) int64 {
+	size := store.tail
+	for k, c := range chunks {
+		n := len(c.value)
+		if c.data != nil {
+			n = len(c.data)
+		}
+		size += int64(len(k)+n) + indexEntryEstimate
+	}
+	return size
+}
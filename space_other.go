@@ -0,0 +1,8 @@
+//go:build !(darwin || dragonfly || freebsd || linux || windows)
+
+package sunduk
+
+// diskFree doesn't know the free space on this platform, so free space isn't checked there
+func diskFree(dir string) (int64, bool) {
+	return 0, false
+}
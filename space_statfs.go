@@ -0,0 +1,14 @@
+//go:build darwin || dragonfly || freebsd || linux
+
+package sunduk
+
+import "syscall"
+
+// diskFree returns the bytes available to unprivileged users on the file system holding dir with statfs
+func diskFree(dir string) (int64, bool) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false
+	}
+	return int64(uint64(st.Bavail) * uint64(st.Bsize)), true
+}
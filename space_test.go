@@ -0,0 +1,71 @@
+package sunduk
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// useFreeSpace makes the file system report free bytes available until the test ends
+func useFreeSpace(t *testing.T, free *int64) {
+	previous := freeSpace
+	freeSpace = func(string) (int64, bool) { return *free, true }
+	t.Cleanup(func() { freeSpace = previous })
+}
+
+func TestSunduk_NoSpace(t *testing.T) {
+	defer deleteTestStoreFile()
+	free := int64(1 << 30)
+	useFreeSpace(t, &free)
+	store := New(TestStoreFile, WithSpaceHeadroom(1000))
+	defer store.Close()
+	if err := store.Put("key", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	free = 1000
+	if err := store.Put("other", []byte("other value")); !errors.Is(err, ErrNoSpace) {
+		t.Errorf("Expected ErrNoSpace for a put past the headroom, got %v instead", err)
+	}
+	checkKeyNotExists(t, store, "other")
+	if err := store.Compact(); !errors.Is(err, ErrNoSpace) {
+		t.Errorf("Expected ErrNoSpace for a compaction past the headroom, got %v instead", err)
+	}
+	if _, err := os.Stat(store.tempPath()); !os.IsNotExist(err) {
+		t.Errorf("Expected no temporary file left by the compaction, got %v instead", err)
+	}
+	checkValueForKey(t, store, "key", []byte("value"))
+
+	free = 1 << 30
+	if err := store.Put("other", []byte("other value")); err != nil {
+		t.Errorf("Expected the put to succeed once there is free space, got %v instead", err)
+	}
+	checkValueForKey(t, store, "other", []byte("other value"))
+}
+
+func TestSunduk_WithSpaceHeadroomDisabled(t *testing.T) {
+	defer deleteTestStoreFile()
+	free := int64(0)
+	useFreeSpace(t, &free)
+	store := New(TestStoreFile, WithSpaceHeadroom(-1))
+	defer store.Close()
+	if err := store.Put("key", []byte("value")); err != nil {
+		t.Errorf("Expected no free space check with a negative headroom, got %v instead", err)
+	}
+	if err := store.Compact(); err != nil {
+		t.Errorf("Expected no free space check with a negative headroom, got %v instead", err)
+	}
+}
+
+func TestSunduk_CommitSize(t *testing.T) {
+	defer deleteTestStoreFile()
+	store := New(TestStoreFile)
+	defer store.Close()
+	chunks := map[string]chunk{
+		"value": {value: make([]byte, 100)},
+		"data":  {value: make([]byte, 100), data: make([]byte, 10)},
+	}
+	if size, expected := store.commitSize(chunks), store.tail+int64(5+100+4+10)+2*indexEntryEstimate; size != expected {
+		t.Errorf("Expected a commit of %d bytes counting the data of chunks loaded with it, got %d instead", expected, size)
+	}
+}
@@ -0,0 +1,26 @@
+package sunduk
+
+// Store is the interface of key-value stores, implemented by Sunduk, ShardedStore, SegmentedStore and MemoryStore,
+// and by stores outside of the package such as the client of sundukhttp and the fake of sunduktest, so application
+// code and its tests are written against any of them
+type Store interface {
+	// Get returns the value of key and true, or false if the store has no value for key
+	Get(key string) ([]byte, bool)
+	// Put creates or updates the value of key
+	Put(key string, value []byte, opts ...PutOption) error
+	// Delete removes key
+	Delete(key string) error
+	// Keys returns the keys selected by opts, in no particular order without options
+	Keys(opts ...KeysOption) []string
+	// Count returns the number of entries of the store
+	Count() int
+	// Close releases the store, which can't be used any more
+	Close() error
+}
+
+var (
+	_ Store = (*Sunduk)(nil)
+	_ Store = (*ShardedStore)(nil)
+	_ Store = (*SegmentedStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
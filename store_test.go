@@ -0,0 +1,69 @@
+package sunduk
+
+import (
+	"strings"
+	"testing"
+)
+
+// testStore checks the behavior of store through the Store interface, and closes it
+func testStore(t *testing.T, store Store) {
+	for _, key := range []string{"b", "a/2", "a/1"} {
+		if err := store.Put(key, []byte("value of "+key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := store.Get("a/1"); !ok || string(v) != "value of a/1" {
+		t.Errorf("Expected 'value of a/1', got '%s' instead", v)
+	}
+	if _, ok := store.Get("b"); ok {
+		t.Error("Expected deleted key to have no value")
+	}
+	if keys := store.Keys(Sorted()); strings.Join(keys, ",") != "a/1,a/2" || store.Count() != 2 {
+		t.Errorf("Expected keys [a/1 a/2], got %v and a count of %d instead", keys, store.Count())
+	}
+	if err := store.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Run("sunduk", func(t *testing.T) {
+		defer deleteTestStoreFile()
+		testStore(t, New(TestStoreFile))
+	})
+	t.Run("sharded", func(t *testing.T) {
+		defer deleteShardedStoreFiles(2)
+		store, err := OpenSharded(TestStoreFile, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testStore(t, store)
+	})
+	t.Run("segmented", func(t *testing.T) {
+		defer deleteSegmentedStoreFiles()
+		store, err := OpenSegmented(TestStoreFile, 4<<10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testStore(t, store)
+	})
+	t.Run("memory", func(t *testing.T) {
+		testStore(t, NewMemoryStore())
+	})
+}
+
+func TestMemoryStore_NilValues(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+	_ = store.Put("nil", nil)
+	_ = store.Put("empty", []byte{})
+	if v, ok := store.Get("nil"); !ok || v != nil {
+		t.Errorf("Expected a nil value, got %#v %t instead", v, ok)
+	}
+	if v, ok := store.Get("empty"); !ok || v == nil || len(v) != 0 {
+		t.Errorf("Expected an empty value, got %#v %t instead", v, ok)
+	}
+}
@@ -1,370 +1,707 @@
 package sunduk
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
-	"github.com/andybalholm/brotli"
-	"io"
-	"io/ioutil"
 	"os"
 	"sort"
 	"strings"
+	"sunduk/internal/format"
+	"sunduk/internal/vfs"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type entry struct {
-	Offset int64
-	Size   int32
+	Offset  int64       // Offset of compressed chunk in file
+	Size    int64       // Size of compressed chunk
+	RawSize int64       // Size of uncompressed value
+	Sum     uint32      // Checksum of uncompressed value
+	Flags   uint64      // Flags of chunk, such as format.FlagRaw
+	Base    format.Base // Base of chunk flagged with format.FlagDelta or format.FlagAppend
+	Digest  string      // Digest of uncompressed value with the hash of the store file, empty if the entry has none
+
+	hasSum  bool // hasSum is false for entries loaded from legacy files, which have no checksums
+	pending bool // pending is true for entries of pending writes, which have no chunk yet
 }
 
 type Sunduk struct {
 	FilePath string // FilePath is the path to the file used to persist
 
-	file  *os.File
-	data  map[string][]byte
-	index map[string]entry
+	mu     sync.RWMutex // mu guards the fields below against concurrent readers
+	file   *handle
+	data   map[string][]byte // data holds the values of pending writes, see WithWriteBuffer
+	cache  *cache            // cache holds values written to or read from the store file
+	index  map[string]entry
+	lazy   *format.Lookup // lazy is the lookup table of the store file looked up instead of index, see WithLazyIndex
+	size   int64          // size is the end of the last committed trailer
+	tail   int64          // tail is the size of the last committed index and trailer
+	legacy bool           // legacy is true for files in older formats, which can't be appended to
+	dict   *dictionary    // dict is the compression dictionary of the store file
+
+	fileID    format.FileID // fileID is the ID of the store file if its index is in an index file, zero otherwise
+	indexInfo os.FileInfo   // indexInfo identifies the index file the index was read from or written to
+
+	secondary  map[string]secondaryIndex // secondary holds the secondary indexes by name, see WithSecondaryIndex
+	expiry     map[string]int64          // expiry holds the expiry times of committed values which expire, see TTL
+	audit      []format.Base             // audit locates the audit chunks of the store file, see WithAuditLog
+	tombstones map[string]tombstone      // tombstones holds the entries of soft-deleted keys, see SoftDelete
+	trash      []trashed                 // trash holds the last values overwritten or deleted, see WithTrash
+	collation  *Collation                // collation orders keys, nil for bytewise order, see WithCollation
+	changes    *changeLog                // changes holds the last changes applied to the store, nil without WithChangeLog
+
+	generation uint64             // generation is the count of mutations committed to the store
+	meta       format.Meta        // meta is the metadata of the store file
+	bloom      format.Bloom       // bloom is the bloom filter of the keys of the store file, see WithBloomFilter
+	signature  []byte             // signature is the signature of the store file, see Sign
+	sealed     int64              // sealed is the time the store was sealed in unix nanoseconds, 0 if it isn't sealed, see Seal
+	access     *accessStats       // access counts reads of keys, nil without WithAccessStats. It is never replaced
+	chunks     map[chunkKey]entry // chunks locates the chunks of the store file by content, see dedupIndex. It is guarded by writeMu
+	pending    pending            // pending holds the writes not written to the store file yet, it is guarded by writeMu
+
+	opts       options
+	enc        encoder
+	workers    int // workers is the count of compression workers
+	compaction compaction
+	reloader   reloader
+	notifier   expiryNotifier
+
+	writeMu sync.Mutex // writeMu serializes writers, it is held by Freeze until Thaw
+	frozen  int32
+	closed  int32    // closed is set by Close, it is guarded by writeMu
+	lock    *os.File // lock is the lock file holding the advisory lock of the store file while it is open
+
+	leak     *leakTracker  // leak tracks the store file and the lock to release if the store leaks, nil without WithLeakDetection
+	sentinel *leakSentinel // sentinel reports the store if it is garbage collected without Close, see WithLeakDetection
+
+	counters *counters // counters is allocated separately to keep its 64-bit fields aligned for atomic access
 }
 
-// New creates a new Sunduk
-func New(filePath string) *Sunduk {
+// New creates a new Sunduk. It panics if the store file can't be opened or created
+func New(filePath string, opts ...Option) *Sunduk {
+	store, err := Open(filePath, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return store
+}
+
+// Open opens the store persisted at filePath, creating an empty file if there is no file.
+// The store file is locked until Close, see WithSharedReadLock
+func Open(filePath string, opts ...Option) (*Sunduk, error) {
 	store := &Sunduk{
 		FilePath: filePath,
 		data:     make(map[string][]byte),
 		index:    make(map[string]entry),
+		opts:     defaultOptions(),
+		counters: &counters{},
+	}
+	for _, opt := range opts {
+		opt(&store.opts)
+	}
+	store.cache = newCache(store.opts.cacheSize)
+	store.pending = newPending()
+	store.collation = store.opts.collation
+	store.compaction.throttle = newThrottle(store.opts.backgroundIO)
+	store.access = newAccessStats(store.opts)
+	store.enc, store.workers = store.opts.compression()
+	if err := store.openWithTimeout(); err != nil {
+		store.opts.logger.Error("unable to open store", "file", filePath, "err", err)
+		return nil, err
+	}
+	store.changes = newChangeLog(store.opts, store.generation)
+	store.opts.logger.Info("opened store", "file", filePath, "entries", store.count(), "size", store.size, "legacy", store.legacy)
+	store.detectLeak()
+	store.startCompactor()
+	store.startReloader()
+	store.startNotifier()
+	return store, nil
+}
+
+// loadStore checks the temp directory, registers and locks the store file and reads it. The store file is released
+// if loading fails
+func (store *Sunduk) loadStore() error {
+	if store.opts.tempDir != "" && !store.opts.readOnly {
+		if err := CheckTempDir(store.FilePath, store.opts.tempDir); err != nil {
+			return err
+		}
+	}
+	store.leak = newLeakTracker(store.opts)
+	if err := store.register(); err != nil {
+		return err
+	}
+	err := store.acquireLock()
+	if err == nil {
+		if err = store.loadFromDisk(); err == nil {
+			err = store.loadSecondary()
+		}
+		if err != nil {
+			store.file.release()
+			store.releaseLock()
+		}
+	}
+	if err == nil {
+		store.leak.track(store.file, store.lock)
 	}
-	err := store.loadFromDisk()
 	if err != nil {
-		panic(err)
+		store.unregister()
 	}
-	return store
+	return err
 }
 
-// Close closes the store's file if it isn't already closed.
-// Note that any write actions, such as the usage of Put, PutAll or Delete, will automatically re-open the store
-func (store *Sunduk) Close() {
-	if store.file == nil {
-		return
+// Close stops background compaction, reloads and expiry notifications, writes pending writes and access statistics,
+// syncs the store file to stable storage, closes it and releases its lock. The store is closed even if Close fails,
+// it returns the first error met. Later writes, reads of values not held in memory and Close itself fail with ErrClosed.
+// A store frozen by Freeze is thawed by Close
+func (store *Sunduk) Close() error {
+	// Background goroutines may wait for the write lock held by Freeze, so the store is thawed before stopping them
+	store.Thaw()
+	store.stopCompactor()
+	store.stopReloader()
+	store.stopNotifier()
+
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if atomic.LoadInt32(&store.closed) != 0 {
+		return ErrClosed
+	}
+	if store.leak.destroyed() {
+		// The store file and the lock were released by Destroy
+		atomic.StoreInt32(&store.closed, 1)
+		store.mu.Lock()
+		store.file, store.lock = nil, nil
+		store.mu.Unlock()
+		return ErrClosed
+	}
+	var first error
+	keep := func(err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	if store.opts.writeBuffer > 0 || store.access != nil {
+		keep(store.flushPending())
+		keep(store.flushAccess())
 	}
-	_ = store.file.Close()
+	if !store.opts.readOnly {
+		if err := syncFile(store.FilePath); !os.IsNotExist(err) {
+			keep(err)
+		}
+	}
+	atomic.StoreInt32(&store.closed, 1)
+
+	store.mu.Lock()
+	file := store.file
 	store.file = nil
+	store.mu.Unlock()
+	// Destroy may have released the store file and the lock of a store detecting leaks meanwhile
+	if store.leak.detach() {
+		keep(file.release())
+		store.releaseLock()
+	} else {
+		store.mu.Lock()
+		store.lock = nil
+		store.mu.Unlock()
+	}
+	store.unregister()
+	if first != nil {
+		store.opts.logger.Error("unable to close store", "file", store.FilePath, "err", first)
+	}
+	return first
 }
 
-// Get returns the value of a key as well as a bool that indicates whether an entry exists for that key
+// Get returns the value of a key as well as a bool that indicates whether an entry exists for that key.
+// Values failing checksum verification are repaired from the repair source, if one is configured.
+// Values put by this store are shared with the store and must not be modified, see WithValueCopies and Borrow
 func (store *Sunduk) Get(key string) (value []byte, ok bool) {
+	if store.authorize(key, OperationRead) != nil {
+		return nil, false
+	}
+	atomic.AddUint64(&store.counters.gets, 1)
+	store.mu.RLock()
+	if !store.mayContain(key) {
+		store.mu.RUnlock()
+		return
+	}
 	value, ok = store.data[key]
+	if !ok {
+		value, ok = store.cache.get(key)
+	}
 	if ok {
-		return
+		store.mu.RUnlock()
+		atomic.AddUint64(&store.counters.cacheHits, 1)
+		store.access.record(key)
+		return store.own(value), true
 	}
-	entry, ok := store.index[key]
+	e, ok := store.lookup(key)
 	if !ok {
+		store.mu.RUnlock()
 		return
 	}
-
-	ok = false
-	_, err := store.file.Seek(entry.Offset, 0)
+	atomic.AddUint64(&store.counters.cacheMisses, 1)
+	file := store.file.acquire()
+	store.mu.RUnlock()
+	value, repaired, err := store.fetch(file, key, e)
+	file.release()
 	if err != nil {
-		return
+		return nil, false
+	}
+	store.access.record(key)
+	if repaired {
+		store.rewriteRepaired(key, e, value)
+	} else {
+		store.cacheRead(key, e, value)
+	}
+	return value, true
+}
+
+// cacheRead caches the value of an entry read from the store file, unless the entry was overwritten meanwhile
+func (store *Sunduk) cacheRead(key string, e entry, value []byte) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	if current, ok := store.lookup(key); ok && current == e {
+		store.cache.add(key, store.own(value))
 	}
+}
 
-	data := make([]byte, entry.Size)
-	data = data[:cap(data)]
-	n, err := store.file.Read(data)
-	if int32(n) != entry.Size || err != nil {
+// rewriteRepaired rewrites the chunk of an entry repaired with value, unless the entry was overwritten meanwhile
+func (store *Sunduk) rewriteRepaired(key string, e entry, value []byte) {
+	// While writers are blocked, e.g. by Freeze, the repaired value is served without rewriting the chunk
+	if store.writable() != nil || !store.writeMu.TryLock() {
 		return
 	}
-
-	var zb bytes.Buffer
-	zr := brotli.NewReader(&zb)
-	_, _ = zr.Read(data)
-	value = zb.Bytes()
-	ok = true
-	return
+	defer store.writeMu.Unlock()
+	store.mu.RLock()
+	current, ok := store.lookup(key)
+	store.mu.RUnlock()
+	if ok && current == e {
+		if err := store.commit(map[string][]byte{key: value}, nil, putOptions{repair: true}); err != nil {
+			store.opts.logger.Error("unable to rewrite repaired entry", "file", store.FilePath, "key", key, "err", err)
+		}
+	}
 }
 
-// Put creates an entry or updates the value of an existing key
-func (store *Sunduk) Put(key string, value []byte) error {
-	store.index[key] = entry{0, int32(len(value))}
-	store.data[key] = value
-	return store.flush()
+// Put creates an entry or updates the value of an existing key.
+// Values are compressed unless they are small or already compressed, see PutOption to override it.
+// The store may keep value in memory, so it must not be modified after Put, see WithValueCopies
+func (store *Sunduk) Put(key string, value []byte, opts ...PutOption) error {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	return store.write(map[string][]byte{key: value}, nil, newPutOptions(opts))
 }
 
-// PutAll creates or updates a map of entries
-func (store *Sunduk) PutAll(entries map[string][]byte) error {
-	for key, value := range entries {
-		store.data[key] = value
-		store.index[key] = entry{0, 0}
+// PutAll creates or updates a map of entries. Every entry is checked by OnBeforePut hooks, the authorizer and the
+// quotas before any is applied, then they are applied all at once, so readers see none or all of them. On failure
+// it returns a *PutAllError telling which entries were applied
+func (store *Sunduk) PutAll(entries map[string][]byte, opts ...PutOption) error {
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	staged, err := store.apply(entries, nil, newPutOptions(opts))
+	if err == nil {
+		return nil
+	}
+	var applied []string
+	if staged {
+		applied = make([]string, 0, len(entries))
+		for k := range entries {
+			applied = append(applied, k)
+		}
+		sort.Strings(applied)
 	}
-	return store.flush()
+	return &PutAllError{Applied: applied, Err: err}
 }
 
 // Delete removes a key from the store
 func (store *Sunduk) Delete(key string) error {
-	delete(store.data, key)
-	return nil
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	// Absent keys aren't written, but deleting them fails like other writes on closed or read-only stores
+	if err := store.writable(); err != nil {
+		return err
+	}
+	if !store.Has(key) {
+		return nil
+	}
+	return store.write(nil, []string{key}, putOptions{})
 }
 
 // Count returns the total number of entries in the store
 func (store *Sunduk) Count() int {
-	length := len(store.data)
+	store.mu.RLock()
+	length := store.count()
+	store.mu.RUnlock()
 	return length
 }
 
-// Keys returns a list of all keys
-func (store *Sunduk) Keys() []string {
-	keys := make([]string, len(store.data))
-	i := 0
-	for k := range store.data {
-		keys[i] = k
-		i++
+// CountPrefix returns the number of entries which keys start with prefix
+func (store *Sunduk) CountPrefix(prefix string) int {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	n := 0
+	store.scan(prefix, func(string, entry) {
+		n++
+	})
+	return n
+}
+
+// SizePrefix returns the total size of the values which keys start with prefix, as stored in the file and
+// uncompressed. Values aren't read, so the uncompressed size of entries of legacy files, which isn't recorded, is 0
+func (store *Sunduk) SizePrefix(prefix string) (compressed, raw int64) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	store.scan(prefix, func(_ string, e entry) {
+		compressed += e.Size
+		raw += e.RawSize
+	})
+	return compressed, raw
+}
+
+// Keys returns a list of keys. Without options it returns all keys in no particular order, with any
+// option keys are in bytewise ascending order, or in the order of the collation of the store, see KeysOption
+func (store *Sunduk) Keys(opts ...KeysOption) []string {
+	store.mu.RLock()
+	index, overlaid, collation := store.index, store.lazy != nil || store.staged(), store.collation
+	store.mu.RUnlock()
+	if overlaid {
+		keys, _ := store.orderedKeys()
+		return newKeysOptions(opts).apply(keys.keys, collation)
+	}
+	if len(opts) == 0 {
+		keys := make([]string, 0, len(index))
+		for k := range index {
+			keys = append(keys, k)
+		}
+		return keys
+	}
+
+	return newKeysOptions(opts).apply(newOrderedKeys(index).keys, collation)
+}
+
+// apply selects keys in bytewise ascending order as the options tell, in the order of collation, reusing the slice
+func (ko keysOptions) apply(keys []string, collation *Collation) []string {
+	if ko.prefix != "" {
+		start := sort.SearchStrings(keys, ko.prefix)
+		keys = keys[start:]
+		keys = keys[:sort.Search(len(keys), func(i int) bool { return !strings.HasPrefix(keys[i], ko.prefix) })]
+	}
+	if collation != nil {
+		collation.sortStrings(keys)
+	}
+	if ko.reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	if ko.offset >= len(keys) {
+		return []string{}
+	}
+	keys = keys[ko.offset:]
+	if ko.limit > 0 && ko.limit < len(keys) {
+		keys = keys[:ko.limit]
 	}
 	return keys
 }
 
-// loadFromDisk loads the store from the disk and consolidates the entries, or creates an empty file if there is no file
+// loadFromDisk loads the store from the disk and consolidates the entries, or creates an empty store if there is no file.
+// An empty store is written as a preamble followed by an empty index and the trailer
 func (store *Sunduk) loadFromDisk() error {
 	store.index = make(map[string]entry)
 	store.data = make(map[string][]byte)
-	file, err := os.Open(store.FilePath)
+	file, err := fsys.OpenFile(store.FilePath, store.openFlag(), 0)
+	if os.IsNotExist(err) {
+		file, err = store.openBackup(err)
+	}
 	if err != nil {
 		// Check if the file exists, if it doesn't, then create it and return
-		if os.IsNotExist(err) {
-			file, err := os.Create(store.FilePath)
+		if os.IsNotExist(err) && !store.opts.readOnly {
+			file, err := store.createFile(store.FilePath, "")
 			if err != nil {
 				return err
 			}
-			store.file = file
-			return nil
+			store.file = newHandle(file, nil)
+			store.file.hash = store.enc.hash
+			store.meta.Created = store.timestamp()
+			return store.commit(nil, nil, putOptions{})
 		} else {
 			return err
 		}
 	}
 	// File exist, so we need to read it
-	store.file = file
-	return store.readHeader()
+	store.file = newHandle(file, nil)
+	store.file.hash = store.enc.hash
+	return store.readFormat()
 }
 
-// readHeader read, decompress and unmarshall storage header
-func (store *Sunduk) readHeader() error {
-	makeErr := func(action string, err error) error {
-		return fmt.Errorf("unable to %s storage header: %v", action, err)
-	}
-
-	// Read count of keys in storage
-	var sb [4]byte
-	if n, err := store.file.Read(sb[:]); n != len(sb) || err != nil {
-		return makeErr("read count of keys in", err)
-	}
-	kc := binary.LittleEndian.Uint32(sb[:])
-
-	// Read compressed size of keys
-	if n, err := store.file.Read(sb[:]); n != len(sb) || err != nil {
-		return makeErr("read size of keys chunk in", err)
+// checkOpen returns ErrClosed once the store file is closed by Close. It must be called with writeMu held
+func (store *Sunduk) checkOpen() error {
+	if store.file == nil || store.leak.destroyed() {
+		return ErrClosed
 	}
-	ks := binary.LittleEndian.Uint32(sb[:])
+	return nil
+}
 
-	// Read compressed sizes of data chunks
-	sizes := make([]uint32, kc)
-	for i := uint32(0); i < kc; i++ {
-		if n, err := store.file.Read(sb[:]); n != len(sb) || err != nil {
-			return makeErr("read size of data chunk in", err)
+// readValue reads and decompresses the chunk of an entry and verifies its checksum
+func (store *Sunduk) readValue(file *handle, e entry) ([]byte, error) {
+	// Compressed chunks are decoded into new memory, so they are read into pooled buffers
+	if file != nil && e.hasSum && e.Flags&format.FlagRaw == 0 && e.Size <= store.opts.bufferPool {
+		buf := chunkPool.Get().(*[]byte)
+		defer chunkPool.Put(buf)
+		if int64(cap(*buf)) < e.Size {
+			*buf = make([]byte, e.Size)
 		}
-		sizes[i] = binary.LittleEndian.Uint32(sb[:])
-	}
-
-	// Read compressed header content
-	data := make([]byte, ks)
-	if n, err := store.file.Read(data[:]); uint32(n) != ks || err != nil {
-		return makeErr("read", err)
-	}
-
-	// Save offset of storage data
-	offset, err := store.file.Seek(0, io.SeekCurrent)
-	if err != nil {
-		return makeErr("seek position in", err)
+		data := (*buf)[:e.Size]
+		n, err := store.readAt(file, data, e.Offset)
+		atomic.AddUint64(&store.counters.bytesRead, uint64(n))
+		if err != nil {
+			return nil, err
+		}
+		return store.decodeValue(file, data, e)
 	}
-
-	// Decompress header
-	zr := brotli.NewReader(bytes.NewReader(data))
-	header, err := ioutil.ReadAll(zr)
+	data, err := store.readChunk(file, e)
 	if err != nil {
-		return makeErr("decompress", err)
-	}
-
-	// Unmarshall header data
-	keys := strings.Split(string(header), "#")
-	if uint32(len(keys)) != kc {
-		return makeErr("decode keys in", err)
+		return nil, err
 	}
-	for i, k := range keys {
-		store.index[k] = entry{offset, int32(sizes[i])}
-		offset += int64(sizes[i])
-	}
-	//dec := gob.NewDecoder(bytes.NewBuffer(header))
-	//if err := dec.Decode(&store.index); err != nil {
-	//	return makeErr("decode", err)
-	//}
-
-	return nil
+	return store.decodeValue(file, data, e)
 }
 
-// flush combines all entries recorded in the file and re-saves only the necessary entries.
-// The function is executed on creation, but can also be executed manually if storage space is a concern.
-// The original file is backed up
-func (store *Sunduk) flush() error {
-	// Create new file for saving data
-	newname := store.FilePath + ".new"
-	file, err := os.Create(newname)
-	if err != nil {
-		return err
-	}
-	defer func(file *os.File) {
-		_ = file.Close()
-		_ = os.Remove(newname)
-	}(file)
-
-	// Save storage contents on disk
-	if err := store.save(file); err != nil {
-		return fmt.Errorf("unable to create %s file for flushing: %s", newname, err.Error())
-	}
-	if err := file.Close(); err != nil {
-		return fmt.Errorf("unable to close %s file after flushing: %s", newname, err.Error())
+// decodeValue decompresses the chunk of an entry read from file and verifies its checksum.
+// The base of a delta or an appended chunk is read from file
+func (store *Sunduk) decodeValue(file *handle, data []byte, e entry) ([]byte, error) {
+	var value []byte
+	var err error
+	if e.Flags&(format.FlagDelta|format.FlagAppend) != 0 {
+		base, rerr := store.readChunk(file, entry{Offset: e.Base.Offset, Size: e.Base.Size})
+		if rerr != nil {
+			return nil, rerr
+		}
+		if e.Flags&format.FlagDelta != 0 {
+			value, err = decodeDelta(data, base, e.Base, file.dict)
+		} else {
+			value, err = decodeAppended(data, base, e.Flags, e.Base, file.dict)
+		}
+	} else {
+		value, err = decodeChunk(data, e.Flags, file.dict)
 	}
-
-	// Back up the old file before doing the flushing
-	store.Close()
-	bakname := store.FilePath + ".bak"
-	if err := os.Rename(store.FilePath, bakname); err != nil {
-		return fmt.Errorf("unable to rename %s to %s during flushing: %s", store.FilePath, bakname, err.Error())
+	if err != nil {
+		if e.hasSum {
+			return nil, fmt.Errorf("%w: %v", ErrChecksum, err)
+		}
+		return nil, err
 	}
-	defer func(file *os.File) {
-		_ = os.Remove(bakname)
-	}(file)
-
-	if err := os.Rename(newname, store.FilePath); err != nil {
-		return fmt.Errorf("unable to save new file at %s during flushing: %s", store.FilePath, err.Error())
+	if e.hasSum && (int64(len(value)) != e.RawSize || checksum(value) != e.Sum || !file.checkDigest(value, e.Digest)) {
+		return nil, ErrChecksum
 	}
-
-	return nil
+	return value, nil
 }
 
-// writeSize compress and write data in file
-func writeSize(file *os.File, size uint32) error {
-	// Write size of uncompressed data
-	var sb [4]byte
-	binary.LittleEndian.PutUint32(sb[:], size)
-	if n, err := file.Write(sb[:]); uint32(n) != size || err != nil {
-		return err
+// readChunk reads the chunk of an entry
+func (store *Sunduk) readChunk(file *handle, e entry) ([]byte, error) {
+	if file == nil {
+		return nil, ErrClosed
 	}
-
-	return nil
-}
-
-// writeCompressed compress and write data in file
-func writeCompressed(file *os.File, data []byte) (n int, err error) {
-	// Compress data
-	var zb bytes.Buffer
-	zw := brotli.NewWriter(&zb)
-	_, err = zw.Write(data)
-	_ = zw.Close()
+	data := make([]byte, e.Size)
+	n, err := store.readAt(file, data, e.Offset)
+	atomic.AddUint64(&store.counters.bytesRead, uint64(n))
 	if err != nil {
-		return
-	}
-
-	//// Write size of uncompressed data
-	//if err = writeSize(file, uint32(len(data))); err != nil {
-	//	return
-	//}
-
-	// Write compressed data
-	if n, err = file.Write(zb.Bytes()); n != zb.Len() || err != nil {
-		return
+		return nil, err
 	}
-
-	return
+	return data, nil
 }
 
-// writeHeader format header space for save data
-// Header format is
-// uint32 Count						- count of data chunks
-// uint32 Size of keys chunk		- compressed size of keys chunk
-// uint32 Size of first data chunk  - compressed size of data chunk
-// uint32 Size of next data chunk
-// ...
-// uint32 Size of last  data chunk  - compressed size of data chunk
-//
-func writeHeader(file *os.File, keys []string) (n int, err error) {
-	// Write count of data chunks
-	if err = writeSize(file, uint32(len(keys))); err != nil {
-		return
-	}
-
-	// Write empty index table: compressed size of header & each date item
-	index := make([]byte, (len(keys)+1)*4)
-	if n, err = file.Write(index); n != len(index) || err != nil {
-		return
-	}
-
-	// Write join & compressed keys
-	keyStr := strings.Join(keys, "#")
-	if n, err = writeCompressed(file, []byte(keyStr)); err != nil {
-		return
+// commit appends the chunks of values and pending writes and a new index to the store file and removes deleted keys.
+// Appended data becomes visible only once the new trailer is written, or once the new index file replaces the index
+// file, so a failed commit leaves the store as it was. It must be called with writeMu held
+func (store *Sunduk) commit(values map[string][]byte, deleted []string, po putOptions) error {
+	if err := store.checkOpen(); err != nil {
+		return err
 	}
-	ks := uint32(n)
-
-	// Write compressed size of header in begin of index table
-	if _, err = file.Seek(4, io.SeekStart); err != nil {
-		return
+	header := store.header()
+	header.Generation = store.nextGeneration(values, deleted, po)
+	if po.meta != nil {
+		header.Meta = *po.meta
+	}
+	records, trash := store.pending.audit, store.pending.trash
+	if !po.repair {
+		store.mu.RLock()
+		records = append(records[:len(records):len(records)], store.auditRecords(values, deleted, po, header.Generation)...)
+		trash = append(trash[:len(trash):len(trash)], store.trashed(values, deleted, po, header.Generation)...)
+		store.mu.RUnlock()
+	}
+	chunks, deleted := store.merge(values, deleted, po)
+	secondary, serr := store.nextSecondary(chunks, deleted, po.renames)
+	if serr != nil {
+		return fmt.Errorf("unable to update secondary indexes of %s: %v", store.FilePath, serr)
+	}
+	header.Secondary = encodeSecondary(secondary)
+	header.Expiry = encodeExpiry(store.nextExpiry(chunks, deleted, po.renames))
+	header.Tombstones = encodeTombstones(store.nextTombstones(po))
+	header.Trash = encodeTrash(store.nextTrash(trash, po))
+	header.Signature = store.signed(chunks, deleted, po)
+	if po.seal {
+		// Deterministic files are sealed at the epoch, as 0 tells files not sealed
+		if header.Sealed = store.timestamp(); header.Sealed == 0 {
+			header.Sealed = 1
+		}
 	}
-	if err = writeSize(file, ks); err != nil {
-		return
+	if store.legacy {
+		return store.convert(chunks, deleted, values, header, records)
 	}
-	if _, err = file.Seek(0, io.SeekEnd); err != nil {
-		return
+	if err := store.checkSpace(store.FilePath, store.commitSize(chunks)); err != nil {
+		return err
 	}
+	start := time.Now()
+	store.opts.logger.Debug("flush started", "file", store.FilePath, "puts", len(chunks), "deletes", len(deleted))
 
-	n += len(index) + 4
-	return
-}
-
-// save make physical saving data on disk
-func (store *Sunduk) save(file *os.File) error {
-	// Sort keys
-	keys := make([]string, 0, len(store.index))
-	for k := range store.index {
-		keys = append(keys, k)
+	index := make(map[string]entry, len(store.index)+len(chunks))
+	for k, e := range store.index {
+		index[k] = e
 	}
-	sort.Strings(keys)
-
-	if _, err := writeHeader(file, keys); err != nil {
-		return err
+	for _, k := range deleted {
+		delete(index, k)
+	}
+	for from, to := range po.renames {
+		index[to] = store.index[from]
 	}
 
-	// Pack data & recalc offsets
-	sizes := make([]uint32, len(keys))
-	for i, k := range keys {
-		value, ok := store.data[k]
-		if !ok {
-			value, ok = store.Get(k)
-			if !ok {
-				return fmt.Errorf("storage consistancy is broken: value for key %q is not found", k)
+	w := &offsetWriter{file: store.file.File, offset: store.size}
+	var indexOffset, indexSize int64
+	var indexInfo os.FileInfo
+	var reads uint64
+	err := func() (err error) {
+		if w.offset == 0 {
+			if header.Data.ID, err = store.writeFilePreamble(w); err != nil {
+				return err
 			}
 		}
-		n, err := writeCompressed(file, value)
+		keys := make([]string, 0, len(chunks))
+		for k := range chunks {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		load := func(i int) (chunk, error) {
+			return store.deltaChunk(chunks[keys[i]], keys[i])
+		}
+		chunks := store.dedupIndex()
+		err = store.enc.withDict(store.dict).compressOrdered(store.workers, len(keys), load, func(i int, c chunk) (err error) {
+			index[keys[i]], err = writeShared(w, store.file.File, chunks, c)
+			return
+		})
 		if err != nil {
 			return err
 		}
-		sizes[i] = uint32(n)
-	}
-
-	// Update header index
-	if _, err := file.Seek(2*4, io.SeekStart); err != nil {
-		return err
-	}
-	for _, cs := range sizes {
-		if err := writeSize(file, cs); err != nil {
+		if len(records) > 0 {
+			c, err := writeAudit(w, store.enc, records)
+			if err != nil {
+				return err
+			}
+			header.Audit = append(header.Audit[:len(header.Audit):len(header.Audit)], c)
+		}
+		indexOffset = w.offset
+		ordered := newOrderedKeys(index)
+		header.Dictionary = store.dict.location()
+		header.Bloom = store.newBloom(ordered)
+		header.Access, reads = store.access.list(index)
+		header.Offset = indexOffset
+		if header.Data.ID != (format.FileID{}) {
+			// The index file is replaced once the chunks it indexes are in the store file
+			header.Data.Size = w.offset
+			if err := store.syncData(store.file.File); err != nil {
+				return err
+			}
+			if indexSize, err = store.writeIndexFile(store.enc, ordered, index, header); err != nil {
+				return err
+			}
+			if indexInfo, err = store.replaceIndexFile(); err != nil {
+				return err
+			}
+			if err := store.syncEntry(); err != nil {
+				// The index file is replaced already, it may only be lost with the directory entry on a power loss
+				store.opts.logger.Warn("unable to sync directory of replaced index file", "file", store.indexPath(), "err", err)
+			}
+			return nil
+		}
+		if err := store.writeIndex(w, store.enc, ordered, index, header); err != nil {
 			return err
 		}
+		if err := store.syncData(store.file.File); err != nil {
+			return err
+		}
+		// The first commit completes the store file created by loadFromDisk
+		if store.size == 0 {
+			return store.syncEntry()
+		}
+		return nil
+	}()
+	if err != nil {
+		// Drop whatever was partially appended, so the last trailer stays at the end of file
+		store.chunks = nil
+		if terr := store.file.Truncate(store.size); terr != nil {
+			store.opts.logger.Error("unable to truncate store file after failed flush", "file", store.FilePath, "err", terr)
+		}
+		store.opts.logger.Error("flush failed", "file", store.FilePath, "err", err)
+		return fmt.Errorf("unable to commit to %s: %v", store.FilePath, err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.settle(values, deleted)
+	for _, to := range po.renames {
+		store.cache.remove(to)
+	}
+	for k := range po.chunks {
+		store.cache.remove(k)
+	}
+	store.index = index
+	keys := changedKeys(values, chunks)
+	for _, to := range po.renames {
+		keys = append(keys, to)
+	}
+	store.changes.record(store.generation, Change{Generation: header.Generation, Keys: keys, Deleted: deleted, Meta: po.meta != nil})
+	store.setHeader(header)
+	store.access.committed(reads)
+	if store.opts.sizeCap > 0 {
+		for k := range chunks {
+			store.access.wrote(k)
+		}
+		for _, to := range po.renames {
+			store.access.wrote(to)
+		}
 	}
-
+	store.tail = w.offset - indexOffset
+	if indexInfo != nil {
+		store.indexInfo = indexInfo
+	}
+	atomic.AddUint64(&store.counters.bytesWritten, uint64(w.offset-store.size+indexSize))
+	atomic.StoreInt64(&store.counters.lastFlush, int64(time.Since(start)))
+	store.opts.logger.Debug("flush finished", "file", store.FilePath, "bytes", w.offset-store.size, "duration", time.Since(start))
+	store.size = w.offset
 	return nil
 }
+
+// offsetWriter writes sequentially to file starting at offset
+type offsetWriter struct {
+	file   vfs.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.file.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+// writeChunk writes the data of a chunk, returning its index entry
+func writeChunk(w *offsetWriter, c chunk) (entry, error) {
+	e := entry{
+		Offset:  w.offset,
+		Size:    int64(len(c.data)),
+		RawSize: c.rawSize,
+		Sum:     c.sum,
+		Flags:   c.flags,
+		Base:    c.base,
+		Digest:  string(c.digest),
+		hasSum:  true,
+	}
+	_, err := w.Write(c.data)
+	return e, err
+}
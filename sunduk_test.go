@@ -1,10 +1,12 @@
 package sunduk
 
 import (
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/require"
-	"io/ioutil"
 	"os"
+	"strings"
+	"sunduk/internal/vfs"
 	"testing"
 )
 
@@ -42,15 +44,10 @@ func TestNewWithExistingStoreFile(t *testing.T) {
 		t.Errorf("Expected to have 0 entries, but got %d instead", store.Count())
 	}
 
-	//_ = store.Put("test1", []byte("..."))
-	//_ = store.Put("test2", []byte("..."))
-	//_ = store.Put("test3", []byte("..."))
-	//_ = store.Delete("test3")
-	fns := []string{"ALE2G", "Chn4x4", "clew", "ALE3G", "Clover2000"}
-	for _, fn := range fns {
-		b, _ := ioutil.ReadFile(fn + ".dll")
-		_ = store.Put(fn, b)
-	}
+	_ = store.Put("test1", []byte("..."))
+	_ = store.Put("test2", []byte("..."))
+	_ = store.Put("test3", []byte("..."))
+	_ = store.Delete("test3")
 	store.Close()
 
 	// Check if the previous store was persisted to the file
@@ -103,6 +100,34 @@ func TestSunduk_PutNilValue(t *testing.T) {
 	store.Close()
 }
 
+func TestSunduk_NilAndEmptyValues(t *testing.T) {
+	deleteTestStoreFile()
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("nil", nil)
+	_ = store.Put("empty", []byte{})
+	check := func(when string) {
+		if value, ok := store.Get("nil"); !ok || value != nil {
+			t.Errorf("Expected nil value %s, got %q (%t) instead", when, value, ok)
+		}
+		if value, ok := store.Get("empty"); !ok || value == nil || len(value) != 0 {
+			t.Errorf("Expected empty value %s, got %q (%t) instead", when, value, ok)
+		}
+		if _, ok := store.Get("absent"); ok {
+			t.Errorf("Expected no value for absent key %s", when)
+		}
+	}
+	check("after Put")
+	store.Close()
+	store = New(TestStoreFile)
+	check("after reopen")
+	_ = store.Compact()
+	store.Close()
+	store = New(TestStoreFile)
+	check("after compaction")
+	store.Close()
+}
+
 func TestSunduk_PutAll(t *testing.T) {
 	deleteTestStoreFile()
 	store := New(TestStoreFile)
@@ -121,6 +146,7 @@ func TestSunduk_PutAll(t *testing.T) {
 	checkValueForKey(t, store, "1", []byte("apple"))
 	checkValueForKey(t, store, "2", []byte("banana"))
 	checkValueForKey(t, store, "3", []byte("orange"))
+	store.Close()
 
 	//fns := []string{
 	//	"ALE2G", "ALE3G", "Chn4x4", "clew", "Clover2000", "CODAN", "CW", "hfdl",
@@ -147,6 +173,40 @@ func TestSunduk_PutThenDelete(t *testing.T) {
 	store.Close()
 }
 
+func TestSunduk_Close(t *testing.T) {
+	deleteTestStoreFile()
+	store := New(TestStoreFile, WithWriteBuffer(1<<20))
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	if err := store.Close(); err != nil {
+		t.Fatalf("Expected Close to succeed, got %v instead", err)
+	}
+	if err := store.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed closing a closed store, got %v instead", err)
+	}
+	if err := store.Put("other", []byte("value")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed on Put to a closed store, got %v instead", err)
+	}
+	if err := store.Delete("key"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed on Delete from a closed store, got %v instead", err)
+	}
+	if err := store.Delete("absent"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed on Delete of an absent key from a closed store, got %v instead", err)
+	}
+	if err := store.Flush(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed on Flush of a closed store, got %v instead", err)
+	}
+	if err := store.Reload(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed on Reload of a closed store, got %v instead", err)
+	}
+
+	// The pending write was written by Close
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "key", []byte("value"))
+	checkKeyNotExists(t, store, "other")
+	store.Close()
+}
+
 func TestSunduk_Keys(t *testing.T) {
 	store := New(TestStoreFile)
 	defer deleteTestStoreFile()
@@ -165,6 +225,99 @@ func TestSunduk_Keys(t *testing.T) {
 	store.Close()
 }
 
+func TestSunduk_PutAllError(t *testing.T) {
+	errRejected := errors.New("rejected")
+	hooks := Hooks{OnBeforePut: func(key string, _ []byte) error {
+		if key == "bad" {
+			return errRejected
+		}
+		return nil
+	}}
+	fs := useFaultFS(t)
+	store := New(TestStoreFile, WithHooks(hooks), WithWriteBuffer(8))
+	defer deleteTestStoreFile()
+	defer store.Close()
+
+	err := store.PutAll(map[string][]byte{"a": []byte("apple"), "bad": nil})
+	var perr *PutAllError
+	if !errors.As(err, &perr) || len(perr.Applied) != 0 || !errors.Is(err, errRejected) || !strings.Contains(err.Error(), `"bad"`) {
+		t.Fatalf("Expected a PutAllError naming the rejected key with no entry applied, got %v instead", err)
+	}
+	checkKeyNotExists(t, store, "a")
+
+	// Entries staged in the write buffer are applied even though writing the buffer fails
+	fs.Inject(vfs.Faults{Fail: func(op, _ string) bool { return op == "write" }})
+	err = store.PutAll(map[string][]byte{"b": []byte("banana"), "c": []byte("cherry")})
+	if !errors.As(err, &perr) || strings.Join(perr.Applied, ",") != "b,c" {
+		t.Fatalf("Expected a PutAllError with keys b and c applied, got %v instead", err)
+	}
+	checkValueForKey(t, store, "b", []byte("banana"))
+	// Entries pending from an earlier put aren't applied by a rejected one
+	err = store.PutAll(map[string][]byte{"d": nil, "bad": nil})
+	if !errors.As(err, &perr) || len(perr.Applied) != 0 {
+		t.Fatalf("Expected a PutAllError with no entry applied while others are pending, got %v instead", err)
+	}
+	fs.Inject(vfs.Faults{})
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSunduk_KeysWithOptions(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.PutAll(map[string][]byte{"a/1": nil, "a/2": nil, "a/3": nil, "b/1": nil, "c": nil})
+
+	scenarios := []struct {
+		name     string
+		opts     []KeysOption
+		expected []string
+	}{
+		{"sorted", []KeysOption{Sorted()}, []string{"a/1", "a/2", "a/3", "b/1", "c"}},
+		{"reversed", []KeysOption{Reversed()}, []string{"c", "b/1", "a/3", "a/2", "a/1"}},
+		{"prefix", []KeysOption{Prefix("a/")}, []string{"a/1", "a/2", "a/3"}},
+		{"missing-prefix", []KeysOption{Prefix("d")}, []string{}},
+		{"reversed-prefix", []KeysOption{Reversed(), Prefix("a/")}, []string{"a/3", "a/2", "a/1"}},
+		{"page", []KeysOption{Offset(1), Limit(2)}, []string{"a/2", "a/3"}},
+		{"last-page", []KeysOption{Offset(4), Limit(2)}, []string{"c"}},
+		{"past-end", []KeysOption{Offset(5)}, []string{}},
+		{"reversed-page", []KeysOption{Reversed(), Prefix("a/"), Offset(1), Limit(1)}, []string{"a/2"}},
+	}
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			keys := store.Keys(scenario.opts...)
+			if strings.Join(keys, ",") != strings.Join(scenario.expected, ",") {
+				t.Errorf("Expected keys %v, got %v instead", scenario.expected, keys)
+			}
+			keys = SelectKeys([]string{"a/1", "a/2", "a/3", "b/1", "c"}, scenario.opts...)
+			if strings.Join(keys, ",") != strings.Join(scenario.expected, ",") {
+				t.Errorf("Expected selected keys %v, got %v instead", scenario.expected, keys)
+			}
+		})
+	}
+}
+
+func TestSunduk_CountAndSizePrefix(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.PutAll(map[string][]byte{"a/1": []byte("apple"), "a/2": []byte("apricot"), "b/1": []byte("banana")}, Uncompressed())
+
+	if n := store.CountPrefix("a/"); n != 2 {
+		t.Errorf("Expected 2 keys with prefix a/, got %d instead", n)
+	}
+	if n := store.CountPrefix(""); n != 3 {
+		t.Errorf("Expected 3 keys with empty prefix, got %d instead", n)
+	}
+	if compressed, raw := store.SizePrefix("a/"); compressed != 12 || raw != 12 {
+		t.Errorf("Expected 12 bytes of raw values with prefix a/, got %d compressed and %d raw instead", compressed, raw)
+	}
+	if compressed, raw := store.SizePrefix("c/"); compressed != 0 || raw != 0 {
+		t.Errorf("Expected no values with prefix c/, got %d compressed and %d raw instead", compressed, raw)
+	}
+}
+
 ///////////////////////
 // Utility functions //
 ///////////////////////
@@ -186,6 +339,13 @@ func checkKeyNotExists(t *testing.T, store *Sunduk, key string) {
 }
 
 func deleteTestStoreFile() {
-	_ = os.Remove(TestStoreFile)
-	_ = os.Remove(fmt.Sprintf("%s.bak", TestStoreFile))
+	deleteStoreFile(TestStoreFile)
+}
+
+func deleteStoreFile(filePath string) {
+	_ = os.Remove(filePath)
+	_ = os.Remove(fmt.Sprintf("%s.bak", filePath))
+	_ = os.Remove(fmt.Sprintf("%s.lock", filePath))
+	_ = os.Remove(fmt.Sprintf("%s.idx", filePath))
+	_ = os.Remove(fmt.Sprintf("%s.idx.new", filePath))
 }
@@ -0,0 +1,293 @@
+// Package sundukconvert converts between sunduk stores and other embedded key-value stores and archives. BoltDB
+// (bbolt) files are read and written by ImportBolt and ExportBolt, BadgerDB directories by ImportBadger and
+// ExportBadger, zip archives by FromZip and ToZip, other stores are imported from any Source
+package sundukconvert
+
+import (
+	"archive/zip"
+	"errors"
+	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/options"
+	bolt "go.etcd.io/bbolt"
+	"io"
+	"os"
+	"strings"
+	"sunduk"
+	"time"
+)
+
+// DefaultBucket is the bucket of BoltDB files that ExportBolt writes keys without a bucket to
+const DefaultBucket = "sunduk"
+
+// batchSize is the number of entries Import writes at once
+const batchSize = 1000
+
+// ErrEmptyKey is returned by ExportBolt and ExportBadger for stores holding the empty key, which BoltDB and BadgerDB
+// don't allow
+var ErrEmptyKey = errors.New("empty key can't be exported")
+
+// Source calls fn for every entry of a store to import, stopping at the first error returned by fn. value is
+// only valid during the call
+type Source func(fn func(key string, value []byte) error) error
+
+// Import puts every entry of src into store, and returns the number of entries put. Entries are written in
+// batches, so entries put before a failure stay in store. The progress is reported after every batch as
+// sunduk.OperationImport, see sunduk.WithProgress
+func Import(store *sunduk.Sunduk, src Source) (int, error) {
+	n, size := 0, int64(0)
+	batch := make(map[string][]byte, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := store.PutAll(batch); err != nil {
+			return err
+		}
+		n += len(batch)
+		for _, v := range batch {
+			size += int64(len(v))
+		}
+		store.ReportProgress(sunduk.Progress{Operation: sunduk.OperationImport, Done: n, Bytes: size})
+		batch = make(map[string][]byte, batchSize)
+		return nil
+	}
+	err := src(func(key string, value []byte) error {
+		batch[key] = append([]byte(nil), value...)
+		if len(batch) < batchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	return n, err
+}
+
+// exportEach calls fn for every entry of store like ForEach, reporting the progress as sunduk.OperationExport,
+// and returns the number of entries passed to fn
+func exportEach(store *sunduk.Sunduk, fn func(key string, value []byte) error) (int, error) {
+	n, total, size := 0, store.Count(), int64(0)
+	err := store.ForEach(func(key string, value []byte) error {
+		if err := fn(key, value); err != nil {
+			return err
+		}
+		n++
+		size += int64(len(value))
+		store.ReportProgress(sunduk.Progress{Operation: sunduk.OperationExport, Done: n, Total: total, Bytes: size})
+		return nil
+	})
+	return n, err
+}
+
+// BoltSource returns a Source of the entries of the BoltDB file at path. The key of an entry is the path of its
+// bucket joined with its key by sep, nested buckets included, except for the entries of DefaultBucket which are
+// keyed by their key alone, so that files written by ExportBolt are imported with the keys they were exported with
+func BoltSource(path, sep string) Source {
+	return func(fn func(key string, value []byte) error) error {
+		// bbolt creates missing files even when opened read-only
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+		db, err := bolt.Open(path, 0600, &bolt.Options{ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.View(func(tx *bolt.Tx) error {
+			return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+				if string(name) == DefaultBucket {
+					return walkBucket(b, "", sep, fn)
+				}
+				return walkBucket(b, string(name)+sep, sep, fn)
+			})
+		})
+	}
+}
+
+// walkBucket calls fn for every entry of b and of its nested buckets, with keys prefixed by prefix
+func walkBucket(b *bolt.Bucket, prefix, sep string, fn func(key string, value []byte) error) error {
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			if nested := b.Bucket(k); nested != nil {
+				return walkBucket(nested, prefix+string(k)+sep, sep, fn)
+			}
+		}
+		return fn(prefix+string(k), v)
+	})
+}
+
+// ImportBolt puts every entry of the BoltDB file at path into store, see BoltSource, and returns the number of
+// entries put
+func ImportBolt(store *sunduk.Sunduk, path, sep string) (int, error) {
+	return Import(store, BoltSource(path, sep))
+}
+
+// ExportBolt writes every entry of store to the BoltDB file at path, creating it if needed, and returns the
+// number of entries written. Keys are split at the first sep into the bucket and the key in the bucket. Keys
+// without sep, keys which bucket or key in the bucket would be empty as they start or end with sep, and keys which
+// bucket would be DefaultBucket are written whole to DefaultBucket, so that every key has an entry of its own and
+// ImportBolt restores the keys of the store. Stores holding the empty key fail with ErrEmptyKey. Entries are written
+// in a single transaction, the progress is reported as sunduk.OperationExport, see sunduk.WithProgress
+func ExportBolt(store *sunduk.Sunduk, path, sep string) (int, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = db.Update(func(tx *bolt.Tx) error {
+		var err error
+		n, err = exportEach(store, func(key string, value []byte) error {
+			bucket, k := splitBoltKey(key, sep)
+			if k == "" {
+				return ErrEmptyKey
+			}
+			b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+			if err != nil {
+				return err
+			}
+			// bbolt holds keys and values until the transaction commits
+			return b.Put([]byte(k), append([]byte(nil), value...))
+		})
+		return err
+	})
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// splitBoltKey splits key at the first sep into its bucket and its key in the bucket, see ExportBolt
+func splitBoltKey(key, sep string) (bucket, k string) {
+	if i := strings.Index(key, sep); sep != "" && i > 0 && i+len(sep) < len(key) && key[:i] != DefaultBucket {
+		return key[:i], key[i+len(sep):]
+	}
+	return DefaultBucket, key
+}
+
+// badgerOptions returns the options of the BadgerDB directory at dir. Value logs are read rather than mapped in
+// memory, as the mappings of their default size don't fit the address space of 32-bit platforms
+func badgerOptions(dir string) badger.Options {
+	return badger.DefaultOptions(dir).WithLogger(nil).WithValueLogLoadingMode(options.FileIO)
+}
+
+// BadgerSource returns a Source of the entries of the BadgerDB directory at dir, in key order
+func BadgerSource(dir string) Source {
+	return func(fn func(key string, value []byte) error) error {
+		// Badger creates missing directories even when opened read-only
+		if _, err := os.Stat(dir); err != nil {
+			return err
+		}
+		db, err := badger.Open(badgerOptions(dir).WithReadOnly(true))
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer it.Close()
+			for it.Rewind(); it.Valid(); it.Next() {
+				item := it.Item()
+				if err := item.Value(func(value []byte) error {
+					return fn(string(item.Key()), value)
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// ImportBadger puts every entry of the BadgerDB directory at dir into store, see BadgerSource, and returns the
+// number of entries put
+func ImportBadger(store *sunduk.Sunduk, dir string) (int, error) {
+	return Import(store, BadgerSource(dir))
+}
+
+// ExportBadger writes every entry of store to the BadgerDB directory at dir, creating it if needed, and returns
+// the number of entries written. Stores holding the empty key fail with ErrEmptyKey. Entries are written in batches,
+// so entries written before a failure stay in the directory. The progress is reported as sunduk.OperationExport
+func ExportBadger(store *sunduk.Sunduk, dir string) (int, error) {
+	db, err := badger.Open(badgerOptions(dir))
+	if err != nil {
+		return 0, err
+	}
+	wb := db.NewWriteBatch()
+	n, err := exportEach(store, func(key string, value []byte) error {
+		if key == "" {
+			return ErrEmptyKey
+		}
+		// Badger holds keys and values until the batch is flushed
+		return wb.Set([]byte(key), append([]byte(nil), value...))
+	})
+	if err == nil {
+		err = wb.Flush()
+	} else {
+		wb.Cancel()
+	}
+	if cerr := db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ZipSource returns a Source of the files of the zip archive r, keyed by their paths in the archive. Directories
+// are skipped
+func ZipSource(r *zip.Reader) Source {
+	return func(fn func(key string, value []byte) error) error {
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			value, err := io.ReadAll(rc)
+			_ = rc.Close()
+			if err != nil {
+				return err
+			}
+			if err := fn(f.Name, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// FromZip puts every file of the zip archive r into store under its path in the archive, see ZipSource, and
+// returns the number of files put
+func FromZip(store *sunduk.Sunduk, r *zip.Reader) (int, error) {
+	return Import(store, ZipSource(r))
+}
+
+// ToZip writes every entry of store to w as a zip archive, which files are the values of the entries at their
+// keys as paths, deflated, in key order and dated at the time of the export. It returns the number of entries
+// written. The progress is reported as sunduk.OperationExport, see sunduk.WithProgress
+func ToZip(store *sunduk.Sunduk, w io.Writer) (int, error) {
+	zw := zip.NewWriter(w)
+	now := time.Now()
+	n, err := exportEach(store, func(key string, value []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: key, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(value)
+		return err
+	})
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,238 @@
+package sundukconvert
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	bolt "go.etcd.io/bbolt"
+	"os"
+	"strings"
+	"sunduk"
+	"testing"
+)
+
+const (
+	TestStoreFile = "sunduk.data"
+	TestBoltFile  = "bolt.db"
+	TestBadgerDir = "badger"
+)
+
+func deleteTestFiles() {
+	_ = os.Remove(TestStoreFile)
+	_ = os.Remove(TestStoreFile + ".lock")
+	_ = os.Remove(TestBoltFile)
+	_ = os.RemoveAll(TestBadgerDir)
+}
+
+func TestExportBolt_ImportBolt(t *testing.T) {
+	deleteTestFiles()
+	defer deleteTestFiles()
+	entries := map[string][]byte{"fruit/a": []byte("apple"), "fruit/b": []byte("banana"), "plain": []byte("value"), "sunduk/plain": []byte("other")}
+	var progress []sunduk.Progress
+	store := sunduk.New(TestStoreFile, sunduk.WithProgress(func(p sunduk.Progress) {
+		progress = append(progress, p)
+	}))
+	if err := store.PutAll(entries); err != nil {
+		t.Fatal(err)
+	}
+	n, err := ExportBolt(store, TestBoltFile, "/")
+	store.Close()
+	if err != nil || n != 4 {
+		t.Fatalf("Expected 4 entries exported, got %d (%v) instead", n, err)
+	}
+	if len(progress) != 4 || progress[3].Operation != sunduk.OperationExport || progress[3].Done != 4 || progress[3].Total != 4 {
+		t.Errorf("Expected the progress of 4 entries exported, got %+v instead", progress)
+	}
+
+	db, err := bolt.Open(TestBoltFile, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte("fruit")).Get([]byte("b")); string(v) != "banana" {
+			t.Errorf("Expected 'banana' in bucket 'fruit', got '%s' instead", v)
+		}
+		if v := tx.Bucket([]byte(DefaultBucket)).Get([]byte("plain")); string(v) != "value" {
+			t.Errorf("Expected 'value' in the default bucket, got '%s' instead", v)
+		}
+		if v := tx.Bucket([]byte(DefaultBucket)).Get([]byte("sunduk/plain")); string(v) != "other" {
+			t.Errorf("Expected 'other' for the whole key in the default bucket, got '%s' instead", v)
+		}
+		return nil
+	})
+	_ = db.Update(func(tx *bolt.Tx) error {
+		b, _ := tx.Bucket([]byte("fruit")).CreateBucket([]byte("red"))
+		return b.Put([]byte("c"), []byte("cherry"))
+	})
+	_ = db.Close()
+
+	_ = os.Remove(TestStoreFile)
+	store = sunduk.New(TestStoreFile)
+	defer store.Close()
+	n, err = ImportBolt(store, TestBoltFile, "/")
+	if err != nil || n != 5 {
+		t.Fatalf("Expected 5 entries imported, got %d (%v) instead", n, err)
+	}
+	entries["fruit/red/c"] = []byte("cherry")
+	if store.Count() != len(entries) {
+		t.Errorf("Expected %d keys, got %v instead", len(entries), store.Keys(sunduk.Sorted()))
+	}
+	for key, value := range entries {
+		if v, _ := store.Get(key); !bytes.Equal(v, value) {
+			t.Errorf("Expected '%s' for key '%s', got '%s' instead", value, key, v)
+		}
+	}
+}
+
+func TestExportBolt_EdgeKeys(t *testing.T) {
+	deleteTestFiles()
+	defer deleteTestFiles()
+	entries := map[string][]byte{"/lead": []byte("1"), "trail/": []byte("2"), "/": []byte("3"), "a//b": []byte("4")}
+	store := sunduk.New(TestStoreFile)
+	if err := store.PutAll(entries); err != nil {
+		t.Fatal(err)
+	}
+	n, err := ExportBolt(store, TestBoltFile, "/")
+	if err != nil || n != 4 {
+		t.Fatalf("Expected 4 entries exported, got %d (%v) instead", n, err)
+	}
+
+	db, err := bolt.Open(TestBoltFile, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.View(func(tx *bolt.Tx) error {
+		for _, key := range []string{"/lead", "trail/", "/"} {
+			if v := tx.Bucket([]byte(DefaultBucket)).Get([]byte(key)); !bytes.Equal(v, entries[key]) {
+				t.Errorf("Expected '%s' for key '%s' in the default bucket, got '%s' instead", entries[key], key, v)
+			}
+		}
+		if v := tx.Bucket([]byte("a")).Get([]byte("/b")); string(v) != "4" {
+			t.Errorf("Expected '4' for key '/b' in bucket 'a', got '%s' instead", v)
+		}
+		return nil
+	})
+	_ = db.Close()
+
+	// Round trips keep keys exactly
+	imported := sunduk.New(TestStoreFile + ".imported")
+	defer os.Remove(TestStoreFile + ".imported")
+	if n, err := ImportBolt(imported, TestBoltFile, "/"); err != nil || n != 4 {
+		t.Fatalf("Expected 4 entries imported, got %d (%v) instead", n, err)
+	}
+	if keys, expected := imported.Keys(sunduk.Sorted()), store.Keys(sunduk.Sorted()); strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected keys %q, got %q instead", expected, keys)
+	}
+	imported.Close()
+	_ = os.Remove(TestStoreFile + ".imported.lock")
+
+	_ = os.Remove(TestBoltFile)
+	if err := store.Put("", []byte("empty")); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := ExportBolt(store, TestBoltFile, "/"); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("Expected ErrEmptyKey exporting the empty key, got %v instead", err)
+	}
+}
+
+func TestExportBadger_ImportBadger(t *testing.T) {
+	deleteTestFiles()
+	defer deleteTestFiles()
+	entries := map[string][]byte{"fruit/a": []byte("apple"), "fruit/b": []byte("banana"), "plain": []byte("value")}
+	store := sunduk.New(TestStoreFile)
+	if err := store.PutAll(entries); err != nil {
+		t.Fatal(err)
+	}
+	n, err := ExportBadger(store, TestBadgerDir)
+	store.Close()
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 entries exported, got %d (%v) instead", n, err)
+	}
+
+	_ = os.Remove(TestStoreFile)
+	store = sunduk.New(TestStoreFile)
+	defer store.Close()
+	n, err = ImportBadger(store, TestBadgerDir)
+	if err != nil || n != 3 {
+		t.Fatalf("Expected 3 entries imported, got %d (%v) instead", n, err)
+	}
+	for key, value := range entries {
+		if v, _ := store.Get(key); !bytes.Equal(v, value) {
+			t.Errorf("Expected '%s' for key '%s', got '%s' instead", value, key, v)
+		}
+	}
+	if _, err := ImportBadger(store, "missing"); err == nil {
+		t.Errorf("Expected an error importing a missing directory")
+	}
+
+	if err := store.Put("", []byte("empty")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExportBadger(store, TestBadgerDir); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("Expected ErrEmptyKey exporting the empty key, got %v instead", err)
+	}
+}
+
+func TestImport_Batches(t *testing.T) {
+	deleteTestFiles()
+	defer deleteTestFiles()
+	var progress []sunduk.Progress
+	store := sunduk.New(TestStoreFile, sunduk.WithProgress(func(p sunduk.Progress) {
+		progress = append(progress, p)
+	}))
+	defer store.Close()
+	src := func(fn func(key string, value []byte) error) error {
+		value := make([]byte, 1)
+		for i := 0; i < batchSize*2+1; i++ {
+			value[0] = byte(i)
+			if err := fn(string(rune('a'+i%26))+string(rune(i)), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	n, err := Import(store, src)
+	if err != nil || n != batchSize*2+1 || len(store.Keys()) != n {
+		t.Fatalf("Expected %d entries imported, got %d (%v) instead", batchSize*2+1, n, err)
+	}
+	if len(progress) != 3 || progress[2].Operation != sunduk.OperationImport || progress[2].Done != n || progress[2].Bytes != int64(n) {
+		t.Errorf("Expected the progress of 3 batches imported, got %+v instead", progress)
+	}
+}
+
+func TestToZip_FromZip(t *testing.T) {
+	deleteTestFiles()
+	defer deleteTestFiles()
+	entries := map[string][]byte{"assets/logo.svg": []byte("<svg/>"), "index.html": []byte("<html></html>")}
+	store := sunduk.New(TestStoreFile)
+	if err := store.PutAll(entries); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := ToZip(store, &buf)
+	store.Close()
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 entries zipped, got %d (%v) instead", n, err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "assets/logo.svg" {
+		t.Fatalf("Expected 2 files in key order, got %d starting with '%s' instead", len(zr.File), zr.File[0].Name)
+	}
+
+	_ = os.Remove(TestStoreFile)
+	store = sunduk.New(TestStoreFile)
+	defer store.Close()
+	if n, err = FromZip(store, zr); err != nil || n != 2 {
+		t.Fatalf("Expected 2 files unzipped, got %d (%v) instead", n, err)
+	}
+	for key, value := range entries {
+		if v, _ := store.Get(key); !bytes.Equal(v, value) {
+			t.Errorf("Expected '%s' for key '%s', got '%s' instead", value, key, v)
+		}
+	}
+}
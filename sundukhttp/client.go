@@ -0,0 +1,173 @@
+package sundukhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sunduk"
+)
+
+// ErrPutOptions is returned by Client.Put given options, which can't be sent to the server
+var ErrPutOptions = errors.New("put options aren't supported by remote stores")
+
+// Client reads and writes the values of a store served by Handler. It implements sunduk.Store, so code switches
+// between a local store file and a remote store
+type Client struct {
+	url    string
+	client *http.Client
+	owned  bool // owned is true if client was created for the Client rather than given to NewClient
+}
+
+var _ sunduk.Store = (*Client)(nil)
+
+// NewClient returns a client of the store served at baseURL, such as http://host:8080, sending requests with
+// client, or with a client of its own if client is nil
+func NewClient(baseURL string, client *http.Client) *Client {
+	owned := client == nil
+	if owned {
+		client = &http.Client{}
+	}
+	return &Client{url: strings.TrimSuffix(baseURL, "/"), client: client, owned: owned}
+}
+
+// do sends a request with method to path, and returns the response if it has the status expected
+func (c *Client) do(method, path string, body io.Reader, expected int) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != expected {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// statusError is the error of a response with an unexpected status
+type statusError struct {
+	missing bool // missing is true for reads of keys without value
+	msg     string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// responseError returns the error of a failed response, matching the errors of sunduk the server fails with
+func responseError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	missing := resp.StatusCode == http.StatusNotFound && resp.Header.Get(headerMissing) != ""
+	err := &statusError{missing: missing, msg: fmt.Sprintf("%s: %s", resp.Status, bytes.TrimSpace(msg))}
+	switch resp.StatusCode {
+	case http.StatusForbidden:
+		return fmt.Errorf("%w: %v", sunduk.ErrPermission, err)
+	case http.StatusInsufficientStorage:
+		return fmt.Errorf("%w: %v", sunduk.ErrQuotaExceeded, err)
+	default:
+		return err
+	}
+}
+
+// valuePath returns the path of the value of key. The key is sent as a query parameter, which servers don't clean
+// like paths
+func valuePath(key string) string {
+	return "/value?key=" + url.QueryEscape(key)
+}
+
+// Get returns the value of key and true, or false if the store has no value for key or the request fails, see Lookup
+func (c *Client) Get(key string) ([]byte, bool) {
+	value, ok, err := c.Lookup(key)
+	return value, ok && err == nil
+}
+
+// Lookup returns the value of key and true, or false if the store has no value for key. Unlike Get, it returns
+// the error of failed requests
+func (c *Client) Lookup(key string) ([]byte, bool, error) {
+	resp, err := c.do(http.MethodGet, valuePath(key), nil, http.StatusOK)
+	var status *statusError
+	if errors.As(err, &status) && status.missing {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	value, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Put creates or updates the value of key. Values are stored with the options of the server, Put fails with
+// ErrPutOptions given any option
+func (c *Client) Put(key string, value []byte, opts ...sunduk.PutOption) error {
+	if len(opts) > 0 {
+		return ErrPutOptions
+	}
+	resp, err := c.do(http.MethodPut, valuePath(key), bytes.NewReader(value), http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Delete removes key
+func (c *Client) Delete(key string) error {
+	resp, err := c.do(http.MethodDelete, valuePath(key), nil, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Keys returns the keys of the store selected by opts, see sunduk.Sunduk.Keys, nil if the request fails, see
+// ListKeys. Keys are in bytewise order whatever the collation of the store
+func (c *Client) Keys(opts ...sunduk.KeysOption) []string {
+	keys, _ := c.ListKeys(opts...)
+	return keys
+}
+
+// ListKeys returns the keys of the store selected by opts like Keys, and the error of failed requests
+func (c *Client) ListKeys(opts ...sunduk.KeysOption) ([]string, error) {
+	resp, err := c.do(http.MethodGet, "/keys", nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var keys []string
+	if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+		return nil, err
+	}
+	return sunduk.SelectKeys(keys, opts...), nil
+}
+
+// Count returns the number of entries of the store, 0 if the request fails, see CountKeys
+func (c *Client) Count() int {
+	n, _ := c.CountKeys()
+	return n
+}
+
+// CountKeys returns the number of entries of the store like Count, and the error of failed requests
+func (c *Client) CountKeys() (int, error) {
+	keys, err := c.ListKeys()
+	return len(keys), err
+}
+
+// Close closes the idle connections of the client to the server, if the client was created by NewClient. Clients
+// given to NewClient are left to their owner, as other users may share their connections
+func (c *Client) Close() error {
+	if c.owned {
+		c.client.CloseIdleConnections()
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package sundukhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sunduk"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	defer deleteTestStoreFiles()
+	store := sunduk.New(TestStoreFile)
+	defer store.Close()
+	server := httptest.NewServer(Handler(store))
+	defer server.Close()
+	client := NewClient(server.URL, nil)
+
+	for _, key := range []string{"a/1", "a/2", "b c"} {
+		if err := client.Put(key, []byte("value of "+key)); err != nil {
+			t.Fatalf("Expected value of key '%s' to be put, got %v instead", key, err)
+		}
+	}
+	if v, ok := store.Get("b c"); !ok || string(v) != "value of b c" {
+		t.Errorf("Expected 'value of b c' in the served store, got '%s' instead", v)
+	}
+	if v, ok := client.Get("a/2"); !ok || string(v) != "value of a/2" {
+		t.Errorf("Expected 'value of a/2', got '%s' instead", v)
+	}
+	if keys := client.Keys(sunduk.Prefix("a/"), sunduk.Reversed()); strings.Join(keys, ",") != "a/2,a/1" {
+		t.Errorf("Expected keys [a/2 a/1], got %v instead", keys)
+	}
+	if n := client.Count(); n != 3 {
+		t.Errorf("Expected 3 entries, got %d instead", n)
+	}
+
+	if err := client.Delete("a/1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.Get("a/1"); ok {
+		t.Error("Expected deleted key to have no value")
+	}
+	if err := client.Put("a/3", nil, sunduk.Compressed()); err != ErrPutOptions {
+		t.Errorf("Expected ErrPutOptions, got %v instead", err)
+	}
+}
+
+func TestClient_Forbidden(t *testing.T) {
+	defer deleteTestStoreFiles()
+	store := sunduk.New(TestStoreFile)
+	defer store.Close()
+	readOnly := func(*http.Request) sunduk.Authorizer {
+		return sunduk.AuthorizerFunc(func(_ string, op sunduk.Operation) error {
+			if op != sunduk.OperationRead {
+				return sunduk.ErrPermission
+			}
+			return nil
+		})
+	}
+	server := httptest.NewServer(Handler(store, WithAuthorizer(readOnly)))
+	defer server.Close()
+
+	if err := NewClient(server.URL, nil).Put("a", []byte("value")); !errors.Is(err, sunduk.ErrPermission) {
+		t.Errorf("Expected ErrPermission, got %v instead", err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	defer deleteTestStoreFiles()
+	store := sunduk.New(TestStoreFile)
+	defer store.Close()
+	server := httptest.NewServer(Handler(store))
+	client := NewClient(server.URL, nil)
+
+	if v, ok, err := client.Lookup("missing"); v != nil || ok || err != nil {
+		t.Errorf("Expected no value and no error for a missing key, got '%s' %t (%v) instead", v, ok, err)
+	}
+	server.Close()
+	if _, ok, err := client.Lookup("a"); ok || err == nil {
+		t.Error("Expected Lookup to fail once the server is closed")
+	}
+	if _, err := client.ListKeys(); err == nil {
+		t.Error("Expected ListKeys to fail once the server is closed")
+	}
+	if _, err := client.CountKeys(); err == nil {
+		t.Error("Expected CountKeys to fail once the server is closed")
+	}
+	if _, ok := client.Get("a"); ok {
+		t.Error("Expected Get to report no value once the server is closed")
+	}
+}
+
+func TestClient_Close(t *testing.T) {
+	if client := NewClient("http://localhost", nil); !client.owned || client.client == http.DefaultClient {
+		t.Error("Expected a client of its own without a given client")
+	}
+	if client := NewClient("http://localhost", http.DefaultClient); client.owned {
+		t.Error("Expected the given client to be left to its owner on Close")
+	}
+}
+
+func TestClient_UncleanKeys(t *testing.T) {
+	defer deleteTestStoreFiles()
+	store := sunduk.New(TestStoreFile)
+	defer store.Close()
+	server := httptest.NewServer(Handler(store))
+	defer server.Close()
+	client := NewClient(server.URL, nil)
+
+	for _, key := range []string{"a//b", "/lead", "./x", "a/../b", "q?x=1&key=y", ""} {
+		if err := client.Put(key, []byte("value of "+key)); err != nil {
+			t.Fatalf("Expected value of key '%s' to be put, got %v instead", key, err)
+		}
+		if v, ok := store.Get(key); !ok || string(v) != "value of "+key {
+			t.Errorf("Expected 'value of %s' in the served store, got '%s' instead", key, v)
+		}
+		if v, ok, err := client.Lookup(key); !ok || err != nil || string(v) != "value of "+key {
+			t.Errorf("Expected 'value of %s', got '%s' %t (%v) instead", key, v, ok, err)
+		}
+		if err := client.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := store.Get(key); ok {
+			t.Errorf("Expected key '%s' to be deleted", key)
+		}
+	}
+
+	// Unknown paths aren't read as keys without value
+	if _, ok, err := NewClient(server.URL+"/unknown", nil).Lookup("a"); ok || err == nil {
+		t.Error("Expected Lookup to fail for an unknown path")
+	}
+}
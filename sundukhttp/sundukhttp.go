@@ -0,0 +1,206 @@
+// Package sundukhttp serves a store over HTTP. It serves the values of the store, and the sync endpoint
+// sunduk.SyncTo sends changed values to, so stores on edge devices are kept up to date with a central store
+// by transferring only what changed. Client reads and writes the values of a served store
+package sundukhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sunduk"
+)
+
+// maxValueSize is the largest value put by a PUT request
+const maxValueSize = 1 << 30
+
+// headerMissing is the header marking responses to reads of keys without value, telling them apart from requests
+// for unknown paths which fail with 404 Not Found as well
+const headerMissing = "X-Sunduk-Missing"
+
+// Option configures the handler returned by Handler
+type Option func(*handler)
+
+// WithAuthorizer makes the handler check requests with the authorizer authorizer returns for them, such as an
+// authorizer of the user the request authenticates. Denied requests fail with 403 Forbidden. The sync endpoint
+// reads or writes the whole store, it is checked as an access to the empty key
+func WithAuthorizer(authorizer func(r *http.Request) sunduk.Authorizer) Option {
+	return func(h *handler) {
+		h.authorizer = authorizer
+	}
+}
+
+// handler serves a store
+type handler struct {
+	store      *sunduk.Sunduk
+	authorizer func(r *http.Request) sunduk.Authorizer
+}
+
+// Handler returns the handler serving store:
+//
+//	GET /values/{key}     returns the value of key
+//	PUT /values/{key}     puts the request body as the value of key
+//	DELETE /values/{key}  deletes key
+//	GET /value?key={key}  returns the value of key, PUT and DELETE as with /values/{key}
+//	GET /keys             returns the keys of the store in bytewise ascending order
+//	GET /sync             returns the checksums of the values of the store, see sunduk.SyncSums
+//	POST /sync            applies the values sent by sunduk.SyncTo, see sunduk.ApplySync
+//
+// Reads of keys without value fail with 404 Not Found and the X-Sunduk-Missing header. The paths of /values/{key}
+// are cleaned, so keys with empty or dot segments, such as a//b or a/../b, are only reached by /value?key={key}.
+// SyncTo is given the URL of the sync endpoint, such as http://host:8080/sync
+func Handler(store *sunduk.Sunduk, opts ...Option) http.Handler {
+	h := &handler{store: store}
+	for _, opt := range opts {
+		opt(h)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/values/", h.serveValue)
+	mux.HandleFunc("/value", h.serveQueryValue)
+	mux.HandleFunc("/keys", h.serveKeys)
+	mux.HandleFunc("/sync", h.serveSync)
+	return mux
+}
+
+// authorize checks op on key with the authorizer of r and fails the request if it is denied
+func (h *handler) authorize(w http.ResponseWriter, r *http.Request, key string, op sunduk.Operation) bool {
+	if h.authorizer == nil {
+		return true
+	}
+	a := h.authorizer(r)
+	if a == nil {
+		return true
+	}
+	if err := a.Authorize(key, op); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// serveValue serves a value of the store, which key is the rest of the path
+func (h *handler) serveValue(w http.ResponseWriter, r *http.Request) {
+	h.serveKey(w, r, strings.TrimPrefix(r.URL.Path, "/values/"))
+}
+
+// serveQueryValue serves a value of the store, which key is the key query parameter
+func (h *handler) serveQueryValue(w http.ResponseWriter, r *http.Request) {
+	keys, ok := r.URL.Query()["key"]
+	if !ok || len(keys) != 1 {
+		http.Error(w, "a single key parameter is required", http.StatusBadRequest)
+		return
+	}
+	h.serveKey(w, r, keys[0])
+}
+
+// serveKey serves the value of key
+func (h *handler) serveKey(w http.ResponseWriter, r *http.Request, key string) {
+	store := h.store
+	op := sunduk.OperationRead
+	switch r.Method {
+	case http.MethodPut:
+		op = sunduk.OperationWrite
+	case http.MethodDelete:
+		op = sunduk.OperationDelete
+	}
+	if !h.authorize(w, r, key, op) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		value, ok := store.GetLazy(key)
+		if !ok {
+			w.Header().Set(headerMissing, "true")
+			http.NotFound(w, r)
+			return
+		}
+		defer value.Close()
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(value.Size(), 10))
+		w.Header().Set("ETag", store.ETag())
+		if r.Method == http.MethodGet {
+			_, _ = value.WriteTo(w)
+		}
+	case http.MethodPut:
+		value, err := io.ReadAll(io.LimitReader(r.Body, maxValueSize+1))
+		if err == nil && len(value) > maxValueSize {
+			http.Error(w, "value too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err == nil {
+			err = store.Put(key, value)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if err := store.Delete(key); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeError fails a request with the status matching err
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sunduk.ErrPermission):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sunduk.ErrQuotaExceeded):
+		http.Error(w, err.Error(), http.StatusInsufficientStorage)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveKeys serves the keys of the store. Listing keys reads the whole store, it is checked as an access to the
+// empty key
+func (h *handler) serveKeys(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r, "", sunduk.OperationRead) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	keys := h.store.Keys()
+	sort.Strings(keys)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(keys)
+}
+
+// serveSync serves the sync endpoint of the store
+func (h *handler) serveSync(w http.ResponseWriter, r *http.Request) {
+	store := h.store
+	op := sunduk.OperationRead
+	if r.Method == http.MethodPost {
+		op = sunduk.OperationWrite
+	}
+	if !h.authorize(w, r, "", op) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(store.SyncSums())
+	case http.MethodPost:
+		if err := store.ApplySync(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
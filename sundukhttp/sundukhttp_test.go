@@ -0,0 +1,159 @@
+package sundukhttp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sunduk"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	TestStoreFile  = "sunduk.data"
+	TestRemoteFile = "remote.data"
+)
+
+func deleteTestStoreFiles() {
+	for _, f := range []string{TestStoreFile, TestRemoteFile} {
+		_ = os.Remove(f)
+		_ = os.Remove(f + ".lock")
+	}
+}
+
+// countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
+}
+
+func TestHandler_Values(t *testing.T) {
+	defer deleteTestStoreFiles()
+	store := sunduk.New(TestStoreFile)
+	defer store.Close()
+	server := httptest.NewServer(Handler(store))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/values/a/b", strings.NewReader("value"))
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected value to be put, got %v instead", err)
+	}
+	resp, err := http.Get(server.URL + "/values/a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "value" {
+		t.Errorf("Expected value, got %q instead", body)
+	}
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/values/a/b", nil)
+	_, _ = http.DefaultClient.Do(req)
+	if resp, _ := http.Get(server.URL + "/values/a/b"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected deleted key to be not found, got %s instead", resp.Status)
+	}
+}
+
+func TestSyncTo(t *testing.T) {
+	defer deleteTestStoreFiles()
+	store := sunduk.New(TestStoreFile)
+	defer store.Close()
+	remote := sunduk.New(TestRemoteFile)
+	defer remote.Close()
+	var received int64
+	handler := Handler(remote)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = countingReader{r.Body, &received}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	bundle := bytes.Repeat([]byte("plugin bundle "), 100<<10)
+	_ = store.PutAll(map[string][]byte{"bundle": bundle, "small": []byte("small")})
+	_ = remote.PutAll(map[string][]byte{"small": []byte("stale"), "old": []byte("old")})
+	if err := store.SyncTo(server.URL + "/sync"); err != nil {
+		t.Fatal(err)
+	}
+	keys := remote.Keys()
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"bundle", "small"}) {
+		t.Errorf("Expected keys of the store, got %v instead", keys)
+	}
+	if v, _ := remote.Get("bundle"); !bytes.Equal(v, bundle) {
+		t.Errorf("Expected synced bundle, got %d bytes instead", len(v))
+	}
+	if received > int64(len(bundle))/10 {
+		t.Errorf("Expected compressed chunks to be sent, got %d bytes instead", received)
+	}
+
+	// Only changed values are sent again
+	received = 0
+	_ = store.Put("small", []byte("changed"))
+	if err := store.SyncTo(server.URL + "/sync"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := remote.Get("small"); string(v) != "changed" {
+		t.Errorf("Expected changed value, got %q instead", v)
+	}
+	if received > 100 {
+		t.Errorf("Expected only the changed value to be sent, got %d bytes instead", received)
+	}
+}
+
+func TestHandler_Authorizer(t *testing.T) {
+	defer deleteTestStoreFiles()
+	store := sunduk.New(TestStoreFile)
+	defer store.Close()
+	_ = store.Put("public/a", []byte("1"))
+	// Requests without user may only read public keys
+	authorizer := func(r *http.Request) sunduk.Authorizer {
+		if r.Header.Get("X-User") != "" {
+			return nil
+		}
+		return sunduk.AuthorizerFunc(func(key string, op sunduk.Operation) error {
+			if op != sunduk.OperationRead || !strings.HasPrefix(key, "public/") {
+				return sunduk.ErrPermission
+			}
+			return nil
+		})
+	}
+	server := httptest.NewServer(Handler(store, WithAuthorizer(authorizer)))
+	defer server.Close()
+
+	do := func(method, path, user string) int {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader("2"))
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, c := range []struct {
+		method, path, user string
+		status             int
+	}{
+		{http.MethodGet, "/values/public/a", "", http.StatusOK},
+		{http.MethodPut, "/values/public/a", "", http.StatusForbidden},
+		{http.MethodGet, "/sync", "", http.StatusForbidden},
+		{http.MethodPut, "/values/public/a", "admin", http.StatusNoContent},
+	} {
+		if status := do(c.method, c.path, c.user); status != c.status {
+			t.Errorf("Expected status %d for %s %s, got %d instead", c.status, c.method, c.path, status)
+		}
+	}
+}
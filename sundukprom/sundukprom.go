@@ -0,0 +1,77 @@
+// Package sundukprom exports the metrics of sunduk stores as Prometheus collectors.
+// It is a separate package so that stores don't depend on the Prometheus client
+package sundukprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sunduk"
+)
+
+// Collector collects the metrics of a store. Metrics are labelled with the path of the store file,
+// so collectors of several stores can be registered together
+type Collector struct {
+	store *sunduk.Sunduk
+
+	gets, puts, deletes       *prometheus.Desc
+	cacheHits, cacheMisses    *prometheus.Desc
+	bytesRead, bytesWritten   *prometheus.Desc
+	compressionRatio          *prometheus.Desc
+	lastFlush, lastCompaction *prometheus.Desc
+}
+
+// NewCollector returns a collector of the metrics of store
+func NewCollector(store *sunduk.Sunduk) *Collector {
+	labels := prometheus.Labels{"store": store.FilePath}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc("sunduk_"+name, help, nil, labels)
+	}
+	return &Collector{
+		store:            store,
+		gets:             desc("gets_total", "Count of values got from the store."),
+		puts:             desc("puts_total", "Count of values put into the store."),
+		deletes:          desc("deletes_total", "Count of keys deleted from the store."),
+		cacheHits:        desc("cache_hits_total", "Count of values got from memory."),
+		cacheMisses:      desc("cache_misses_total", "Count of values read from the store file."),
+		bytesRead:        desc("read_bytes_total", "Count of bytes of chunks read from the store file."),
+		bytesWritten:     desc("written_bytes_total", "Count of bytes written to the store file, including rewrites."),
+		compressionRatio: desc("compression_ratio", "Size of live values divided by the size of their chunks."),
+		lastFlush:        desc("last_flush_duration_seconds", "Duration of the last write to the store file."),
+		lastCompaction:   desc("last_compaction_duration_seconds", "Duration of the last compaction."),
+	}
+}
+
+// Register registers a collector of the metrics of store with reg
+func Register(reg prometheus.Registerer, store *sunduk.Sunduk) error {
+	return reg.Register(NewCollector(store))
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.gets, c.puts, c.deletes, c.cacheHits, c.cacheMisses, c.bytesRead, c.bytesWritten,
+		c.compressionRatio, c.lastFlush, c.lastCompaction,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	m := c.store.Metrics()
+	counter := func(d *prometheus.Desc, v uint64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(v))
+	}
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter(c.gets, m.Gets)
+	counter(c.puts, m.Puts)
+	counter(c.deletes, m.Deletes)
+	counter(c.cacheHits, m.CacheHits)
+	counter(c.cacheMisses, m.CacheMisses)
+	counter(c.bytesRead, m.BytesRead)
+	counter(c.bytesWritten, m.BytesWritten)
+	gauge(c.compressionRatio, m.CompressionRatio)
+	gauge(c.lastFlush, m.LastFlushDuration.Seconds())
+	gauge(c.lastCompaction, m.LastCompactionDuration.Seconds())
+}
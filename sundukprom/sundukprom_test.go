@@ -0,0 +1,41 @@
+package sundukprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"os"
+	"sunduk"
+	"testing"
+)
+
+const (
+	TestStoreFile = "sunduk.data"
+)
+
+func TestRegister(t *testing.T) {
+	store := sunduk.New(TestStoreFile)
+	defer os.Remove(TestStoreFile)
+	defer os.Remove(TestStoreFile + ".lock")
+	defer store.Close()
+	_ = store.Put("key", []byte("value"))
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := Register(reg, store); err != nil {
+		t.Fatal(err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 10 {
+		t.Errorf("Expected 10 metrics, got %d instead", len(families))
+	}
+	for _, f := range families {
+		if f.GetName() != "sunduk_puts_total" {
+			continue
+		}
+		m := f.GetMetric()[0]
+		if m.GetCounter().GetValue() != 1 || m.GetLabel()[0].GetValue() != TestStoreFile {
+			t.Errorf("Expected 1 put into %s, got %v instead", TestStoreFile, m)
+		}
+	}
+}
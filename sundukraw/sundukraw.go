@@ -0,0 +1,346 @@
+// Package sundukraw gives low-level access to the chunks and the index of sunduk store files.
+// It powers recovery, analysis and conversion tools without them reimplementing the file format.
+// Store files must not be modified by these functions while a Sunduk has them open
+package sundukraw
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sunduk"
+	"sunduk/internal/format"
+)
+
+// DefaultWindowBits is the brotli window used to compress indexes written by WriteIndex
+const DefaultWindowBits = 22
+
+// Chunk describes the stored value of a key in a store file
+type Chunk struct {
+	Key      string
+	Offset   int64  // Offset is the position of the stored value in the file
+	Size     int64  // Size is the size of the stored value
+	RawSize  int64  // RawSize is the size of the uncompressed value, -1 for legacy files
+	Checksum uint32 // Checksum is the CRC-32 (Castagnoli) of the uncompressed value
+	Raw      bool   // Raw is true for values stored uncompressed
+	Dict     bool   // Dict is true for values compressed with the dictionary of the file
+	Delta    bool   // Delta is true for values stored as a delta against the value of Base
+	Append   bool   // Append is true for values stored as data appended to the value of Base
+	Framed   bool   // Framed is true for values compressed in independent frames
+	Base     Base   // Base is the chunk a delta or appended data applies to
+}
+
+// Base describes the stored value a delta or an appended chunk applies to
+type Base struct {
+	Offset   int64
+	Size     int64
+	Checksum uint32 // Checksum is the CRC-32 (Castagnoli) of the uncompressed value
+	Raw      bool
+	Dict     bool
+	Framed   bool
+}
+
+// flags returns the flags of the base in the index
+func (b Base) flags() uint64 {
+	var flags uint64
+	if b.Raw {
+		flags |= format.FlagRaw
+	}
+	if b.Dict {
+		flags |= format.FlagDict
+	}
+	if b.Framed {
+		flags |= format.FlagFramed
+	}
+	return flags
+}
+
+// flags returns the flags of the chunk in the index
+func (c Chunk) flags() uint64 {
+	var flags uint64
+	if c.Raw {
+		flags |= format.FlagRaw
+	}
+	if c.Dict {
+		flags |= format.FlagDict
+	}
+	if c.Delta {
+		flags |= format.FlagDelta
+	}
+	if c.Append {
+		flags |= format.FlagAppend
+	}
+	if c.Framed {
+		flags |= format.FlagFramed
+	}
+	return flags
+}
+
+// hasBase returns true if the chunk applies to a base
+func (c Chunk) hasBase() bool {
+	return c.Delta || c.Append
+}
+
+// base returns the base of the chunk in the index
+func (c Chunk) base() format.Base {
+	if !c.hasBase() {
+		return format.Base{}
+	}
+	return format.Base{Offset: c.Base.Offset, Size: c.Base.Size, Flags: c.Base.flags(), Sum: c.Base.Checksum}
+}
+
+// File is a store file opened for reading its chunks
+type File struct {
+	Version int // Version is the format version of the file, 0 for the legacy format
+
+	file   *os.File
+	chunks []Chunk
+	dict   []byte
+}
+
+// Open opens the store file at path and reads its index
+func Open(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{file: file}
+	if err := f.readIndex(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) readIndex() error {
+	version, err := format.ReadVersion(f.file)
+	if err != nil {
+		return err
+	}
+	f.Version = version
+
+	var index format.Index
+	switch version {
+	case 0:
+		index.Entries, err = format.ReadLegacyIndex(f.file)
+	case 1, 2, format.Version:
+		var info os.FileInfo
+		if info, err = f.file.Stat(); err == nil {
+			index, err = f.readIndexFile(info.Size())
+		}
+	default:
+		err = fmt.Errorf("unsupported storage format version %d", version)
+	}
+	if err != nil {
+		return err
+	}
+	if index.Dictionary.Size > 0 {
+		if f.dict, err = format.ReadDictionary(f.file, index.Dictionary); err != nil {
+			return err
+		}
+	}
+
+	f.chunks = make([]Chunk, len(index.Entries))
+	for i, e := range index.Entries {
+		f.chunks[i] = Chunk{
+			Key:      e.Key,
+			Offset:   e.Offset,
+			Size:     e.Size,
+			RawSize:  e.RawSize,
+			Checksum: e.Sum,
+			Raw:      e.Flags&format.FlagRaw != 0,
+			Dict:     e.Flags&format.FlagDict != 0,
+			Delta:    e.Flags&format.FlagDelta != 0,
+			Append:   e.Flags&format.FlagAppend != 0,
+			Framed:   e.Flags&format.FlagFramed != 0,
+		}
+		if e.Flags&(format.FlagDelta|format.FlagAppend) != 0 {
+			f.chunks[i].Base = Base{
+				Offset:   e.Base.Offset,
+				Size:     e.Base.Size,
+				Checksum: e.Base.Sum,
+				Raw:      e.Base.Flags&format.FlagRaw != 0,
+				Dict:     e.Base.Flags&format.FlagDict != 0,
+				Framed:   e.Base.Flags&format.FlagFramed != 0,
+			}
+		}
+		if version == 0 {
+			f.chunks[i].RawSize = -1
+		}
+	}
+	return nil
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	return f.file.Close()
+}
+
+// Len returns the count of chunks in the index
+func (f *File) Len() int {
+	return len(f.chunks)
+}
+
+// Chunks returns the chunks of the index, in the order of the index
+func (f *File) Chunks() []Chunk {
+	return append([]Chunk(nil), f.chunks...)
+}
+
+// Dictionary returns the compression dictionary of the file, nil if it has none
+func (f *File) Dictionary() []byte {
+	return f.dict
+}
+
+// ReadRaw returns the stored bytes of the i-th chunk of the index, which are compressed unless the chunk is raw
+func (f *File) ReadRaw(i int) ([]byte, error) {
+	c := f.chunks[i]
+	return f.readAt(c.Offset, c.Size)
+}
+
+// readAt reads size bytes of the file at offset
+func (f *File) readAt(offset, size int64) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := f.file.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Read returns the uncompressed value of the i-th chunk of the index, verifying its checksum.
+// Values of legacy files aren't verified
+func (f *File) Read(i int) ([]byte, error) {
+	data, err := f.ReadRaw(i)
+	if err != nil {
+		return nil, err
+	}
+	c := f.chunks[i]
+	var value []byte
+	if c.hasBase() {
+		var base []byte
+		if base, err = f.readAt(c.Base.Offset, c.Base.Size); err != nil {
+			return nil, err
+		}
+		if base, err = format.DecodeChunk(base, c.Base.flags(), f.dict); err == nil && format.Checksum(base) != c.Base.Checksum {
+			return nil, sunduk.ErrChecksum
+		}
+		if err == nil && c.Delta {
+			value, err = format.DecodeDelta(data, base)
+		} else if err == nil {
+			var tail []byte
+			if tail, err = format.DecodeChunk(data, c.flags(), f.dict); err == nil {
+				value = append(base, tail...)
+			}
+		}
+	} else {
+		value, err = format.DecodeChunk(data, c.flags(), f.dict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", sunduk.ErrChecksum, err)
+	}
+	if c.RawSize >= 0 && (int64(len(value)) != c.RawSize || format.Checksum(value) != c.Checksum) {
+		return nil, sunduk.ErrChecksum
+	}
+	return value, nil
+}
+
+// readIndexFile reads the index of the file of size bytes, from its index file if it has one
+func (f *File) readIndexFile(size int64) (format.Index, error) {
+	id, ok, err := format.ReadFileID(f.file)
+	if err != nil || !ok {
+		if err != nil {
+			return format.Index{}, err
+		}
+		return format.ReadIndex(f.file, size)
+	}
+	file, err := os.Open(f.file.Name() + ".idx")
+	if err != nil {
+		return format.Index{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return format.Index{}, err
+	}
+	index, err := format.ReadIndexFile(file, info.Size())
+	if err != nil {
+		return format.Index{}, err
+	}
+	if index.Data.ID != id || index.Data.Size > size {
+		return format.Index{}, fmt.Errorf("%w: %s isn't the index of %s", sunduk.ErrCorruptHeader, file.Name(), f.file.Name())
+	}
+	return index, nil
+}
+
+// WriteIndex appends a new index made of chunks to the store file at path, superseding its current index.
+// Chunks are sorted by key and must lie in the data region of the file; the space of the superseded
+// index and of chunks left out is reclaimed by compaction. Files in older formats and files which index
+// is in an index file can't be given a new index
+func WriteIndex(path string, chunks []Chunk) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	version, err := format.ReadVersion(file)
+	if err != nil {
+		return err
+	}
+	if version != format.Version {
+		return fmt.Errorf("unable to write index of storage format version %d", version)
+	}
+	if _, ok, err := format.ReadFileID(file); err != nil || ok {
+		if err == nil {
+			err = fmt.Errorf("unable to write index of %s, its index is in an index file", path)
+		}
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	// The dictionary, the generation, the metadata and the seal of the current index are kept, unless the index is broken and being recovered
+	current, err := format.ReadIndex(file, size)
+	if err != nil {
+		current = format.Index{}
+	}
+
+	entries := make([]format.Entry, len(chunks))
+	for i, c := range chunks {
+		if c.Offset < format.PreambleSize || c.Size < 0 || c.RawSize < 0 || c.Offset+c.Size > size {
+			return fmt.Errorf("chunk of key %q is out of data bounds", c.Key)
+		}
+		if c.Dict && current.Dictionary.Size == 0 {
+			return fmt.Errorf("chunk of key %q is compressed with a dictionary, but the file has none", c.Key)
+		}
+		if b := c.Base; c.hasBase() && (b.Offset < format.PreambleSize || b.Size < 0 || b.Offset+b.Size > size) {
+			return fmt.Errorf("base of key %q is out of data bounds", c.Key)
+		}
+		if c.hasBase() && c.Base.Dict && current.Dictionary.Size == 0 {
+			return fmt.Errorf("base of key %q is compressed with a dictionary, but the file has none", c.Key)
+		}
+		entries[i] = format.Entry{Key: c.Key, Offset: c.Offset, Size: c.Size, RawSize: c.RawSize, Sum: c.Checksum, Flags: c.flags(), Base: c.base()}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Key == entries[i-1].Key {
+			return fmt.Errorf("duplicate key %q", entries[i].Key)
+		}
+	}
+
+	if _, err := file.Seek(size, 0); err != nil {
+		return err
+	}
+	// The bloom filter, the signature and the access statistics of the current index don't hold for chunks, so the new index has none
+	index := format.Index{Entries: entries, Dictionary: current.Dictionary, Generation: current.Generation + 1, Meta: current.Meta, Sealed: current.Sealed, Offset: size}
+	if err := format.WriteIndex(file, index, DefaultWindowBits); err != nil {
+		_ = file.Truncate(size)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return file.Close()
+}
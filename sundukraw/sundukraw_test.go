@@ -0,0 +1,130 @@
+package sundukraw
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"sunduk"
+	"sunduk/internal/format"
+	"testing"
+)
+
+const (
+	TestStoreFile = "sunduk.data"
+)
+
+func TestFile_ReadChunks(t *testing.T) {
+	store := sunduk.New(TestStoreFile)
+	defer os.Remove(TestStoreFile)
+	defer os.Remove(TestStoreFile + ".lock")
+	_ = store.PutAll(map[string][]byte{"a": []byte("apple"), "b": []byte("banana")}, sunduk.Compressed())
+	_ = store.Put("c", []byte("cherry"))
+	store.Close()
+
+	f, err := Open(TestStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Version != format.Version || f.Len() != 3 {
+		t.Fatalf("Expected version %d file with 3 chunks, got version %d with %d chunks", format.Version, f.Version, f.Len())
+	}
+	chunks := f.Chunks()
+	if chunks[0].Key != "a" || chunks[1].Key != "b" {
+		t.Errorf("Expected chunks of keys 'a' and 'b', got '%s' and '%s' instead", chunks[0].Key, chunks[1].Key)
+	}
+
+	raw, err := f.ReadRaw(1)
+	if err != nil || int64(len(raw)) != chunks[1].Size {
+		t.Errorf("Expected %d raw bytes, got %d (%v) instead", chunks[1].Size, len(raw), err)
+	}
+	if value, _ := format.Decompress(raw); string(value) != "banana" {
+		t.Errorf("Expected raw bytes to decompress to 'banana', got '%s' instead", value)
+	}
+	if value, err := f.Read(0); err != nil || string(value) != "apple" {
+		t.Errorf("Expected value 'apple', got '%s' (%v) instead", value, err)
+	}
+	if !chunks[2].Raw || chunks[0].Raw {
+		t.Error("Expected only the small value put without options to be stored raw")
+	}
+	if raw, _ := f.ReadRaw(2); string(raw) != "cherry" {
+		t.Errorf("Expected raw bytes of raw chunk to be 'cherry', got '%s' instead", raw)
+	}
+	if value, err := f.Read(2); err != nil || string(value) != "cherry" {
+		t.Errorf("Expected value 'cherry', got '%s' (%v) instead", value, err)
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	store := sunduk.New(TestStoreFile)
+	defer os.Remove(TestStoreFile)
+	defer os.Remove(TestStoreFile + ".lock")
+	_ = store.PutAll(map[string][]byte{"a": []byte("apple"), "b": []byte("banana"), "c": []byte("cherry")})
+	store.Close()
+
+	f, err := Open(TestStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := f.Chunks()
+	_ = f.Close()
+
+	// Drop 'b' and rename 'c' to 'z'
+	chunks[2].Key = "z"
+	if err := WriteIndex(TestStoreFile, []Chunk{chunks[2], chunks[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteIndex(TestStoreFile, []Chunk{chunks[0], chunks[0]}); err == nil {
+		t.Error("Expected WriteIndex to reject duplicate keys")
+	}
+
+	store = sunduk.New(TestStoreFile)
+	defer store.Close()
+	if store.Count() != 2 {
+		t.Errorf("Expected to have 2 entries, but got %d instead", store.Count())
+	}
+	if value, ok := store.Get("z"); !ok || string(value) != "cherry" {
+		t.Errorf("Expected key 'z' to have value 'cherry', got '%s' instead", value)
+	}
+	if _, ok := store.Get("b"); ok {
+		t.Error("Expected key 'b' to not exist")
+	}
+}
+
+func TestFile_ReadDeltaChunks(t *testing.T) {
+	store, err := sunduk.Open(TestStoreFile, sunduk.WithDeltaEncoding())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(TestStoreFile)
+	defer os.Remove(TestStoreFile + ".lock")
+	v1 := make([]byte, 64<<10)
+	rand.New(rand.NewSource(1)).Read(v1)
+	v2 := append([]byte("patched"), v1[7:]...)
+	_ = store.Put("key", v1)
+	_ = store.Put("key", v2)
+	store.Close()
+
+	f, err := Open(TestStoreFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := f.Chunks()
+	if !chunks[0].Delta || chunks[0].Base.Size != int64(len(v1)) {
+		t.Fatalf("Expected a delta chunk with the raw first value as base, got %+v instead", chunks[0])
+	}
+	if value, err := f.Read(0); err != nil || !bytes.Equal(value, v2) {
+		t.Errorf("Expected Read to rebuild the delta chunk, got %v instead", err)
+	}
+	_ = f.Close()
+
+	chunks[0].Key = "renamed"
+	if err := WriteIndex(TestStoreFile, chunks); err != nil {
+		t.Fatal(err)
+	}
+	store = sunduk.New(TestStoreFile)
+	defer store.Close()
+	if value, ok := store.Get("renamed"); !ok || !bytes.Equal(value, v2) {
+		t.Error("Expected WriteIndex to keep the base of delta chunks")
+	}
+}
@@ -0,0 +1,324 @@
+// Package sundukrepl replicates a store to a follower over TCP, for warm-standby copies of a store.
+// A Replicator reads the changes of the leader store from its change log, see sunduk.WithChangeLog, and pushes
+// the current values of the keys changed to a Follower, which applies them to its own store. The follower
+// records the generation of the leader it caught up with in its metadata, so replication resumes from there
+// after restarts, and falls back to a full copy when the change log no longer holds the changes since then
+package sundukrepl
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sunduk"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GenerationMeta is the metadata value of the follower store holding the generation of the leader it caught up with
+const GenerationMeta = "sundukrepl.generation"
+
+const (
+	// defaultInterval is the interval of polling the leader store for changes
+	defaultInterval = 100 * time.Millisecond
+	// retryDelay is the delay before reconnecting to a follower after a failure
+	retryDelay = time.Second
+	// batchSize is the largest count of values sent at once
+	batchSize = 1000
+)
+
+// hello is sent by the follower once connected
+type hello struct {
+	Generation uint64 // Generation is the generation of the leader the follower caught up with
+}
+
+// update is a batch of changes sent by the replicator
+type update struct {
+	Full       bool              // Full is true for the updates of a full copy of the leader store
+	Values     map[string][]byte // Values are the values put
+	Deleted    []string          // Deleted are the keys deleted
+	Meta       *sunduk.Meta      // Meta is the metadata of the leader if it changed
+	Last       bool              // Last is true for the last update of a batch of changes or of a full copy
+	Generation uint64            // Generation is the generation of the leader once the last update is applied
+}
+
+// ack is sent by the follower once it applied the updates up to a generation of the leader
+type ack struct {
+	Generation uint64
+}
+
+// Replicator pushes the changes of a leader store to a follower
+type Replicator struct {
+	acked uint64 // acked is the first field to keep it aligned for atomic access on 32-bit platforms
+
+	// Interval is the interval of polling the leader store for changes, 100 ms by default. It must be set before Run
+	Interval time.Duration
+
+	store *sunduk.Sunduk
+	addr  string
+}
+
+// NewReplicator returns a replicator pushing the changes of store to the follower listening at addr
+func NewReplicator(store *sunduk.Sunduk, addr string) *Replicator {
+	return &Replicator{Interval: defaultInterval, store: store, addr: addr}
+}
+
+// Acked returns the generation of the leader store the follower acknowledged last
+func (r *Replicator) Acked() uint64 {
+	return atomic.LoadUint64(&r.acked)
+}
+
+// Run pushes changes to the follower until ctx is done, reconnecting after failures. It returns the error of ctx
+func (r *Replicator) Run(ctx context.Context) error {
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, "tcp", r.addr)
+		if err == nil {
+			err = r.push(ctx, conn)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// push sends changes over conn until ctx is done or conn fails
+func (r *Replicator) push(ctx context.Context, conn net.Conn) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		_ = conn.Close()
+	}()
+	enc, dec := gob.NewEncoder(conn), gob.NewDecoder(conn)
+	var h hello
+	if err := dec.Decode(&h); err != nil {
+		return err
+	}
+	generation := h.Generation
+	for {
+		changes, err := r.store.Changes(generation)
+		switch {
+		case errors.Is(err, sunduk.ErrChangesTruncated):
+			generation, err = r.sendAll(enc)
+		case err != nil:
+		case len(changes) == 0:
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(r.Interval):
+			}
+			continue
+		default:
+			generation, err = r.sendChanges(enc, changes)
+		}
+		if err != nil {
+			return err
+		}
+		var a ack
+		if err := dec.Decode(&a); err != nil {
+			return err
+		}
+		if a.Generation != generation {
+			return fmt.Errorf("follower acknowledged generation %d instead of %d", a.Generation, generation)
+		}
+		atomic.StoreUint64(&r.acked, generation)
+	}
+}
+
+// sendAll sends a full copy of the leader store and returns its generation
+func (r *Replicator) sendAll(enc *gob.Encoder) (uint64, error) {
+	// Changes made while copying are sent next, as they follow the generation the copy starts at
+	generation := r.store.Generation()
+	meta := r.store.GetMeta()
+	keys := r.store.Keys()
+	for {
+		n := len(keys)
+		if n > batchSize {
+			n = batchSize
+		}
+		values, err := r.store.GetMany(keys[:n])
+		if err != nil {
+			return 0, err
+		}
+		u := update{Full: true, Values: values}
+		if keys = keys[n:]; len(keys) == 0 {
+			u.Last, u.Meta, u.Generation = true, &meta, generation
+		}
+		if err := enc.Encode(u); err != nil {
+			return 0, err
+		}
+		if u.Last {
+			return generation, nil
+		}
+	}
+}
+
+// sendChanges sends the current values of the keys changed by changes and returns the generation they lead to
+func (r *Replicator) sendChanges(enc *gob.Encoder, changes []sunduk.Change) (uint64, error) {
+	changed := make(map[string]bool)
+	var meta bool
+	for _, c := range changes {
+		for _, k := range c.Keys {
+			changed[k] = true
+		}
+		for _, k := range c.Deleted {
+			changed[k] = false
+		}
+		meta = meta || c.Meta
+	}
+	var keys, deleted []string
+	for k, put := range changed {
+		if put {
+			keys = append(keys, k)
+		} else {
+			deleted = append(deleted, k)
+		}
+	}
+	generation := changes[len(changes)-1].Generation
+	for {
+		n := len(keys)
+		if n > batchSize {
+			n = batchSize
+		}
+		values, err := r.store.GetMany(keys[:n])
+		if err != nil {
+			return 0, err
+		}
+		// Keys deleted or expired since they changed have no value anymore
+		for _, k := range keys[:n] {
+			if _, ok := values[k]; !ok {
+				deleted = append(deleted, k)
+			}
+		}
+		u := update{Values: values}
+		if keys = keys[n:]; len(keys) == 0 {
+			u.Last, u.Deleted, u.Generation = true, deleted, generation
+			if meta {
+				m := r.store.GetMeta()
+				u.Meta = &m
+			}
+		}
+		if err := enc.Encode(u); err != nil {
+			return 0, err
+		}
+		if u.Last {
+			return generation, nil
+		}
+	}
+}
+
+// Follower applies the changes pushed by a replicator to a follower store
+type Follower struct {
+	store *sunduk.Sunduk
+	mu    sync.Mutex // mu serializes replicators
+}
+
+// NewFollower returns a follower applying changes to store
+func NewFollower(store *sunduk.Sunduk) *Follower {
+	return &Follower{store: store}
+}
+
+// Generation returns the generation of the leader store the follower store caught up with, 0 if it never did
+func (f *Follower) Generation() uint64 {
+	g, _ := strconv.ParseUint(f.store.GetMeta().Values[GenerationMeta], 10, 64)
+	return g
+}
+
+// Serve accepts replicators on ln and applies their changes until ln is closed. Replicators are served one at a time
+func (f *Follower) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			// Failed connections are retried by the replicator
+			_ = f.serve(conn)
+		}()
+	}
+}
+
+// serve applies the changes sent over conn until it fails
+func (f *Follower) serve(conn net.Conn) error {
+	defer conn.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	enc, dec := gob.NewEncoder(conn), gob.NewDecoder(conn)
+	if err := enc.Encode(hello{Generation: f.Generation()}); err != nil {
+		return err
+	}
+	var copied map[string]bool // copied holds the keys of the full copy being received
+	for {
+		var u update
+		if err := dec.Decode(&u); err != nil {
+			return err
+		}
+		if u.Full {
+			if copied == nil {
+				copied = make(map[string]bool)
+			}
+			for k := range u.Values {
+				copied[k] = true
+			}
+		}
+		if err := f.apply(u, copied); err != nil {
+			return err
+		}
+		if !u.Last {
+			continue
+		}
+		copied = nil
+		if err := enc.Encode(ack{Generation: u.Generation}); err != nil {
+			return err
+		}
+	}
+}
+
+// apply applies an update to the follower store. The last update of a full copy deletes the keys that aren't copied
+func (f *Follower) apply(u update, copied map[string]bool) error {
+	if len(u.Values) > 0 {
+		if err := f.store.PutAll(u.Values); err != nil {
+			return err
+		}
+	}
+	deleted := u.Deleted
+	if u.Full && u.Last {
+		for _, k := range f.store.Keys() {
+			if !copied[k] {
+				deleted = append(deleted, k)
+			}
+		}
+	}
+	for _, k := range deleted {
+		if err := f.store.Delete(k); err != nil {
+			return err
+		}
+	}
+	if !u.Last {
+		return nil
+	}
+	// Updates are applied again if the follower stops before recording the generation, which is harmless
+	meta := f.store.GetMeta()
+	if u.Meta != nil {
+		meta = *u.Meta
+	}
+	values := make(map[string]string, len(meta.Values)+1)
+	for k, v := range meta.Values {
+		values[k] = v
+	}
+	values[GenerationMeta] = strconv.FormatUint(u.Generation, 10)
+	meta.Values = values
+	return f.store.SetMeta(meta)
+}
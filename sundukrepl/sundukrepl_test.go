@@ -0,0 +1,125 @@
+package sundukrepl
+
+import (
+	"context"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"sunduk"
+	"testing"
+	"time"
+)
+
+const (
+	TestLeaderFile   = "leader.data"
+	TestFollowerFile = "follower.data"
+)
+
+func deleteTestStoreFiles() {
+	for _, f := range []string{TestLeaderFile, TestFollowerFile} {
+		_ = os.Remove(f)
+		_ = os.Remove(f + ".lock")
+	}
+}
+
+// sortedKeys returns the keys of store in ascending order
+func sortedKeys(store *sunduk.Sunduk) []string {
+	keys := store.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+// waitAcked waits until the follower acknowledged the generation of the leader
+func waitAcked(t *testing.T, r *Replicator, leader *sunduk.Sunduk) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Acked() != leader.Generation() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected follower to catch up with generation %d, got %d instead", leader.Generation(), r.Acked())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// replicate starts replicating leader to a follower store, it returns the replicator and a function stopping it
+func replicate(t *testing.T, leader *sunduk.Sunduk) (*sunduk.Sunduk, *Replicator, func()) {
+	follower := sunduk.New(TestFollowerFile)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = NewFollower(follower).Serve(ln) }()
+	r := NewReplicator(leader, ln.Addr().String())
+	r.Interval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = r.Run(ctx)
+		close(done)
+	}()
+	return follower, r, func() {
+		cancel()
+		<-done
+		_ = ln.Close()
+		follower.Close()
+	}
+}
+
+func TestReplicator(t *testing.T) {
+	defer deleteTestStoreFiles()
+	leader := sunduk.New(TestLeaderFile, sunduk.WithChangeLog(100))
+	defer leader.Close()
+	_ = leader.PutAll(map[string][]byte{"a": []byte("1"), "b": []byte("2"), "c": []byte("3")})
+
+	follower, r, stop := replicate(t, leader)
+	waitAcked(t, r, leader)
+	if keys := sortedKeys(follower); !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Errorf("Expected full copy of the leader, got keys %v instead", keys)
+	}
+
+	_ = leader.Put("a", []byte("10"))
+	_ = leader.Delete("b")
+	_ = leader.Rename("c", "d")
+	_ = leader.SetMeta(sunduk.Meta{Application: "config"})
+	waitAcked(t, r, leader)
+	if keys := sortedKeys(follower); !reflect.DeepEqual(keys, []string{"a", "d"}) {
+		t.Errorf("Expected changes to be applied, got keys %v instead", keys)
+	}
+	if v, _ := follower.Get("a"); string(v) != "10" {
+		t.Errorf("Expected value 10, got %q instead", v)
+	}
+	if app := follower.GetMeta().Application; app != "config" {
+		t.Errorf("Expected metadata of the leader, got application %q instead", app)
+	}
+	stop()
+
+	// Replication resumes from the generation recorded by the follower
+	_ = leader.Put("e", []byte("5"))
+	follower, r, stop = replicate(t, leader)
+	defer stop()
+	waitAcked(t, r, leader)
+	if keys := sortedKeys(follower); !reflect.DeepEqual(keys, []string{"a", "d", "e"}) {
+		t.Errorf("Expected resumed replication, got keys %v instead", keys)
+	}
+}
+
+func TestReplicator_FullCopy(t *testing.T) {
+	defer deleteTestStoreFiles()
+	follower := sunduk.New(TestFollowerFile)
+	_ = follower.Put("stale", []byte("stale"))
+	follower.Close()
+
+	// Without change log, every change is sent as a full copy
+	leader := sunduk.New(TestLeaderFile)
+	defer leader.Close()
+	_ = leader.Put("a", []byte("1"))
+	follower, r, stop := replicate(t, leader)
+	defer stop()
+	waitAcked(t, r, leader)
+	_ = leader.Put("b", []byte("2"))
+	waitAcked(t, r, leader)
+	if keys := sortedKeys(follower); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("Expected keys of the leader only, got %v instead", keys)
+	}
+}
@@ -0,0 +1,96 @@
+// Package sunduksearch searches the text values of sunduk stores by content. It keeps an inverted index of the
+// terms of values as a secondary index of the store, so the index is persisted in the store file along with
+// the values and kept up to date on every commit, see sunduk.WithSecondaryIndex
+package sunduksearch
+
+import (
+	"sort"
+	"strings"
+	"sunduk"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IndexName is the name of the secondary index holding the terms of values
+const IndexName = "sunduksearch"
+
+// Analyzer splits text into the terms it is indexed and searched by
+type Analyzer func(text string) []string
+
+// DefaultAnalyzer splits text into lowercase words made of letters and digits
+func DefaultAnalyzer(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Store is a store which values are searchable by content
+type Store struct {
+	*sunduk.Sunduk
+	analyzer Analyzer
+}
+
+// Open opens the store persisted at path with opts, indexing the terms of its values with analyzer, or with
+// DefaultAnalyzer if analyzer is nil. Values that aren't valid UTF-8 text aren't indexed. The values of existing
+// stores are indexed on the first open, values put before the analyzer is changed keep the terms of the analyzer
+// they were put with
+func Open(path string, analyzer Analyzer, opts ...sunduk.Option) (*Store, error) {
+	if analyzer == nil {
+		analyzer = DefaultAnalyzer
+	}
+	index := sunduk.WithSecondaryIndex(IndexName, func(_ string, value []byte) []string {
+		if !utf8.Valid(value) {
+			return nil
+		}
+		return analyzer(string(value))
+	})
+	store, err := sunduk.Open(path, append(opts, index)...)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Sunduk: store, analyzer: analyzer}, nil
+}
+
+// Search returns the keys which values hold every term of query in ascending order, nil if query has no terms
+func (s *Store) Search(query string) []string {
+	terms := s.analyzer(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	// The rarest term is matched first, so that the keys left are few
+	matches := make([][]string, len(terms))
+	for i, term := range terms {
+		if matches[i] = s.QueryIndex(IndexName, term); len(matches[i]) == 0 {
+			return nil
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return len(matches[i]) < len(matches[j])
+	})
+	keys := matches[0]
+	for _, m := range matches[1:] {
+		keys = intersect(keys, m)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys
+}
+
+// intersect returns the keys of a that are in b, both in ascending order
+func intersect(a, b []string) []string {
+	var keys []string
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			keys = append(keys, a[i])
+			i++
+			j++
+		}
+	}
+	return keys
+}
@@ -0,0 +1,46 @@
+package sunduksearch
+
+import (
+	"os"
+	"reflect"
+	"sunduk"
+	"testing"
+)
+
+const (
+	TestStoreFile = "sunduk.data"
+)
+
+func TestStore_Search(t *testing.T) {
+	defer os.Remove(TestStoreFile)
+	defer os.Remove(TestStoreFile + ".lock")
+	store, err := Open(TestStoreFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.PutAll(map[string][]byte{
+		"modbus": []byte("Modbus RTU runs over serial lines, Modbus TCP over Ethernet."),
+		"can":    []byte("CAN bus frames carry up to 8 bytes."),
+		"blob":   {0xff, 0xfe, 0xfd},
+	})
+	_ = store.Put("profinet", []byte("PROFINET is Industrial Ethernet."))
+	for query, want := range map[string][]string{
+		"ethernet":        {"modbus", "profinet"},
+		"Ethernet serial": {"modbus"},
+		"bus":             {"can"},
+		"ethernet can":    nil,
+		"":                nil,
+	} {
+		if got := store.Search(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected keys %v for %q, got %v instead", want, query, got)
+		}
+	}
+	store.Close()
+
+	// The index is persisted along with the values
+	plain := sunduk.New(TestStoreFile, sunduk.WithReadOnly())
+	if got := plain.QueryIndex(IndexName, "ethernet"); !reflect.DeepEqual(got, []string{"modbus", "profinet"}) {
+		t.Errorf("Expected persisted index, got %v instead", got)
+	}
+	plain.Close()
+}
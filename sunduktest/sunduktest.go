@@ -0,0 +1,126 @@
+// Package sunduktest provides a fake store for testing code written against sunduk.Store. The fake keeps its
+// values in memory and injects failures and latency, so applications test their error handling paths
+package sunduktest
+
+import (
+	"sunduk"
+	"sync"
+	"time"
+)
+
+// Option configures the fake returned by NewFake
+type Option func(*Fake)
+
+// ErrAfterNOps makes every operation after the first n fail with err. Failed reads act as if the key had no value,
+// failed listings return no keys
+func ErrAfterNOps(n int, err error) Option {
+	return func(f *Fake) {
+		f.failAfter, f.err = n, err
+	}
+}
+
+// WithLatency delays every operation by d
+func WithLatency(d time.Duration) Option {
+	return func(f *Fake) {
+		f.latency = d
+	}
+}
+
+// Fake is an in-memory sunduk.Store, a sunduk.MemoryStore injecting failures and latency. Put options are ignored.
+// It is safe for concurrent use
+type Fake struct {
+	store     *sunduk.MemoryStore
+	mu        sync.Mutex
+	ops       int
+	closed    bool
+	failAfter int // failAfter is the number of operations before operations fail with err, if err isn't nil
+	err       error
+	latency   time.Duration
+}
+
+var _ sunduk.Store = (*Fake)(nil)
+
+// NewFake returns an empty fake configured with opts
+func NewFake(opts ...Option) *Fake {
+	f := &Fake{store: sunduk.NewMemoryStore()}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Ops returns the number of operations on the fake, failed ones included
+func (f *Fake) Ops() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ops
+}
+
+// op counts an operation after the latency. It returns sunduk.ErrClosed once the fake is closed, or the injected
+// error
+func (f *Fake) op() error {
+	if f.latency > 0 {
+		time.Sleep(f.latency)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ops++
+	switch {
+	case f.closed:
+		return sunduk.ErrClosed
+	case f.err != nil && f.ops > f.failAfter:
+		return f.err
+	}
+	return nil
+}
+
+// Get returns a copy of the value of key and true, or false if the fake has no value for key or the operation fails
+func (f *Fake) Get(key string) ([]byte, bool) {
+	if f.op() != nil {
+		return nil, false
+	}
+	return f.store.Get(key)
+}
+
+// Put sets a copy of value as the value of key
+func (f *Fake) Put(key string, value []byte, opts ...sunduk.PutOption) error {
+	if err := f.op(); err != nil {
+		return err
+	}
+	return f.store.Put(key, value, opts...)
+}
+
+// Delete removes key
+func (f *Fake) Delete(key string) error {
+	if err := f.op(); err != nil {
+		return err
+	}
+	return f.store.Delete(key)
+}
+
+// Keys returns the keys selected by opts, in bytewise ascending order, see sunduk.Sunduk.Keys
+func (f *Fake) Keys(opts ...sunduk.KeysOption) []string {
+	if f.op() != nil {
+		return nil
+	}
+	return f.store.Keys(opts...)
+}
+
+// Count returns the number of entries of the fake, 0 if the operation fails
+func (f *Fake) Count() int {
+	if f.op() != nil {
+		return 0
+	}
+	return f.store.Count()
+}
+
+// Close closes the fake, later operations fail with sunduk.ErrClosed
+func (f *Fake) Close() error {
+	if err := f.op(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	return f.store.Close()
+}
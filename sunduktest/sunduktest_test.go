@@ -0,0 +1,57 @@
+package sunduktest
+
+import (
+	"errors"
+	"strings"
+	"sunduk"
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	f := NewFake()
+	_ = f.Put("b", []byte("banana"))
+	_ = f.Put("a", []byte("apple"))
+	if v, ok := f.Get("a"); !ok || string(v) != "apple" {
+		t.Errorf("Expected 'apple', got '%s' instead", v)
+	}
+	_ = f.Delete("b")
+	if keys := f.Keys(); strings.Join(keys, ",") != "a" || f.Count() != 1 {
+		t.Errorf("Expected keys [a], got %v instead", keys)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Put("c", nil); !errors.Is(err, sunduk.ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v instead", err)
+	}
+	if f.Ops() != 8 {
+		t.Errorf("Expected 8 operations, got %d instead", f.Ops())
+	}
+}
+
+func TestFake_ErrAfterNOps(t *testing.T) {
+	injected := errors.New("disk full")
+	f := NewFake(ErrAfterNOps(2, injected))
+	if err := f.Put("a", []byte("apple")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.Get("a"); !ok {
+		t.Error("Expected a value before the failures")
+	}
+	if err := f.Put("b", []byte("banana")); err != injected {
+		t.Errorf("Expected the injected error, got %v instead", err)
+	}
+	if _, ok := f.Get("a"); ok {
+		t.Error("Expected failed reads to return no value")
+	}
+}
+
+func TestFake_WithLatency(t *testing.T) {
+	f := NewFake(WithLatency(20 * time.Millisecond))
+	start := time.Now()
+	_ = f.Put("a", nil)
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected a latency of 20ms, got %v instead", elapsed)
+	}
+}
@@ -0,0 +1,98 @@
+package sunduk
+
+import (
+	"fmt"
+	"os"
+)
+
+// SwapFile moves the store file at path, such as a newly downloaded version of the store, in place of the store
+// file and switches the store to it atomically. The new file is validated first: its index is read and every
+// value is checked against its checksum, and the store is left as it was if validation fails. Reads are served
+// meanwhile, reads in progress finish on the old file. Pending writes are discarded, as the new file replaces
+// the content of the store. The index file of the new file, if it has one, is moved along with it, see
+// WithIndexFile. The file at path must be on the same file system as the store file
+func (store *Sunduk) SwapFile(path string) error {
+	if store.opts.readOnly {
+		return ErrReadOnly
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	file, err := fsys.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	next := &Sunduk{FilePath: path, index: make(map[string]entry), file: newHandle(file, nil), opts: store.opts, enc: store.enc, counters: &counters{}, access: newAccessStats(store.opts)}
+	if err := next.validate(); err != nil {
+		next.file.release()
+		return fmt.Errorf("unable to swap in %s: %w", path, err)
+	}
+
+	// The index file of the new file is moved next to the index file first, to be recovered on open
+	// if the swap is interrupted before it is in place
+	if next.hasIndexFile() {
+		if err := fsys.Rename(next.indexPath(), store.indexPath()+".new"); err != nil {
+			next.file.release()
+			return fmt.Errorf("unable to move %s to %s: %w", next.indexPath(), store.indexPath(), err)
+		}
+	}
+	// The file stays open across the rename, so the store keeps the file it has validated
+	if err := fsys.Rename(path, store.FilePath); err != nil {
+		next.file.release()
+		if next.hasIndexFile() {
+			_ = fsys.Rename(store.indexPath()+".new", next.indexPath())
+		}
+		return fmt.Errorf("unable to move %s to %s: %w", path, store.FilePath, err)
+	}
+	if next.hasIndexFile() {
+		if next.indexInfo, err = store.replaceIndexFile(); err != nil {
+			// The store file is replaced already, its index file is recovered on open
+			store.opts.logger.Warn("unable to move index file of swapped store file", "file", store.indexPath(), "err", err)
+		}
+	} else if store.hasIndexFile() {
+		store.removeIndexFile()
+	}
+	if err := store.syncEntry(); err != nil {
+		// The store file is replaced already, it may only be lost with the directory entry on a power loss
+		store.opts.logger.Warn("unable to sync directory of swapped store file", "file", store.FilePath, "err", err)
+	}
+
+	store.mu.Lock()
+	previous := store.file
+	store.file = next.file
+	store.leak.track(store.file, store.lock)
+	store.data = make(map[string][]byte)
+	store.pending = newPending()
+	store.cache.clear()
+	store.index = next.index
+	store.lazy = nil
+	store.size = next.size
+	store.tail = next.tail
+	store.indexInfo = next.indexInfo
+	store.legacy = next.legacy
+	store.enc.hash = next.enc.hash
+	store.dict = next.dict
+	if next.collation != nil {
+		store.collation = next.collation
+	}
+	store.setHeader(next.header())
+	store.access.replace(next.access)
+	store.chunks = nil
+	store.mu.Unlock()
+	previous.release()
+	store.loadPins()
+	store.opts.logger.Info("swapped store file", "file", store.FilePath, "from", path, "entries", len(next.index), "size", next.size)
+	return nil
+}
+
+// validate reads the index of the store file and checks every value against its checksum
+func (store *Sunduk) validate() error {
+	if err := store.readFormat(); err != nil {
+		return err
+	}
+	for k, e := range store.index {
+		if _, err := store.readValue(store.file, e); err != nil {
+			return fmt.Errorf("unable to verify value for key %q: %w", k, err)
+		}
+	}
+	return nil
+}
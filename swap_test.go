@@ -0,0 +1,61 @@
+package sunduk
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestSunduk_SwapFile(t *testing.T) {
+	const update = TestStoreFile + ".update"
+	next := New(update)
+	defer deleteStoreFile(update)
+	_ = next.PutAll(map[string][]byte{"plugin.so": []byte("code v2"), "README": []byte("read me")})
+	next.Close()
+
+	store, err := Open(TestStoreFile, WithWriteBuffer(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	_ = store.Put("plugin.so", []byte("code v1"))
+	_ = store.Flush()
+	_ = store.Put("pending", []byte("discarded"))
+	if err := store.SwapFile(update); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "plugin.so", []byte("code v2"))
+	checkValueForKey(t, store, "README", []byte("read me"))
+	checkKeyNotExists(t, store, "pending")
+	if _, err := os.Stat(update); !os.IsNotExist(err) {
+		t.Errorf("Expected swapped file to be moved, got %v instead", err)
+	}
+	_ = store.Put("local", []byte("value"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	checkValueForKey(t, store, "plugin.so", []byte("code v2"))
+	checkValueForKey(t, store, "local", []byte("value"))
+}
+
+func TestSunduk_SwapFileCorrupted(t *testing.T) {
+	const update = TestStoreFile + ".update"
+	next := New(update)
+	defer deleteStoreFile(update)
+	_ = next.PutAll(map[string][]byte{"a": []byte("apple"), "b": []byte("banana")})
+	next.Close()
+	corruptEntry(t, update, "b")
+
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	defer store.Close()
+	_ = store.Put("a", []byte("apricot"))
+	if err := store.SwapFile(update); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum for a corrupted file, got %v instead", err)
+	}
+	checkValueForKey(t, store, "a", []byte("apricot"))
+	if _, err := os.Stat(update); err != nil {
+		t.Errorf("Expected corrupted file to be left in place, got %v instead", err)
+	}
+}
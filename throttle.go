@@ -0,0 +1,49 @@
+package sunduk
+
+import (
+	"sync"
+	"time"
+)
+
+// minThrottleDelay is the delay under which throttled IO doesn't sleep, the delay is carried over to the next IO
+const minThrottleDelay = 10 * time.Millisecond
+
+// throttle limits the rate of IO of maintenance tasks, see WithBackgroundIOLimit
+type throttle struct {
+	rate int64 // rate is the limit in bytes per second
+
+	mu   sync.Mutex
+	next time.Time // next is the time the IO accounted so far is paid off at the rate
+}
+
+// newThrottle returns a throttle limiting IO to rate bytes per second, or nil for no limit
+func newThrottle(rate int64) *throttle {
+	if rate <= 0 {
+		return nil
+	}
+	return &throttle{rate: rate}
+}
+
+// wait accounts n bytes of IO and sleeps until the IO accounted so far fits in the rate, or until stop is closed
+func (t *throttle) wait(n int64, stop <-chan struct{}) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	t.next = t.next.Add(time.Duration(float64(n) / float64(t.rate) * float64(time.Second)))
+	delay := t.next.Sub(now)
+	t.mu.Unlock()
+	if delay < minThrottleDelay {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-stop:
+	}
+}
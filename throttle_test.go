@@ -0,0 +1,37 @@
+package sunduk
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+)
+
+func TestSunduk_BackgroundIOLimit(t *testing.T) {
+	store := New(TestStoreFile, WithBackgroundIOLimit(100<<10))
+	defer deleteTestStoreFile()
+	defer store.Close()
+	for _, k := range []string{"a", "b", "c", "d"} {
+		value := make([]byte, 5<<10)
+		_, _ = rand.Read(value)
+		_ = store.Put(k, value)
+	}
+
+	// 20 KiB of incompressible values are read and written again, which takes about 400ms at 100 KiB/s
+	start := time.Now()
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected compaction to be throttled, it took %v", elapsed)
+	}
+}
+
+func TestThrottle_Stop(t *testing.T) {
+	stop := make(chan struct{})
+	close(stop)
+	start := time.Now()
+	newThrottle(1).wait(1<<20, stop)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected throttled IO to stop waiting once stopped, it took %v", elapsed)
+	}
+}
@@ -0,0 +1,97 @@
+package sunduk
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// States of an open running past its timeout, see Open
+const (
+	loadRunning int32 = iota
+	loadDone
+	loadAbandoned
+)
+
+// WithOpenTimeout makes Open fail with ErrTimeout once opening the store takes longer than d, 0 to wait forever.
+// Reads of files on network file systems can hang rather than fail, and the open is left running in the background:
+// the store file is released once it finishes, and until then opening it again in the process fails with ErrLocked
+func WithOpenTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.openTimeout = d
+	}
+}
+
+// WithReadTimeout makes reads of values from the store file fail with ErrTimeout once they take longer than d,
+// 0 to wait forever. Reads running past the timeout are left running in the background, each in its own goroutine
+func WithReadTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.readTimeout = d
+	}
+}
+
+// withTimeout calls fn and returns its error, or ErrTimeout once fn takes longer than d, leaving fn running in
+// the background. fn is called in the calling goroutine if d is 0
+func withTimeout(d time.Duration, action string, fn func() error) error {
+	if d <= 0 {
+		return fn()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%w: %s took longer than %v", ErrTimeout, action, d)
+	}
+}
+
+// openWithTimeout loads the store within the open timeout. A load finishing after the timeout releases the
+// store file, so that the abandoned store doesn't keep it open and locked
+func (store *Sunduk) openWithTimeout() error {
+	state := loadRunning
+	err := withTimeout(store.opts.openTimeout, "opening "+store.FilePath, func() error {
+		if err := store.loadStore(); err != nil {
+			return err
+		}
+		if !atomic.CompareAndSwapInt32(&state, loadRunning, loadDone) {
+			store.opts.logger.Warn("releasing store opened after timeout", "file", store.FilePath)
+			store.file.release()
+			store.releaseLock()
+			store.unregister()
+		}
+		return nil
+	})
+	// The load may finish between the timeout and now, in which case the store is open after all
+	if errors.Is(err, ErrTimeout) && !atomic.CompareAndSwapInt32(&state, loadRunning, loadAbandoned) {
+		return nil
+	}
+	return err
+}
+
+// readAt reads len(p) bytes from file at off within the read timeout. Past the timeout the read goes on into
+// its own buffer, so p can be reused at once, and keeps its reference to the file until it returns
+func (store *Sunduk) readAt(file *handle, p []byte, off int64) (int, error) {
+	if store.opts.readTimeout <= 0 {
+		return file.ReadAt(p, off)
+	}
+	var n int
+	var buf []byte
+	file = file.acquire()
+	err := withTimeout(store.opts.readTimeout, "reading "+store.FilePath, func() (err error) {
+		defer file.release()
+		buf = make([]byte, len(p))
+		n, err = file.ReadAt(buf, off)
+		return err
+	})
+	if errors.Is(err, ErrTimeout) {
+		return 0, err
+	}
+	copy(p, buf[:n])
+	return n, err
+}
@@ -0,0 +1,74 @@
+package sunduk
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	err := withTimeout(10*time.Millisecond, "hanging", func() error {
+		<-hang
+		return nil
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v instead", err)
+	}
+
+	failed := errors.New("failed")
+	if err := withTimeout(time.Second, "failing", func() error { return failed }); err != failed {
+		t.Errorf("Expected error of fn, got %v instead", err)
+	}
+}
+
+func TestSunduk_WithTimeouts(t *testing.T) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	opts := []Option{WithOpenTimeout(time.Minute), WithReadTimeout(time.Minute), WithCacheSize(0)}
+	store, err := Open(TestStoreFile, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Put("key", []byte("value"))
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = Open(TestStoreFile, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	checkValueForKey(t, store, "key", []byte("value"))
+	if b, ok := store.Borrow("key"); !ok || string(b.Bytes()) != "value" {
+		t.Errorf("Expected to borrow 'value'")
+	} else {
+		b.Release()
+	}
+}
+
+func TestSunduk_OpenTimeoutAbandoned(t *testing.T) {
+	deleteTestStoreFile()
+	defer deleteTestStoreFile()
+	store, err := Open(TestStoreFile, WithOpenTimeout(time.Nanosecond))
+	if err == nil {
+		store.Close()
+	} else if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout or an open store, got %v instead", err)
+	}
+	// The abandoned open releases the store file once it finishes
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		store, err := Open(TestStoreFile)
+		if err == nil {
+			store.Close()
+			break
+		}
+		if !errors.Is(err, ErrLocked) || time.Now().After(deadline) {
+			t.Fatalf("Expected store file to be released, got %v instead", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,193 @@
+package sunduk
+
+import (
+	"sort"
+	"sunduk/internal/format"
+	"sync/atomic"
+	"time"
+)
+
+// defaultTombstoneRetention is the time soft-deleted values are kept by default, see WithTombstoneRetention
+const defaultTombstoneRetention = 7 * 24 * time.Hour
+
+// tombstone holds the entry of a soft-deleted key, see SoftDelete
+type tombstone struct {
+	entry
+	deleted int64 // deleted is the deletion time in unix nanoseconds
+	expires int64 // expires is the expiry time of the value in unix nanoseconds, 0 if it doesn't expire
+}
+
+// WithTombstoneRetention sets the time the values of soft-deleted keys are kept, 7 days by default.
+// Compactions purge the values soft-deleted longer than retention ago, see SoftDelete
+func WithTombstoneRetention(retention time.Duration) Option {
+	return func(o *options) {
+		o.tombstoneRetention = retention
+	}
+}
+
+// SoftDelete deletes key but keeps its value in the store file, so that Undelete restores it, until a compaction
+// runs once the retention time has passed, see WithTombstoneRetention. Soft-deleting a key again replaces the
+// value kept. Pending writes are committed along with the deletion. It returns ErrNotFound if key has no value
+func (store *Sunduk) SoftDelete(key string) error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	// Pending values have no chunk to keep, and legacy files are converted first, as converting copies chunks by key
+	if err := store.flushPending(); err != nil {
+		return err
+	}
+	if store.legacy {
+		if err := store.commit(nil, nil, putOptions{}); err != nil {
+			return err
+		}
+	}
+	store.mu.RLock()
+	e, ok := store.lookup(key)
+	expired, expires := store.expired(key), store.expiresAt(key)
+	store.mu.RUnlock()
+	if !ok || expired {
+		return ErrNotFound
+	}
+
+	t := tombstone{entry: e, deleted: time.Now().UnixNano(), expires: expires}
+	info, err := store.flush(nil, []string{key}, putOptions{tombstones: map[string]tombstone{key: t}})
+	if err != nil {
+		return err
+	}
+	atomic.AddUint64(&store.counters.deletes, 1)
+	for _, h := range store.opts.hooks {
+		if h.OnDelete != nil {
+			h.OnDelete(key)
+		}
+	}
+	store.notifyFlush(info)
+	return nil
+}
+
+// Undelete restores the value of a soft-deleted key, replacing the value of key if it was put again since.
+// The value is copied like Copy copies values, and keeps its expiry time. It returns ErrNotFound if key
+// wasn't soft-deleted, or if its value was purged
+func (store *Sunduk) Undelete(key string) error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if err := store.checkOpen(); err != nil {
+		return err
+	}
+	store.mu.RLock()
+	file := store.file.acquire()
+	t, ok := store.tombstones[key]
+	store.mu.RUnlock()
+	defer file.release()
+	if !ok {
+		return ErrNotFound
+	}
+
+	c, err := store.copyChunk(file, key, map[string]entry{key: t.entry}, nil, false)
+	if err != nil {
+		return err
+	}
+	c.expires = t.expires
+	info, err := store.flush(nil, nil, putOptions{chunks: map[string]chunk{key: c}, undelete: key})
+	if err != nil {
+		return err
+	}
+	store.notifyFlush(info)
+	return nil
+}
+
+// SoftDeleted returns the soft-deleted keys which values can be restored by Undelete, in ascending order
+func (store *Sunduk) SoftDeleted() []string {
+	store.mu.RLock()
+	keys := make([]string, 0, len(store.tombstones))
+	for k := range store.tombstones {
+		keys = append(keys, k)
+	}
+	store.mu.RUnlock()
+	sort.Strings(keys)
+	return keys
+}
+
+// nextTombstones returns the tombstones of the store once the soft deletions and the undeletion of po are
+// committed. It must be called with writeMu held
+func (store *Sunduk) nextTombstones(po putOptions) map[string]tombstone {
+	if len(po.tombstones) == 0 && po.undelete == "" {
+		return store.tombstones
+	}
+	next := make(map[string]tombstone, len(store.tombstones)+len(po.tombstones))
+	for k, t := range store.tombstones {
+		next[k] = t
+	}
+	for k, t := range po.tombstones {
+		next[k] = t
+	}
+	delete(next, po.undelete)
+	if len(next) == 0 {
+		return nil
+	}
+	return next
+}
+
+// rewriteTombstones copies the chunks of the tombstones of the store to the new file of r, unless they are older
+// than the retention time, and returns the tombstones of the new file and the count of tombstones purged.
+// It must be called with writeMu held
+func (store *Sunduk) rewriteTombstones(r *rewrite, recompress func(e entry) bool) (map[string]tombstone, int, error) {
+	if len(store.tombstones) == 0 {
+		return nil, 0, nil
+	}
+	purge := time.Now().Add(-store.opts.tombstoneRetention).UnixNano()
+	var keys []string
+	for k, t := range store.tombstones {
+		if t.deleted > purge {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	entries := make([]entry, len(keys))
+	for i, k := range keys {
+		entries[i] = store.tombstones[k].entry
+	}
+	copied, err := store.rewriteChunks(r, keys, entries, recompress)
+	if err != nil || len(keys) == 0 {
+		return nil, len(store.tombstones) - len(keys), err
+	}
+	next := make(map[string]tombstone, len(keys))
+	for i, k := range keys {
+		t := store.tombstones[k]
+		t.entry = copied[i]
+		next[k] = t
+	}
+	return next, len(store.tombstones) - len(keys), nil
+}
+
+// encodeTombstones returns the tombstone section of the index of the store file for tombstones
+func encodeTombstones(tombstones map[string]tombstone) []format.Tombstone {
+	if len(tombstones) == 0 {
+		return nil
+	}
+	list := make([]format.Tombstone, 0, len(tombstones))
+	for k, t := range tombstones {
+		e := format.Entry{Key: k, Offset: t.Offset, Size: t.Size, RawSize: t.RawSize, Sum: t.Sum, Flags: t.Flags, Base: t.Base}
+		list = append(list, format.Tombstone{Entry: e, Deleted: t.deleted, Expires: t.expires})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// decodeTombstones returns the tombstones of keys for the tombstone section of the index of the store file
+func decodeTombstones(list []format.Tombstone) map[string]tombstone {
+	if len(list) == 0 {
+		return nil
+	}
+	tombstones := make(map[string]tombstone, len(list))
+	for _, t := range list {
+		tombstones[t.Key] = tombstone{entry: newEntry(t.Entry), deleted: t.Deleted, expires: t.Expires}
+	}
+	return tombstones
+}
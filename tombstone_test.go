@@ -0,0 +1,56 @@
+package sunduk
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSunduk_SoftDelete(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": []byte("1"), "b": []byte("2")})
+	if err := store.SoftDelete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SoftDelete("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing key, got %v instead", err)
+	}
+	if store.Has("a") {
+		t.Error("Expected soft-deleted key to be deleted")
+	}
+	if keys := store.SoftDeleted(); !reflect.DeepEqual(keys, []string{"a"}) {
+		t.Errorf("Expected soft-deleted keys [a], got %v instead", keys)
+	}
+
+	// Soft-deleted values are kept by compactions and reopening
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	store = New(TestStoreFile)
+	if err := store.Undelete("a"); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "a", []byte("1"))
+	if keys := store.SoftDeleted(); len(keys) != 0 {
+		t.Errorf("Expected no soft-deleted keys after undelete, got %v instead", keys)
+	}
+	if err := store.Undelete("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for key which isn't soft-deleted, got %v instead", err)
+	}
+	store.Close()
+
+	// Compactions purge values soft-deleted longer than the retention time ago
+	store = New(TestStoreFile, WithTombstoneRetention(time.Millisecond))
+	defer store.Close()
+	_ = store.SoftDelete("b")
+	time.Sleep(2 * time.Millisecond)
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Undelete("b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for purged key, got %v instead", err)
+	}
+}
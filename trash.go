@@ -0,0 +1,224 @@
+package sunduk
+
+import (
+	"sort"
+	"sunduk/internal/format"
+	"time"
+)
+
+// trashed holds the entry of a value overwritten or deleted, see WithTrash
+type trashed struct {
+	entry
+	key        string
+	generation uint64 // generation is the generation of the store once the value was overwritten or deleted
+	time       int64  // time is the time the value was overwritten or deleted in unix nanoseconds
+	expires    int64  // expires is the expiry time of the value in unix nanoseconds, 0 if it doesn't expire
+}
+
+// trashKey identifies a value of the trash
+type trashKey struct {
+	key        string
+	generation uint64
+}
+
+// TrashedValue describes a value of the trash, see WithTrash
+type TrashedValue struct {
+	Key     string
+	Version uint64    // Version identifies the value among the values of key in the trash, see Restore
+	Time    time.Time // Time is when the value was overwritten or deleted
+}
+
+// WithTrash makes the store keep the last size values overwritten or deleted in the store file, so that Undo and
+// Restore bring them back. Values are trashed once their replacement is written to the store file, so the values
+// of pending writes overwritten before they are committed aren't kept, see WithWriteBuffer. Values moved by
+// Rename aren't trashed, the values they replace are
+func WithTrash(size int) Option {
+	return func(o *options) {
+		o.trash = size
+	}
+}
+
+// Trash returns the values of the trash, the last values trashed first
+func (store *Sunduk) Trash() []TrashedValue {
+	store.mu.RLock()
+	trash := store.trash
+	store.mu.RUnlock()
+	list := make([]TrashedValue, len(trash))
+	for i, t := range trash {
+		list[len(trash)-1-i] = TrashedValue{Key: t.key, Version: t.generation, Time: time.Unix(0, t.time)}
+	}
+	return list
+}
+
+// Undo undoes the last operation which trashed values by restoring them, and drops them from the trash.
+// The values it replaces aren't trashed, so that calling it again undoes the operation before.
+// It returns ErrNotFound if the trash is empty
+func (store *Sunduk) Undo() error {
+	return store.untrash(func(trash []trashed) []trashed {
+		if len(trash) == 0 {
+			return nil
+		}
+		last := trash[len(trash)-1].generation
+		i := len(trash) - 1
+		for i > 0 && trash[i-1].generation == last {
+			i--
+		}
+		return trash[i:]
+	}, false)
+}
+
+// Restore restores the value of key in the trash with version, see Trash, and drops it from the trash.
+// The value it replaces is trashed. It returns ErrNotFound if the trash doesn't hold it
+func (store *Sunduk) Restore(key string, version uint64) error {
+	return store.untrash(func(trash []trashed) []trashed {
+		for i, t := range trash {
+			if t.key == key && t.generation == version {
+				return trash[i : i+1]
+			}
+		}
+		return nil
+	}, true)
+}
+
+// untrash restores the values of the trash chosen by choose, trashing the values they replace if trash is true
+func (store *Sunduk) untrash(choose func(trash []trashed) []trashed, trash bool) error {
+	if err := store.writable(); err != nil {
+		return err
+	}
+	store.writeMu.Lock()
+	defer store.writeMu.Unlock()
+	if err := store.checkOpen(); err != nil {
+		return err
+	}
+	store.mu.RLock()
+	file, chosen := store.file.acquire(), choose(store.trash)
+	store.mu.RUnlock()
+	defer file.release()
+	if len(chosen) == 0 {
+		return ErrNotFound
+	}
+
+	chunks := make(map[string]chunk, len(chosen))
+	restored := make(map[trashKey]bool, len(chosen))
+	for _, t := range chosen {
+		c, err := store.copyChunk(file, t.key, map[string]entry{t.key: t.entry}, nil, false)
+		if err != nil {
+			return err
+		}
+		c.expires = t.expires
+		chunks[t.key] = c
+		restored[trashKey{t.key, t.generation}] = true
+	}
+	info, err := store.flush(nil, nil, putOptions{chunks: chunks, restored: restored, untrashed: !trash})
+	if err != nil {
+		return err
+	}
+	store.notifyFlush(info)
+	return nil
+}
+
+// trashed returns the committed values overwritten or deleted by values, deleted keys, and the chunks and the
+// renames of po, applied at generation, nil without WithTrash. It must be called with mu held
+func (store *Sunduk) trashed(values map[string][]byte, deleted []string, po putOptions, generation uint64) []trashed {
+	if store.opts.trash <= 0 || po.repair || po.untrashed {
+		return nil
+	}
+	keys := make([]string, 0, len(values)+len(deleted)+len(po.chunks)+len(po.renames))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	for _, k := range deleted {
+		if _, renamed := po.renames[k]; !renamed {
+			keys = append(keys, k)
+		}
+	}
+	for k := range po.chunks {
+		keys = append(keys, k)
+	}
+	for _, to := range po.renames {
+		keys = append(keys, to)
+	}
+	sort.Strings(keys)
+	now := time.Now().UnixNano()
+	var list []trashed
+	for i, k := range keys {
+		if i > 0 && k == keys[i-1] {
+			continue
+		}
+		// Pending values have no chunk to keep, and values of legacy files are copied by key when converting
+		if e, ok := store.lookup(k); ok && !e.pending && e.hasSum {
+			list = append(list, trashed{entry: e, key: k, generation: generation, time: now, expires: store.expiry[k]})
+		}
+	}
+	return list
+}
+
+// nextTrash returns the trash of the store once the values trashed by a commit are added and the values restored
+// by po are dropped, keeping the last values up to the size of WithTrash. It must be called with writeMu held
+func (store *Sunduk) nextTrash(added []trashed, po putOptions) []trashed {
+	if len(added) == 0 && len(po.restored) == 0 {
+		return store.trash
+	}
+	next := make([]trashed, 0, len(store.trash)+len(added))
+	for _, t := range store.trash {
+		if !po.restored[trashKey{t.key, t.generation}] {
+			next = append(next, t)
+		}
+	}
+	next = append(next, added...)
+	if size := store.opts.trash; size > 0 && len(next) > size {
+		next = next[len(next)-size:]
+	}
+	if len(next) == 0 {
+		return nil
+	}
+	return next
+}
+
+// rewriteTrash copies the chunks of the trash of the store to the new file of r and returns the trash of the
+// new file. It must be called with writeMu held
+func (store *Sunduk) rewriteTrash(r *rewrite, recompress func(e entry) bool) ([]trashed, error) {
+	if len(store.trash) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(store.trash))
+	entries := make([]entry, len(store.trash))
+	for i, t := range store.trash {
+		keys[i], entries[i] = t.key, t.entry
+	}
+	copied, err := store.rewriteChunks(r, keys, entries, recompress)
+	if err != nil {
+		return nil, err
+	}
+	next := make([]trashed, len(store.trash))
+	for i, t := range store.trash {
+		t.entry = copied[i]
+		next[i] = t
+	}
+	return next, nil
+}
+
+// encodeTrash returns the trash section of the index of the store file for trash
+func encodeTrash(trash []trashed) []format.Trashed {
+	if len(trash) == 0 {
+		return nil
+	}
+	list := make([]format.Trashed, len(trash))
+	for i, t := range trash {
+		e := format.Entry{Key: t.key, Offset: t.Offset, Size: t.Size, RawSize: t.RawSize, Sum: t.Sum, Flags: t.Flags, Base: t.Base}
+		list[i] = format.Trashed{Entry: e, Generation: t.generation, Time: t.time, Expires: t.expires}
+	}
+	return list
+}
+
+// decodeTrash returns the trash for the trash section of the index of the store file
+func decodeTrash(list []format.Trashed) []trashed {
+	if len(list) == 0 {
+		return nil
+	}
+	trash := make([]trashed, len(list))
+	for i, t := range list {
+		trash[i] = trashed{entry: newEntry(t.Entry), key: t.Key, generation: t.Generation, time: t.Time, expires: t.Expires}
+	}
+	return trash
+}
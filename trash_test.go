@@ -0,0 +1,53 @@
+package sunduk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSunduk_Undo(t *testing.T) {
+	store := New(TestStoreFile, WithTrash(3))
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": []byte("1"), "b": []byte("2")})
+	_ = store.PutAll(map[string][]byte{"a": []byte("10"), "b": []byte("20")})
+	_ = store.Delete("a")
+	if trash := store.Trash(); len(trash) != 3 || trash[0].Key != "a" || trash[0].Version != store.Generation() {
+		t.Fatalf("Expected 3 trashed values, the deletion of a first, got %v instead", trash)
+	}
+
+	// Values are kept by compactions and reopening
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	store = New(TestStoreFile, WithTrash(3))
+	defer store.Close()
+	if err := store.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "a", []byte("10"))
+	if err := store.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "a", []byte("1"))
+	checkValueForKey(t, store, "b", []byte("2"))
+	if err := store.Undo(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for empty trash, got %v instead", err)
+	}
+
+	// Restore trashes the value it replaces, and the trash keeps the last values
+	_ = store.Put("c", []byte("1"))
+	_ = store.Put("c", []byte("2"))
+	version := store.Generation()
+	_ = store.Put("c", []byte("3"))
+	if err := store.Restore("c", version); err != nil {
+		t.Fatal(err)
+	}
+	checkValueForKey(t, store, "c", []byte("1"))
+	if err := store.Restore("c", version); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for restored value, got %v instead", err)
+	}
+	if trash := store.Trash(); len(trash) != 2 || trash[0].Key != "c" {
+		t.Errorf("Expected values 2 and 3 of c in trash, got %v instead", trash)
+	}
+}
@@ -0,0 +1,153 @@
+package sunduk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sunduk/internal/format"
+	"sync/atomic"
+)
+
+// Value is the value of a key returned by GetLazy, read only as the caller consumes it. It holds the store
+// file it is read from, even once compaction replaces it, so every value must be closed
+type Value struct {
+	store *Sunduk
+	key   string
+	value []byte  // value is the value if it is read already, such as pending or cached values
+	file  *handle // file is the store file holding the chunk of e
+	e     entry
+}
+
+// GetLazy returns the value of a key without reading it, as well as a bool that indicates whether an entry exists
+// for that key, so that callers stream large values, such as to HTTP responses or to files, instead of reading
+// them whole. Values of legacy files and delta-encoded values are read whole
+func (store *Sunduk) GetLazy(key string) (*Value, bool) {
+	if store.authorize(key, OperationRead) != nil {
+		return nil, false
+	}
+	return store.getLazy(key)
+}
+
+// getLazy returns the value of a key without reading it, see GetLazy
+func (store *Sunduk) getLazy(key string) (*Value, bool) {
+	atomic.AddUint64(&store.counters.gets, 1)
+	store.mu.RLock()
+	if !store.mayContain(key) {
+		store.mu.RUnlock()
+		return nil, false
+	}
+	value, ok := store.data[key]
+	if !ok {
+		value, ok = store.cache.get(key)
+	}
+	if ok {
+		store.mu.RUnlock()
+		atomic.AddUint64(&store.counters.cacheHits, 1)
+		store.access.record(key)
+		return &Value{store: store, key: key, value: store.own(value)}, true
+	}
+	e, ok := store.lookup(key)
+	if !ok {
+		store.mu.RUnlock()
+		return nil, false
+	}
+	atomic.AddUint64(&store.counters.cacheMisses, 1)
+	file := store.file.acquire()
+	store.mu.RUnlock()
+	if file != nil && e.hasSum && e.Flags&format.FlagDelta == 0 {
+		store.access.record(key)
+		return &Value{store: store, key: key, file: file, e: e}, true
+	}
+	value, repaired, err := store.fetch(file, key, e)
+	file.release()
+	if err != nil {
+		return nil, false
+	}
+	store.access.record(key)
+	if repaired {
+		store.rewriteRepaired(key, e, value)
+	} else {
+		store.cacheRead(key, e, value)
+	}
+	return &Value{store: store, key: key, value: value}, true
+}
+
+// Size returns the size of the value
+func (v *Value) Size() int64 {
+	if v.file == nil {
+		return int64(len(v.value))
+	}
+	return v.e.RawSize
+}
+
+// Reader returns a reader of the value from its beginning. The value is verified against its checksum once it is
+// read to the end, the reader fails with ErrChecksum instead of io.EOF if it doesn't match
+func (v *Value) Reader() io.Reader {
+	if v.file == nil {
+		return bytes.NewReader(v.value)
+	}
+	e, store := v.e, v.store
+	var r io.Reader
+	if e.Flags&format.FlagAppend != 0 {
+		r = io.MultiReader(store.chunkReader(v.file, e.Base.Offset, e.Base.Size, e.Base.Flags), store.chunkReader(v.file, e.Offset, e.Size, e.Flags))
+	} else {
+		r = store.chunkReader(v.file, e.Offset, e.Size, e.Flags)
+	}
+	return &verifyingReader{r: r, size: e.RawSize, sum: e.Sum}
+}
+
+// Bytes reads the whole value. Values failing checksum verification are repaired like with Get
+func (v *Value) Bytes() ([]byte, error) {
+	if v.file == nil {
+		return v.value, nil
+	}
+	value, repaired, err := v.store.fetch(v.file, v.key, v.e)
+	if err != nil {
+		return nil, err
+	}
+	if repaired {
+		v.store.rewriteRepaired(v.key, v.e, value)
+	}
+	return value, nil
+}
+
+// WriteTo writes the value to w, streaming it from the store file. A value failing checksum verification
+// fails with ErrChecksum once it is written
+func (v *Value) WriteTo(w io.Writer) (int64, error) {
+	if v.file == nil {
+		n, err := w.Write(v.value)
+		return int64(n), err
+	}
+	return io.Copy(w, v.Reader())
+}
+
+// Close releases the store file the value is read from, the value must not be read afterwards
+func (v *Value) Close() error {
+	if v.file != nil {
+		v.file.release()
+		v.file = nil
+	}
+	return nil
+}
+
+// verifyingReader reads a value and verifies its size and checksum once read to the end
+type verifyingReader struct {
+	r    io.Reader
+	size int64
+	sum  uint32
+	n    int64
+	crc  uint32
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	r.crc = format.UpdateChecksum(r.crc, p[:n])
+	if err == io.EOF && (r.n != r.size || r.crc != r.sum) {
+		return n, fmt.Errorf("%w: value of %d bytes read instead of %d", ErrChecksum, r.n, r.size)
+	}
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %v", ErrChecksum, err)
+	}
+	return n, err
+}
@@ -0,0 +1,78 @@
+package sunduk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSunduk_GetLazy(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	large := bytes.Repeat([]byte("compressible "), 100)
+	_ = store.Put("raw", []byte("raw value"), Uncompressed())
+	_ = store.Put("compressed", large)
+	_ = store.Put("appended", []byte("first "))
+	_ = store.Append("appended", []byte("second"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	for key, expected := range map[string][]byte{"raw": []byte("raw value"), "compressed": large, "appended": []byte("first second")} {
+		v, ok := store.GetLazy(key)
+		if !ok {
+			t.Fatalf("Expected a value for key %s", key)
+		}
+		if v.Size() != int64(len(expected)) {
+			t.Errorf("Expected size %d of key %s, got %d instead", len(expected), key, v.Size())
+		}
+		read, err := io.ReadAll(v.Reader())
+		if err != nil || !bytes.Equal(read, expected) {
+			t.Errorf("Expected to read %q for key %s, got %q (%v) instead", expected, key, read, err)
+		}
+		var buf bytes.Buffer
+		if n, err := v.WriteTo(&buf); err != nil || n != int64(len(expected)) || !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("Expected to write %q for key %s, got %q (%v) instead", expected, key, buf.Bytes(), err)
+		}
+		if value, err := v.Bytes(); err != nil || !bytes.Equal(value, expected) {
+			t.Errorf("Expected bytes %q for key %s, got %q (%v) instead", expected, key, value, err)
+		}
+		_ = v.Close()
+	}
+	if _, ok := store.GetLazy("missing"); ok {
+		t.Error("Expected no value for missing key")
+	}
+
+	_ = store.Put("cached", []byte("cached value"))
+	v, ok := store.GetLazy("cached")
+	if !ok || v.Size() != 12 {
+		t.Fatalf("Expected a value of 12 bytes for cached key, got %v instead", ok)
+	}
+	if value, _ := v.Bytes(); string(value) != "cached value" {
+		t.Errorf("Expected 'cached value', got %q instead", value)
+	}
+	_ = v.Close()
+}
+
+func TestSunduk_GetLazyCorrupted(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"), Uncompressed())
+	store.Close()
+	corruptEntry(t, TestStoreFile, "key")
+
+	store = New(TestStoreFile)
+	defer store.Close()
+	v, ok := store.GetLazy("key")
+	if !ok {
+		t.Fatal("Expected a value for corrupted key before it is read")
+	}
+	defer v.Close()
+	if _, err := io.ReadAll(v.Reader()); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum reading a corrupted value, got %v instead", err)
+	}
+	if _, err := v.WriteTo(io.Discard); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected ErrChecksum writing a corrupted value, got %v instead", err)
+	}
+}
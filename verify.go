@@ -0,0 +1,27 @@
+package sunduk
+
+import "fmt"
+
+// Verify reads every entry of the store and checks its value against the checksum recorded in the index.
+// It returns an error for the first entry failing verification, wrapping ErrChecksum for corrupted values.
+// Values of legacy files, which have no checksums, are only checked to decompress. Pending writes aren't verified
+func (store *Sunduk) Verify() error {
+	store.mu.RLock()
+	file, index := store.file.acquire(), store.loadIndex()
+	store.mu.RUnlock()
+	defer file.release()
+
+	keys := newOrderedKeys(index)
+	var read int64
+	for i, k := range keys.keys {
+		if !index[k].pending {
+			if _, err := store.readValue(file, index[k]); err != nil {
+				store.opts.logger.Error("corrupted entry detected", "file", store.FilePath, "key", k, "err", err)
+				return fmt.Errorf("unable to verify value for key %q: %w", k, err)
+			}
+			read += index[k].Size
+		}
+		store.progress(Progress{Operation: OperationVerify, Done: i + 1, Total: keys.Len(), Bytes: read})
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package sunduk
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sunduk/internal/format"
+	"testing"
+)
+
+func TestNew_WritesEmptyStore(t *testing.T) {
+	deleteTestStoreFile()
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	store.Close()
+
+	data, _ := os.ReadFile(TestStoreFile)
+	index, err := format.ReadIndex(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Expected new store file to be a valid empty store, got %v instead", err)
+	}
+	if len(index.Entries) != 0 {
+		t.Errorf("Expected 0 entries in the index, got %d instead", len(index.Entries))
+	}
+
+	store = New(TestStoreFile)
+	if store.Count() != 0 {
+		t.Errorf("Expected to have 0 entries, but got %d instead", store.Count())
+	}
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected empty store to verify, got %v instead", err)
+	}
+	if err := store.Compact(); err != nil {
+		t.Errorf("Expected empty store to compact, got %v instead", err)
+	}
+	store.Close()
+}
+
+func TestNew_WithEmptyFile(t *testing.T) {
+	if err := os.WriteFile(TestStoreFile, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	defer deleteTestStoreFile()
+	store, err := Open(TestStoreFile)
+	if err != nil {
+		t.Fatalf("Expected empty file to open as an empty store, got %v instead", err)
+	}
+	_ = store.Put("key", []byte("value"))
+	store.Close()
+
+	store = New(TestStoreFile)
+	checkValueForKey(t, store, "key", []byte("value"))
+	store.Close()
+}
+
+func TestSunduk_DeleteLastKey(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.Put("key", []byte("value"))
+	if err := store.Verify(); err != nil {
+		t.Errorf("Expected single-key store to verify, got %v instead", err)
+	}
+	_ = store.Delete("key")
+	store.Close()
+
+	store = New(TestStoreFile)
+	if store.Count() != 0 {
+		t.Errorf("Expected to have 0 entries, but got %d instead", store.Count())
+	}
+	store.Close()
+}
+
+func TestSunduk_VerifyCorrupted(t *testing.T) {
+	store := New(TestStoreFile)
+	defer deleteTestStoreFile()
+	_ = store.PutAll(map[string][]byte{"a": []byte("apple"), "b": []byte("banana")})
+	store.Close()
+	corruptEntry(t, TestStoreFile, "b")
+
+	store = New(TestStoreFile)
+	if err := store.Verify(); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Expected Verify to return ErrChecksum, got %v instead", err)
+	}
+	store.Close()
+}